@@ -0,0 +1,44 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package batch
+
+// Commitments contains the update and recovery commitments of a document
+type Commitments struct {
+	UpdateCommitment   string `json:"updateCommitment"`
+	RecoveryCommitment string `json:"recoveryCommitment"`
+}
+
+// OperationHistoryEntry describes an anchored operation of a document and the outcome of applying it
+type OperationHistoryEntry struct {
+	Type OperationType `json:"type"`
+
+	// AnchorString is the anchor string of the transaction the operation was batched within
+	AnchorString string `json:"anchorString"`
+
+	// TransactionTime is the logical blockchain time that the operation was anchored on the blockchain
+	TransactionTime uint64 `json:"transactionTime"`
+
+	// TransactionNumber is the transaction number of the transaction the operation was batched within
+	TransactionNumber uint64 `json:"transactionNumber"`
+
+	// OperationIndex is the index of the operation in the batch
+	OperationIndex uint `json:"operationIndex"`
+
+	// Applied is true if the operation was applied to the document
+	Applied bool `json:"applied"`
+
+	// Reason is the reason the operation wasn't applied
+	Reason string `json:"reason,omitempty"`
+
+	// CommitmentsBefore contains the commitments of the document before the operation was applied
+	// (or when the document was resolved if the operation wasn't applied)
+	CommitmentsBefore *Commitments `json:"commitmentsBefore,omitempty"`
+
+	// CommitmentsAfter contains the commitments of the document after the operation was applied
+	// (the same as CommitmentsBefore if the operation wasn't applied)
+	CommitmentsAfter *Commitments `json:"commitmentsAfter,omitempty"`
+}
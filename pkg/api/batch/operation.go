@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package batch
 
 import (
+	"time"
+
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
 )
 
@@ -48,6 +50,8 @@ type Operation struct {
 	TransactionNumber uint64 `json:"transactionNumber"`
 	//The index this operation was assigned to in the batch
 	OperationIndex uint `json:"operationIndex"`
+	//The anchor string of the transaction this operation was batched within
+	AnchorString string `json:"anchorString"`
 }
 
 // OperationType defines valid values for operation type
@@ -73,4 +77,32 @@ type OperationInfo struct {
 	Data         []byte
 	UniqueSuffix string
 	Namespace    string
+
+	// Type is the operation type (used for prioritizing operations in the queue)
+	Type OperationType
+
+	// Time is the time that the operation was added to a priority queue
+	Time time.Time
+}
+
+// RejectedOperation contains an operation that was rejected by the batch writer
+// since it can never be processed, along with the reason for the rejection
+type RejectedOperation struct {
+	*OperationInfo
+
+	// Reason is the reason the operation was rejected
+	Reason string
+}
+
+// OutstandingAnchor contains an anchor that was written to the ledger by the batch writer
+// but hasn't been observed in a Sidetree transaction yet, along with the operations of the batch
+type OutstandingAnchor struct {
+	AnchorString string       `json:"anchorString"`
+	Operations   []*Operation `json:"operations"`
+
+	// Attempts is the number of times that the anchor was written
+	Attempts uint `json:"attempts"`
+
+	// WrittenBlock is the last observed transaction time when the anchor was written
+	WrittenBlock uint64 `json:"writtenBlock"`
 }
@@ -0,0 +1,65 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package batch
+
+import (
+	"time"
+)
+
+// OperationState defines the states in the lifecycle of an operation
+type OperationState string
+
+const (
+
+	// OperationStateQueued captures "queued" state: the operation was accepted and added to the batch queue
+	OperationStateQueued OperationState = "queued"
+
+	// OperationStateBatched captures "batched" state: the operation was added to batch files and the anchor string was written
+	OperationStateBatched OperationState = "batched"
+
+	// OperationStateAnchored captures "anchored" state: the operation was observed in a Sidetree transaction
+	OperationStateAnchored OperationState = "anchored"
+
+	// OperationStateAccepted captures "accepted" state: the anchored operation was validated and persisted
+	OperationStateAccepted OperationState = "accepted"
+
+	// OperationStateRejected captures "rejected" state: the anchored operation was found to be invalid
+	OperationStateRejected OperationState = "rejected"
+)
+
+// OperationStatus contains a state transition of an operation
+type OperationStatus struct {
+
+	// TrackingID identifies the operation throughout its lifecycle
+	TrackingID string `json:"trackingId"`
+
+	Namespace string `json:"namespace"`
+
+	UniqueSuffix string `json:"uniqueSuffix"`
+
+	Type OperationType `json:"type"`
+
+	State OperationState `json:"state"`
+
+	// Time is the time of the state transition
+	Time time.Time `json:"time"`
+
+	// AnchorString is the anchor string of the batch that contains the operation
+	AnchorString string `json:"anchorString,omitempty"`
+
+	// TransactionTime is the logical blockchain time that the operation was anchored on the blockchain
+	TransactionTime uint64 `json:"transactionTime,omitempty"`
+
+	// TransactionNumber is the transaction number of the transaction the operation was batched within
+	TransactionNumber uint64 `json:"transactionNumber,omitempty"`
+
+	// OperationIndex is the index of the operation in the batch
+	OperationIndex uint `json:"operationIndex,omitempty"`
+
+	// Reason is the reason the operation was rejected
+	Reason string `json:"reason,omitempty"`
+}
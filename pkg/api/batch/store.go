@@ -0,0 +1,18 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package batch
+
+// OperationStoreTxn stages the operations of a Sidetree transaction until they are committed
+type OperationStoreTxn interface {
+	// Put stages the given operation under the given idempotency key. The operation must be ignored
+	// if an operation with the same key was already committed.
+	Put(key string, op *Operation) error
+	// Commit stores all of the staged operations atomically
+	Commit() error
+	// Rollback discards the staged operations
+	Rollback() error
+}
@@ -6,6 +6,13 @@ SPDX-License-Identifier: Apache-2.0
 
 package protocol
 
+import (
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+	"github.com/trustbloc/sidetree-core-go/pkg/patch"
+)
+
 // Protocol defines protocol parameters
 type Protocol struct {
 	// StartingBlockChainTime is inclusive starting logical blockchain time that this protocol applies to.
@@ -29,11 +36,93 @@ type Protocol struct {
 // Client defines interface for accessing protocol version/information
 type Client interface {
 
-	// Current returns latest version of protocol
+	// Current returns the version of protocol that is in force at the current ledger time
 	Current() Protocol
+
+	// Get returns the version of protocol that applies at the given transaction time
+	Get(transactionTime uint64) (Protocol, error)
 }
 
 // ClientProvider returns a protocol client for the given namespace
 type ClientProvider interface {
 	ForNamespace(namespace string) (Client, error)
 }
+
+// ResolutionModel contains the document state that results from applying operations
+type ResolutionModel struct {
+	Doc                            document.Document
+	LastOperationTransactionTime   uint64
+	LastOperationTransactionNumber uint64
+	UpdateCommitment               string
+	RecoveryCommitment             string
+}
+
+// DocumentState is the materialized state of a document, i.e. the resolution model that results from applying
+// the operations of the document along with the version IDs of the operations that were considered
+type DocumentState struct {
+	ResolutionModel
+
+	UniqueSuffix string
+	// AppliedOperations contains the version IDs of the operations that were applied (in the order they were applied)
+	AppliedOperations []string
+	// UnappliedOperations contains the version IDs of the operations that could not be applied
+	UnappliedOperations []string
+	// MaxTransactionTime is the greatest transaction time of the operations that were considered
+	MaxTransactionTime uint64
+}
+
+// OperationParser parses an operation request into an operation
+type OperationParser interface {
+	// Parse parses and validates the given operation request
+	Parse(namespace string, operationBuffer []byte) (*batch.Operation, error)
+}
+
+// OperationApplier applies operations to the resolution model
+type OperationApplier interface {
+	// Apply validates the given operation against the resolution model and returns the new resolution model
+	Apply(op *batch.Operation, rm *ResolutionModel) (*ResolutionModel, error)
+}
+
+// DocumentComposer applies patches to the document
+type DocumentComposer interface {
+	// ApplyPatches applies the given patches to the document and returns the new document
+	ApplyPatches(doc document.Document, patches []patch.Patch) (document.Document, error)
+}
+
+// OperationHandler creates batch files(chunk, map, anchor) from batch operations
+type OperationHandler interface {
+	// PrepareTxnFiles will create batch files, store them in CAS and return anchor string along with the number
+	// of operations (from the start of ops) in the batch, which may be less than all of the operations
+	// if their batch files would exceed the maximum file sizes
+	PrepareTxnFiles(ops []*batch.Operation) (string, int, error)
+}
+
+// OperationProvider assembles batch operations from batch files(chunk, map, anchor)
+type OperationProvider interface {
+	// GetTxnOperations will read batch files and assemble batch operations from those files
+	GetTxnOperations(txn *txn.SidetreeTxn) ([]*batch.Operation, error)
+}
+
+// Version contains the protocol parameters and the protocol-specific implementations of a protocol version
+type Version interface {
+	Protocol() Protocol
+	OperationParser() OperationParser
+	OperationApplier() OperationApplier
+	DocumentComposer() DocumentComposer
+	OperationHandler() OperationHandler
+	OperationProvider() OperationProvider
+}
+
+// VersionManager returns the protocol version that applies at a given transaction time
+type VersionManager interface {
+	// Current returns the version of protocol that is in force at the current ledger time
+	Current() (Version, error)
+
+	// Get returns the version of protocol that applies at the given transaction time
+	Get(transactionTime uint64) (Version, error)
+}
+
+// VersionManagerProvider returns a version manager for the given namespace
+type VersionManagerProvider interface {
+	ForNamespace(namespace string) (VersionManager, error)
+}
@@ -6,6 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 
 package txn
 
+import (
+	"time"
+)
+
 // SidetreeTxn defines info about sidetree transaction
 type SidetreeTxn struct {
 	TransactionTime   uint64
@@ -13,3 +17,42 @@ type SidetreeTxn struct {
 	AnchorString      string
 	Namespace         string
 }
+
+// Checkpoint identifies the last Sidetree transaction that was fully processed for a namespace
+type Checkpoint struct {
+	TransactionTime   uint64
+	TransactionNumber uint64
+}
+
+// Fork notifies that the ledger was reorganized. The transactions after the rollback point
+// are no longer part of the canonical ledger.
+type Fork struct {
+	// RollbackPoint is the last transaction that is still part of the canonical ledger
+	RollbackPoint Checkpoint
+}
+
+// PendingTxn is a Sidetree transaction that couldn't be processed (e.g. since its batch files
+// were temporarily unavailable in CAS) and is pending retry
+type PendingTxn struct {
+	SidetreeTxn
+
+	// Attempts is the number of times that processing the transaction was attempted
+	Attempts uint
+	// LastError is the error of the last attempt
+	LastError string
+	// NextRetry is the time of the next attempt
+	NextRetry time.Time
+	// Subsequent contains the transactions of the same namespace that were processed after the transaction
+	// failed and that rejected operations. They are reprocessed for the rejected suffixes that are also
+	// suffixes of the transaction once the transaction is processed.
+	Subsequent []SubsequentTxn
+}
+
+// SubsequentTxn is a Sidetree transaction that was processed after a pending transaction
+// along with the suffixes whose operations it rejected
+type SubsequentTxn struct {
+	SidetreeTxn
+
+	// Suffixes contains the unique suffixes of the rejected operations
+	Suffixes []string
+}
@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchorstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+const (
+	fileExt    = ".json"
+	tmpFileExt = ".tmp"
+)
+
+// FileStore implements a durable store for the anchors that the batch writer is waiting to observe on the ledger.
+// Each anchor is stored in its own file in the store directory. An anchor is written to a temporary file that
+// is renamed once it's complete, so an anchor that was only partially written (due to a crash) is never loaded.
+type FileStore struct {
+	mutex sync.Mutex
+	dir   string
+}
+
+// NewFileStore opens the file store in the given directory (the directory is created if it doesn't exist)
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create anchor store directory [%s]: %s", dir, err.Error())
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// Put stores the given anchor (replacing the anchor with the same anchor string).
+// The anchor is persisted before Put returns.
+func (s *FileStore) Put(anchor *batch.OutstandingAnchor) error {
+	content, err := json.Marshal(anchor)
+	if err != nil {
+		return fmt.Errorf("failed to marshal anchor[%s]: %s", anchor.AnchorString, err.Error())
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := s.path(anchor.AnchorString)
+	tmpPath := path + tmpFileExt
+
+	if err := writeFile(tmpPath, content); err != nil {
+		return fmt.Errorf("failed to write anchor[%s]: %s", anchor.AnchorString, err.Error())
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write anchor[%s]: %s", anchor.AnchorString, err.Error())
+	}
+
+	return syncDir(s.dir)
+}
+
+// Delete deletes the anchor with the given anchor string
+func (s *FileStore) Delete(anchorString string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.Remove(s.path(anchorString)); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to delete anchor[%s]: %s", anchorString, err.Error())
+	}
+
+	return syncDir(s.dir)
+}
+
+// GetAll returns all of the stored anchors
+func (s *FileStore) GetAll() ([]*batch.OutstandingAnchor, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anchor store directory [%s]: %s", s.dir, err.Error())
+	}
+
+	var anchors []*batch.OutstandingAnchor
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), fileExt) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read anchor file [%s]: %s", file.Name(), err.Error())
+		}
+
+		anchor := &batch.OutstandingAnchor{}
+		if err := json.Unmarshal(content, anchor); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal anchor file [%s]: %s", file.Name(), err.Error())
+		}
+
+		anchors = append(anchors, anchor)
+	}
+
+	return anchors, nil
+}
+
+// path returns the path of the file for the given anchor string. The anchor string is hashed
+// since it may contain characters that aren't allowed in file names.
+func (s *FileStore) path(anchorString string) string {
+	hash := sha256.Sum256([]byte(anchorString))
+
+	return filepath.Join(s.dir, hex.EncodeToString(hash[:])+fileExt)
+}
+
+func writeFile(path string, content []byte) error {
+	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if e := f.Close(); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return fmt.Errorf("failed to open anchor store directory: %s", err.Error())
+	}
+
+	err = d.Sync()
+
+	if e := d.Close(); err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to sync anchor store directory: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchorstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+func TestFileStore(t *testing.T) {
+	anchor1 := &batch.OutstandingAnchor{AnchorString: "1.anchor/1", Operations: []*batch.Operation{{UniqueSuffix: "op1"}}, Attempts: 1, WrittenBlock: 5}
+	anchor2 := &batch.OutstandingAnchor{AnchorString: "1.anchor/2", Operations: []*batch.Operation{{UniqueSuffix: "op2"}}, Attempts: 1, WrittenBlock: 6}
+
+	t.Run("success", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		s, err := NewFileStore(dir)
+		require.NoError(t, err)
+
+		anchors, err := s.GetAll()
+		require.NoError(t, err)
+		require.Empty(t, anchors)
+
+		require.NoError(t, s.Put(anchor1))
+		require.NoError(t, s.Put(anchor2))
+
+		updated := *anchor1
+		updated.Attempts = 2
+		require.NoError(t, s.Put(&updated))
+
+		// the anchors survive re-opening the store
+		s, err = NewFileStore(dir)
+		require.NoError(t, err)
+
+		anchors, err = s.GetAll()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []*batch.OutstandingAnchor{&updated, anchor2}, anchors)
+
+		require.NoError(t, s.Delete(anchor2.AnchorString))
+		require.NoError(t, s.Delete("unknown"))
+
+		anchors, err = s.GetAll()
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OutstandingAnchor{&updated}, anchors)
+	})
+
+	t.Run("partially written anchor is ignored", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		s, err := NewFileStore(dir)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Put(anchor1))
+		require.NoError(t, ioutil.WriteFile(s.path(anchor2.AnchorString)+tmpFileExt, []byte(`{"anchorStr`), 0600))
+
+		anchors, err := s.GetAll()
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OutstandingAnchor{anchor1}, anchors)
+	})
+
+	t.Run("invalid anchor file", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		s, err := NewFileStore(dir)
+		require.NoError(t, err)
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid"+fileExt), []byte("invalid"), 0600))
+
+		anchors, err := s.GetAll()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal anchor file")
+		require.Nil(t, anchors)
+	})
+
+	t.Run("directory error", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		file := filepath.Join(dir, "file")
+		require.NoError(t, ioutil.WriteFile(file, []byte("file"), 0600))
+
+		s, err := NewFileStore(filepath.Join(file, "anchors"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create anchor store directory")
+		require.Nil(t, s)
+	})
+}
+
+func newTestDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "anchorstore")
+	require.NoError(t, err)
+
+	return dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchorstore
+
+import (
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+// MemStore implements an in-memory store for the anchors that the batch writer is waiting to observe on the ledger.
+// The anchors don't survive process restarts so MemStore should only be used along with a non-durable operation queue.
+type MemStore struct {
+	mutex   sync.RWMutex
+	anchors map[string]*batch.OutstandingAnchor
+}
+
+// NewMemStore returns a new in-memory anchor store
+func NewMemStore() *MemStore {
+	return &MemStore{anchors: make(map[string]*batch.OutstandingAnchor)}
+}
+
+// Put stores the given anchor (replacing the anchor with the same anchor string)
+func (s *MemStore) Put(anchor *batch.OutstandingAnchor) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a := *anchor
+	s.anchors[anchor.AnchorString] = &a
+
+	return nil
+}
+
+// Delete deletes the anchor with the given anchor string
+func (s *MemStore) Delete(anchorString string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.anchors, anchorString)
+
+	return nil
+}
+
+// GetAll returns all of the stored anchors
+func (s *MemStore) GetAll() ([]*batch.OutstandingAnchor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var anchors []*batch.OutstandingAnchor
+	for _, anchor := range s.anchors {
+		a := *anchor
+		anchors = append(anchors, &a)
+	}
+
+	return anchors, nil
+}
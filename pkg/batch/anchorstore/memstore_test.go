@@ -0,0 +1,44 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchorstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+func TestMemStore(t *testing.T) {
+	anchor1 := &batch.OutstandingAnchor{AnchorString: "1.anchor1", Operations: []*batch.Operation{{UniqueSuffix: "op1"}}, Attempts: 1}
+	anchor2 := &batch.OutstandingAnchor{AnchorString: "1.anchor2", Operations: []*batch.Operation{{UniqueSuffix: "op2"}}, Attempts: 1}
+
+	s := NewMemStore()
+
+	anchors, err := s.GetAll()
+	require.NoError(t, err)
+	require.Empty(t, anchors)
+
+	require.NoError(t, s.Put(anchor1))
+	require.NoError(t, s.Put(anchor2))
+
+	anchors, err = s.GetAll()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*batch.OutstandingAnchor{anchor1, anchor2}, anchors)
+
+	updated := *anchor1
+	updated.Attempts = 2
+	require.NoError(t, s.Put(&updated))
+
+	require.NoError(t, s.Delete(anchor2.AnchorString))
+	require.NoError(t, s.Delete("unknown"))
+
+	anchors, err = s.GetAll()
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OutstandingAnchor{&updated}, anchors)
+}
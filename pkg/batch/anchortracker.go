@@ -0,0 +1,248 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package batch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+// Ledger provides the Sidetree transactions that are observed on the ledger
+type Ledger interface {
+	RegisterForSidetreeTxn() <-chan []txn.SidetreeTxn
+}
+
+// AnchorStore defines an interface for persisting the anchors that were written to the ledger but haven't been
+// observed yet. The operations of an anchor are removed from the operation queue once the anchor is written,
+// so a durable operation queue requires a durable anchor store in order to track the anchors across restarts.
+type AnchorStore interface {
+
+	// Put stores the given anchor (replacing the anchor with the same anchor string)
+	Put(anchor *batch.OutstandingAnchor) error
+
+	// Delete deletes the anchor with the given anchor string
+	Delete(anchorString string) error
+
+	// GetAll returns all of the stored anchors
+	GetAll() ([]*batch.OutstandingAnchor, error)
+}
+
+// ConfirmationOptions defines when an anchor that was written to the ledger but hasn't been observed
+// in a Sidetree transaction is written again
+type ConfirmationOptions struct {
+	// Ledger provides the observed Sidetree transactions
+	Ledger Ledger
+	// Timeout is the time after which an anchor that hasn't been observed is written again (zero disables the timeout)
+	Timeout time.Duration
+	// MaxBlocks is the number of blocks (transaction times) after which an anchor that hasn't been observed
+	// is written again (zero disables the block limit)
+	MaxBlocks uint64
+	// MaxAttempts is the maximum number of times that an anchor is written
+	MaxAttempts uint
+}
+
+// AnchorNotConfirmedError is sent to the error channel when an anchor was not observed
+// on the ledger after the maximum number of attempts
+type AnchorNotConfirmedError struct {
+	AnchorString string
+	Attempts     uint
+}
+
+// Error returns the reason for the error
+func (e *AnchorNotConfirmedError) Error() string {
+	return fmt.Sprintf("anchor[%s] was not observed on the ledger after %d attempts", e.AnchorString, e.Attempts)
+}
+
+// outstandingAnchor is an anchor that was written to the ledger but hasn't been observed yet
+type outstandingAnchor struct {
+	anchorString string
+	ops          []*batch.Operation
+	writtenTime  time.Time
+	// writtenBlock is the last observed transaction time when the anchor was written
+	writtenBlock uint64
+	attempts     uint
+}
+
+func (a *outstandingAnchor) record() *batch.OutstandingAnchor {
+	return &batch.OutstandingAnchor{
+		AnchorString: a.anchorString,
+		Operations:   a.ops,
+		Attempts:     a.attempts,
+		WrittenBlock: a.writtenBlock,
+	}
+}
+
+// anchorTracker keeps track of the anchors that were written to the ledger until they are observed.
+// The outstanding anchors are persisted to the anchor store (if provided).
+type anchorTracker struct {
+	ConfirmationOptions
+
+	mutex       sync.Mutex
+	store       AnchorStore
+	outstanding map[string]*outstandingAnchor
+	lastBlock   uint64
+}
+
+func newAnchorTracker(opts ConfirmationOptions, store AnchorStore) *anchorTracker {
+	return &anchorTracker{
+		ConfirmationOptions: opts,
+		store:               store,
+		outstanding:         make(map[string]*outstandingAnchor),
+	}
+}
+
+// load starts tracking the anchors in the anchor store and returns them. The timeout of the loaded anchors starts over.
+func (t *anchorTracker) load() ([]*outstandingAnchor, error) {
+	if t.store == nil {
+		return nil, nil
+	}
+
+	records, err := t.store.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var anchors []*outstandingAnchor
+
+	for _, record := range records {
+		anchor := &outstandingAnchor{
+			anchorString: record.AnchorString,
+			ops:          record.Operations,
+			writtenTime:  time.Now(),
+			writtenBlock: record.WrittenBlock,
+			attempts:     record.Attempts,
+		}
+
+		t.outstanding[anchor.anchorString] = anchor
+		anchors = append(anchors, anchor)
+	}
+
+	return anchors, nil
+}
+
+// add starts tracking the given anchor. The anchor isn't tracked if it can't be persisted.
+func (t *anchorTracker) add(anchorString string, ops []*batch.Operation) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	anchor := &outstandingAnchor{
+		anchorString: anchorString,
+		ops:          ops,
+		writtenTime:  time.Now(),
+		writtenBlock: t.lastBlock,
+		attempts:     1,
+	}
+
+	if t.store != nil {
+		if err := t.store.Put(anchor.record()); err != nil {
+			return fmt.Errorf("failed to store anchor[%s]: %s", anchorString, err)
+		}
+	}
+
+	t.outstanding[anchorString] = anchor
+
+	return nil
+}
+
+// observed stops tracking the anchors of the given transactions and returns the number of anchors that were confirmed
+func (t *anchorTracker) observed(txns []txn.SidetreeTxn) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	confirmed := 0
+
+	for _, sidetreeTxn := range txns {
+		if sidetreeTxn.TransactionTime > t.lastBlock {
+			t.lastBlock = sidetreeTxn.TransactionTime
+		}
+
+		if _, ok := t.outstanding[sidetreeTxn.AnchorString]; ok {
+			log.Debugf("anchor[%s] was observed at transaction time %d", sidetreeTxn.AnchorString, sidetreeTxn.TransactionTime)
+
+			t.delete(sidetreeTxn.AnchorString)
+			confirmed++
+		}
+	}
+
+	return confirmed
+}
+
+// expired returns the anchors that weren't observed within the timeout or the maximum number of blocks
+func (t *anchorTracker) expired() []*outstandingAnchor {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var expired []*outstandingAnchor
+
+	for _, anchor := range t.outstanding {
+		if t.Timeout > 0 && time.Since(anchor.writtenTime) >= t.Timeout ||
+			t.MaxBlocks > 0 && t.lastBlock >= anchor.writtenBlock && t.lastBlock-anchor.writtenBlock >= t.MaxBlocks {
+			expired = append(expired, anchor)
+		}
+	}
+
+	return expired
+}
+
+// rewritten records that the given anchor was written again
+func (t *anchorTracker) rewritten(anchor *outstandingAnchor) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	anchor.writtenTime = time.Now()
+	anchor.writtenBlock = t.lastBlock
+	anchor.attempts++
+
+	if t.store != nil {
+		// the anchor is still tracked in memory so it's only written again too often after a restart
+		if err := t.store.Put(anchor.record()); err != nil {
+			log.Warnf("failed to store anchor[%s]: %s", anchor.anchorString, err)
+		}
+	}
+}
+
+// remove stops tracking the given anchor
+func (t *anchorTracker) remove(anchorString string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.delete(anchorString)
+}
+
+// delete stops tracking the given anchor. The caller must hold the lock.
+func (t *anchorTracker) delete(anchorString string) {
+	delete(t.outstanding, anchorString)
+
+	if t.store != nil {
+		// an anchor that remains in the store is loaded again after a restart and eventually given up
+		if err := t.store.Delete(anchorString); err != nil {
+			log.Warnf("failed to delete anchor[%s] from the anchor store: %s", anchorString, err)
+		}
+	}
+}
+
+// len returns the number of outstanding anchors
+func (t *anchorTracker) len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return len(t.outstanding)
+}
+
+// checkInterval returns the interval at which outstanding anchors are checked for the timeout
+func (t *anchorTracker) checkInterval() time.Duration {
+	return t.Timeout / 2
+}
@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package batch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+	"github.com/trustbloc/sidetree-core-go/pkg/batch/anchorstore"
+)
+
+func TestAnchorTracker(t *testing.T) {
+	ops := []*batch.Operation{{UniqueSuffix: "suffix"}}
+
+	t.Run("observed", func(t *testing.T) {
+		tracker := newAnchorTracker(ConfirmationOptions{Timeout: time.Hour}, nil)
+
+		require.NoError(t, tracker.add("anchor1", ops))
+		require.NoError(t, tracker.add("anchor2", ops))
+		require.Equal(t, 2, tracker.len())
+
+		n := tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 5}, {AnchorString: "anchor1", TransactionTime: 6}})
+		require.Equal(t, 1, n)
+		require.Equal(t, 1, tracker.len())
+		require.Equal(t, uint64(6), tracker.lastBlock)
+		require.Empty(t, tracker.expired())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		tracker := newAnchorTracker(ConfirmationOptions{Timeout: 50 * time.Millisecond}, nil)
+		require.Equal(t, 25*time.Millisecond, tracker.checkInterval())
+
+		require.NoError(t, tracker.add("anchor", ops))
+		require.Empty(t, tracker.expired())
+
+		time.Sleep(50 * time.Millisecond)
+
+		expired := tracker.expired()
+		require.Len(t, expired, 1)
+		require.Equal(t, "anchor", expired[0].anchorString)
+		require.Equal(t, uint(1), expired[0].attempts)
+
+		tracker.rewritten(expired[0])
+		require.Equal(t, uint(2), expired[0].attempts)
+		require.Empty(t, tracker.expired())
+
+		tracker.remove("anchor")
+		require.Zero(t, tracker.len())
+	})
+
+	t.Run("max blocks", func(t *testing.T) {
+		tracker := newAnchorTracker(ConfirmationOptions{MaxBlocks: 2}, nil)
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 10}})
+		require.NoError(t, tracker.add("anchor", ops))
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 11}})
+		require.Empty(t, tracker.expired())
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 12}})
+		require.Len(t, tracker.expired(), 1)
+	})
+	t.Run("anchor store", func(t *testing.T) {
+		store := anchorstore.NewMemStore()
+
+		tracker := newAnchorTracker(ConfirmationOptions{Timeout: 50 * time.Millisecond}, store)
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 10}})
+		require.NoError(t, tracker.add("anchor1", ops))
+		require.NoError(t, tracker.add("anchor2", ops))
+
+		time.Sleep(50 * time.Millisecond)
+
+		for _, anchor := range tracker.expired() {
+			if anchor.anchorString == "anchor1" {
+				tracker.rewritten(anchor)
+			}
+		}
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "anchor2", TransactionTime: 11}})
+
+		records, err := store.GetAll()
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OutstandingAnchor{{AnchorString: "anchor1", Operations: ops, Attempts: 2, WrittenBlock: 10}}, records)
+
+		// the outstanding anchors are loaded by a new tracker (e.g. after a restart)
+		tracker = newAnchorTracker(ConfirmationOptions{Timeout: 50 * time.Millisecond}, store)
+
+		loaded, err := tracker.load()
+		require.NoError(t, err)
+		require.Len(t, loaded, 1)
+		require.Equal(t, 1, tracker.len())
+		require.Equal(t, "anchor1", loaded[0].anchorString)
+		require.Equal(t, ops, loaded[0].ops)
+		require.Equal(t, uint(2), loaded[0].attempts)
+		require.Equal(t, uint64(10), loaded[0].writtenBlock)
+		require.Empty(t, tracker.expired())
+
+		tracker.remove("anchor1")
+
+		records, err = store.GetAll()
+		require.NoError(t, err)
+		require.Empty(t, records)
+	})
+
+	t.Run("anchor store error", func(t *testing.T) {
+		errExpected := errors.New("store error")
+
+		tracker := newAnchorTracker(ConfirmationOptions{Timeout: time.Hour}, &mockAnchorStore{err: errExpected})
+
+		err := tracker.add("anchor", ops)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), errExpected.Error())
+		require.Zero(t, tracker.len())
+
+		loaded, err := tracker.load()
+		require.EqualError(t, err, errExpected.Error())
+		require.Empty(t, loaded)
+	})
+
+	t.Run("loaded anchor isn't expired before blocks are observed", func(t *testing.T) {
+		store := anchorstore.NewMemStore()
+		require.NoError(t, store.Put(&batch.OutstandingAnchor{AnchorString: "anchor", Operations: ops, Attempts: 1, WrittenBlock: 10}))
+
+		tracker := newAnchorTracker(ConfirmationOptions{MaxBlocks: 2}, store)
+
+		_, err := tracker.load()
+		require.NoError(t, err)
+		require.Empty(t, tracker.expired())
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 11}})
+		require.Empty(t, tracker.expired())
+
+		tracker.observed([]txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 12}})
+		require.Len(t, tracker.expired(), 1)
+	})
+}
+
+type mockAnchorStore struct {
+	err error
+}
+
+func (m *mockAnchorStore) Put(*batch.OutstandingAnchor) error {
+	return m.err
+}
+
+func (m *mockAnchorStore) Delete(string) error {
+	return m.err
+}
+
+func (m *mockAnchorStore) GetAll() ([]*batch.OutstandingAnchor, error) {
+	return nil, m.err
+}
@@ -28,22 +28,41 @@ type OperationQueue interface {
 	Len() uint
 }
 
-// Committer is invoked to commit a batch Cut. The new number of pending items
-// in the queue is returned.
-type Committer = func() (pending uint, err error)
+// Committer is invoked to commit a batch Cut. The first num operations of the batch (in the order that they
+// were returned by Cut) are removed from the queue and the remaining operations of the batch stay in the queue.
+// The new number of pending items in the queue is returned.
+type Committer = func(num uint) (pending uint, err error)
 
 // BatchCutter implements batch cutting
 type BatchCutter struct {
 	pendingBatch OperationQueue
 	client       protocol.Client
+	policy       Policy
+}
+
+// Option is an option for the batch cutter
+type Option func(c *BatchCutter)
+
+// WithPolicy sets the policy that selects the operations for a batch. The default policy is FIFOPolicy.
+func WithPolicy(policy Policy) Option {
+	return func(c *BatchCutter) {
+		c.policy = policy
+	}
 }
 
 // New creates a Cutter implementation
-func New(client protocol.Client, queue OperationQueue) *BatchCutter {
-	return &BatchCutter{
+func New(client protocol.Client, queue OperationQueue, opts ...Option) *BatchCutter {
+	c := &BatchCutter{
 		client:       client,
 		pendingBatch: queue,
+		policy:       &FIFOPolicy{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 // Add adds the given operation to pending batch queue and returns the total
@@ -56,7 +75,10 @@ func (r *BatchCutter) Add(operation *batch.OperationInfo) (uint, error) {
 // Cut returns the current batch along with number of items that should be remaining in the queue after the committer is called.
 // If force is false then the batch will be cut only if it has reached the max batch size (as specified in the protocol)
 // If force is true then the batch will be cut if there is at least one Data in the batch
+// The operations in the batch are selected by the policy of the batch cutter. A batch may contain only one operation
+// per unique suffix, so an operation whose unique suffix is already in the batch remains in the queue for the next batch.
 // Note that the operations are removed from the queue when the committer is invoked, otherwise they remain in the queue.
+// The committer may commit only part of the batch (e.g. if the batch files of all of the operations would be too large).
 func (r *BatchCutter) Cut(force bool) ([]*batch.OperationInfo, uint, Committer, error) {
 	pending := r.pendingBatch.Len()
 
@@ -65,23 +87,16 @@ func (r *BatchCutter) Cut(force bool) ([]*batch.OperationInfo, uint, Committer,
 		return nil, pending, nil, nil
 	}
 
-	batchSize := min(pending, maxOperationsPerBatch)
-	ops, err := r.pendingBatch.Peek(batchSize)
+	ops, committer, err := r.policy.Select(r.pendingBatch, min(pending, maxOperationsPerBatch))
 	if err != nil {
 		return nil, pending, nil, err
 	}
 
+	batchSize := uint(len(ops))
 	pending -= batchSize
 
 	logger.Infof("Pending Size: %d, MaxOperationsPerBatch: %d, Batch Size: %d", pending, maxOperationsPerBatch, batchSize)
 
-	committer := func() (uint, error) {
-		logger.Infof("Removing %d operations from the queue", batchSize)
-
-		_, p, err := r.pendingBatch.Remove(batchSize)
-		return p, err
-	}
-
 	return ops, pending, committer, nil
 }
 
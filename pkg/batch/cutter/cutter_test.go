@@ -61,7 +61,7 @@ func TestBatchCutter(t *testing.T) {
 	require.Equal(t, operation2, ops[1])
 	require.Zero(t, pending)
 
-	pending, err = commit()
+	pending, err = commit(uint(len(ops)))
 	require.NoError(t, err)
 	require.Zero(t, pending)
 
@@ -100,7 +100,7 @@ func TestBatchCutter(t *testing.T) {
 	require.Equal(t, uint(1), pending)
 	require.NotNil(t, commit)
 
-	pending, err = commit()
+	pending, err = commit(uint(len(ops)))
 	require.NoError(t, err)
 	require.Equal(t, uint(1), pending)
 
@@ -110,7 +110,74 @@ func TestBatchCutter(t *testing.T) {
 	require.Equal(t, operation4, ops[0])
 	require.Zero(t, pending)
 
-	pending, err = commit()
+	pending, err = commit(uint(len(ops)))
+	require.NoError(t, err)
+	require.Zero(t, pending)
+}
+
+func TestBatchCutter_PartialCommit(t *testing.T) {
+	c := mocks.NewMockProtocolClient()
+	c.Protocol.MaxOperationsPerBatch = 3
+	r := New(c, &opqueue.MemQueue{})
+
+	for _, op := range []*batch.OperationInfo{operation1, operation2, operation3, operation4} {
+		_, err := r.Add(op)
+		require.NoError(t, err)
+	}
+
+	ops, _, commit, err := r.Cut(false)
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationInfo{operation1, operation2, operation3}, ops)
+
+	// the operations of the batch that weren't committed remain at the head of the queue
+	pending, err := commit(2)
+	require.NoError(t, err)
+	require.Equal(t, uint(2), pending)
+
+	ops, _, _, err = r.Cut(true)
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationInfo{operation3, operation4}, ops)
+}
+
+func TestBatchCutter_DuplicateSuffix(t *testing.T) {
+	operation1b := &batch.OperationInfo{UniqueSuffix: "1", Data: []byte("operation1b")}
+	operation1c := &batch.OperationInfo{UniqueSuffix: "1", Data: []byte("operation1c")}
+
+	c := mocks.NewMockProtocolClient()
+	c.Protocol.MaxOperationsPerBatch = 3
+	r := New(c, &opqueue.MemQueue{})
+
+	for _, op := range []*batch.OperationInfo{operation1, operation2, operation1b, operation3, operation1c, operation4} {
+		_, err := r.Add(op)
+		require.NoError(t, err)
+	}
+
+	// The later operations for suffix 1 remain in the queue and the batch is filled with the operations that follow them
+	ops, pending, commit, err := r.Cut(false)
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationInfo{operation1, operation2, operation3}, ops)
+	require.Equal(t, uint(3), pending)
+
+	pending, err = commit(uint(len(ops)))
+	require.NoError(t, err)
+	require.Equal(t, uint(3), pending)
+
+	// The deferred operations remain in submission order
+	ops, pending, commit, err = r.Cut(false)
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationInfo{operation1b, operation4}, ops)
+	require.Equal(t, uint(1), pending)
+
+	pending, err = commit(uint(len(ops)))
+	require.NoError(t, err)
+	require.Equal(t, uint(1), pending)
+
+	ops, pending, commit, err = r.Cut(true)
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationInfo{operation1c}, ops)
+	require.Zero(t, pending)
+
+	pending, err = commit(uint(len(ops)))
 	require.NoError(t, err)
 	require.Zero(t, pending)
 }
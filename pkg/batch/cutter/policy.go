@@ -0,0 +1,319 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cutter
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/batch/opqueue"
+)
+
+// Policy selects the operations in the queue that make up the next batch
+type Policy interface {
+	// Select returns (up to) the given number of operations for the next batch along with the committer
+	// that removes (the first num of) the selected operations from the queue
+	Select(queue OperationQueue, maxOperations uint) ([]*batch.OperationInfo, Committer, error)
+}
+
+// PriorityOperationQueue defines an operation queue that holds operations in lanes of different priority
+type PriorityOperationQueue interface {
+	OperationQueue
+	// PeekLane returns (up to) the given number of operations from the head of the given lane but does not remove them.
+	PeekLane(lane opqueue.Lane, num uint) ([]*batch.OperationInfo, error)
+	// RemoveLane removes (up to) the given number of operations from the head of the given lane.
+	// Returns the actual number of items that were removed and the new length of the queue.
+	RemoveLane(lane opqueue.Lane, num uint) (uint, uint, error)
+}
+
+// SelectiveOperationQueue defines an operation queue from which operations may also be removed
+// at positions other than the head of the queue
+type SelectiveOperationQueue interface {
+	OperationQueue
+	// RemoveAt removes the operations at the given positions in the queue.
+	// Returns the actual number of items that were removed and the new length of the queue.
+	RemoveAt(indexes []uint) (uint, uint, error)
+}
+
+// FIFOPolicy fills batches with operations in the order that they were added to the queue
+type FIFOPolicy struct {
+}
+
+// Select returns the operations in the order that they were added to the queue. An operation whose unique suffix
+// is already in the batch remains in the queue (in order) and the batch is filled with the operations that follow it.
+// If the queue isn't a SelectiveOperationQueue then the operations are only taken from the head of the queue,
+// so the batch ends before the first operation whose unique suffix is already in the batch.
+func (p *FIFOPolicy) Select(queue OperationQueue, maxOperations uint) ([]*batch.OperationInfo, Committer, error) {
+	sq, ok := queue.(SelectiveOperationQueue)
+	if !ok {
+		ops, err := queue.Peek(maxOperations)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ops = uniqueSuffixPrefix(ops)
+
+		return ops, removeHead(queue, uint(len(ops))), nil
+	}
+
+	ops, indexes, err := selectUniqueSuffixes(queue, maxOperations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	committer := func(num uint) (uint, error) {
+		selected := indexes[:min(num, uint(len(indexes)))]
+
+		logger.Infof("Removing %d operations from the queue", len(selected))
+
+		// the selected operations are at the head of the queue unless operations were deferred
+		if len(selected) == 0 || selected[len(selected)-1] == uint(len(selected)-1) {
+			_, p, err := queue.Remove(uint(len(selected)))
+			return p, err
+		}
+
+		_, p, err := sq.RemoveAt(selected)
+		return p, err
+	}
+
+	return ops, committer, nil
+}
+
+// removeHead returns a committer that removes (the first num of) the given number of operations from the head of the queue
+func removeHead(queue OperationQueue, batchSize uint) Committer {
+	return func(num uint) (uint, error) {
+		num = min(num, batchSize)
+
+		logger.Infof("Removing %d operations from the queue", num)
+
+		_, p, err := queue.Remove(num)
+		return p, err
+	}
+}
+
+// selectUniqueSuffixes returns (up to) the given number of operations in the queue with distinct unique suffixes
+// along with their positions in the queue. Further operations are peeked until the batch is full or the queue
+// is exhausted.
+func selectUniqueSuffixes(queue OperationQueue, maxOperations uint) ([]*batch.OperationInfo, []uint, error) {
+	num := maxOperations
+
+	for {
+		queued, err := queue.Peek(num)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		suffixes := make(map[string]bool)
+
+		var ops []*batch.OperationInfo
+		var indexes []uint
+
+		for i, op := range queued {
+			if uint(len(ops)) == maxOperations {
+				break
+			}
+
+			if suffixes[op.UniqueSuffix] {
+				logger.Debugf("Operation for suffix [%s] is already in the batch. Deferring it to the next batch.", op.UniqueSuffix)
+				continue
+			}
+
+			suffixes[op.UniqueSuffix] = true
+			ops = append(ops, op)
+			indexes = append(indexes, uint(i))
+		}
+
+		if uint(len(ops)) == maxOperations || uint(len(queued)) < num {
+			return ops, indexes, nil
+		}
+
+		num *= 2
+	}
+}
+
+// PriorityPolicy fills batches with recover and deactivate operations before all other operations.
+// In order to guarantee that the other operations make progress during a backlog of high priority operations,
+// an operation that has been in the queue for longer than the max age competes with the high priority
+// operations by age. The operations in each lane are selected in the order that they were added to the queue,
+// and a high priority operation never overtakes an older normal priority operation for the same unique suffix.
+// The policy requires a PriorityOperationQueue.
+type PriorityPolicy struct {
+	maxAge time.Duration
+}
+
+// NewPriorityPolicy returns a new priority policy with the given max age of normal priority operations
+func NewPriorityPolicy(maxAge time.Duration) *PriorityPolicy {
+	return &PriorityPolicy{maxAge: maxAge}
+}
+
+// Select returns the operations of the highest priority from the heads of the lanes of the queue.
+// No further operations are taken from a lane once an operation is found whose unique suffix is already in the batch.
+// No further high priority operations are taken once an operation is found for which an older operation
+// with the same unique suffix is still queued in the normal priority lane.
+func (p *PriorityPolicy) Select(queue OperationQueue, maxOperations uint) ([]*batch.OperationInfo, Committer, error) {
+	pq, ok := queue.(PriorityOperationQueue)
+	if !ok {
+		return nil, nil, errors.New("priority policy requires a priority operation queue")
+	}
+
+	high, err := pq.PeekLane(opqueue.HighPriority, maxOperations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	queued, err := peekNormal(pq, maxOperations, high)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	normal := queued
+	if uint(len(normal)) > maxOperations {
+		normal = normal[:maxOperations]
+	}
+
+	queuedBySuffix := indexBySuffix(queued)
+
+	now := time.Now()
+	suffixes := make(map[string]bool)
+
+	var ops []*batch.OperationInfo
+	var lanes []opqueue.Lane
+	var numHigh, numNormal uint
+
+	for uint(len(ops)) < maxOperations {
+		hasHigh := numHigh < uint(len(high))
+		hasNormal := numNormal < uint(len(normal))
+
+		if !hasHigh && !hasNormal {
+			break
+		}
+
+		var op *batch.OperationInfo
+		if hasNormal && (!hasHigh || p.precedes(normal[numNormal], high[numHigh], now)) {
+			op = normal[numNormal]
+
+			if suffixes[op.UniqueSuffix] {
+				// the rest of the lane is deferred to the next batch
+				normal = normal[:numNormal]
+				continue
+			}
+
+			numNormal++
+			lanes = append(lanes, opqueue.NormalPriority)
+		} else {
+			op = high[numHigh]
+
+			if suffixes[op.UniqueSuffix] || queuedBefore(queued, queuedBySuffix[op.UniqueSuffix], numNormal, op) {
+				high = high[:numHigh]
+				continue
+			}
+
+			numHigh++
+			lanes = append(lanes, opqueue.HighPriority)
+		}
+
+		ops = append(ops, op)
+		suffixes[op.UniqueSuffix] = true
+	}
+
+	// the operations are removed from each lane in turn, so if removing the normal priority operations fails
+	// then the high priority operations remain removed (i.e. the commit isn't atomic across lanes)
+	committer := func(num uint) (uint, error) {
+		// the operations of each lane were selected from the head of the lane
+		var removeHigh, removeNormal uint
+		for _, lane := range lanes[:min(num, uint(len(lanes)))] {
+			if lane == opqueue.HighPriority {
+				removeHigh++
+			} else {
+				removeNormal++
+			}
+		}
+
+		logger.Infof("Removing %d high priority and %d normal priority operations from the queue", removeHigh, removeNormal)
+
+		_, _, err := pq.RemoveLane(opqueue.HighPriority, removeHigh)
+		if err != nil {
+			return pq.Len(), err
+		}
+
+		_, pending, err := pq.RemoveLane(opqueue.NormalPriority, removeNormal)
+
+		return pending, err
+	}
+
+	return ops, committer, nil
+}
+
+// peekNormal returns the operations at the head of the normal priority lane: (up to) the given number of operations
+// and any further operations that were added before the last of the given high priority operations, which are
+// needed to check that the high priority operations don't overtake older operations for the same unique suffix
+func peekNormal(pq PriorityOperationQueue, maxOperations uint, high []*batch.OperationInfo) ([]*batch.OperationInfo, error) {
+	num := maxOperations
+
+	for {
+		ops, err := pq.PeekLane(opqueue.NormalPriority, num)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(high) == 0 || uint(len(ops)) < num || !ops[len(ops)-1].Time.Before(high[len(high)-1].Time) {
+			return ops, nil
+		}
+
+		num *= 2
+	}
+}
+
+// precedes returns true if the given normal priority operation has exceeded the max age
+// and was added to the queue before the given high priority operation
+func (p *PriorityPolicy) precedes(normal, high *batch.OperationInfo, now time.Time) bool {
+	return now.Sub(normal.Time) >= p.maxAge && normal.Time.Before(high.Time)
+}
+
+// queuedBefore returns true if one of the given normal priority operations that hasn't been selected
+// (i.e. whose index is not less than the number of selected operations) was added to the queue before the given operation
+func queuedBefore(queued []*batch.OperationInfo, indexes []int, numSelected uint, op *batch.OperationInfo) bool {
+	for _, i := range indexes {
+		if uint(i) >= numSelected {
+			// the operations in the lane are ordered by time so only the first unselected operation needs to be checked
+			return queued[i].Time.Before(op.Time)
+		}
+	}
+
+	return false
+}
+
+// indexBySuffix returns the indexes of the given operations by unique suffix
+func indexBySuffix(ops []*batch.OperationInfo) map[string][]int {
+	indexes := make(map[string][]int)
+
+	for i, op := range ops {
+		indexes[op.UniqueSuffix] = append(indexes[op.UniqueSuffix], i)
+	}
+
+	return indexes
+}
+
+// uniqueSuffixPrefix returns the operations up to (but not including) the first operation
+// whose unique suffix appears earlier in the given operations
+func uniqueSuffixPrefix(ops []*batch.OperationInfo) []*batch.OperationInfo {
+	suffixes := make(map[string]bool)
+
+	for i, op := range ops {
+		if suffixes[op.UniqueSuffix] {
+			logger.Infof("Operation for suffix [%s] is already in the batch. Deferring it and %d subsequent operations to the next batch.", op.UniqueSuffix, len(ops)-i-1)
+
+			return ops[:i]
+		}
+
+		suffixes[op.UniqueSuffix] = true
+	}
+
+	return ops
+}
@@ -0,0 +1,462 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cutter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/batch/opqueue"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+)
+
+func TestPriorityPolicy(t *testing.T) {
+	update := func(suffix string) *batch.OperationInfo {
+		return &batch.OperationInfo{UniqueSuffix: suffix, Type: batch.OperationTypeUpdate, Data: []byte(suffix)}
+	}
+	recover := func(suffix string) *batch.OperationInfo {
+		return &batch.OperationInfo{UniqueSuffix: suffix, Type: batch.OperationTypeRecover, Data: []byte(suffix)}
+	}
+	deactivate := func(suffix string) *batch.OperationInfo {
+		return &batch.OperationInfo{UniqueSuffix: suffix, Type: batch.OperationTypeDeactivate, Data: []byte(suffix)}
+	}
+
+	c := mocks.NewMockProtocolClient()
+	c.Protocol.MaxOperationsPerBatch = 3
+
+	t.Run("high priority operations first", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		for _, op := range []*batch.OperationInfo{update("u1"), update("u2"), update("u3"), recover("r1"), deactivate("d1")} {
+			_, err := r.Add(op)
+			require.NoError(t, err)
+		}
+
+		ops, pending, commit, err := r.Cut(false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "d1", "u1"}, suffixes(ops))
+		require.Equal(t, uint(2), pending)
+
+		pending, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Equal(t, uint(2), pending)
+
+		ops, pending, commit, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u2", "u3"}, suffixes(ops))
+		require.Zero(t, pending)
+
+		pending, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Zero(t, pending)
+	})
+
+	t.Run("partial commit", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		for _, op := range []*batch.OperationInfo{update("u1"), update("u2"), recover("r1"), deactivate("d1")} {
+			_, err := r.Add(op)
+			require.NoError(t, err)
+		}
+
+		ops, _, commit, err := r.Cut(false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "d1", "u1"}, suffixes(ops))
+
+		// only the first operation of the batch is removed from the queue
+		pending, err := commit(1)
+		require.NoError(t, err)
+		require.Equal(t, uint(3), pending)
+
+		ops, _, _, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"d1", "u1", "u2"}, suffixes(ops))
+	})
+
+	t.Run("aged operations are not starved", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(50*time.Millisecond)))
+
+		_, err := r.Add(update("u1"))
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		// u1 has exceeded the max age so it is ordered by age along with the high priority operations
+		for _, op := range []*batch.OperationInfo{recover("r1"), recover("r2"), recover("r3"), update("u2")} {
+			_, err = r.Add(op)
+			require.NoError(t, err)
+		}
+
+		ops, _, commit, err := r.Cut(false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u1", "r1", "r2"}, suffixes(ops))
+
+		_, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+
+		ops, _, _, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r3", "u2"}, suffixes(ops))
+	})
+
+	t.Run("duplicate suffix", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		for _, op := range []*batch.OperationInfo{update("s1"), update("u2"), recover("s1"), recover("r2"), update("u3")} {
+			_, err := r.Add(op)
+			require.NoError(t, err)
+		}
+
+		// the recover for s1 (and all subsequent high priority operations) are deferred since
+		// the older update for s1 is still in the normal priority lane
+		ops, pending, commit, err := r.Cut(false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1", "u2", "u3"}, suffixes(ops))
+		require.Equal(t, batch.OperationTypeUpdate, ops[0].Type)
+		require.Equal(t, uint(2), pending)
+
+		_, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+
+		ops, _, commit, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1", "r2"}, suffixes(ops))
+		require.Equal(t, batch.OperationTypeRecover, ops[0].Type)
+
+		pending, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Zero(t, pending)
+	})
+
+	t.Run("duplicate suffix in normal priority lane", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		for _, op := range []*batch.OperationInfo{recover("s1"), update("s1"), update("u2"), recover("r2")} {
+			_, err := r.Add(op)
+			require.NoError(t, err)
+		}
+
+		// the update for s1 (and all subsequent updates) are deferred since the recover for s1 is in the batch
+		ops, pending, commit, err := r.Cut(false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1", "r2"}, suffixes(ops))
+		require.Equal(t, batch.OperationTypeRecover, ops[0].Type)
+		require.Equal(t, uint(2), pending)
+
+		_, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+
+		ops, _, _, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1", "u2"}, suffixes(ops))
+		require.Equal(t, batch.OperationTypeUpdate, ops[0].Type)
+	})
+
+	t.Run("high priority operation doesn't overtake an older operation beyond the batch", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		for _, op := range []*batch.OperationInfo{update("u1"), update("u2"), update("u3"), update("s1"), deactivate("s1")} {
+			_, err := r.Add(op)
+			require.NoError(t, err)
+		}
+
+		ops, _, commit, err := r.Cut(false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u1", "u2", "u3"}, suffixes(ops))
+
+		_, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+
+		ops, _, _, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1"}, suffixes(ops))
+		require.Equal(t, batch.OperationTypeUpdate, ops[0].Type)
+	})
+
+	t.Run("duplicate suffix in high priority lane", func(t *testing.T) {
+		q := opqueue.NewMemPriorityQueue()
+		r := New(c, q, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		for _, op := range []*batch.OperationInfo{recover("r1"), deactivate("r1"), recover("r2"), update("u1")} {
+			_, err := r.Add(op)
+			require.NoError(t, err)
+		}
+
+		ops, _, commit, err := r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "u1"}, suffixes(ops))
+
+		_, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+
+		ops, _, _, err = r.Cut(true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "r2"}, suffixes(ops))
+		require.Equal(t, batch.OperationTypeDeactivate, ops[0].Type)
+	})
+
+	t.Run("normal priority lane is only peeked as far as needed", func(t *testing.T) {
+		q := &mockPriorityQueue{PriorityQueue: opqueue.NewMemPriorityQueue()}
+
+		_, err := q.Add(recover("r1"))
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			_, err = q.Add(update(fmt.Sprintf("u%d", i)))
+			require.NoError(t, err)
+		}
+
+		ops, _, err := NewPriorityPolicy(time.Hour).Select(q, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "u0", "u1"}, suffixes(ops))
+		require.Equal(t, []uint{3}, q.peeked[opqueue.NormalPriority])
+
+		// the normal priority operations that were added before the high priority operation are needed
+		_, err = q.Add(recover("u9"))
+		require.NoError(t, err)
+
+		q.peeked = nil
+
+		ops, _, err = NewPriorityPolicy(time.Hour).Select(q, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "u0", "u1"}, suffixes(ops))
+		require.Equal(t, []uint{3, 6, 12}, q.peeked[opqueue.NormalPriority])
+	})
+
+	t.Run("not a priority queue", func(t *testing.T) {
+		r := New(c, &opqueue.MemQueue{}, WithPolicy(NewPriorityPolicy(time.Hour)))
+
+		_, err := r.Add(update("u1"))
+		require.NoError(t, err)
+
+		ops, pending, commit, err := r.Cut(true)
+		require.EqualError(t, err, "priority policy requires a priority operation queue")
+		require.Empty(t, ops)
+		require.Equal(t, uint(1), pending)
+		require.Nil(t, commit)
+	})
+
+	t.Run("lane errors", func(t *testing.T) {
+		errExpected := errors.New("lane error")
+
+		q := &mockPriorityQueue{PriorityQueue: opqueue.NewMemPriorityQueue(), peekErr: map[opqueue.Lane]error{opqueue.HighPriority: errExpected}}
+		_, _, err := NewPriorityPolicy(time.Hour).Select(q, 3)
+		require.EqualError(t, err, errExpected.Error())
+
+		q = &mockPriorityQueue{PriorityQueue: opqueue.NewMemPriorityQueue(), peekErr: map[opqueue.Lane]error{opqueue.NormalPriority: errExpected}}
+		_, _, err = NewPriorityPolicy(time.Hour).Select(q, 3)
+		require.EqualError(t, err, errExpected.Error())
+
+		q = &mockPriorityQueue{PriorityQueue: opqueue.NewMemPriorityQueue(), removeErr: errExpected}
+		_, err = q.Add(recover("r1"))
+		require.NoError(t, err)
+
+		ops, commit, err := NewPriorityPolicy(time.Hour).Select(q, 3)
+		require.NoError(t, err)
+		require.Len(t, ops, 1)
+
+		pending, err := commit(uint(len(ops)))
+		require.EqualError(t, err, errExpected.Error())
+		require.Equal(t, uint(1), pending)
+	})
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	op := func(suffix, data string) *batch.OperationInfo {
+		return &batch.OperationInfo{UniqueSuffix: suffix, Data: []byte(data)}
+	}
+
+	t.Run("interleaved duplicates", func(t *testing.T) {
+		q := &opqueue.MemQueue{}
+
+		queued := []*batch.OperationInfo{
+			op("1", "1a"), op("1", "1b"), op("2", "2a"), op("1", "1c"), op("2", "2b"),
+			op("3", "3a"), op("2", "2c"), op("4", "4a"), op("5", "5a"), op("6", "6a"),
+		}
+
+		for _, o := range queued {
+			_, err := q.Add(o)
+			require.NoError(t, err)
+		}
+
+		ops, commit, err := (&FIFOPolicy{}).Select(q, 5)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2", "3", "4", "5"}, suffixes(ops))
+		require.Equal(t, []*batch.OperationInfo{queued[0], queued[2], queued[5], queued[7], queued[8]}, ops)
+
+		// only the first three selected operations are committed
+		pending, err := commit(3)
+		require.NoError(t, err)
+		require.Equal(t, uint(7), pending)
+
+		remaining, err := q.Peek(10)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{
+			queued[1], queued[3], queued[4], queued[6], queued[7], queued[8], queued[9],
+		}, remaining)
+
+		ops, commit, err = (&FIFOPolicy{}).Select(q, 5)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{queued[1], queued[4], queued[7], queued[8], queued[9]}, ops)
+
+		pending, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Equal(t, uint(2), pending)
+
+		ops, commit, err = (&FIFOPolicy{}).Select(q, 5)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{queued[3], queued[6]}, ops)
+
+		pending, err = commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Zero(t, pending)
+	})
+
+	t.Run("operations at the head of the queue", func(t *testing.T) {
+		q := &removeAtQueue{MemQueue: &opqueue.MemQueue{}}
+
+		for _, o := range []*batch.OperationInfo{op("1", "1a"), op("2", "2a"), op("1", "1b")} {
+			_, err := q.Add(o)
+			require.NoError(t, err)
+		}
+
+		ops, commit, err := (&FIFOPolicy{}).Select(q, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2"}, suffixes(ops))
+
+		pending, err := commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Equal(t, uint(1), pending)
+		require.Zero(t, q.removedAt)
+	})
+
+	t.Run("queue does not support removal by position", func(t *testing.T) {
+		q := &headOnlyQueue{OperationQueue: &opqueue.MemQueue{}}
+
+		for _, o := range []*batch.OperationInfo{op("1", "1a"), op("2", "2a"), op("1", "1b"), op("3", "3a")} {
+			_, err := q.Add(o)
+			require.NoError(t, err)
+		}
+
+		// the batch ends before the second operation for suffix 1
+		ops, commit, err := (&FIFOPolicy{}).Select(q, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2"}, suffixes(ops))
+
+		pending, err := commit(uint(len(ops)))
+		require.NoError(t, err)
+		require.Equal(t, uint(2), pending)
+	})
+
+	t.Run("remove error", func(t *testing.T) {
+		errExpected := errors.New("remove error")
+
+		q := &removeAtQueue{MemQueue: &opqueue.MemQueue{}, err: errExpected}
+
+		for _, o := range []*batch.OperationInfo{op("1", "1a"), op("1", "1b"), op("2", "2a")} {
+			_, err := q.Add(o)
+			require.NoError(t, err)
+		}
+
+		ops, commit, err := (&FIFOPolicy{}).Select(q, 3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1", "2"}, suffixes(ops))
+
+		pending, err := commit(uint(len(ops)))
+		require.EqualError(t, err, errExpected.Error())
+		require.Equal(t, uint(3), pending)
+	})
+}
+
+func TestFIFOPolicy_Error(t *testing.T) {
+	errExpected := errors.New("peek error")
+
+	_, _, err := (&FIFOPolicy{}).Select(&mockPriorityQueue{
+		PriorityQueue: opqueue.NewMemPriorityQueue(),
+		fifoErr:       errExpected,
+	}, 3)
+	require.EqualError(t, err, errExpected.Error())
+}
+
+func suffixes(ops []*batch.OperationInfo) []string {
+	var result []string
+	for _, op := range ops {
+		result = append(result, op.UniqueSuffix)
+	}
+
+	return result
+}
+
+type mockPriorityQueue struct {
+	*opqueue.PriorityQueue
+	peekErr   map[opqueue.Lane]error
+	removeErr error
+	fifoErr   error
+	peeked    map[opqueue.Lane][]uint
+}
+
+func (q *mockPriorityQueue) Peek(num uint) ([]*batch.OperationInfo, error) {
+	if q.fifoErr != nil {
+		return nil, q.fifoErr
+	}
+
+	return q.PriorityQueue.Peek(num)
+}
+
+func (q *mockPriorityQueue) PeekLane(lane opqueue.Lane, num uint) ([]*batch.OperationInfo, error) {
+	if err := q.peekErr[lane]; err != nil {
+		return nil, err
+	}
+
+	if q.peeked == nil {
+		q.peeked = make(map[opqueue.Lane][]uint)
+	}
+
+	q.peeked[lane] = append(q.peeked[lane], num)
+
+	return q.PriorityQueue.PeekLane(lane, num)
+}
+
+func (q *mockPriorityQueue) RemoveLane(lane opqueue.Lane, num uint) (uint, uint, error) {
+	if q.removeErr != nil {
+		return 0, q.Len(), q.removeErr
+	}
+
+	return q.PriorityQueue.RemoveLane(lane, num)
+}
+
+type removeAtQueue struct {
+	*opqueue.MemQueue
+	err       error
+	removedAt int
+}
+
+func (q *removeAtQueue) RemoveAt(indexes []uint) (uint, uint, error) {
+	if q.err != nil {
+		return 0, q.Len(), q.err
+	}
+
+	q.removedAt++
+
+	return q.MemQueue.RemoveAt(indexes)
+}
+
+// headOnlyQueue hides the RemoveAt function of the underlying queue
+type headOnlyQueue struct {
+	OperationQueue
+}
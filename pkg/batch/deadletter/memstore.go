@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deadletter
+
+import (
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+const defaultMaxOperations = 1000
+
+// MemStore implements an in-memory store for operations that were rejected by the batch writer.
+// The store keeps (up to) the maximum number of the most recently rejected operations per namespace.
+type MemStore struct {
+	mutex         sync.RWMutex
+	ops           map[string][]*batch.RejectedOperation
+	maxOperations int
+}
+
+// Option is an option for the dead-letter store
+type Option func(s *MemStore)
+
+// WithMaxOperations sets the maximum number of rejected operations that are kept per namespace (zero for no limit)
+func WithMaxOperations(maxOperations int) Option {
+	return func(s *MemStore) {
+		s.maxOperations = maxOperations
+	}
+}
+
+// NewMemStore returns a new in-memory dead-letter store
+func NewMemStore(opts ...Option) *MemStore {
+	s := &MemStore{
+		ops:           make(map[string][]*batch.RejectedOperation),
+		maxOperations: defaultMaxOperations,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Put stores the rejected operation. The oldest rejected operation of the namespace is dropped
+// if the store already holds the maximum number of operations for the namespace.
+func (s *MemStore) Put(op *batch.RejectedOperation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ops := append(s.ops[op.Namespace], op)
+	if s.maxOperations > 0 && len(ops) > s.maxOperations {
+		ops = append([]*batch.RejectedOperation(nil), ops[len(ops)-s.maxOperations:]...)
+	}
+
+	s.ops[op.Namespace] = ops
+
+	return nil
+}
+
+// Get returns the rejected operations for the given namespace
+func (s *MemStore) Get(namespace string) ([]*batch.RejectedOperation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ops := make([]*batch.RejectedOperation, len(s.ops[namespace]))
+	copy(ops, s.ops[namespace])
+
+	return ops, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deadletter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+func TestMemStore(t *testing.T) {
+	op1 := &batch.RejectedOperation{
+		OperationInfo: &batch.OperationInfo{Namespace: "ns1", UniqueSuffix: "op1", Data: []byte("op1")},
+		Reason:        "reason1",
+	}
+	op2 := &batch.RejectedOperation{
+		OperationInfo: &batch.OperationInfo{Namespace: "ns1", UniqueSuffix: "op2", Data: []byte("op2")},
+		Reason:        "reason2",
+	}
+	op3 := &batch.RejectedOperation{
+		OperationInfo: &batch.OperationInfo{Namespace: "ns2", UniqueSuffix: "op3", Data: []byte("op3")},
+		Reason:        "reason3",
+	}
+
+	s := NewMemStore()
+
+	ops, err := s.Get("ns1")
+	require.NoError(t, err)
+	require.Empty(t, ops)
+
+	require.NoError(t, s.Put(op1))
+	require.NoError(t, s.Put(op2))
+	require.NoError(t, s.Put(op3))
+
+	ops, err = s.Get("ns1")
+	require.NoError(t, err)
+	require.Equal(t, []*batch.RejectedOperation{op1, op2}, ops)
+
+	ops, err = s.Get("ns2")
+	require.NoError(t, err)
+	require.Equal(t, []*batch.RejectedOperation{op3}, ops)
+}
+
+func TestMemStore_MaxOperations(t *testing.T) {
+	s := NewMemStore(WithMaxOperations(2))
+
+	var ops []*batch.RejectedOperation
+	for _, suffix := range []string{"op1", "op2", "op3"} {
+		op := &batch.RejectedOperation{
+			OperationInfo: &batch.OperationInfo{Namespace: "ns1", UniqueSuffix: suffix, Data: []byte(suffix)},
+			Reason:        "reason",
+		}
+
+		require.NoError(t, s.Put(op))
+
+		ops = append(ops, op)
+	}
+
+	// the oldest operation was dropped
+	rejected, err := s.Get("ns1")
+	require.NoError(t, err)
+	require.Equal(t, ops[1:], rejected)
+}
@@ -0,0 +1,545 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opqueue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+const (
+	logFileName       = "operations.log"
+	logFilePrefix     = "operations."
+	logFileExt        = ".log"
+	headFileName      = "head"
+	headSize          = 16
+	defaultCompaction = 1 << 20
+
+	// each record in the log consists of the payload length, the CRC32 checksum of the payload and the payload
+	recordHeaderSize = 8
+)
+
+// FileQueue implements a durable operation queue that survives process restarts. Operations are appended
+// to a log file and the head pointer (the generation of the log and the offset in the log of the first operation
+// that has not been removed) is stored in a separate file. The log is replaced by an empty log of the next generation
+// whenever the queue becomes empty. Once the removed operations at the start of the log exceed the compaction threshold (and take up at least half
+// of the log), the remaining operations are copied to a log of the next generation and the old log is deleted.
+//
+// Records that were only partially written to the log (due to a crash) are discarded when the queue is opened.
+type FileQueue struct {
+	mutex               sync.RWMutex
+	dir                 string
+	log                 *os.File
+	gen                 uint64
+	size                int64
+	items               []*fileItem
+	compactionThreshold int64
+}
+
+// FileQueueOption is an option for the file queue
+type FileQueueOption func(q *FileQueue)
+
+// WithCompactionThreshold sets the number of bytes of removed operations at the start of the log
+// after which the log is compacted
+func WithCompactionThreshold(threshold int64) FileQueueOption {
+	return func(q *FileQueue) {
+		q.compactionThreshold = threshold
+	}
+}
+
+type fileItem struct {
+	op *batch.OperationInfo
+	// end is the offset in the log after this operation's record
+	end int64
+}
+
+// NewFileQueue opens the file queue in the given directory (the directory is created if it doesn't exist)
+// and loads the operations that have not been removed from the queue.
+func NewFileQueue(dir string, opts ...FileQueueOption) (*FileQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory [%s]: %s", dir, err.Error())
+	}
+
+	q := &FileQueue{dir: dir, compactionThreshold: defaultCompaction}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	gen, head, err := q.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(q.logPath(gen), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue log: %s", err.Error())
+	}
+
+	q.log = f
+	q.gen = gen
+
+	if err := q.load(head); err != nil {
+		if e := f.Close(); e != nil {
+			log.Warnf("failed to close queue log: %s", e)
+		}
+
+		return nil, err
+	}
+
+	q.removeStaleLogs()
+
+	return q, nil
+}
+
+// Add adds the given data to the tail of the queue and returns the new length of the queue.
+// The operation is persisted before Add returns.
+func (q *FileQueue) Add(data *batch.OperationInfo) (uint, error) {
+	record, err := encodeRecord(data)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if err := q.append(record); err != nil {
+		return 0, err
+	}
+
+	q.size += int64(len(record))
+	q.items = append(q.items, &fileItem{op: data, end: q.size})
+
+	return uint(len(q.items)), nil
+}
+
+// Peek returns (up to) the given number of operations from the head of the queue but does not remove them.
+func (q *FileQueue) Peek(num uint) ([]*batch.OperationInfo, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	n := int(num)
+	if len(q.items) < n {
+		n = len(q.items)
+	}
+
+	ops := make([]*batch.OperationInfo, n)
+	for i, item := range q.items[0:n] {
+		ops[i] = item.op
+	}
+
+	return ops, nil
+}
+
+// Remove removes (up to) the given number of items from the head of the queue.
+// Returns the actual number of items that were removed and the new length of the queue.
+// The new head of the queue is persisted before Remove returns.
+func (q *FileQueue) Remove(num uint) (uint, uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.remove(num)
+}
+
+// RemoveAt removes the operations at the given positions in the queue. Returns the actual number of items that
+// were removed and the new length of the queue. Unless the operations are at the head of the queue, the remaining
+// operations are written to a log of the next generation (see compact). The queue is persisted before RemoveAt returns.
+func (q *FileQueue) RemoveAt(indexes []uint) (uint, uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	remove := positions(indexes, len(q.items))
+	if isHead(remove) {
+		return q.remove(uint(len(remove)))
+	}
+
+	var records []byte
+	var items []*fileItem
+
+	for i, item := range q.items {
+		if remove[i] {
+			continue
+		}
+
+		record, err := encodeRecord(item.op)
+		if err != nil {
+			return 0, uint(len(q.items)), err
+		}
+
+		records = append(records, record...)
+		items = append(items, &fileItem{op: item.op, end: int64(len(records))})
+	}
+
+	f, err := q.createLog(q.gen+1, records)
+	if err != nil {
+		return 0, uint(len(q.items)), err
+	}
+
+	q.replaceLog(f, q.gen+1, int64(len(records)))
+	q.items = items
+
+	return uint(len(remove)), uint(len(q.items)), nil
+}
+
+// remove removes (up to) the given number of items from the head of the queue
+func (q *FileQueue) remove(num uint) (uint, uint, error) {
+	n := int(num)
+	if len(q.items) < n {
+		n = len(q.items)
+	}
+
+	if n == 0 {
+		return 0, uint(len(q.items)), nil
+	}
+
+	if n == len(q.items) {
+		if err := q.truncate(); err != nil {
+			return 0, uint(len(q.items)), err
+		}
+
+		return uint(n), 0, nil
+	}
+
+	head := q.items[n-1].end
+
+	if err := q.writeHead(q.gen, head); err != nil {
+		return 0, uint(len(q.items)), err
+	}
+
+	q.items = q.items[n:]
+
+	if head >= q.compactionThreshold && head >= q.size-head {
+		// the operations were removed so a failed compaction is retried on the next removal
+		if err := q.compact(head); err != nil {
+			log.Warnf("failed to compact queue log: %s", err)
+		}
+	}
+
+	return uint(n), uint(len(q.items)), nil
+}
+
+// Len returns the length of the queue.
+func (q *FileQueue) Len() uint {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	return uint(len(q.items))
+}
+
+// Close closes the queue log
+func (q *FileQueue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.log.Close()
+}
+
+// load reads the operations in the log starting at the given head. A partially
+// written record at the end of the log is discarded.
+func (q *FileQueue) load(head int64) error {
+	bytes, err := ioutil.ReadAll(q.log)
+	if err != nil {
+		return fmt.Errorf("failed to read queue log: %s", err.Error())
+	}
+
+	var offset int64
+
+	for offset < int64(len(bytes)) {
+		op, n, err := decodeRecord(bytes[offset:])
+		if err != nil {
+			log.Warnf("discarding queue log from offset [%d]: %s", offset, err)
+
+			if err := q.log.Truncate(offset); err != nil {
+				return fmt.Errorf("failed to truncate queue log: %s", err.Error())
+			}
+
+			break
+		}
+
+		if offset >= head {
+			q.items = append(q.items, &fileItem{op: op, end: offset + n})
+		}
+
+		offset += n
+	}
+
+	q.size = offset
+
+	if head > q.size {
+		// the log was truncated but the head pointer was not reset
+		return q.writeHead(q.gen, q.size)
+	}
+
+	return nil
+}
+
+func (q *FileQueue) append(record []byte) error {
+	_, err := q.log.WriteAt(record, q.size)
+	if err == nil {
+		err = q.log.Sync()
+	}
+
+	if err != nil {
+		// discard any partially written record
+		if e := q.log.Truncate(q.size); e != nil {
+			log.Warnf("failed to discard partially written record from queue log: %s", e)
+		}
+
+		return fmt.Errorf("failed to write operation to queue log: %s", err.Error())
+	}
+
+	return nil
+}
+
+// truncate removes all operations by replacing the log with an empty log of the next generation (see compact).
+// The queue is only updated once the head pointer refers to the new log, so the queue is unchanged if
+// truncation fails and the removed operations are never added back if the process crashes in between.
+func (q *FileQueue) truncate() error {
+	if err := q.compact(q.size); err != nil {
+		return err
+	}
+
+	q.items = nil
+
+	return nil
+}
+
+// compact copies the records after the given head to a log of the next generation and deletes the current log.
+// The new log is only used once the head pointer refers to it, so if the process crashes during compaction
+// then the queue is loaded from the current log.
+func (q *FileQueue) compact(head int64) error {
+	records := make([]byte, q.size-head)
+	if _, err := q.log.ReadAt(records, head); err != nil {
+		return fmt.Errorf("failed to read queue log: %s", err.Error())
+	}
+
+	f, err := q.createLog(q.gen+1, records)
+	if err != nil {
+		return err
+	}
+
+	q.replaceLog(f, q.gen+1, q.size-head)
+
+	for _, item := range q.items {
+		item.end -= head
+	}
+
+	log.Debugf("compacted queue log: reclaimed %d bytes", head)
+
+	return nil
+}
+
+// createLog writes the given records to the log of the given generation and points the head pointer to the
+// start of the log. The log is deleted if it can't be written, in which case the current log remains in use.
+func (q *FileQueue) createLog(gen uint64, records []byte) (*os.File, error) {
+	f, err := os.OpenFile(q.logPath(gen), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue log: %s", err.Error())
+	}
+
+	_, err = f.Write(records)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err == nil {
+		err = syncDir(q.dir)
+	}
+
+	if err == nil {
+		err = q.writeHead(gen, 0)
+	}
+
+	if err != nil {
+		q.discardLog(f)
+
+		return nil, fmt.Errorf("failed to write queue log: %s", err.Error())
+	}
+
+	return f, nil
+}
+
+// replaceLog switches to the given log (which the head pointer already refers to) and deletes the current log
+func (q *FileQueue) replaceLog(f *os.File, gen uint64, size int64) {
+	old, oldGen := q.log, q.gen
+
+	q.log = f
+	q.gen = gen
+	q.size = size
+
+	if err := old.Close(); err != nil {
+		log.Warnf("failed to close queue log: %s", err)
+	}
+
+	if err := os.Remove(q.logPath(oldGen)); err != nil {
+		log.Warnf("failed to delete queue log: %s", err)
+	}
+}
+
+// discardLog closes and deletes the given (partially written) log
+func (q *FileQueue) discardLog(f *os.File) {
+	if err := f.Close(); err != nil {
+		log.Warnf("failed to close queue log: %s", err)
+	}
+
+	if err := os.Remove(f.Name()); err != nil {
+		log.Warnf("failed to delete queue log: %s", err)
+	}
+}
+
+// removeStaleLogs deletes the logs of other generations, which remain if the process crashed during compaction
+func (q *FileQueue) removeStaleLogs() {
+	files, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		log.Warnf("failed to read queue directory: %s", err)
+		return
+	}
+
+	current := filepath.Base(q.logPath(q.gen))
+
+	for _, file := range files {
+		name := file.Name()
+		if name == current || !strings.HasPrefix(name, logFilePrefix) || !strings.HasSuffix(name, logFileExt) {
+			continue
+		}
+
+		log.Infof("deleting stale queue log [%s]", name)
+
+		if err := os.Remove(filepath.Join(q.dir, name)); err != nil {
+			log.Warnf("failed to delete stale queue log [%s]: %s", name, err)
+		}
+	}
+}
+
+// logPath returns the path of the log of the given generation
+func (q *FileQueue) logPath(gen uint64) string {
+	if gen == 0 {
+		return filepath.Join(q.dir, logFileName)
+	}
+
+	return filepath.Join(q.dir, fmt.Sprintf("%s%d%s", logFilePrefix, gen, logFileExt))
+}
+
+// readHead returns the generation of the log and the head offset
+func (q *FileQueue) readHead() (uint64, int64, error) {
+	bytes, err := ioutil.ReadFile(filepath.Join(q.dir, headFileName))
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read queue head: %s", err.Error())
+	}
+
+	if len(bytes) != headSize {
+		return 0, 0, fmt.Errorf("invalid queue head size [%d]", len(bytes))
+	}
+
+	return binary.BigEndian.Uint64(bytes[0:8]), int64(binary.BigEndian.Uint64(bytes[8:16])), nil
+}
+
+// writeHead atomically replaces the head pointer file
+func (q *FileQueue) writeHead(gen uint64, head int64) error {
+	bytes := make([]byte, headSize)
+	binary.BigEndian.PutUint64(bytes[0:8], gen)
+	binary.BigEndian.PutUint64(bytes[8:16], uint64(head))
+
+	tmpPath := filepath.Join(q.dir, headFileName+".tmp")
+
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write queue head: %s", err.Error())
+	}
+
+	_, err = f.Write(bytes)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if e := f.Close(); err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write queue head: %s", err.Error())
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(q.dir, headFileName)); err != nil {
+		return fmt.Errorf("failed to write queue head: %s", err.Error())
+	}
+
+	return syncDir(q.dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return fmt.Errorf("failed to open queue directory: %s", err.Error())
+	}
+
+	err = d.Sync()
+
+	if e := d.Close(); err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to sync queue directory: %s", err.Error())
+	}
+
+	return nil
+}
+
+func encodeRecord(op *batch.OperationInfo) ([]byte, error) {
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal operation: %s", err.Error())
+	}
+
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	return record, nil
+}
+
+// decodeRecord decodes the record at the start of the given bytes and returns the operation
+// along with the size of the record
+func decodeRecord(bytes []byte) (*batch.OperationInfo, int64, error) {
+	if len(bytes) < recordHeaderSize {
+		return nil, 0, fmt.Errorf("incomplete record header")
+	}
+
+	size := int64(binary.BigEndian.Uint32(bytes[0:4]))
+	if int64(len(bytes)-recordHeaderSize) < size {
+		return nil, 0, fmt.Errorf("incomplete record")
+	}
+
+	payload := bytes[recordHeaderSize : recordHeaderSize+size]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(bytes[4:8]) {
+		return nil, 0, fmt.Errorf("record checksum mismatch")
+	}
+
+	op := &batch.OperationInfo{}
+	if err := json.Unmarshal(payload, op); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal operation: %s", err.Error())
+	}
+
+	return op, recordHeaderSize + size, nil
+}
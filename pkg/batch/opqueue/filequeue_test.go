@@ -0,0 +1,588 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opqueue
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+func TestFileQueue(t *testing.T) {
+	dir, cleanup := newTestDir(t)
+	defer cleanup()
+
+	q, err := NewFileQueue(dir)
+	require.NoError(t, err)
+	defer closeQueue(t, q)
+
+	require.Zero(t, q.Len())
+
+	ops, err := q.Peek(1)
+	require.NoError(t, err)
+	require.Empty(t, ops)
+
+	n, l, err := q.Remove(1)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Zero(t, l)
+
+	l, err = q.Add(op1)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), l)
+
+	l, err = q.Add(op2)
+	require.NoError(t, err)
+	require.Equal(t, uint(2), l)
+
+	l, err = q.Add(op3)
+	require.NoError(t, err)
+	require.Equal(t, uint(3), l)
+	require.Equal(t, uint(3), q.Len())
+
+	ops, err = q.Peek(1)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.Equal(t, op1, ops[0])
+
+	ops, err = q.Peek(4)
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	require.Equal(t, op1, ops[0])
+	require.Equal(t, op2, ops[1])
+	require.Equal(t, op3, ops[2])
+
+	n, l, err = q.Remove(1)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), n)
+	require.Equal(t, uint(2), l)
+
+	ops, err = q.Peek(1)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.Equal(t, op2, ops[0])
+
+	n, l, err = q.Remove(5)
+	require.NoError(t, err)
+	require.Equal(t, uint(2), n)
+	require.Zero(t, l)
+
+	// the log is replaced by an empty log once the queue is empty
+	require.Zero(t, fileSize(t, q.logPath(q.gen)))
+
+	_, err = os.Stat(filepath.Join(dir, logFileName))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestFileQueue_Recovery(t *testing.T) {
+	t.Run("operations survive restart", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+
+		_, err = q.Add(op1)
+		require.NoError(t, err)
+		_, err = q.Add(op2)
+		require.NoError(t, err)
+		_, err = q.Add(op3)
+		require.NoError(t, err)
+
+		n, _, err := q.Remove(1)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), n)
+
+		// simulate a crash: the queue is reopened without being closed
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		require.Equal(t, uint(2), q2.Len())
+
+		ops, err := q2.Peek(3)
+		require.NoError(t, err)
+		require.Len(t, ops, 2)
+		require.Equal(t, op2, ops[0])
+		require.Equal(t, op3, ops[1])
+
+		l, err := q2.Add(op1)
+		require.NoError(t, err)
+		require.Equal(t, uint(3), l)
+
+		closeQueue(t, q)
+	})
+
+	t.Run("partially written record is discarded", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+
+		_, err = q.Add(op1)
+		require.NoError(t, err)
+
+		size := logSize(t, dir)
+		closeQueue(t, q)
+
+		// simulate a crash while appending a record
+		record, err := encodeRecord(op2)
+		require.NoError(t, err)
+		appendToLog(t, dir, record[:len(record)-2])
+
+		q, err = NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		require.Equal(t, uint(1), q.Len())
+		require.Equal(t, size, logSize(t, dir))
+
+		l, err := q.Add(op3)
+		require.NoError(t, err)
+		require.Equal(t, uint(2), l)
+
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		ops, err := q2.Peek(3)
+		require.NoError(t, err)
+		require.Len(t, ops, 2)
+		require.Equal(t, op1, ops[0])
+		require.Equal(t, op3, ops[1])
+	})
+
+	t.Run("partially written record header is discarded", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		appendToLog(t, dir, []byte{0, 0, 1})
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		require.Zero(t, q.Len())
+		require.Zero(t, logSize(t, dir))
+	})
+
+	t.Run("corrupted record is discarded", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		record, err := encodeRecord(op1)
+		require.NoError(t, err)
+		record[len(record)-1] ^= 0xff
+		appendToLog(t, dir, record)
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		require.Zero(t, q.Len())
+	})
+
+	t.Run("head is reset when log was truncated", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		// simulate a crash after the log was truncated but before the head was reset
+		writeHead(t, dir, 0, 1000)
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		require.Zero(t, q.Len())
+
+		_, err = q.Add(op1)
+		require.NoError(t, err)
+
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		require.Equal(t, uint(1), q2.Len())
+	})
+}
+
+func TestFileQueue_Compaction(t *testing.T) {
+	op4 := &batch.OperationInfo{Namespace: "ns", UniqueSuffix: "op4", Data: []byte("op4")}
+
+	t.Run("log is compacted once the removed operations exceed the threshold", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		record, err := encodeRecord(op1)
+		require.NoError(t, err)
+
+		recordSize := int64(len(record))
+
+		q, err := NewFileQueue(dir, WithCompactionThreshold(2*recordSize))
+		require.NoError(t, err)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op3, op4} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		// the removed operations don't exceed the threshold
+		_, _, err = q.Remove(1)
+		require.NoError(t, err)
+		require.Equal(t, 4*recordSize, logSize(t, dir))
+
+		_, _, err = q.Remove(1)
+		require.NoError(t, err)
+
+		// the remaining operations were copied to a new log and the old log was deleted
+		_, err = os.Stat(filepath.Join(dir, logFileName))
+		require.True(t, os.IsNotExist(err))
+		require.Equal(t, 2*recordSize, fileSize(t, q.logPath(1)))
+
+		ops, err := q.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op3, op4}, ops)
+
+		_, err = q.Add(op1)
+		require.NoError(t, err)
+
+		n, l, err := q.Remove(1)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), n)
+		require.Equal(t, uint(2), l)
+
+		// the compacted log survives restart
+		closeQueue(t, q)
+
+		q, err = NewFileQueue(dir, WithCompactionThreshold(2*recordSize))
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		ops, err = q.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op4, op1}, ops)
+	})
+
+	t.Run("log isn't compacted while most of the log remains", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir, WithCompactionThreshold(1))
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op3, op4} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		_, _, err = q.Remove(1)
+		require.NoError(t, err)
+
+		_, err = os.Stat(q.logPath(1))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("crash during compaction", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op3} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		_, _, err = q.Remove(1)
+		require.NoError(t, err)
+
+		closeQueue(t, q)
+
+		// simulate a crash after the new log was written but before the head pointer refers to it
+		require.NoError(t, ioutil.WriteFile(q.logPath(1), []byte("partial"), 0600))
+
+		q, err = NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		ops, err := q.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op2, op3}, ops)
+
+		// the stale log was deleted
+		_, err = os.Stat(q.logPath(1))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("compaction error", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir, WithCompactionThreshold(1))
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		for _, op := range []*batch.OperationInfo{op1, op2} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		// the new log can't be created
+		require.NoError(t, os.Mkdir(q.logPath(1), 0700))
+
+		n, l, err := q.Remove(1)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), n)
+		require.Equal(t, uint(1), l)
+
+		// the operations are loaded from the current log
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		ops, err := q2.Peek(2)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op2}, ops)
+	})
+
+	t.Run("truncation error", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		for _, op := range []*batch.OperationInfo{op1, op2} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		// the empty log can't be created
+		require.NoError(t, os.Mkdir(q.logPath(1), 0700))
+
+		n, l, err := q.Remove(2)
+		require.Error(t, err)
+		require.Zero(t, n)
+		require.Equal(t, uint(2), l)
+
+		// the queue is unchanged
+		ops, err := q.Peek(2)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op1, op2}, ops)
+
+		_, err = q.Add(op3)
+		require.NoError(t, err)
+
+		require.NoError(t, os.Remove(q.logPath(1)))
+
+		n, l, err = q.Remove(3)
+		require.NoError(t, err)
+		require.Equal(t, uint(3), n)
+		require.Zero(t, l)
+
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		require.Zero(t, q2.Len())
+	})
+}
+
+func TestFileQueue_RemoveAt(t *testing.T) {
+	t.Run("operations survive restart", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op3} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		n, l, err := q.RemoveAt([]uint{0, 2})
+		require.NoError(t, err)
+		require.Equal(t, uint(2), n)
+		require.Equal(t, uint(1), l)
+
+		// the remaining operations were written to the log of the next generation
+		_, err = os.Stat(filepath.Join(dir, logFileName))
+		require.True(t, os.IsNotExist(err))
+		require.NotZero(t, fileSize(t, q.logPath(1)))
+
+		l, err = q.Add(op1)
+		require.NoError(t, err)
+		require.Equal(t, uint(2), l)
+
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		ops, err := q2.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op2, op1}, ops)
+	})
+
+	t.Run("operations at the head of the queue", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op3} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		n, l, err := q.RemoveAt([]uint{1, 0})
+		require.NoError(t, err)
+		require.Equal(t, uint(2), n)
+		require.Equal(t, uint(1), l)
+
+		// the head pointer was advanced in the current log
+		require.Zero(t, q.gen)
+
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		ops, err := q2.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op3}, ops)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op3} {
+			_, err = q.Add(op)
+			require.NoError(t, err)
+		}
+
+		// the new log can't be created
+		require.NoError(t, os.Mkdir(q.logPath(1), 0700))
+
+		n, l, err := q.RemoveAt([]uint{1})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create queue log")
+		require.Zero(t, n)
+		require.Equal(t, uint(3), l)
+
+		// the queue is unchanged
+		ops, err := q.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []*batch.OperationInfo{op1, op2, op3}, ops)
+
+		q2, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		defer closeQueue(t, q2)
+
+		require.Equal(t, uint(3), q2.Len())
+	})
+}
+
+func TestFileQueue_Error(t *testing.T) {
+	t.Run("invalid head", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, headFileName), []byte("head"), 0600))
+
+		q, err := NewFileQueue(dir)
+		require.Error(t, err)
+		require.Nil(t, q)
+		require.Contains(t, err.Error(), "invalid queue head size [4]")
+	})
+
+	t.Run("invalid directory", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		path := filepath.Join(dir, "file")
+		require.NoError(t, ioutil.WriteFile(path, []byte("file"), 0600))
+
+		q, err := NewFileQueue(path)
+		require.Error(t, err)
+		require.Nil(t, q)
+		require.Contains(t, err.Error(), "failed to create queue directory")
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		dir, cleanup := newTestDir(t)
+		defer cleanup()
+
+		q, err := NewFileQueue(dir)
+		require.NoError(t, err)
+		require.NoError(t, q.Close())
+
+		l, err := q.Add(op1)
+		require.Error(t, err)
+		require.Zero(t, l)
+		require.Contains(t, err.Error(), "failed to write operation to queue log")
+		require.Zero(t, q.Len())
+	})
+}
+
+func newTestDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "opqueue")
+	require.NoError(t, err)
+
+	return dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func closeQueue(t *testing.T, q *FileQueue) {
+	require.NoError(t, q.Close())
+}
+
+func logSize(t *testing.T, dir string) int64 {
+	return fileSize(t, filepath.Join(dir, logFileName))
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	return info.Size()
+}
+
+func appendToLog(t *testing.T, dir string, bytes []byte) {
+	f, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	require.NoError(t, err)
+
+	_, err = f.Write(bytes)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func writeHead(t *testing.T, dir string, gen, head uint64) {
+	bytes := make([]byte, headSize)
+	binary.BigEndian.PutUint64(bytes[0:8], gen)
+	binary.BigEndian.PutUint64(bytes[8:16], head)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, headFileName), bytes, 0600))
+}
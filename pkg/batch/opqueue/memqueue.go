@@ -58,6 +58,27 @@ func (q *MemQueue) Remove(num uint) (uint, uint, error) {
 	return uint(len(items)), uint(len(q.items)), nil
 }
 
+// RemoveAt removes the operations at the given positions in the queue.
+// Returns the actual number of items that were removed and the new length of the queue.
+func (q *MemQueue) RemoveAt(indexes []uint) (uint, uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	remove := positions(indexes, len(q.items))
+
+	// a new slice is allocated since the operations returned by Peek share the current slice
+	items := make([]*batch.OperationInfo, 0, len(q.items)-len(remove))
+	for i, item := range q.items {
+		if !remove[i] {
+			items = append(items, item)
+		}
+	}
+
+	q.items = items
+
+	return uint(len(remove)), uint(len(q.items)), nil
+}
+
 // Len returns the length of the queue.
 func (q *MemQueue) Len() uint {
 	q.mutex.RLock()
@@ -65,3 +86,27 @@ func (q *MemQueue) Len() uint {
 
 	return uint(len(q.items))
 }
+
+// positions returns the given positions that are less than the given length of the queue
+func positions(indexes []uint, length int) map[int]bool {
+	remove := make(map[int]bool)
+
+	for _, i := range indexes {
+		if i < uint(length) {
+			remove[int(i)] = true
+		}
+	}
+
+	return remove
+}
+
+// isHead returns true if the given positions are the positions at the head of the queue
+func isHead(remove map[int]bool) bool {
+	for i := 0; i < len(remove); i++ {
+		if !remove[i] {
+			return false
+		}
+	}
+
+	return true
+}
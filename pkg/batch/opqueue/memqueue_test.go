@@ -70,3 +70,33 @@ func TestMemQueue(t *testing.T) {
 	require.Equal(t, uint(2), n)
 	require.Zero(t, l)
 }
+
+func TestMemQueue_RemoveAt(t *testing.T) {
+	q := &MemQueue{}
+
+	for _, op := range []*batch.OperationInfo{op1, op2, op3} {
+		_, err := q.Add(op)
+		require.NoError(t, err)
+	}
+
+	peeked, err := q.Peek(3)
+	require.NoError(t, err)
+
+	// positions beyond the end of the queue are ignored
+	n, l, err := q.RemoveAt([]uint{1, 5})
+	require.NoError(t, err)
+	require.Equal(t, uint(1), n)
+	require.Equal(t, uint(2), l)
+
+	ops, err := q.Peek(3)
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationInfo{op1, op3}, ops)
+
+	// the previously peeked operations are unchanged
+	require.Equal(t, []*batch.OperationInfo{op1, op2, op3}, peeked)
+
+	n, l, err = q.RemoveAt([]uint{0, 1})
+	require.NoError(t, err)
+	require.Equal(t, uint(2), n)
+	require.Zero(t, l)
+}
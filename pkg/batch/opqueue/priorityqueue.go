@@ -0,0 +1,267 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opqueue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+// Lane identifies a lane of the priority queue
+type Lane int
+
+const (
+	// HighPriority is the lane for recover and deactivate operations
+	HighPriority Lane = iota
+
+	// NormalPriority is the lane for all other operations
+	NormalPriority
+)
+
+// Queue defines the functions of a FIFO queue that may be used as a lane of the priority queue
+type Queue interface {
+	Add(data *batch.OperationInfo) (uint, error)
+	Remove(num uint) (uint, uint, error)
+	RemoveAt(indexes []uint) (uint, uint, error)
+	Peek(num uint) ([]*batch.OperationInfo, error)
+	Len() uint
+}
+
+// PriorityQueue holds recover and deactivate operations in a separate lane from all other operations,
+// so that a batch cutter policy may select operations by priority. The time that each operation was added
+// is recorded in the operation.
+//
+// Peek and Remove return the operations of both lanes in the order that they were added, so the queue
+// behaves as a FIFO queue unless the batch cutter selects operations by lane.
+type PriorityQueue struct {
+	mutex    sync.Mutex
+	lanes    []Queue
+	lastTime time.Time
+}
+
+// NewPriorityQueue returns a priority queue that is made up of the given high and normal priority lanes
+func NewPriorityQueue(high, normal Queue) *PriorityQueue {
+	return &PriorityQueue{lanes: []Queue{high, normal}}
+}
+
+// NewMemPriorityQueue returns a priority queue with in-memory lanes
+func NewMemPriorityQueue() *PriorityQueue {
+	return NewPriorityQueue(&MemQueue{}, &MemQueue{})
+}
+
+// Add adds the given operation to the tail of the lane for the operation type and returns the new length of the queue
+func (q *PriorityQueue) Add(data *batch.OperationInfo) (uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	// the times of the operations must be unique so that the order of the operations across lanes is stable
+	now := time.Now()
+	if !now.After(q.lastTime) {
+		now = q.lastTime.Add(time.Nanosecond)
+	}
+
+	op := *data
+	op.Time = now
+
+	if _, err := q.lanes[LaneForType(op.Type)].Add(&op); err != nil {
+		return 0, err
+	}
+
+	q.lastTime = now
+
+	return q.len(), nil
+}
+
+// Peek returns (up to) the given number of operations from the head of the queue (in the order that they
+// were added) but does not remove them.
+func (q *PriorityQueue) Peek(num uint) ([]*batch.OperationInfo, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	ops, _, err := q.peek(num)
+
+	return ops, err
+}
+
+// Remove removes (up to) the given number of operations from the head of the queue (in the order that they
+// were added). Returns the actual number of items that were removed and the new length of the queue.
+//
+// The operations are removed from each lane in turn, so Remove isn't atomic across lanes: if removing the operations
+// from a lane fails then the operations that were already removed from the other lanes remain removed, and their
+// number is returned along with the error.
+func (q *PriorityQueue) Remove(num uint) (uint, uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	_, lanes, err := q.peek(num)
+	if err != nil {
+		return 0, q.len(), err
+	}
+
+	counts := make([]uint, len(q.lanes))
+	for _, lane := range lanes {
+		counts[lane]++
+	}
+
+	var removed uint
+
+	for lane, n := range counts {
+		if n == 0 {
+			continue
+		}
+
+		n, _, err := q.lanes[lane].Remove(n)
+		removed += n
+
+		if err != nil {
+			return removed, q.len(), err
+		}
+	}
+
+	return removed, q.len(), nil
+}
+
+// RemoveAt removes the operations at the given positions in the queue (in the order that the operations were added).
+// Returns the actual number of items that were removed and the new length of the queue.
+// Like Remove, RemoveAt isn't atomic across lanes.
+func (q *PriorityQueue) RemoveAt(indexes []uint) (uint, uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var num uint
+	for _, i := range indexes {
+		if i >= num {
+			num = i + 1
+		}
+	}
+
+	_, lanes, err := q.peek(num)
+	if err != nil {
+		return 0, q.len(), err
+	}
+
+	remove := positions(indexes, len(lanes))
+
+	// the positions of the operations within their lanes
+	laneIndexes := make([][]uint, len(q.lanes))
+	laneLens := make([]uint, len(q.lanes))
+
+	for i, lane := range lanes {
+		if remove[i] {
+			laneIndexes[lane] = append(laneIndexes[lane], laneLens[lane])
+		}
+
+		laneLens[lane]++
+	}
+
+	var removed uint
+
+	for lane, indexes := range laneIndexes {
+		if len(indexes) == 0 {
+			continue
+		}
+
+		n, _, err := q.lanes[lane].RemoveAt(indexes)
+		removed += n
+
+		if err != nil {
+			return removed, q.len(), err
+		}
+	}
+
+	return removed, q.len(), nil
+}
+
+// Len returns the number of operations in the queue
+func (q *PriorityQueue) Len() uint {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.len()
+}
+
+// PeekLane returns (up to) the given number of operations from the head of the given lane but does not remove them.
+func (q *PriorityQueue) PeekLane(lane Lane, num uint) ([]*batch.OperationInfo, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.lanes[lane].Peek(num)
+}
+
+// RemoveLane removes (up to) the given number of operations from the head of the given lane.
+// Returns the actual number of items that were removed and the new length of the queue.
+func (q *PriorityQueue) RemoveLane(lane Lane, num uint) (uint, uint, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	n, _, err := q.lanes[lane].Remove(num)
+
+	return n, q.len(), err
+}
+
+// LaneForType returns the lane for the given operation type
+func LaneForType(opType batch.OperationType) Lane {
+	switch opType {
+	case batch.OperationTypeRecover, batch.OperationTypeDeactivate:
+		return HighPriority
+	default:
+		return NormalPriority
+	}
+}
+
+// peek merges the heads of the lanes by time and returns the first num operations
+// along with the lane of each operation
+func (q *PriorityQueue) peek(num uint) ([]*batch.OperationInfo, []int, error) {
+	heads := make([][]*batch.OperationInfo, len(q.lanes))
+	for i, lane := range q.lanes {
+		ops, err := lane.Peek(num)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		heads[i] = ops
+	}
+
+	counts := make([]uint, len(q.lanes))
+
+	var ops []*batch.OperationInfo
+	var lanes []int
+
+	for uint(len(ops)) < num {
+		next := -1
+		for i, head := range heads {
+			if counts[i] == uint(len(head)) {
+				continue
+			}
+
+			if next == -1 || head[counts[i]].Time.Before(heads[next][counts[next]].Time) {
+				next = i
+			}
+		}
+
+		if next == -1 {
+			break
+		}
+
+		ops = append(ops, heads[next][counts[next]])
+		lanes = append(lanes, next)
+		counts[next]++
+	}
+
+	return ops, lanes, nil
+}
+
+func (q *PriorityQueue) len() uint {
+	var n uint
+	for _, lane := range q.lanes {
+		n += lane.Len()
+	}
+
+	return n
+}
@@ -0,0 +1,264 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opqueue
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+var (
+	update1    = &batch.OperationInfo{Namespace: "ns", UniqueSuffix: "u1", Type: batch.OperationTypeUpdate, Data: []byte("u1")}
+	update2    = &batch.OperationInfo{Namespace: "ns", UniqueSuffix: "u2", Type: batch.OperationTypeUpdate, Data: []byte("u2")}
+	recover1   = &batch.OperationInfo{Namespace: "ns", UniqueSuffix: "r1", Type: batch.OperationTypeRecover, Data: []byte("r1")}
+	deactivate = &batch.OperationInfo{Namespace: "ns", UniqueSuffix: "d1", Type: batch.OperationTypeDeactivate, Data: []byte("d1")}
+)
+
+func TestPriorityQueue(t *testing.T) {
+	q := NewMemPriorityQueue()
+	require.Zero(t, q.Len())
+
+	for i, op := range []*batch.OperationInfo{update1, recover1, update2, deactivate} {
+		l, err := q.Add(op)
+		require.NoError(t, err)
+		require.Equal(t, uint(i+1), l)
+	}
+
+	// the given operation isn't modified
+	require.True(t, update1.Time.IsZero())
+
+	t.Run("lanes", func(t *testing.T) {
+		ops, err := q.PeekLane(HighPriority, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"r1", "d1"}, suffixes(ops))
+
+		ops, err = q.PeekLane(NormalPriority, 10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u1", "u2"}, suffixes(ops))
+		require.True(t, ops[0].Time.Before(ops[1].Time))
+	})
+
+	t.Run("peek in the order added", func(t *testing.T) {
+		ops, err := q.Peek(3)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u1", "r1", "u2"}, suffixes(ops))
+
+		ops, err = q.Peek(10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u1", "r1", "u2", "d1"}, suffixes(ops))
+	})
+
+	t.Run("remove in the order added", func(t *testing.T) {
+		n, l, err := q.Remove(2)
+		require.NoError(t, err)
+		require.Equal(t, uint(2), n)
+		require.Equal(t, uint(2), l)
+
+		ops, err := q.Peek(10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u2", "d1"}, suffixes(ops))
+	})
+
+	t.Run("remove from lane", func(t *testing.T) {
+		n, l, err := q.RemoveLane(HighPriority, 5)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), n)
+		require.Equal(t, uint(1), l)
+
+		ops, err := q.Peek(10)
+		require.NoError(t, err)
+		require.Equal(t, []string{"u2"}, suffixes(ops))
+
+		n, l, err = q.Remove(5)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), n)
+		require.Zero(t, l)
+	})
+}
+
+func TestPriorityQueue_RemoveAt(t *testing.T) {
+	q := NewMemPriorityQueue()
+
+	for _, op := range []*batch.OperationInfo{update1, recover1, update2, deactivate} {
+		_, err := q.Add(op)
+		require.NoError(t, err)
+	}
+
+	// the positions are in the order that the operations were added
+	n, l, err := q.RemoveAt([]uint{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, uint(2), n)
+	require.Equal(t, uint(2), l)
+
+	ops, err := q.Peek(10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1", "d1"}, suffixes(ops))
+
+	ops, err = q.PeekLane(HighPriority, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"d1"}, suffixes(ops))
+
+	n, l, err = q.RemoveAt([]uint{1, 5})
+	require.NoError(t, err)
+	require.Equal(t, uint(1), n)
+	require.Equal(t, uint(1), l)
+
+	ops, err = q.Peek(10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1"}, suffixes(ops))
+}
+
+func TestPriorityQueue_FileLanes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "priorityqueue")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	high, err := NewFileQueue(dir + "/high")
+	require.NoError(t, err)
+
+	normal, err := NewFileQueue(dir + "/normal")
+	require.NoError(t, err)
+
+	q := NewPriorityQueue(high, normal)
+
+	for _, op := range []*batch.OperationInfo{update1, recover1, update2} {
+		_, err = q.Add(op)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, high.Close())
+	require.NoError(t, normal.Close())
+
+	// the order of the operations across lanes is restored from the persisted times
+	high, err = NewFileQueue(dir + "/high")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, high.Close()) }()
+
+	normal, err = NewFileQueue(dir + "/normal")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, normal.Close()) }()
+
+	q = NewPriorityQueue(high, normal)
+	require.Equal(t, uint(3), q.Len())
+
+	ops, err := q.Peek(10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1", "r1", "u2"}, suffixes(ops))
+	require.Equal(t, batch.OperationTypeRecover, ops[1].Type)
+}
+
+func TestPriorityQueue_Error(t *testing.T) {
+	errExpected := errors.New("lane error")
+
+	t.Run("add error", func(t *testing.T) {
+		q := NewPriorityQueue(&MemQueue{}, &errQueue{err: errExpected})
+
+		_, err := q.Add(update1)
+		require.EqualError(t, err, errExpected.Error())
+		require.Zero(t, q.Len())
+	})
+
+	t.Run("peek error", func(t *testing.T) {
+		q := NewPriorityQueue(&MemQueue{}, &errQueue{err: errExpected})
+
+		_, err := q.Peek(1)
+		require.EqualError(t, err, errExpected.Error())
+
+		_, _, err = q.Remove(1)
+		require.EqualError(t, err, errExpected.Error())
+	})
+
+	t.Run("remove error", func(t *testing.T) {
+		normal := &errQueue{removeErr: errExpected}
+		q := NewPriorityQueue(&MemQueue{}, normal)
+
+		_, err := q.Add(update1)
+		require.NoError(t, err)
+
+		_, _, err = q.Remove(1)
+		require.EqualError(t, err, errExpected.Error())
+
+		// the operations that were removed from the other lane remain removed
+		_, err = q.Add(recover1)
+		require.NoError(t, err)
+
+		n, l, err := q.Remove(2)
+		require.EqualError(t, err, errExpected.Error())
+		require.Equal(t, uint(1), n)
+		require.Equal(t, uint(1), l)
+	})
+
+	t.Run("remove at error", func(t *testing.T) {
+		q := NewPriorityQueue(&MemQueue{}, &errQueue{removeErr: errExpected})
+
+		for _, op := range []*batch.OperationInfo{recover1, update1, deactivate, update2} {
+			_, err := q.Add(op)
+			require.NoError(t, err)
+		}
+
+		n, l, err := q.RemoveAt([]uint{2, 3})
+		require.EqualError(t, err, errExpected.Error())
+		require.Equal(t, uint(1), n)
+		require.Equal(t, uint(3), l)
+
+		_, _, err = NewPriorityQueue(&MemQueue{}, &errQueue{err: errExpected}).RemoveAt([]uint{0})
+		require.EqualError(t, err, errExpected.Error())
+	})
+}
+
+func suffixes(ops []*batch.OperationInfo) []string {
+	var result []string
+	for _, op := range ops {
+		result = append(result, op.UniqueSuffix)
+	}
+
+	return result
+}
+
+type errQueue struct {
+	MemQueue
+	err       error
+	removeErr error
+}
+
+func (q *errQueue) Add(data *batch.OperationInfo) (uint, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	return q.MemQueue.Add(data)
+}
+
+func (q *errQueue) Peek(num uint) ([]*batch.OperationInfo, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	return q.MemQueue.Peek(num)
+}
+
+func (q *errQueue) Remove(num uint) (uint, uint, error) {
+	if q.removeErr != nil {
+		return 0, 0, q.removeErr
+	}
+
+	return q.MemQueue.Remove(num)
+}
+
+func (q *errQueue) RemoveAt(indexes []uint) (uint, uint, error) {
+	if q.removeErr != nil {
+		return 0, 0, q.removeErr
+	}
+
+	return q.MemQueue.RemoveAt(indexes)
+}
@@ -16,10 +16,13 @@ SPDX-License-Identifier: Apache-2.0
 // 4) create an anchor file based on batch file address
 // 5) store anchor file into CAS
 // 6) write the address of anchor file to the underlying blockchain
+// 7) optionally, track the anchor until it's observed on the ledger and write it again if it isn't observed in time
 package batch
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -33,12 +36,17 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/batch/cutter"
 	"github.com/trustbloc/sidetree-core-go/pkg/compression"
 	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 	"github.com/trustbloc/sidetree-core-go/pkg/txnhandler"
 )
 
 const (
 	defaultBatchTimeout    = 2 * time.Second
 	defaultSendChannelSize = 100
+	defaultMaxRetries      = 3
+	defaultInitialBackoff  = 100 * time.Millisecond
+	defaultMaxBackoff      = 2 * time.Second
+	defaultMaxAttempts     = 3
 )
 
 // Option defines Writer options such as batch timeout
@@ -55,17 +63,37 @@ type process struct {
 	force bool
 }
 
+type flush struct {
+	ctx context.Context
+	// pending receives the number of operations that remain in the queue after the flush
+	pending chan uint
+}
+
 // Writer implements batch writer
 type Writer struct {
 	namespace    string
 	context      Context
 	batchCutter  batchCutter
 	sendChan     chan process
+	flushChan    chan flush
 	exitChan     chan struct{}
+	exitOnce     sync.Once
+	doneChan     chan struct{}
+	started      uint32
 	batchTimeout time.Duration
 	opsHandler   TxnHandler
+	opsParser    OperationParser
+	deadLetters  DeadLetterStore
+	statusStore  OperationStatusStore
+	validator    OperationValidator
+	errChan      chan<- error
+	anchors      *anchorTracker
+	txnChan      <-chan []txn.SidetreeTxn
+	retry        RetryOptions
 	stopped      uint32
 	protocol     protocol.Client
+	// protocolVersion is the starting blockchain time of the protocol version that was last used to cut a batch
+	protocolVersion uint
 }
 
 // Context contains batch writer context
@@ -91,7 +119,53 @@ type BlockchainClient interface {
 type TxnHandler interface {
 
 	// GetTxnOperations operations will create relevant files, store them in CAS and return anchor string
-	PrepareTxnFiles(ops []*batch.Operation) (string, error)
+	// along with the number of operations (from the start of ops) in the batch
+	PrepareTxnFiles(ops []*batch.Operation) (string, int, error)
+}
+
+// OperationParser defines an interface for parsing operations
+type OperationParser interface {
+
+	// Parse parses and validates operation
+	Parse(namespace string, operationBuffer []byte) (*batch.Operation, error)
+}
+
+// DeadLetterStore defines an interface for storing operations that can never be processed
+type DeadLetterStore interface {
+
+	// Put stores the rejected operation
+	Put(op *batch.RejectedOperation) error
+}
+
+// OperationStatusStore defines an interface for recording the state transitions of operations
+type OperationStatusStore interface {
+
+	// Put records the state transition of an operation
+	Put(status *batch.OperationStatus) error
+}
+
+// OperationValidator defines an interface for validating operations against the current
+// state of their documents before they are anchored
+type OperationValidator interface {
+
+	// Validate validates the given operations (in order) and returns the ones that are invalid along with the reason
+	Validate(ops []*batch.Operation) (map[*batch.Operation]error, error)
+
+	// Anchored notifies the validator that the given operations were anchored
+	Anchored(ops []*batch.Operation)
+
+	// Abandoned notifies the validator that the anchor of the given operations was abandoned
+	Abandoned(ops []*batch.Operation)
+}
+
+// RejectedOperationError is sent to the error channel when the batch writer rejects an operation
+type RejectedOperationError struct {
+	*batch.RejectedOperation
+}
+
+// Error returns the reason the operation was rejected
+func (e *RejectedOperationError) Error() string {
+	return fmt.Sprintf("operation for suffix[%s] was rejected: %s", e.UniqueSuffix, e.Reason)
 }
 
 // CompressionProvider defines an interface for handling different types of compression
@@ -130,38 +204,130 @@ func New(namespace string, context Context, options ...Option) (*Writer, error)
 		txnHandler = txnhandler.NewOperationHandler(context.CAS(), context.Protocol(), compressionProvider)
 	}
 
+	var opsParser OperationParser
+	if rOpts.OpsParser != nil {
+		opsParser = rOpts.OpsParser
+	} else {
+		opsParser = operation.NewParser(context.Protocol())
+	}
+
+	retry := RetryOptions{
+		MaxRetries:     defaultMaxRetries,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+	}
+	if rOpts.Retry != nil {
+		retry = *rOpts.Retry
+	}
+
+	var anchors *anchorTracker
+	if rOpts.Confirmation != nil {
+		anchors, err = loadAnchors(namespace, *rOpts.Confirmation, rOpts.AnchorStore, rOpts.OperationValidator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cutterOpts []cutter.Option
+	if rOpts.CutterPolicy != nil {
+		cutterOpts = append(cutterOpts, cutter.WithPolicy(rOpts.CutterPolicy))
+	}
+
 	return &Writer{
-		namespace:    namespace,
-		batchCutter:  cutter.New(context.Protocol(), context.OperationQueue()),
-		sendChan:     make(chan process, defaultSendChannelSize),
-		exitChan:     make(chan struct{}),
-		batchTimeout: batchTimeout,
-		context:      context,
-		opsHandler:   txnHandler,
-		protocol:     context.Protocol(),
+		namespace:       namespace,
+		batchCutter:     cutter.New(context.Protocol(), context.OperationQueue(), cutterOpts...),
+		sendChan:        make(chan process, defaultSendChannelSize),
+		flushChan:       make(chan flush),
+		exitChan:        make(chan struct{}),
+		doneChan:        make(chan struct{}),
+		batchTimeout:    batchTimeout,
+		context:         context,
+		opsHandler:      txnHandler,
+		opsParser:       opsParser,
+		deadLetters:     rOpts.DeadLetterStore,
+		statusStore:     rOpts.OperationStatusStore,
+		validator:       rOpts.OperationValidator,
+		errChan:         rOpts.ErrorChannel,
+		anchors:         anchors,
+		retry:           retry,
+		protocol:        context.Protocol(),
+		protocolVersion: context.Protocol().Current().StartingBlockChainTime,
 	}, nil
 }
 
 // Start periodic anchoring of operation batches to blockchain.
 func (r *Writer) Start() {
+	atomic.StoreUint32(&r.started, 1)
+
+	if r.anchors != nil {
+		r.txnChan = r.anchors.Ledger.RegisterForSidetreeTxn()
+	}
+
 	go r.main()
 }
 
 // Stop frees the resources which were allocated by start.
+// Pending operations remain in the queue. Use Shutdown to anchor pending operations before stopping.
 func (r *Writer) Stop() {
-	if !atomic.CompareAndSwapUint32(&r.stopped, 0, 1) {
-		// Already stopped
-		return
+	atomic.StoreUint32(&r.stopped, 1)
+
+	// Allow multiple halts without panic
+	r.exitOnce.Do(func() {
+		close(r.exitChan)
+	})
+}
+
+// Shutdown stops accepting new operations, cuts and anchors batches of all pending operations and then stops
+// the writer. It returns the number of operations that remain in the queue and were therefore not anchored.
+// If the given context is done before all pending operations are anchored then the writer is stopped
+// (the remaining operations stay in the queue) and the context error is returned.
+func (r *Writer) Shutdown(ctx context.Context) (uint, error) {
+	atomic.StoreUint32(&r.stopped, 1)
+	defer r.Stop()
+
+	if atomic.LoadUint32(&r.started) == 0 {
+		return r.pending(), nil
 	}
 
+	log.Infof("[%s] Shutting down batch writer. Anchoring pending operations: %d", r.namespace, r.pending())
+
+	req := flush{ctx: ctx, pending: make(chan uint, 1)}
+
 	select {
-	case <-r.exitChan:
-		// Allow multiple halts without panic
-	default:
-		close(r.exitChan)
+	case r.flushChan <- req:
+	case <-r.doneChan:
+		log.Infof("[%s] Batch writer was already stopped. Pending operations: %d", r.namespace, r.pending())
+		return r.pending(), nil
+	case <-ctx.Done():
+		return r.abortShutdown(ctx)
+	}
+
+	select {
+	case pending := <-req.pending:
+		if pending > 0 {
+			log.Warnf("[%s] Batch writer shut down with %d operations that were not anchored", r.namespace, pending)
+			return pending, ctx.Err()
+		}
+
+		log.Infof("[%s] Batch writer shut down. All pending operations were anchored.", r.namespace)
+		return 0, nil
+	case <-ctx.Done():
+		return r.abortShutdown(ctx)
 	}
 }
 
+func (r *Writer) abortShutdown(ctx context.Context) (uint, error) {
+	pending := r.pending()
+
+	log.Warnf("[%s] Batch writer shutdown aborted: %s. Operations that were not anchored: %d", r.namespace, ctx.Err(), pending)
+
+	return pending, ctx.Err()
+}
+
+func (r *Writer) pending() uint {
+	return r.context.OperationQueue().Len()
+}
+
 // Stopped returns true if the writer has been stopped
 func (r *Writer) Stopped() bool {
 	return atomic.LoadUint32(&r.stopped) == 1
@@ -189,8 +355,18 @@ func (r *Writer) Add(operation *batch.OperationInfo) error {
 }
 
 func (r *Writer) main() {
+	defer close(r.doneChan)
+
 	var timer <-chan time.Time
 
+	var confirmTimer <-chan time.Time
+	if r.anchors != nil && r.anchors.Timeout > 0 {
+		ticker := time.NewTicker(r.anchors.checkInterval())
+		defer ticker.Stop()
+
+		confirmTimer = ticker.C
+	}
+
 	// On startup, there may be operations in the queue. Send a notification
 	// so that any pending items in the queue may be immediately processed.
 	r.sendChan <- process{force: true}
@@ -207,6 +383,28 @@ func (r *Writer) main() {
 			pending := r.processAvailable(true) > 0
 			timer = r.handleTimer(nil, pending)
 
+		case f := <-r.flushChan:
+			log.Infof("[%s] Handling flush request for batch writer", r.namespace)
+			pending := r.flush(f.ctx)
+			timer = r.handleTimer(timer, pending > 0)
+			f.pending <- pending
+
+		case txns, ok := <-r.txnChan:
+			if !ok {
+				log.Warnf("[%s] Sidetree transaction channel was closed. Anchors will be confirmed by timeout only.", r.namespace)
+				r.txnChan = nil
+				continue
+			}
+
+			if n := r.anchors.observed(txns); n > 0 {
+				log.Infof("[%s] %d anchors were observed on the ledger. Outstanding anchors: %d", r.namespace, n, r.anchors.len())
+			}
+
+			r.checkAnchors()
+
+		case <-confirmTimer:
+			r.checkAnchors()
+
 		case <-r.exitChan:
 			log.Infof("[%s] exiting batch writer", r.namespace)
 			return
@@ -214,6 +412,24 @@ func (r *Writer) main() {
 	}
 }
 
+// flush processes all pending operations until the queue is empty, the given context is done
+// or no further progress can be made. Returns the number of pending operations.
+func (r *Writer) flush(ctx context.Context) uint {
+	pending := r.processAvailable(true)
+
+	for pending > 0 && ctx.Err() == nil {
+		p := r.processAvailable(true)
+		if p >= pending {
+			log.Warnf("[%s] Unable to process pending operations while flushing batch writer. Pending operations: %d", r.namespace, p)
+			return p
+		}
+
+		pending = p
+	}
+
+	return pending
+}
+
 func (r *Writer) processAvailable(forceCut bool) uint {
 	// First drain the queue of all of the operations that are ready to form a batch
 	pending, err := r.drain()
@@ -258,7 +474,7 @@ func (r *Writer) drain() (pending uint, err error) {
 }
 
 func (r *Writer) cutAndProcess(forceCut bool) (numProcessed int, pending uint, err error) {
-	operations, pending, commit, err := r.batchCutter.Cut(forceCut)
+	operations, pending, commit, p, err := r.cut(forceCut)
 	if err != nil {
 		log.Errorf("[%s] Error cutting batch: %s", r.namespace, err)
 		return 0, pending, err
@@ -271,15 +487,15 @@ func (r *Writer) cutAndProcess(forceCut bool) (numProcessed int, pending uint, e
 
 	log.Infof("[%s] processing %d batch operations ...", r.namespace, len(operations))
 
-	err = r.process(operations)
+	n, err := r.process(operations, p)
 	if err != nil {
 		log.Errorf("[%s] Error processing %d batch operations: %s", r.namespace, len(operations), err)
 		return 0, pending + uint(len(operations)), err
 	}
 
-	log.Infof("[%s] Successfully processed %d batch operations. Committing to batch cutter ...", r.namespace, len(operations))
+	log.Infof("[%s] Successfully processed %d of %d batch operations. Committing to batch cutter ...", r.namespace, n, len(operations))
 
-	pending, err = commit()
+	pending, err = commit(n)
 	if err != nil {
 		log.Errorf("[%s] Batch operations were committed but could not be removed from the queue due to error [%s]. Stopping the batch writer so that no further operations are added.", r.namespace, err)
 		r.Stop()
@@ -288,42 +504,370 @@ func (r *Writer) cutAndProcess(forceCut bool) (numProcessed int, pending uint, e
 
 	log.Infof("[%s] Successfully committed to batch cutter. Pending operations: %d", r.namespace, pending)
 
-	return len(operations), pending, nil
+	return int(n), pending, nil
+}
+
+// cut cuts a batch using the current protocol version. If the protocol version changes while the batch
+// is being cut then the batch is cut again so that the batch size conforms to the new protocol version.
+func (r *Writer) cut(forceCut bool) ([]*batch.OperationInfo, uint, cutter.Committer, protocol.Protocol, error) {
+	for {
+		p := r.protocol.Current()
+
+		if p.StartingBlockChainTime != r.protocolVersion {
+			log.Infof("[%s] Protocol version changed from [%d] to [%d]. Pending operations will be validated against the new protocol version.", r.namespace, r.protocolVersion, p.StartingBlockChainTime)
+			r.protocolVersion = p.StartingBlockChainTime
+		}
+
+		operations, pending, commit, err := r.batchCutter.Cut(forceCut)
+		if err != nil || r.protocol.Current().StartingBlockChainTime == p.StartingBlockChainTime {
+			return operations, pending, commit, p, err
+		}
+
+		log.Infof("[%s] Protocol version changed while cutting batch. Cutting batch again ...", r.namespace)
+	}
 }
 
-func (r *Writer) process(ops []*batch.OperationInfo) error {
+// process validates the given operations against the given protocol version, creates the batch files
+// and writes the anchor string to the blockchain. Operations that do not conform to the protocol version are moved
+// to the dead-letter store. Transient CAS and blockchain errors are retried with exponential backoff.
+// If the protocol version changes while the batch files are being created then the anchor string is not written
+// since the batch files may violate the new protocol version. The operations remain in the queue and are processed again.
+//
+// Returns the number of operations (from the start of ops) that were processed, i.e. either anchored or rejected.
+// If the batch files of all of the valid operations would exceed the maximum file sizes then the batch ends before
+// the first operation that doesn't fit, and that operation and the ones after it remain in the queue for the next batch.
+func (r *Writer) process(ops []*batch.OperationInfo, p protocol.Protocol) (uint, error) {
 	if len(ops) == 0 {
-		return errors.New("create batch called with no pending operations, should not happen")
+		return 0, errors.New("create batch called with no pending operations, should not happen")
 	}
 
 	batchSuffixes := make(map[string]bool)
 
 	var operations []*batch.Operation
-	for _, d := range ops {
-		op, err := operation.ParseOperation(d.Namespace, d.Data, r.protocol.Current())
+	var infos []*batch.OperationInfo
+	var indexes []int
+	var rejected []*rejection
+	for i, d := range ops {
+		op, err := r.opsParser.Parse(d.Namespace, d.Data)
+		if err == nil && len(op.EncodedDelta) > int(p.MaxDeltaByteSize) {
+			err = fmt.Errorf("delta size [%d] exceeds maximum delta size [%d]", len(op.EncodedDelta), p.MaxDeltaByteSize)
+		}
+
 		if err != nil {
-			return err
+			reason := fmt.Sprintf("operation does not conform to protocol version [%d]: %s", p.StartingBlockChainTime, err)
+			rejected = append(rejected, &rejection{index: i, info: d, reason: reason})
+
+			continue
 		}
 
-		_, ok := batchSuffixes[op.UniqueSuffix]
-		if ok {
-			log.Warnf("[%s] duplicate suffix[%s] found in batch operations: discarding operation %v", r.namespace, op.UniqueSuffix, op)
+		// The batch cutter defers operations for a suffix that is already in the batch to the next batch,
+		// so a duplicate suffix means that the suffix of the queued operation doesn't match the operation.
+		if batchSuffixes[op.UniqueSuffix] {
+			reason := fmt.Sprintf("duplicate suffix[%s] found in batch operations: queued with suffix[%s]", op.UniqueSuffix, d.UniqueSuffix)
+			rejected = append(rejected, &rejection{index: i, info: d, reason: reason})
+
 			continue
 		}
 
 		operations = append(operations, op)
+		infos = append(infos, d)
+		indexes = append(indexes, i)
 		batchSuffixes[op.UniqueSuffix] = true
 	}
 
-	anchorString, err := r.opsHandler.PrepareTxnFiles(operations)
+	operations, indexes, invalid, err := r.validate(operations, infos, indexes)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	rejected = append(rejected, invalid...)
+
+	if len(operations) == 0 {
+		log.Warnf("[%s] no valid operations in batch of %d operations: nothing to anchor", r.namespace, len(ops))
+
+		return uint(len(ops)), r.rejectAll(rejected, len(ops))
+	}
+
+	var anchorString string
+	var n int
+
+	err = r.withRetry("prepare batch files", func() error {
+		var e error
+		anchorString, n, e = r.opsHandler.PrepareTxnFiles(operations)
+
+		return e
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if current := r.protocol.Current(); current.StartingBlockChainTime != p.StartingBlockChainTime {
+		return 0, fmt.Errorf("protocol version changed from [%d] to [%d] while preparing batch files", p.StartingBlockChainTime, current.StartingBlockChainTime)
+	}
+
+	processed := len(ops)
+	if n < len(operations) {
+		processed = indexes[n]
+
+		log.Infof("[%s] batch files of %d operations exceed the maximum file size: anchoring %d operations. The remaining operations stay in the queue.", r.namespace, len(operations), n)
+
+		operations = operations[:n]
+	}
+
+	// the operations after the batch remain in the queue so they're only rejected once they're processed
+	if err := r.rejectAll(rejected, processed); err != nil {
+		return 0, err
+	}
+
+	// The anchor is tracked before it's written so that it isn't lost if the writer is restarted
+	// after the operations were removed from the queue
+	if r.anchors != nil {
+		if err := r.anchors.add(anchorString, operations); err != nil {
+			return 0, err
+		}
 	}
 
 	log.Infof("[%s] writing anchor string: %s", r.namespace, anchorString)
 
 	// Create Sidetree transaction in blockchain (write anchor string)
-	return r.context.Blockchain().WriteAnchor(anchorString)
+	err = r.withRetry("write anchor", func() error {
+		return r.context.Blockchain().WriteAnchor(anchorString)
+	})
+	if err != nil {
+		if r.anchors != nil {
+			r.anchors.remove(anchorString)
+		}
+
+		return 0, err
+	}
+
+	if r.validator != nil {
+		r.validator.Anchored(operations)
+	}
+
+	r.updateStatus(operations, anchorString)
+
+	return uint(processed), nil
+}
+
+// rejection is an operation of the batch that is rejected along with the reason
+type rejection struct {
+	// index is the index of the operation in the batch
+	index  int
+	info   *batch.OperationInfo
+	reason string
+	// op and statusReason are set if the operation was parsed, so that its status is updated
+	op           *batch.Operation
+	statusReason string
+}
+
+// rejectAll rejects the given operations whose index in the batch is less than the given number of processed operations
+func (r *Writer) rejectAll(rejected []*rejection, processed int) error {
+	for _, rj := range rejected {
+		if rj.index >= processed {
+			continue
+		}
+
+		if err := r.reject(rj.info, rj.reason); err != nil {
+			return err
+		}
+
+		if rj.op != nil {
+			r.rejectStatus(rj.op, rj.statusReason)
+		}
+	}
+
+	return nil
+}
+
+// loadAnchors returns the anchor tracker for the given options along with the outstanding anchors from the anchor store.
+// The validator is notified of the operations of the outstanding anchors since they may not have been persisted yet.
+func loadAnchors(namespace string, opts ConfirmationOptions, store AnchorStore, validator OperationValidator) (*anchorTracker, error) {
+	anchors := newAnchorTracker(opts, store)
+
+	loaded, err := anchors.load()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to load outstanding anchors")
+	}
+
+	if len(loaded) > 0 {
+		log.Infof("[%s] Loaded %d outstanding anchors from the anchor store", namespace, len(loaded))
+	}
+
+	if validator != nil {
+		for _, anchor := range loaded {
+			validator.Anchored(anchor.ops)
+		}
+	}
+
+	return anchors, nil
+}
+
+// checkAnchors writes the anchors that weren't observed on the ledger in time again. The batch files
+// are already stored in CAS so only the anchor string is written. An anchor that wasn't observed after
+// the maximum number of attempts is dropped and reported to the error channel, and its operations
+// are no longer taken into account when validating new operations.
+func (r *Writer) checkAnchors() {
+	for _, anchor := range r.anchors.expired() {
+		if anchor.attempts >= r.anchors.MaxAttempts {
+			err := &AnchorNotConfirmedError{AnchorString: anchor.anchorString, Attempts: anchor.attempts}
+
+			log.Errorf("[%s] %s. Giving up on %d operations.", r.namespace, err, len(anchor.ops))
+
+			r.anchors.remove(anchor.anchorString)
+
+			if r.validator != nil {
+				r.validator.Abandoned(anchor.ops)
+			}
+
+			for _, op := range anchor.ops {
+				r.rejectStatus(op, err.Error())
+			}
+
+			r.notify(err)
+
+			continue
+		}
+
+		log.Warnf("[%s] anchor[%s] was not observed on the ledger. Writing anchor again (attempt %d) ...", r.namespace, anchor.anchorString, anchor.attempts+1)
+
+		err := r.withRetry("write anchor again", func() error {
+			return r.context.Blockchain().WriteAnchor(anchor.anchorString)
+		})
+		if err != nil {
+			// the anchor is still outstanding so it's written again on the next check
+			log.Errorf("[%s] failed to write anchor[%s] again: %s", r.namespace, anchor.anchorString, err)
+			continue
+		}
+
+		r.anchors.rewritten(anchor)
+	}
+}
+
+// validate validates the given operations against the current state of their documents (if an operation
+// validator was provided). Returns the valid operations along with their indexes in the batch,
+// and the rejections of the invalid ones.
+func (r *Writer) validate(ops []*batch.Operation, infos []*batch.OperationInfo, indexes []int) ([]*batch.Operation, []int, []*rejection, error) {
+	if r.validator == nil || len(ops) == 0 {
+		return ops, indexes, nil, nil
+	}
+
+	invalid, err := r.validator.Validate(ops)
+	if err != nil {
+		return nil, nil, nil, errors.WithMessage(err, "validate operations")
+	}
+
+	var valid []*batch.Operation
+	var validIndexes []int
+	var rejected []*rejection
+	for i, op := range ops {
+		reason, ok := invalid[op]
+		if !ok {
+			valid = append(valid, op)
+			validIndexes = append(validIndexes, indexes[i])
+			continue
+		}
+
+		rejected = append(rejected, &rejection{
+			index:        indexes[i],
+			info:         infos[i],
+			op:           op,
+			reason:       fmt.Sprintf("operation is not valid for the current document state: %s", reason),
+			statusReason: reason.Error(),
+		})
+	}
+
+	return valid, validIndexes, rejected, nil
+}
+
+// updateStatus records that the operations were batched. Status tracking doesn't affect batch processing
+// so errors are only logged.
+func (r *Writer) updateStatus(ops []*batch.Operation, anchorString string) {
+	if r.statusStore == nil {
+		return
+	}
+
+	for _, op := range ops {
+		status := opstatus.NewStatus(op, batch.OperationStateBatched)
+		status.AnchorString = anchorString
+
+		if err := r.statusStore.Put(status); err != nil {
+			log.Warnf("[%s] failed to record status of operation for suffix[%s]: %s", r.namespace, op.UniqueSuffix, err)
+		}
+	}
+}
+
+// rejectStatus records that the operation was rejected. Status tracking doesn't affect batch processing
+// so errors are only logged.
+func (r *Writer) rejectStatus(op *batch.Operation, reason string) {
+	if r.statusStore == nil {
+		return
+	}
+
+	status := opstatus.NewStatus(op, batch.OperationStateRejected)
+	status.Reason = reason
+
+	if err := r.statusStore.Put(status); err != nil {
+		log.Warnf("[%s] failed to record status of operation for suffix[%s]: %s", r.namespace, op.UniqueSuffix, err)
+	}
+}
+
+// reject moves the given operation to the dead-letter store (if provided) so that it doesn't block the queue
+// and reports the rejection to the error channel (if provided)
+func (r *Writer) reject(op *batch.OperationInfo, reason string) error {
+	log.Warnf("[%s] rejecting operation for suffix[%s]: %s", r.namespace, op.UniqueSuffix, reason)
+
+	rejected := &batch.RejectedOperation{OperationInfo: op, Reason: reason}
+
+	if r.deadLetters != nil {
+		err := r.deadLetters.Put(rejected)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to store rejected operation for suffix[%s]", op.UniqueSuffix)
+		}
+	}
+
+	r.notify(&RejectedOperationError{RejectedOperation: rejected})
+
+	return nil
+}
+
+// notify sends the given error to the error channel. The batch writer doesn't block on the
+// error channel, so the error is dropped (and logged) if the channel is full.
+func (r *Writer) notify(err error) {
+	if r.errChan == nil {
+		return
+	}
+
+	select {
+	case r.errChan <- err:
+	default:
+		log.Warnf("[%s] error channel is full: dropping error: %s", r.namespace, err)
+	}
+}
+
+// withRetry invokes the given function and retries with exponential backoff if it returns an error
+func (r *Writer) withRetry(name string, fn func() error) error {
+	backoff := r.retry.InitialBackoff
+
+	for attempt := uint(1); ; attempt++ {
+		err := fn()
+		if err == nil || attempt > r.retry.MaxRetries {
+			return err
+		}
+
+		log.Warnf("[%s] %s failed on attempt %d: %s. Retrying in %s ...", r.namespace, name, attempt, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.exitChan:
+			return errors.WithMessagef(err, "batch writer stopped while retrying to %s", name)
+		}
+
+		backoff *= 2
+		if backoff > r.retry.MaxBackoff {
+			backoff = r.retry.MaxBackoff
+		}
+	}
 }
 
 func (r *Writer) handleTimer(timer <-chan time.Time, pending bool) <-chan time.Time {
@@ -358,6 +902,48 @@ func WithOperationHandler(opsHandler TxnHandler) Option {
 	}
 }
 
+//WithOperationParser allows for specifying parser for operations
+func WithOperationParser(opsParser OperationParser) Option {
+	return func(o *Options) error {
+		o.OpsParser = opsParser
+		return nil
+	}
+}
+
+//WithDeadLetterStore allows for specifying store for operations that can never be processed. If no store
+//is specified then rejected operations are only logged and sent to the error channel.
+func WithDeadLetterStore(store DeadLetterStore) Option {
+	return func(o *Options) error {
+		o.DeadLetterStore = store
+		return nil
+	}
+}
+
+//WithOperationStatusStore allows for specifying store that records the state transitions of operations
+func WithOperationStatusStore(store OperationStatusStore) Option {
+	return func(o *Options) error {
+		o.OperationStatusStore = store
+		return nil
+	}
+}
+
+//WithRetry allows for specifying the maximum number of retries and the backoff for transient CAS and blockchain errors.
+//The backoff starts at initialBackoff and is doubled after each retry up to maxBackoff.
+func WithRetry(maxRetries uint, initialBackoff, maxBackoff time.Duration) Option {
+	return func(o *Options) error {
+		if initialBackoff > maxBackoff {
+			return fmt.Errorf("initial backoff [%s] must not exceed max backoff [%s]", initialBackoff, maxBackoff)
+		}
+
+		o.Retry = &RetryOptions{
+			MaxRetries:     maxRetries,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+		}
+		return nil
+	}
+}
+
 //WithCompressionProvider allows for specifying compression provider
 func WithCompressionProvider(compressionProvider CompressionProvider) Option {
 	return func(o *Options) error {
@@ -366,11 +952,92 @@ func WithCompressionProvider(compressionProvider CompressionProvider) Option {
 	}
 }
 
+// WithCutterPolicy allows for specifying the policy that selects the operations for a batch.
+// The priority policy requires that the operation queue in the context is a priority queue.
+func WithCutterPolicy(policy cutter.Policy) Option {
+	return func(o *Options) error {
+		o.CutterPolicy = policy
+		return nil
+	}
+}
+
+//WithOperationValidator allows for specifying validator that validates operations against the current
+//state of their documents before they are anchored. Invalid operations are moved to the dead-letter store.
+func WithOperationValidator(validator OperationValidator) Option {
+	return func(o *Options) error {
+		o.OperationValidator = validator
+		return nil
+	}
+}
+
+//WithErrorChannel allows for specifying channel that receives a RejectedOperationError for each operation
+//that is rejected by the batch writer and an AnchorNotConfirmedError for each anchor that was not observed
+//on the ledger. The batch writer doesn't block if the channel is full.
+func WithErrorChannel(errChan chan<- error) Option {
+	return func(o *Options) error {
+		o.ErrorChannel = errChan
+		return nil
+	}
+}
+
+//WithAnchorConfirmation allows for specifying that anchors are tracked until they are observed in the
+//Sidetree transactions of the given ledger. An anchor that isn't observed within the given timeout or number
+//of blocks is written again, up to maxAttempts times. Zero timeout or maxBlocks disables the respective limit.
+func WithAnchorConfirmation(ledger Ledger, timeout time.Duration, maxBlocks uint64, maxAttempts uint) Option {
+	return func(o *Options) error {
+		if ledger == nil {
+			return errors.New("ledger is required for anchor confirmation")
+		}
+
+		if timeout == 0 && maxBlocks == 0 {
+			return errors.New("either timeout or max blocks is required for anchor confirmation")
+		}
+
+		if maxAttempts == 0 {
+			maxAttempts = defaultMaxAttempts
+		}
+
+		o.Confirmation = &ConfirmationOptions{
+			Ledger:      ledger,
+			Timeout:     timeout,
+			MaxBlocks:   maxBlocks,
+			MaxAttempts: maxAttempts,
+		}
+		return nil
+	}
+}
+
+// WithAnchorStore allows for specifying the store that persists the anchors that are tracked for anchor
+// confirmation (see WithAnchorConfirmation). A durable anchor store is required along with a durable operation queue
+// since the operations of an anchor are removed from the queue before the anchor is confirmed.
+func WithAnchorStore(store AnchorStore) Option {
+	return func(o *Options) error {
+		o.AnchorStore = store
+		return nil
+	}
+}
+
 // Options allows the user to specify more advanced options
 type Options struct {
-	BatchTimeout        time.Duration
-	OpsHandler          TxnHandler
-	CompressionProvider CompressionProvider
+	BatchTimeout         time.Duration
+	OpsHandler           TxnHandler
+	OpsParser            OperationParser
+	DeadLetterStore      DeadLetterStore
+	OperationStatusStore OperationStatusStore
+	Retry                *RetryOptions
+	CompressionProvider  CompressionProvider
+	CutterPolicy         cutter.Policy
+	OperationValidator   OperationValidator
+	ErrorChannel         chan<- error
+	Confirmation         *ConfirmationOptions
+	AnchorStore          AnchorStore
+}
+
+// RetryOptions defines retry with exponential backoff for transient CAS and blockchain errors
+type RetryOptions struct {
+	MaxRetries     uint
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
 }
 
 //prepareOptsFromOptions reads options
@@ -7,9 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package batch
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,15 +25,23 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/cas"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+	"github.com/trustbloc/sidetree-core-go/pkg/batch/anchorstore"
 	"github.com/trustbloc/sidetree-core-go/pkg/batch/cutter"
+	"github.com/trustbloc/sidetree-core-go/pkg/batch/deadletter"
 	"github.com/trustbloc/sidetree-core-go/pkg/batch/opqueue"
 	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
 	"github.com/trustbloc/sidetree-core-go/pkg/compression"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
 	"github.com/trustbloc/sidetree-core-go/pkg/jws"
 	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/patch"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/helper"
 	"github.com/trustbloc/sidetree-core-go/pkg/txnhandler"
 	"github.com/trustbloc/sidetree-core-go/pkg/txnhandler/models"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/ecsigner"
+	"github.com/trustbloc/sidetree-core-go/pkg/versions"
 )
 
 //go:generate counterfeiter -o ../mocks/operationqueue.gen.go --fake-name OperationQueue ./cutter OperationQueue
@@ -57,6 +72,12 @@ func TestNew(t *testing.T) {
 	require.NotNil(t, writer)
 	require.EqualValues(t, writer.opsHandler, opsHandler)
 
+	opsParser := operation.NewParser(ctx.ProtocolClient)
+	writer, err = New(namespace, ctx, WithOperationParser(opsParser))
+	require.Nil(t, err)
+	require.NotNil(t, writer)
+	require.EqualValues(t, writer.opsParser, opsParser)
+
 	writer, err = New(namespace, ctx, WithCompressionProvider(compression.New(compression.WithDefaultAlgorithms())))
 	require.Nil(t, err)
 	require.NotNil(t, writer)
@@ -148,6 +169,220 @@ func getBatchFiles(cc cas.Client, anchor string) (*models.AnchorFile, *models.Ma
 	return &af, &mf, &cf, nil
 }
 
+func TestVersionedWriter(t *testing.T) {
+	ctx := newMockContext()
+	cp := compression.New(compression.WithDefaultAlgorithms())
+
+	v1 := ctx.ProtocolClient.Protocol
+	v2 := ctx.ProtocolClient.Protocol
+	v2.StartingBlockChainTime = 100
+	v2.MaxOperationsPerBatch = 3
+
+	// the ledger has reached the second version
+	clock := versions.NewLedgerClock(100)
+
+	newVersion := func(p protocol.Protocol) protocol.Version {
+		pc, err := versions.NewClient(clock, p)
+		require.NoError(t, err)
+
+		return versions.NewVersion(p,
+			versions.WithOperationHandler(txnhandler.NewOperationHandler(ctx.CasClient, pc, cp)),
+			versions.WithOperationProvider(txnhandler.NewOperationProvider(ctx.CasClient, mocks.NewMockProtocolClientProvider(), cp)),
+		)
+	}
+
+	vm, err := versions.NewManager(clock, newVersion(v1), newVersion(v2))
+	require.NoError(t, err)
+
+	writer, err := New(namespace, ctx,
+		WithOperationParser(versions.NewOperationParser(vm)),
+		WithOperationHandler(versions.NewOperationHandler(vm)))
+	require.Nil(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	for _, op := range generateOperations(2) {
+		require.NoError(t, writer.Add(op))
+	}
+
+	time.Sleep(time.Second)
+
+	require.Equal(t, 1, len(ctx.BlockchainClient.GetAnchors()))
+
+	vmp := versions.NewManagerProvider()
+	vmp.Add(namespace, vm)
+
+	ops, err := versions.NewOperationProvider(vmp).GetTxnOperations(&txn.SidetreeTxn{
+		Namespace:       namespace,
+		AnchorString:    ctx.BlockchainClient.GetAnchors()[0],
+		TransactionTime: 100,
+	})
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+}
+
+func TestProtocolVersionChange(t *testing.T) {
+	t.Run("batches are cut using the version in force at the ledger time", func(t *testing.T) {
+		ctx := newMockContext()
+
+		v1 := ctx.ProtocolClient.Protocol
+		v1.MaxOperationsPerBatch = 4
+
+		v2 := v1
+		v2.StartingBlockChainTime = 100
+		v2.MaxOperationsPerBatch = 2
+
+		clock := versions.NewLedgerClock(50)
+
+		pc, err := versions.NewClient(clock, v1, v2)
+		require.NoError(t, err)
+		ctx.VersionsClient = pc
+
+		q := &hookQueue{MemQueue: &opqueue.MemQueue{}}
+		ctx.OpQueue = q
+
+		writer, err := New(namespace, ctx, WithBatchTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		// the second version isn't in force yet
+		for _, op := range generateOperations(4) {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(time.Second)
+
+		anchors := ctx.BlockchainClient.GetAnchors()
+		require.Equal(t, 1, len(anchors))
+		requireCreateOps(t, ctx, anchors[0], 4)
+
+		// the ledger reaches the second version while the next batch is cut
+		q.onPeek = func() {
+			clock.Set(100)
+		}
+
+		for _, op := range generateOperations(4) {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(time.Second)
+
+		anchors = ctx.BlockchainClient.GetAnchors()
+		require.Equal(t, 3, len(anchors))
+		requireCreateOps(t, ctx, anchors[1], 2)
+		requireCreateOps(t, ctx, anchors[2], 2)
+	})
+
+	t.Run("batch is cut again when protocol version changes while cutting", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 4
+
+		v2 := ctx.ProtocolClient.Protocol
+		v2.StartingBlockChainTime = 100
+		v2.MaxOperationsPerBatch = 2
+
+		q := &hookQueue{MemQueue: &opqueue.MemQueue{}}
+		q.onPeek = func() {
+			ctx.ProtocolClient.Protocol = v2
+		}
+		ctx.OpQueue = q
+
+		for _, op := range generateOperations(4) {
+			_, err := q.Add(op)
+			require.NoError(t, err)
+		}
+
+		writer, err := New(namespace, ctx)
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		time.Sleep(time.Second)
+
+		anchors := ctx.BlockchainClient.GetAnchors()
+		require.Equal(t, 2, len(anchors))
+
+		for _, anchor := range anchors {
+			ad, err := txnhandler.ParseAnchorData(anchor)
+			require.NoError(t, err)
+
+			af, _, _, err := getBatchFiles(ctx.CasClient, ad.AnchorAddress)
+			require.NoError(t, err)
+			require.Equal(t, 2, len(af.Operations.Create))
+		}
+	})
+
+	t.Run("anchor is not written when protocol version changes while preparing batch files", func(t *testing.T) {
+		ctx := newMockContext()
+
+		v2 := ctx.ProtocolClient.Protocol
+		v2.StartingBlockChainTime = 100
+
+		handler := &hookOpsHandler{
+			handler: txnhandler.NewOperationHandler(ctx.CasClient, ctx.ProtocolClient, compression.New(compression.WithDefaultAlgorithms())),
+		}
+		handler.onPrepare = func() {
+			ctx.ProtocolClient.Protocol = v2
+		}
+
+		writer, err := New(namespace, ctx, WithOperationHandler(handler), WithBatchTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		for _, op := range generateOperations(2) {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(time.Second)
+
+		// the batch files were prepared twice but the anchor was written only once (for the new protocol version)
+		require.Equal(t, 2, handler.numCalls())
+		require.Equal(t, 1, len(ctx.BlockchainClient.GetAnchors()))
+		require.Zero(t, ctx.OpQueue.Len())
+	})
+
+	t.Run("operations that do not conform to new protocol version are rejected", func(t *testing.T) {
+		ctx := newMockContext()
+
+		writer, err := New(namespace, ctx)
+		require.NoError(t, err)
+
+		for _, op := range generateOperations(3) {
+			_, err = ctx.OpQueue.Add(op)
+			require.NoError(t, err)
+		}
+
+		v2 := ctx.ProtocolClient.Protocol
+		v2.StartingBlockChainTime = 100
+		v2.MaxDeltaByteSize = 10
+		ctx.ProtocolClient.Protocol = v2
+
+		writer.Start()
+		defer writer.Stop()
+
+		time.Sleep(time.Second)
+
+		require.Zero(t, len(ctx.BlockchainClient.GetAnchors()))
+		require.Zero(t, ctx.OpQueue.Len())
+	})
+}
+
+// requireCreateOps requires that the batch files of the given anchor contain the given number of create operations
+func requireCreateOps(t *testing.T, ctx *mockContext, anchor string, expected int) {
+	ad, err := txnhandler.ParseAnchorData(anchor)
+	require.NoError(t, err)
+
+	af, _, _, err := getBatchFiles(ctx.CasClient, ad.AnchorAddress)
+	require.NoError(t, err)
+	require.Equal(t, expected, len(af.Operations.Create))
+}
+
 func TestBatchTimer(t *testing.T) {
 	ctx := newMockContext()
 	writer, err := New(namespace, ctx, WithBatchTimeout(2*time.Second))
@@ -168,101 +403,894 @@ func TestBatchTimer(t *testing.T) {
 
 	require.Equal(t, 1, len(ctx.BlockchainClient.GetAnchors()))
 
-	ad, err := txnhandler.ParseAnchorData(ctx.BlockchainClient.GetAnchors()[0])
-	require.NoError(t, err)
+	ad, err := txnhandler.ParseAnchorData(ctx.BlockchainClient.GetAnchors()[0])
+	require.NoError(t, err)
+
+	af, mf, cf, err := getBatchFiles(ctx.CasClient, ad.AnchorAddress)
+	require.Nil(t, err)
+
+	require.Equal(t, 1, len(af.Operations.Create))
+	require.Equal(t, 0, len(af.Operations.Recover))
+	require.Equal(t, 0, len(af.Operations.Deactivate))
+
+	require.Equal(t, 0, len(mf.Operations.Update))
+
+	require.Equal(t, 1, len(cf.Deltas))
+}
+
+func TestDuplicateSuffixInBatchFile(t *testing.T) {
+	ctx := newMockContext()
+	writer, err := New(namespace, ctx)
+	require.Nil(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	operation, err := generateOperation(1)
+	require.NoError(t, err)
+
+	err = writer.Add(operation)
+	require.Nil(t, err)
+
+	// add same operation again
+	err = writer.Add(operation)
+	require.Nil(t, err)
+
+	time.Sleep(time.Second)
+
+	// we should have 2 anchors: the second operation is carried over into the next batch
+	require.Equal(t, 2, len(ctx.BlockchainClient.GetAnchors()))
+	require.Zero(t, ctx.OpQueue.Len())
+
+	for _, anchor := range ctx.BlockchainClient.GetAnchors() {
+		ad, err := txnhandler.ParseAnchorData(anchor)
+		require.NoError(t, err)
+
+		// Check that each anchor has one operation per batch
+		af, mf, cf, err := getBatchFiles(ctx.CasClient, ad.AnchorAddress)
+		require.Nil(t, err)
+
+		require.Equal(t, 1, len(af.Operations.Create))
+		require.Equal(t, 0, len(af.Operations.Recover))
+		require.Equal(t, 0, len(af.Operations.Deactivate))
+
+		require.Equal(t, 0, len(mf.Operations.Update))
+
+		require.Equal(t, 1, len(cf.Deltas))
+	}
+}
+
+func TestDuplicateSuffixOrdering(t *testing.T) {
+	ops := generateOperations(3)
+	op1, op2, op3 := ops[0], ops[1], ops[2]
+
+	// a second operation for the same suffix that is distinguishable from the first
+	op1b, err := generateOperation(4)
+	require.NoError(t, err)
+	op1b.UniqueSuffix = op1.UniqueSuffix
+
+	op1c, err := generateOperation(5)
+	require.NoError(t, err)
+	op1c.UniqueSuffix = op1.UniqueSuffix
+
+	t.Run("carried over in submission order", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 10
+
+		handler := &hookOpsHandler{
+			handler: txnhandler.NewOperationHandler(ctx.CasClient, ctx.ProtocolClient, compression.New(compression.WithDefaultAlgorithms())),
+		}
+
+		writer, err := New(namespace, ctx, WithOperationHandler(handler), WithBatchTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+
+		for _, op := range []*batch.OperationInfo{op1, op2, op1b, op3, op1c} {
+			_, err = ctx.OpQueue.Add(op)
+			require.NoError(t, err)
+		}
+
+		writer.Start()
+		defer writer.Stop()
+
+		time.Sleep(time.Second)
+
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 3)
+		require.Zero(t, ctx.OpQueue.Len())
+
+		require.Equal(t, [][]string{
+			suffixes(t, ctx, op1, op2, op3),
+			suffixes(t, ctx, op1b),
+			suffixes(t, ctx, op1c),
+		}, handler.batchSuffixes())
+	})
+
+	t.Run("batch filled past deferred operation", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+
+		handler := &hookOpsHandler{
+			handler: txnhandler.NewOperationHandler(ctx.CasClient, ctx.ProtocolClient, compression.New(compression.WithDefaultAlgorithms())),
+		}
+
+		writer, err := New(namespace, ctx, WithOperationHandler(handler), WithBatchTimeout(100*time.Millisecond))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		for _, op := range []*batch.OperationInfo{op1, op1b, op2, op3} {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(time.Second)
+
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 2)
+		require.Zero(t, ctx.OpQueue.Len())
+
+		require.Equal(t, [][]string{
+			suffixes(t, ctx, op1, op2),
+			suffixes(t, ctx, op1b, op3),
+		}, handler.batchSuffixes())
+	})
+}
+
+func TestMaxMapFileSize(t *testing.T) {
+	ctx := newMockContext()
+	ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 10
+
+	var ops []*batch.OperationInfo
+	var parsed []*batch.Operation
+	for i := 1; i <= 10; i++ {
+		op, err := generateUpdateOperation(i)
+		require.NoError(t, err)
+
+		ops = append(ops, op)
+
+		p, err := operation.NewParser(ctx.ProtocolClient).Parse(namespace, op.Data)
+		require.NoError(t, err)
+
+		parsed = append(parsed, p)
+	}
+
+	// the map file of the full batch exceeds the maximum map file size
+	bytes, err := docutil.MarshalCanonical(models.CreateMapFile([]string{"chunk"}, parsed))
+	require.NoError(t, err)
+
+	content, err := compression.New(compression.WithDefaultAlgorithms()).Compress(compressionAlgorithm, bytes)
+	require.NoError(t, err)
+
+	ctx.ProtocolClient.Protocol.MaxMapFileSize = uint(len(content) - 1)
+
+	writer, err := New(namespace, ctx, WithBatchTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+
+	for _, op := range ops {
+		_, err = ctx.OpQueue.Add(op)
+		require.NoError(t, err)
+	}
+
+	writer.Start()
+	defer writer.Stop()
+
+	time.Sleep(time.Second)
+
+	// the operations that didn't fit remained in the queue and were anchored in the next batch
+	anchors := ctx.BlockchainClient.GetAnchors()
+	require.Len(t, anchors, 2)
+	require.Zero(t, ctx.OpQueue.Len())
+
+	var anchored []string
+	for _, anchor := range anchors {
+		ad, err := txnhandler.ParseAnchorData(anchor)
+		require.NoError(t, err)
+		require.True(t, ad.NumberOfOperations < len(ops))
+
+		af := &models.AnchorFile{}
+		readModel(t, ctx.CasClient, ad.AnchorAddress, af)
+
+		mapFile, err := ctx.CasClient.Read(af.MapFileHash)
+		require.NoError(t, err)
+		require.True(t, uint(len(mapFile)) <= ctx.ProtocolClient.Protocol.MaxMapFileSize)
+
+		mf := &models.MapFile{}
+		readModel(t, ctx.CasClient, af.MapFileHash, mf)
+		require.Len(t, mf.Operations.Update, ad.NumberOfOperations)
+
+		for _, op := range mf.Operations.Update {
+			anchored = append(anchored, op.DidSuffix)
+		}
+	}
+
+	// the operations were anchored in order
+	var expected []string
+	for _, op := range parsed {
+		expected = append(expected, op.UniqueSuffix)
+	}
+
+	require.Equal(t, expected, anchored)
+}
+
+func TestProcessOperationsError(t *testing.T) {
+	ctx := newMockContext()
+	ctx.CasClient = mocks.NewMockCasClient(fmt.Errorf("CAS Error"))
+
+	writer, err := New(namespace, ctx, WithBatchTimeout(2*time.Second))
+	require.Nil(t, err)
+
+	ctx.CasClient = mocks.NewMockCasClient(fmt.Errorf("CAS Error"))
+
+	writer.Start()
+	defer writer.Stop()
+
+	operations := generateOperations(3)
+	for _, op := range operations {
+		err = writer.Add(op)
+		require.Nil(t, err)
+	}
+
+	time.Sleep(3 * time.Second)
+
+	require.Equal(t, 0, len(ctx.BlockchainClient.GetAnchors()))
+}
+
+func TestBlockchainError(t *testing.T) {
+	ctx := newMockContext()
+	writer, err := New(namespace, ctx, WithBatchTimeout(2*time.Second))
+	require.Nil(t, err)
+
+	ctx.BlockchainClient = mocks.NewMockBlockchainClient(fmt.Errorf("blockchain error"))
+	writer.Start()
+	defer writer.Stop()
+
+	operations := generateOperations(3)
+	for _, op := range operations {
+		err = writer.Add(op)
+		require.Nil(t, err)
+	}
+
+	time.Sleep(3 * time.Second)
+
+	require.Equal(t, 0, len(ctx.BlockchainClient.GetAnchors()))
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("CAS error is retried", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.CasClient = mocks.NewMockCasClient(fmt.Errorf("CAS Error"))
+
+		handler := &hookOpsHandler{
+			handler: txnhandler.NewOperationHandler(ctx.CasClient, ctx.ProtocolClient, compression.New(compression.WithDefaultAlgorithms())),
+		}
+		handler.onPrepare = func() {
+			ctx.CasClient.SetError(nil)
+		}
+
+		writer, err := New(namespace, ctx, WithOperationHandler(handler),
+			WithBatchTimeout(time.Hour), WithRetry(2, 10*time.Millisecond, 20*time.Millisecond))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		for _, op := range generateOperations(2) {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(500 * time.Millisecond)
+
+		require.Equal(t, 2, handler.numCalls())
+		require.Equal(t, 1, len(ctx.BlockchainClient.GetAnchors()))
+	})
+
+	t.Run("blockchain error is retried", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.BlockchainClient.SetError(fmt.Errorf("blockchain error"))
+
+		writer, err := New(namespace, ctx, WithBatchTimeout(time.Hour), WithRetry(3, 10*time.Millisecond, 100*time.Millisecond))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		for _, op := range generateOperations(2) {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(15 * time.Millisecond)
+		ctx.BlockchainClient.SetError(nil)
+
+		time.Sleep(500 * time.Millisecond)
+
+		require.Equal(t, 1, len(ctx.BlockchainClient.GetAnchors()))
+		require.Zero(t, ctx.OpQueue.Len())
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.BlockchainClient.SetError(fmt.Errorf("blockchain error"))
+
+		writer, err := New(namespace, ctx, WithBatchTimeout(time.Hour), WithRetry(1, 10*time.Millisecond, 10*time.Millisecond))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		for _, op := range generateOperations(2) {
+			require.NoError(t, writer.Add(op))
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		require.Zero(t, len(ctx.BlockchainClient.GetAnchors()))
+		require.Equal(t, uint(2), ctx.OpQueue.Len())
+	})
+
+	t.Run("invalid backoff", func(t *testing.T) {
+		writer, err := New(namespace, newMockContext(), WithRetry(1, time.Second, time.Millisecond))
+		require.Error(t, err)
+		require.Nil(t, writer)
+		require.Contains(t, err.Error(), "initial backoff [1s] must not exceed max backoff [1ms]")
+	})
+}
+
+func TestDeadLetter(t *testing.T) {
+	ctx := newMockContext()
+	ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+
+	deadLetters := deadletter.NewMemStore()
+
+	writer, err := New(namespace, ctx, WithDeadLetterStore(deadLetters))
+	require.NoError(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	poison := &batch.OperationInfo{Data: []byte("{}"), UniqueSuffix: "poison", Namespace: namespace}
+	require.NoError(t, writer.Add(poison))
+
+	for _, op := range generateOperations(3) {
+		require.NoError(t, writer.Add(op))
+	}
+
+	time.Sleep(time.Second)
+
+	// the poison operation doesn't block anchoring of the other operations
+	require.Equal(t, 2, len(ctx.BlockchainClient.GetAnchors()))
+	require.Zero(t, ctx.OpQueue.Len())
+
+	rejected, err := deadLetters.Get(namespace)
+	require.NoError(t, err)
+	require.Len(t, rejected, 1)
+	require.Equal(t, poison, rejected[0].OperationInfo)
+	require.Contains(t, rejected[0].Reason, "operation type [] not implemented")
+}
+
+func TestDeadLetter_NoStore(t *testing.T) {
+	ctx := newMockContext()
+
+	errChan := make(chan error, 10)
+
+	writer, err := New(namespace, ctx, WithErrorChannel(errChan))
+	require.NoError(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	poison := &batch.OperationInfo{Data: []byte("{}"), UniqueSuffix: "poison", Namespace: namespace}
+	require.NoError(t, writer.Add(poison))
+
+	time.Sleep(time.Second)
+
+	// the rejected operation is only reported to the error channel
+	require.Zero(t, ctx.OpQueue.Len())
+
+	select {
+	case err := <-errChan:
+		rejectedErr, ok := err.(*RejectedOperationError)
+		require.True(t, ok)
+		require.Equal(t, poison, rejectedErr.OperationInfo)
+	default:
+		t.Fatal("expecting rejected operation error")
+	}
+}
+
+func TestOperationStatus(t *testing.T) {
+	ctx := newMockContext()
+	ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+
+	statusStore := &mockStatusStore{}
+
+	writer, err := New(namespace, ctx, WithOperationStatusStore(statusStore))
+	require.NoError(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	for _, op := range generateOperations(2) {
+		require.NoError(t, writer.Add(op))
+	}
+
+	time.Sleep(time.Second)
+
+	anchors := ctx.BlockchainClient.GetAnchors()
+	require.Len(t, anchors, 1)
+
+	statuses := statusStore.get()
+	require.Len(t, statuses, 2)
+
+	for _, status := range statuses {
+		require.Equal(t, batch.OperationStateBatched, status.State)
+		require.Equal(t, anchors[0], status.AnchorString)
+		require.NotEmpty(t, status.TrackingID)
+	}
+
+	t.Run("status store error", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		writer, err := New(namespace, ctx, WithOperationStatusStore(&mockStatusStore{err: errors.New("store error")}))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(time.Second)
+
+		// status errors don't fail the batch
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 1)
+		require.Zero(t, ctx.OpQueue.Len())
+	})
+}
+
+func TestOperationValidator(t *testing.T) {
+	ctx := newMockContext()
+	ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+
+	ops := generateOperations(2)
+	invalidSuffix := suffixes(t, ctx, ops[0])[0]
+
+	validator := &mockValidator{invalid: map[string]error{invalidSuffix: errors.New("invalid update commitment")}}
+	deadLetters := deadletter.NewMemStore()
+	statusStore := &mockStatusStore{}
+	errChan := make(chan error, 10)
+
+	writer, err := New(namespace, ctx, WithOperationValidator(validator), WithDeadLetterStore(deadLetters),
+		WithOperationStatusStore(statusStore), WithErrorChannel(errChan))
+	require.NoError(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	for _, op := range ops {
+		require.NoError(t, writer.Add(op))
+	}
+
+	time.Sleep(time.Second)
+
+	// only the valid operation is anchored
+	require.Len(t, ctx.BlockchainClient.GetAnchors(), 1)
+	require.Zero(t, ctx.OpQueue.Len())
+	require.Equal(t, suffixes(t, ctx, ops[1]), validator.anchoredSuffixes())
+
+	rejected, err := deadLetters.Get(namespace)
+	require.NoError(t, err)
+	require.Len(t, rejected, 1)
+	require.Equal(t, ops[0], rejected[0].OperationInfo)
+	require.Contains(t, rejected[0].Reason, "operation is not valid for the current document state: invalid update commitment")
+
+	select {
+	case err := <-errChan:
+		rejectedErr, ok := err.(*RejectedOperationError)
+		require.True(t, ok)
+		require.Equal(t, ops[0], rejectedErr.OperationInfo)
+		require.Contains(t, err.Error(), fmt.Sprintf("operation for suffix[%s] was rejected", ops[0].UniqueSuffix))
+	default:
+		t.Fatal("expecting rejected operation error")
+	}
+
+	var states []batch.OperationState
+	for _, status := range statusStore.get() {
+		states = append(states, status.State)
+	}
+	require.ElementsMatch(t, []batch.OperationState{batch.OperationStateRejected, batch.OperationStateBatched}, states)
+
+	t.Run("validator error", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		writer, err := New(namespace, ctx, WithOperationValidator(&mockValidator{err: errors.New("store error")}))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(time.Second)
+
+		// the operation remains in the queue
+		require.Empty(t, ctx.BlockchainClient.GetAnchors())
+		require.Equal(t, uint(1), ctx.OpQueue.Len())
+	})
+
+	t.Run("error channel full", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+
+		writer, err := New(namespace, ctx, WithErrorChannel(make(chan error)))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Add(&batch.OperationInfo{Data: []byte("{}"), UniqueSuffix: "poison", Namespace: namespace}))
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(time.Second)
+
+		// the writer doesn't block on the error channel
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 1)
+		require.Zero(t, ctx.OpQueue.Len())
+	})
+}
+
+func TestAnchorConfirmation(t *testing.T) {
+	t.Run("anchor observed", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		ledger := &mockLedger{txnChan: make(chan []txn.SidetreeTxn, 10)}
+
+		writer, err := New(namespace, ctx, WithAnchorConfirmation(ledger, 200*time.Millisecond, 0, 3))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(100 * time.Millisecond)
+
+		anchors := ctx.BlockchainClient.GetAnchors()
+		require.Len(t, anchors, 1)
+
+		ledger.txnChan <- []txn.SidetreeTxn{{AnchorString: anchors[0], TransactionTime: 1}}
+
+		time.Sleep(500 * time.Millisecond)
+
+		// the anchor was observed so it isn't written again
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 1)
+		require.Zero(t, writer.anchors.len())
+	})
+
+	t.Run("anchor written again after timeout", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		errChan := make(chan error, 10)
+		statusStore := &mockStatusStore{}
+		validator := &mockValidator{}
+
+		writer, err := New(namespace, ctx, WithAnchorConfirmation(&mockLedger{}, 100*time.Millisecond, 0, 2),
+			WithErrorChannel(errChan), WithOperationStatusStore(statusStore), WithOperationValidator(validator))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(time.Second)
+
+		// the anchor was written twice and then dropped
+		anchors := ctx.BlockchainClient.GetAnchors()
+		require.Len(t, anchors, 2)
+		require.Equal(t, anchors[0], anchors[1])
+		require.Zero(t, writer.anchors.len())
+
+		select {
+		case err := <-errChan:
+			notConfirmedErr, ok := err.(*AnchorNotConfirmedError)
+			require.True(t, ok)
+			require.Equal(t, anchors[0], notConfirmedErr.AnchorString)
+			require.Equal(t, uint(2), notConfirmedErr.Attempts)
+		default:
+			t.Fatal("expecting anchor not confirmed error")
+		}
+
+		statuses := statusStore.get()
+		require.Len(t, statuses, 2)
+		require.Equal(t, batch.OperationStateBatched, statuses[0].State)
+		require.Equal(t, batch.OperationStateRejected, statuses[1].State)
+		require.Contains(t, statuses[1].Reason, "was not observed on the ledger after 2 attempts")
+
+		// the validator no longer validates new operations against the abandoned operations
+		require.Len(t, validator.anchoredSuffixes(), 1)
+		require.Equal(t, validator.anchoredSuffixes(), validator.abandonedSuffixes())
+	})
+
+	t.Run("outstanding anchors are loaded from the anchor store", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		store := anchorstore.NewMemStore()
+
+		writer, err := New(namespace, ctx, WithAnchorConfirmation(&mockLedger{}, 200*time.Millisecond, 0, 3),
+			WithAnchorStore(store))
+		require.NoError(t, err)
+
+		writer.Start()
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(100 * time.Millisecond)
+
+		writer.Stop()
+
+		// the operation was removed from the queue but the anchor wasn't observed
+		require.Zero(t, ctx.OperationQueue().Len())
+
+		anchors := ctx.BlockchainClient.GetAnchors()
+		require.Len(t, anchors, 1)
+
+		records, err := store.GetAll()
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, anchors[0], records[0].AnchorString)
+		require.Len(t, records[0].Operations, 1)
+
+		// the restarted writer keeps tracking the anchor
+		validator := &mockValidator{}
+
+		writer, err = New(namespace, ctx, WithAnchorConfirmation(&mockLedger{}, 200*time.Millisecond, 0, 3),
+			WithAnchorStore(store), WithOperationValidator(validator))
+		require.NoError(t, err)
+		require.Equal(t, 1, writer.anchors.len())
+		require.Len(t, validator.anchoredSuffixes(), 1)
+
+		writer.Start()
+		defer writer.Stop()
+
+		time.Sleep(300 * time.Millisecond)
+
+		anchors = ctx.BlockchainClient.GetAnchors()
+		require.Len(t, anchors, 2)
+		require.Equal(t, anchors[0], anchors[1])
+
+		records, err = store.GetAll()
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, uint(2), records[0].Attempts)
+	})
+
+	t.Run("anchor store error", func(t *testing.T) {
+		ctx := newMockContext()
+
+		writer, err := New(namespace, ctx, WithAnchorConfirmation(&mockLedger{}, time.Second, 0, 3),
+			WithAnchorStore(&mockAnchorStore{err: errors.New("store error")}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load outstanding anchors: store error")
+		require.Nil(t, writer)
+	})
+
+	t.Run("operations remain in the queue if the anchor can't be stored", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		writer, err := New(namespace, ctx, WithAnchorConfirmation(&mockLedger{}, time.Second, 0, 3))
+		require.NoError(t, err)
+
+		writer.anchors.store = &mockAnchorStore{err: errors.New("store error")}
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		n, pending, err := writer.cutAndProcess(true)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store anchor")
+		require.Zero(t, n)
+		require.Equal(t, uint(1), pending)
+		require.Empty(t, ctx.BlockchainClient.GetAnchors())
+		require.Equal(t, uint(1), ctx.OperationQueue().Len())
+	})
+
+	t.Run("anchor written again after max blocks", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+
+		ledger := &mockLedger{txnChan: make(chan []txn.SidetreeTxn, 10)}
+
+		writer, err := New(namespace, ctx, WithAnchorConfirmation(ledger, 0, 2, 3))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		require.NoError(t, writer.Add(generateOperations(1)[0]))
+
+		time.Sleep(100 * time.Millisecond)
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 1)
+
+		ledger.txnChan <- []txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 1}}
+		time.Sleep(100 * time.Millisecond)
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 1)
+
+		ledger.txnChan <- []txn.SidetreeTxn{{AnchorString: "other", TransactionTime: 2}}
+		time.Sleep(100 * time.Millisecond)
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 2)
+		require.Equal(t, 1, writer.anchors.len())
+
+		close(ledger.txnChan)
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	t.Run("error - invalid options", func(t *testing.T) {
+		writer, err := New(namespace, newMockContext(), WithAnchorConfirmation(nil, time.Second, 0, 3))
+		require.Error(t, err)
+		require.Nil(t, writer)
+		require.Contains(t, err.Error(), "ledger is required for anchor confirmation")
+
+		writer, err = New(namespace, newMockContext(), WithAnchorConfirmation(&mockLedger{}, 0, 0, 3))
+		require.Error(t, err)
+		require.Nil(t, writer)
+		require.Contains(t, err.Error(), "either timeout or max blocks is required for anchor confirmation")
+
+		writer, err = New(namespace, newMockContext(), WithAnchorConfirmation(&mockLedger{}, time.Second, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, uint(defaultMaxAttempts), writer.anchors.MaxAttempts)
+	})
+}
+
+func TestPriorityPolicy(t *testing.T) {
+	ctx := newMockContext()
+	ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+	ctx.OpQueue = opqueue.NewMemPriorityQueue()
+
+	handler := &hookOpsHandler{
+		handler: txnhandler.NewOperationHandler(ctx.CasClient, ctx.ProtocolClient, compression.New(compression.WithDefaultAlgorithms())),
+	}
+
+	writer, err := New(namespace, ctx, WithOperationHandler(handler),
+		WithCutterPolicy(cutter.NewPriorityPolicy(time.Hour)), WithBatchTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+
+	ops := generateOperations(4)
+
+	// the last two operations are treated as high priority by the queue
+	ops[2].Type = batch.OperationTypeRecover
+	ops[3].Type = batch.OperationTypeDeactivate
+
+	for _, op := range ops {
+		_, err = ctx.OpQueue.Add(op)
+		require.NoError(t, err)
+	}
+
+	writer.Start()
+	defer writer.Stop()
+
+	time.Sleep(time.Second)
+
+	require.Len(t, ctx.BlockchainClient.GetAnchors(), 2)
+	require.Zero(t, ctx.OpQueue.Len())
+
+	require.Equal(t, [][]string{
+		suffixes(t, ctx, ops[2], ops[3]),
+		suffixes(t, ctx, ops[0], ops[1]),
+	}, handler.batchSuffixes())
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("pending operations are anchored", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
+
+		writer, err := New(namespace, ctx, WithBatchTimeout(time.Hour))
+		require.NoError(t, err)
 
-	af, mf, cf, err := getBatchFiles(ctx.CasClient, ad.AnchorAddress)
-	require.Nil(t, err)
+		writer.Start()
 
-	require.Equal(t, 1, len(af.Operations.Create))
-	require.Equal(t, 0, len(af.Operations.Recover))
-	require.Equal(t, 0, len(af.Operations.Deactivate))
+		for _, op := range generateOperations(3) {
+			require.NoError(t, writer.Add(op))
+		}
 
-	require.Equal(t, 0, len(mf.Operations.Update))
+		// a duplicate suffix requires more than one forced cut
+		op, err := generateOperation(3)
+		require.NoError(t, err)
+		require.NoError(t, writer.Add(op))
 
-	require.Equal(t, 1, len(cf.Deltas))
-}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
 
-func TestDiscardDuplicateSuffixInBatchFile(t *testing.T) {
-	ctx := newMockContext()
-	writer, err := New(namespace, ctx)
-	require.Nil(t, err)
+		pending, err := writer.Shutdown(shutdownCtx)
+		require.NoError(t, err)
+		require.Zero(t, pending)
+		require.Zero(t, ctx.OpQueue.Len())
+		require.Len(t, ctx.BlockchainClient.GetAnchors(), 3)
 
-	writer.Start()
-	defer writer.Stop()
+		require.True(t, writer.Stopped())
+		require.EqualError(t, writer.Add(op), "writer is stopped")
+	})
 
-	operation, err := generateOperation(1)
-	require.NoError(t, err)
+	t.Run("operations that cannot be anchored are reported", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.BlockchainClient.SetError(fmt.Errorf("blockchain error"))
 
-	err = writer.Add(operation)
-	require.Nil(t, err)
+		writer, err := New(namespace, ctx, WithBatchTimeout(time.Hour), WithRetry(1, time.Millisecond, time.Millisecond))
+		require.NoError(t, err)
 
-	// add same operation again
-	err = writer.Add(operation)
-	require.Nil(t, err)
+		writer.Start()
 
-	time.Sleep(time.Second)
+		for _, op := range generateOperations(3) {
+			require.NoError(t, writer.Add(op))
+		}
 
-	// we should have 1 anchors: 2 operations % max 2 operations per batch
-	require.Equal(t, 1, len(ctx.BlockchainClient.GetAnchors()))
+		pending, err := writer.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint(3), pending)
+		require.Equal(t, uint(3), ctx.OpQueue.Len())
+		require.Empty(t, ctx.BlockchainClient.GetAnchors())
+	})
 
-	ad, err := txnhandler.ParseAnchorData(ctx.BlockchainClient.GetAnchors()[0])
-	require.NoError(t, err)
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.BlockchainClient.SetError(fmt.Errorf("blockchain error"))
 
-	// Check that first anchor has one operation per batch; second one has been discarded
-	af, mf, cf, err := getBatchFiles(ctx.CasClient, ad.AnchorAddress)
-	require.Nil(t, err)
+		writer, err := New(namespace, ctx, WithBatchTimeout(time.Hour), WithRetry(10, time.Second, time.Second))
+		require.NoError(t, err)
 
-	require.Equal(t, 1, len(af.Operations.Create))
-	require.Equal(t, 0, len(af.Operations.Recover))
-	require.Equal(t, 0, len(af.Operations.Deactivate))
+		writer.Start()
 
-	require.Equal(t, 0, len(mf.Operations.Update))
+		for _, op := range generateOperations(3) {
+			require.NoError(t, writer.Add(op))
+		}
 
-	require.Equal(t, 1, len(cf.Deltas))
-}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
 
-func TestProcessOperationsError(t *testing.T) {
-	ctx := newMockContext()
-	ctx.CasClient = mocks.NewMockCasClient(fmt.Errorf("CAS Error"))
+		start := time.Now()
 
-	writer, err := New(namespace, ctx, WithBatchTimeout(2*time.Second))
-	require.Nil(t, err)
+		pending, err := writer.Shutdown(shutdownCtx)
+		require.Equal(t, context.DeadlineExceeded, err)
+		require.Equal(t, uint(3), pending)
+		require.True(t, time.Since(start) < time.Second)
+		require.True(t, writer.Stopped())
+	})
 
-	ctx.CasClient = mocks.NewMockCasClient(fmt.Errorf("CAS Error"))
+	t.Run("not started", func(t *testing.T) {
+		ctx := newMockContext()
 
-	writer.Start()
-	defer writer.Stop()
+		writer, err := New(namespace, ctx)
+		require.NoError(t, err)
 
-	operations := generateOperations(3)
-	for _, op := range operations {
-		err = writer.Add(op)
-		require.Nil(t, err)
-	}
+		_, err = ctx.OpQueue.Add(generateOperations(1)[0])
+		require.NoError(t, err)
 
-	time.Sleep(3 * time.Second)
+		pending, err := writer.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint(1), pending)
+		require.True(t, writer.Stopped())
+	})
 
-	require.Equal(t, 0, len(ctx.BlockchainClient.GetAnchors()))
-}
+	t.Run("already stopped", func(t *testing.T) {
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 2
 
-func TestBlockchainError(t *testing.T) {
-	ctx := newMockContext()
-	writer, err := New(namespace, ctx, WithBatchTimeout(2*time.Second))
-	require.Nil(t, err)
+		writer, err := New(namespace, ctx, WithBatchTimeout(time.Hour))
+		require.NoError(t, err)
 
-	ctx.BlockchainClient = mocks.NewMockBlockchainClient(fmt.Errorf("blockchain error"))
-	writer.Start()
-	defer writer.Stop()
+		writer.Start()
+		writer.Stop()
 
-	operations := generateOperations(3)
-	for _, op := range operations {
-		err = writer.Add(op)
-		require.Nil(t, err)
-	}
+		time.Sleep(100 * time.Millisecond)
 
-	time.Sleep(3 * time.Second)
+		_, err = ctx.OpQueue.Add(generateOperations(1)[0])
+		require.NoError(t, err)
 
-	require.Equal(t, 0, len(ctx.BlockchainClient.GetAnchors()))
+		pending, err := writer.Shutdown(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint(1), pending)
+	})
 }
 
 func TestAddAfterStop(t *testing.T) {
@@ -354,20 +1382,75 @@ func TestStartWithExistingItems(t *testing.T) {
 	require.Equal(t, numBatchesExpected, len(ctx.BlockchainClient.GetAnchors()))
 }
 
+func TestRestartWithFileQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writer")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	q, err := opqueue.NewFileQueue(dir)
+	require.NoError(t, err)
+
+	ctx := newMockContext()
+	ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 3
+	ctx.BlockchainClient = mocks.NewMockBlockchainClient(fmt.Errorf("blockchain error"))
+	ctx.OpQueue = q
+
+	writer, err := New(namespace, ctx)
+	require.NoError(t, err)
+
+	writer.Start()
+
+	for _, op := range generateOperations(5) {
+		require.NoError(t, writer.Add(op))
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	writer.Stop()
+
+	require.Zero(t, len(ctx.BlockchainClient.GetAnchors()))
+
+	// simulate a restart: the queue is reopened without being closed
+	q2, err := opqueue.NewFileQueue(dir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, q2.Close())
+		require.NoError(t, q.Close())
+	}()
+
+	require.Equal(t, uint(5), q2.Len())
+
+	ctx2 := newMockContext()
+	ctx2.ProtocolClient.Protocol.MaxOperationsPerBatch = 3
+	ctx2.OpQueue = q2
+
+	writer, err = New(namespace, ctx2)
+	require.NoError(t, err)
+
+	writer.Start()
+	defer writer.Stop()
+
+	time.Sleep(time.Second)
+
+	require.Equal(t, 2, len(ctx2.BlockchainClient.GetAnchors()))
+	require.Zero(t, q2.Len())
+}
+
 func TestProcessError(t *testing.T) {
 	t.Run("process operation error", func(t *testing.T) {
-		q := &mocks.OperationQueue{}
-
-		invalidQueue := []*batch.OperationInfo{{Data: []byte(""), UniqueSuffix: "unique", Namespace: "ns"}}
+		q := &opqueue.MemQueue{}
 
-		q.LenReturns(1)
-		q.PeekReturns(invalidQueue, nil)
+		_, err := q.Add(&batch.OperationInfo{Data: []byte(""), UniqueSuffix: "unique", Namespace: "ns"})
+		require.NoError(t, err)
 
 		ctx := newMockContext()
 		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
 		ctx.OpQueue = q
 
-		writer, err := New("test1", ctx, WithBatchTimeout(10*time.Millisecond))
+		deadLetters := deadletter.NewMemStore()
+
+		writer, err := New("test1", ctx, WithBatchTimeout(10*time.Millisecond), WithDeadLetterStore(deadLetters))
 		require.NoError(t, err)
 
 		writer.Start()
@@ -376,6 +1459,36 @@ func TestProcessError(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 
 		require.Zero(t, len(ctx.BlockchainClient.GetAnchors()))
+		require.Zero(t, q.Len())
+
+		rejected, err := deadLetters.Get("ns")
+		require.NoError(t, err)
+		require.Len(t, rejected, 1)
+		require.Equal(t, "unique", rejected[0].UniqueSuffix)
+		require.Contains(t, rejected[0].Reason, "failed to unmarshal operation buffer")
+	})
+
+	t.Run("dead-letter store error", func(t *testing.T) {
+		q := &opqueue.MemQueue{}
+
+		_, err := q.Add(&batch.OperationInfo{Data: []byte(""), UniqueSuffix: "unique", Namespace: "ns"})
+		require.NoError(t, err)
+
+		ctx := newMockContext()
+		ctx.ProtocolClient.Protocol.MaxOperationsPerBatch = 1
+		ctx.OpQueue = q
+
+		writer, err := New("test1", ctx, WithBatchTimeout(10*time.Millisecond),
+			WithDeadLetterStore(&mockDeadLetterStore{err: errors.New("store error")}))
+		require.NoError(t, err)
+
+		writer.Start()
+		defer writer.Stop()
+
+		time.Sleep(50 * time.Millisecond)
+
+		// the operation remains in the queue since it couldn't be stored
+		require.Equal(t, uint(1), q.Len())
 	})
 
 	t.Run("Cut error", func(t *testing.T) {
@@ -470,19 +1583,73 @@ func generateOperation(num int) (*batch.OperationInfo, error) {
 
 	op := &batch.OperationInfo{
 		Namespace:    "did:sidetree",
-		UniqueSuffix: string(num),
+		UniqueSuffix: fmt.Sprint(num),
 		Data:         request,
 	}
 
 	return op, nil
 }
 
+// readModel reads the compressed batch file with the given address from CAS
+func readModel(t *testing.T, cas *mocks.MockCasClient, address string, model interface{}) {
+	bytes, err := cas.Read(address)
+	require.NoError(t, err)
+
+	content, err := compression.New(compression.WithDefaultAlgorithms()).Decompress(compressionAlgorithm, bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, json.Unmarshal(content, model))
+}
+
+func generateUpdateOperation(num int) (*batch.OperationInfo, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	testPatch, err := patch.NewJSONPatch(fmt.Sprintf(`[{"op": "replace", "path": "/name", "value": "Jane-%d"}]`, num))
+	if err != nil {
+		return nil, err
+	}
+
+	jwk := &jws.JWK{
+		Crv: "crv",
+		Kty: "kty",
+		X:   "x",
+	}
+
+	c, err := commitment.Calculate(jwk, sha2_256)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := helper.NewUpdateRequest(&helper.UpdateRequestInfo{
+		DidSuffix:        fmt.Sprintf("update-%d", num),
+		Signer:           ecsigner.New(privateKey, "ES256", "key-1"),
+		UpdateCommitment: c,
+		UpdateKey:        jwk,
+		Patch:            testPatch,
+		MultihashCode:    sha2_256,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch.OperationInfo{
+		Namespace:    namespace,
+		UniqueSuffix: fmt.Sprintf("update-%d", num),
+		Data:         request,
+	}, nil
+}
+
 // mockContext implements mock batch writer context
 type mockContext struct {
 	ProtocolClient   *mocks.MockProtocolClient
 	CasClient        *mocks.MockCasClient
 	BlockchainClient *mocks.MockBlockchainClient
 	OpQueue          cutter.OperationQueue
+	// VersionsClient is returned as the protocol client (instead of ProtocolClient) if it's set
+	VersionsClient protocol.Client
 }
 
 // newMockContext returns a new mockContext object
@@ -499,6 +1666,10 @@ func newMockContext() *mockContext {
 
 // Protocol returns the Client
 func (m *mockContext) Protocol() protocol.Client {
+	if m.VersionsClient != nil {
+		return m.VersionsClient
+	}
+
 	return m.ProtocolClient
 }
 
@@ -521,6 +1692,186 @@ func (m *mockContext) OperationQueue() cutter.OperationQueue {
 type mockOpsHandler struct{}
 
 // PrepareTxnFiles mocks preparing batch files from operations
-func (h *mockOpsHandler) PrepareTxnFiles(ops []*batch.Operation) (string, error) {
-	return "", nil
+func (h *mockOpsHandler) PrepareTxnFiles(ops []*batch.Operation) (string, int, error) {
+	return "", len(ops), nil
+}
+
+// hookQueue invokes a hook the first time operations are peeked
+type hookQueue struct {
+	*opqueue.MemQueue
+	onPeek func()
+}
+
+// Peek invokes the hook and returns operations from the head of the queue
+func (q *hookQueue) Peek(num uint) ([]*batch.OperationInfo, error) {
+	if q.onPeek != nil {
+		q.onPeek()
+		q.onPeek = nil
+	}
+
+	return q.MemQueue.Peek(num)
+}
+
+// hookOpsHandler invokes a hook the first time batch files are prepared
+type hookOpsHandler struct {
+	handler   TxnHandler
+	onPrepare func()
+	calls     int
+	mutex     sync.Mutex
+	batches   [][]*batch.Operation
+}
+
+// PrepareTxnFiles invokes the hook and prepares batch files from operations
+func (h *hookOpsHandler) PrepareTxnFiles(ops []*batch.Operation) (string, int, error) {
+	h.mutex.Lock()
+	h.calls++
+	h.batches = append(h.batches, ops)
+	h.mutex.Unlock()
+
+	anchor, n, err := h.handler.PrepareTxnFiles(ops)
+
+	if h.onPrepare != nil {
+		h.onPrepare()
+		h.onPrepare = nil
+	}
+
+	return anchor, n, err
+}
+
+// numCalls returns the number of times that batch files were prepared
+func (h *hookOpsHandler) numCalls() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.calls
+}
+
+// batchSuffixes returns the unique suffixes of the operations in each prepared batch
+func (h *hookOpsHandler) batchSuffixes() [][]string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var result [][]string
+	for _, ops := range h.batches {
+		var batchSuffixes []string
+		for _, op := range ops {
+			batchSuffixes = append(batchSuffixes, op.UniqueSuffix)
+		}
+
+		result = append(result, batchSuffixes)
+	}
+
+	return result
+}
+
+// suffixes returns the unique suffixes of the parsed operations
+func suffixes(t *testing.T, ctx *mockContext, ops ...*batch.OperationInfo) []string {
+	var result []string
+	for _, info := range ops {
+		op, err := operation.NewParser(ctx.ProtocolClient).Parse(namespace, info.Data)
+		require.NoError(t, err)
+
+		result = append(result, op.UniqueSuffix)
+	}
+
+	return result
+}
+
+// mockDeadLetterStore mocks a dead-letter store
+type mockDeadLetterStore struct {
+	err error
+}
+
+// Put returns the injected error
+func (s *mockDeadLetterStore) Put(*batch.RejectedOperation) error {
+	return s.err
+}
+
+type mockStatusStore struct {
+	mutex    sync.Mutex
+	statuses []*batch.OperationStatus
+	err      error
+}
+
+func (m *mockStatusStore) Put(status *batch.OperationStatus) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.statuses = append(m.statuses, status)
+
+	return nil
+}
+
+func (m *mockStatusStore) get() []*batch.OperationStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.statuses
+}
+
+type mockValidator struct {
+	mutex     sync.Mutex
+	invalid   map[string]error
+	anchored  []string
+	abandoned []string
+	err       error
+}
+
+func (m *mockValidator) Validate(ops []*batch.Operation) (map[*batch.Operation]error, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	invalid := make(map[*batch.Operation]error)
+	for _, op := range ops {
+		if err, ok := m.invalid[op.UniqueSuffix]; ok {
+			invalid[op] = err
+		}
+	}
+
+	return invalid, nil
+}
+
+func (m *mockValidator) Anchored(ops []*batch.Operation) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, op := range ops {
+		m.anchored = append(m.anchored, op.UniqueSuffix)
+	}
+}
+
+func (m *mockValidator) Abandoned(ops []*batch.Operation) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, op := range ops {
+		m.abandoned = append(m.abandoned, op.UniqueSuffix)
+	}
+}
+
+func (m *mockValidator) abandonedSuffixes() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.abandoned
+}
+
+func (m *mockValidator) anchoredSuffixes() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.anchored
+}
+
+type mockLedger struct {
+	txnChan chan []txn.SidetreeTxn
+}
+
+func (m *mockLedger) RegisterForSidetreeTxn() <-chan []txn.SidetreeTxn {
+	return m.txnChan
 }
@@ -17,6 +17,20 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/patch"
 )
 
+// DocumentComposer applies patches to the document
+type DocumentComposer struct {
+}
+
+// New returns new document composer
+func New() *DocumentComposer {
+	return &DocumentComposer{}
+}
+
+// ApplyPatches applies patches to the document
+func (c *DocumentComposer) ApplyPatches(doc document.Document, patches []patch.Patch) (document.Document, error) {
+	return ApplyPatches(doc, patches)
+}
+
 // ApplyPatches applies patches to the document
 func ApplyPatches(doc document.Document, patches []patch.Patch) (document.Document, error) {
 	var err error
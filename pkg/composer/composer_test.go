@@ -31,6 +31,18 @@ func TestApplyPatches(t *testing.T) {
 	})
 }
 
+func TestDocumentComposer_ApplyPatches(t *testing.T) {
+	dc := New()
+	require.NotNil(t, dc)
+
+	p, err := patch.NewJSONPatch(`[{"op": "replace", "path": "/test", "value": "value"}]`)
+	require.NoError(t, err)
+
+	doc, err := dc.ApplyPatches(document.Document{"test": "old"}, []patch.Patch{p})
+	require.NoError(t, err)
+	require.Equal(t, "value", doc["test"])
+}
+
 func TestApplyPatches_PatchesFromOpaqueDoc(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		patches, err := patch.PatchesFromDocument(testDoc)
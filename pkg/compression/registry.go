@@ -85,6 +85,13 @@ func (r *Registry) Close() error {
 	return nil
 }
 
+// IsSupported returns true if the specified compression algorithm is registered
+func (r *Registry) IsSupported(alg string) bool {
+	_, err := r.resolveAlgorithm(alg)
+
+	return err == nil
+}
+
 func (r *Registry) resolveAlgorithm(alg string) (Algorithm, error) {
 	for _, v := range r.algorithms {
 		if v.Accept(alg) {
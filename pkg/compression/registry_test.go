@@ -115,6 +115,14 @@ func TestRegistry_Close(t *testing.T) {
 	})
 }
 
+func TestRegistry_IsSupported(t *testing.T) {
+	registry := New(WithDefaultAlgorithms())
+	require.True(t, registry.IsSupported(algGZIP))
+	require.False(t, registry.IsSupported("other"))
+
+	require.False(t, New().IsSupported(algGZIP))
+}
+
 type mockAlgorithm struct {
 	CompressErr   error
 	DecompressErr error
@@ -10,7 +10,8 @@ SPDX-License-Identifier: Apache-2.0
 // batch writer to add it to the batch.
 //
 // Document resolution is based on ID or encoded original document.
-// 1) ID - the latest document will be returned if found.
+// 1) ID - the latest document will be returned if found. A previous version of the document may be requested
+// using the versionTime or versionId DID URL query parameters.
 //
 // 2) Encoded original document - The encoded document is hashed using the current supported hashing algorithm to
 // compute ID, after which the resolution is done against the computed ID. If a document cannot be found,
@@ -33,6 +34,7 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
 	"github.com/trustbloc/sidetree-core-go/pkg/internal/request"
 	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 	"github.com/trustbloc/sidetree-core-go/pkg/patch"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
 )
@@ -45,16 +47,20 @@ const (
 
 // DocumentHandler implements document handler
 type DocumentHandler struct {
-	protocol  protocol.Client
-	processor OperationProcessor
-	writer    BatchWriter
-	validator DocumentValidator
-	namespace string
+	protocol    protocol.Client
+	processor   OperationProcessor
+	writer      BatchWriter
+	validator   DocumentValidator
+	namespace   string
+	statusStore OperationStatusStore
 }
 
+// Option is a document handler option
+type Option func(opts *DocumentHandler)
+
 // OperationProcessor is an interface which resolves the document based on the ID
 type OperationProcessor interface {
-	Resolve(uniqueSuffix string) (*document.ResolutionResult, error)
+	Resolve(uniqueSuffix string, opts ...document.ResolutionOption) (*document.ResolutionResult, error)
 }
 
 // BatchWriter is an interface to add an operation to the batch
@@ -69,15 +75,34 @@ type DocumentValidator interface {
 	TransformDocument(doc document.Document) (*document.ResolutionResult, error)
 }
 
+// OperationStatusStore is an interface for recording the state transitions of operations
+type OperationStatusStore interface {
+	Put(status *batch.OperationStatus) error
+}
+
 // New creates a new requestHandler with the context
-func New(namespace string, protocol protocol.Client, validator DocumentValidator, writer BatchWriter, processor OperationProcessor) *DocumentHandler {
-	return &DocumentHandler{
+func New(namespace string, protocol protocol.Client, validator DocumentValidator, writer BatchWriter, processor OperationProcessor, opts ...Option) *DocumentHandler {
+	h := &DocumentHandler{
 		protocol:  protocol,
 		processor: processor,
 		writer:    writer,
 		validator: validator,
 		namespace: namespace,
 	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// WithOperationStatusStore sets the store that records the state transitions of operations
+func WithOperationStatusStore(store OperationStatusStore) Option {
+	return func(opts *DocumentHandler) {
+		opts.statusStore = store
+	}
 }
 
 // Namespace returns the namespace of the document handler
@@ -106,6 +131,8 @@ func (r *DocumentHandler) ProcessOperation(operation *batch.Operation) (*documen
 
 	log.Infof("[%s] operation added to the batch", operation.ID)
 
+	r.updateStatus(operation)
+
 	// create operation will also return document
 	if operation.Type == batch.OperationTypeCreate {
 		return r.getCreateResponse(operation)
@@ -139,6 +166,12 @@ func (r *DocumentHandler) getCreateResponse(operation *batch.Operation) (*docume
 // 2. DID with initial-values DID parameter:
 // did:sidetree:<unique-portion>;initial-values=<encoded-original-did-document>
 //
+// A previous version of the DID Document may be resolved by adding one of the following query parameters:
+//
+// versionTime=<ledger time> - the DID Document as it was at the given ledger time
+//
+// versionId=<transaction number>-<operation index> - the DID Document that was produced by the given operation
+//
 // Standard resolution is performed if the DID is found to be registered on the blockchain.
 // If the DID Document cannot be found, the encoded DID Document given in the initial-values DID parameter is used
 // to generate and return as the resolved DID Document, in which case the supplied encoded DID Document is subject to
@@ -148,8 +181,14 @@ func (r *DocumentHandler) ResolveDocument(idOrInitialDoc string) (*document.Reso
 		return nil, errors.New("must start with configured namespace")
 	}
 
+	// extract the optional version
+	didURL, versionOpts, err := request.GetVersion(idOrInitialDoc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", badRequest, err.Error())
+	}
+
 	// extract did and optional initial document value
-	id, initial, err := request.GetParts(r.namespace, idOrInitialDoc)
+	id, initial, err := request.GetParts(r.namespace, didURL)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", badRequest, err.Error())
 	}
@@ -160,21 +199,22 @@ func (r *DocumentHandler) ResolveDocument(idOrInitialDoc string) (*document.Reso
 	}
 
 	// resolve document from the blockchain
-	doc, err := r.resolveRequestWithID(uniquePortion)
+	doc, err := r.resolveRequestWithID(uniquePortion, versionOpts...)
 	if err == nil {
 		return doc, nil
 	}
 
 	// if document was not found on the blockchain and initial value has been provided resolve using initial value
-	if initial != nil && strings.Contains(err.Error(), "not found") {
+	// (unless a specific version of the document was requested)
+	if initial != nil && len(versionOpts) == 0 && strings.Contains(err.Error(), "not found") {
 		return r.resolveRequestWithDocument(id, initial)
 	}
 
 	return nil, err
 }
 
-func (r *DocumentHandler) resolveRequestWithID(uniquePortion string) (*document.ResolutionResult, error) {
-	internalResult, err := r.processor.Resolve(uniquePortion)
+func (r *DocumentHandler) resolveRequestWithID(uniquePortion string, opts ...document.ResolutionOption) (*document.ResolutionResult, error) {
+	internalResult, err := r.processor.Resolve(uniquePortion, opts...)
 	if err != nil {
 		log.Errorf("Failed to resolve uniquePortion[%s]: %s", uniquePortion, err.Error())
 		return nil, err
@@ -188,6 +228,8 @@ func (r *DocumentHandler) resolveRequestWithID(uniquePortion string) (*document.
 	externalResult.MethodMetadata.Published = true
 	externalResult.MethodMetadata.RecoveryCommitment = internalResult.MethodMetadata.RecoveryCommitment
 	externalResult.MethodMetadata.UpdateCommitment = internalResult.MethodMetadata.UpdateCommitment
+	externalResult.MethodMetadata.VersionID = internalResult.MethodMetadata.VersionID
+	externalResult.MethodMetadata.VersionTime = internalResult.MethodMetadata.VersionTime
 
 	return externalResult, nil
 }
@@ -237,10 +279,26 @@ func (r *DocumentHandler) addToBatch(operation *batch.Operation) error {
 	return r.writer.Add(&batch.OperationInfo{
 		Namespace:    r.namespace,
 		UniqueSuffix: operation.UniqueSuffix,
+		Type:         operation.Type,
 		Data:         operation.OperationBuffer,
 	})
 }
 
+// updateStatus records that the operation was queued. Status tracking doesn't affect operation processing
+// so errors are only logged.
+func (r *DocumentHandler) updateStatus(operation *batch.Operation) {
+	if r.statusStore == nil {
+		return
+	}
+
+	status := opstatus.NewStatus(operation, batch.OperationStateQueued)
+	status.Namespace = r.namespace
+
+	if err := r.statusStore.Put(status); err != nil {
+		log.Warnf("[%s] failed to record status of operation: %s", operation.ID, err.Error())
+	}
+}
+
 // validateOperation validates the operation
 func (r *DocumentHandler) validateOperation(operation *batch.Operation) error {
 	// check maximum operation size against protocol
@@ -8,6 +8,7 @@ package dochandler
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -26,6 +27,7 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/internal/canonicalizer"
 	"github.com/trustbloc/sidetree-core-go/pkg/jws"
 	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 	"github.com/trustbloc/sidetree-core-go/pkg/patch"
 	"github.com/trustbloc/sidetree-core-go/pkg/processor"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
@@ -60,6 +62,57 @@ func TestDocumentHandler_ProcessOperation_Create(t *testing.T) {
 	require.NotNil(t, doc)
 }
 
+func TestDocumentHandler_ProcessOperation_Status(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		statusStore := opstatus.NewMemStore()
+
+		dochandler := getDocumentHandler(mocks.NewMockOperationStore(nil))
+		WithOperationStatusStore(statusStore)(dochandler)
+
+		createOp := getCreateOperation()
+
+		doc, err := dochandler.ProcessOperation(createOp)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		statuses, err := statusStore.Get(opstatus.TrackingID(createOp))
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		require.Equal(t, batchapi.OperationStateQueued, statuses[0].State)
+		require.Equal(t, namespace, statuses[0].Namespace)
+		require.Equal(t, createOp.UniqueSuffix, statuses[0].UniqueSuffix)
+	})
+
+	t.Run("status store error is ignored", func(t *testing.T) {
+		dochandler := getDocumentHandler(mocks.NewMockOperationStore(nil))
+		WithOperationStatusStore(&mockStatusStore{err: errors.New("store error")})(dochandler)
+
+		doc, err := dochandler.ProcessOperation(getCreateOperation())
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+	})
+
+	t.Run("operation not queued", func(t *testing.T) {
+		statusStore := opstatus.NewMemStore()
+
+		dochandler := getDocumentHandler(mocks.NewMockOperationStore(nil))
+		WithOperationStatusStore(statusStore)(dochandler)
+
+		protocol := mocks.NewMockProtocolClient()
+		protocol.Protocol.MaxDeltaByteSize = 2
+		dochandler.protocol = protocol
+
+		createOp := getCreateOperation()
+
+		doc, err := dochandler.ProcessOperation(createOp)
+		require.Error(t, err)
+		require.Nil(t, doc)
+
+		_, err = statusStore.Get(opstatus.TrackingID(createOp))
+		require.Error(t, err)
+	})
+}
+
 func TestDocumentHandler_ProcessOperation_InitialDocumentError(t *testing.T) {
 	dochandler := getDocumentHandler(mocks.NewMockOperationStore(nil))
 	require.NotNil(t, dochandler)
@@ -133,6 +186,51 @@ func TestDocumentHandler_ResolveDocument_DID(t *testing.T) {
 	require.Contains(t, err.Error(), "did suffix is empty")
 }
 
+func TestDocumentHandler_ResolveDocument_Version(t *testing.T) {
+	store := mocks.NewMockOperationStore(nil)
+	dochandler := getDocumentHandler(store)
+	require.NotNil(t, dochandler)
+
+	createOp := getCreateOperation()
+	createOp.TransactionTime = 10
+	createOp.TransactionNumber = 2
+
+	err := store.Put(createOp)
+	require.NoError(t, err)
+
+	docID := createOp.ID
+
+	// scenario: resolved version (success)
+	result, err := dochandler.ResolveDocument(docID + "?versionTime=15")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, "2-0", result.MethodMetadata.VersionID)
+	require.Equal(t, uint64(10), result.MethodMetadata.VersionTime)
+
+	result, err = dochandler.ResolveDocument(docID + "?versionId=2-0")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, "2-0", result.MethodMetadata.VersionID)
+
+	// scenario: document didn't exist at the version time
+	result, err = dochandler.ResolveDocument(docID + "?versionTime=5")
+	require.Error(t, err)
+	require.Nil(t, result)
+	require.Contains(t, err.Error(), "not found")
+
+	// scenario: version not found
+	result, err = dochandler.ResolveDocument(docID + "?versionId=3-0")
+	require.Error(t, err)
+	require.Nil(t, result)
+	require.Contains(t, err.Error(), "not found")
+
+	// scenario: invalid version
+	result, err = dochandler.ResolveDocument(docID + "?versionTime=abc")
+	require.Error(t, err)
+	require.Nil(t, result)
+	require.Contains(t, err.Error(), "bad request")
+}
+
 func TestDocumentHandler_ResolveDocument_InitialValue(t *testing.T) {
 	dochandler := getDocumentHandler(mocks.NewMockOperationStore(nil))
 	require.NotNil(t, dochandler)
@@ -543,3 +641,11 @@ func getUpdateOperation() *batchapi.Operation {
 
 // test value taken from reference implementation
 const interopResolveDidWithInitialState = `did:sidetree:EiBFsUlzmZ3zJtSFeQKwJNtngjmB51ehMWWDuptf9b4Bag?-sidetree-initial-state=eyJkZWx0YV9oYXNoIjoiRWlCWE00b3RMdVAyZkc0WkE3NS1hbnJrV1ZYMDYzN3hadE1KU29Lb3AtdHJkdyIsInJlY292ZXJ5X2NvbW1pdG1lbnQiOiJFaUM4RzRJZGJEN0Q0Q281N0dqTE5LaG1ERWFicnprTzF3c0tFOU1RZVV2T2d3In0.eyJ1cGRhdGVfY29tbWl0bWVudCI6IkVpQ0lQY1hCempqUWFKVUljUjUyZXVJMHJJWHpoTlpfTWxqc0tLOXp4WFR5cVEiLCJwYXRjaGVzIjpbeyJhY3Rpb24iOiJyZXBsYWNlIiwiZG9jdW1lbnQiOnsicHVibGljX2tleXMiOlt7ImlkIjoic2lnbmluZ0tleSIsInR5cGUiOiJFY2RzYVNlY3AyNTZrMVZlcmlmaWNhdGlvbktleTIwMTkiLCJqd2siOnsia3R5IjoiRUMiLCJjcnYiOiJzZWNwMjU2azEiLCJ4IjoieTlrenJWQnFYeDI0c1ZNRVFRazRDZS0wYnFaMWk1VHd4bGxXQ2t6QTd3VSIsInkiOiJjMkpIeFFxVVV0eVdJTEFJaWNtcEJHQzQ3UGdtSlQ0NjV0UG9jRzJxMThrIn0sInB1cnBvc2UiOlsiYXV0aCIsImdlbmVyYWwiXX1dLCJzZXJ2aWNlX2VuZHBvaW50cyI6W3siaWQiOiJzZXJ2aWNlRW5kcG9pbnRJZDEyMyIsInR5cGUiOiJzb21lVHlwZSIsImVuZHBvaW50IjoiaHR0cHM6Ly93d3cudXJsLmNvbSJ9XX19XX0`
+
+type mockStatusStore struct {
+	err error
+}
+
+func (s *mockStatusStore) Put(*batchapi.OperationStatus) error {
+	return s.err
+}
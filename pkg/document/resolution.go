@@ -6,6 +6,12 @@ SPDX-License-Identifier: Apache-2.0
 
 package document
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // ResolutionResult describes resolution result
 type ResolutionResult struct {
 	Context        string         `json:"@context"`
@@ -18,4 +24,71 @@ type MethodMetadata struct {
 	UpdateCommitment   string `json:"updateCommitment"`
 	RecoveryCommitment string `json:"recoveryCommitment"`
 	Published          bool   `json:"published"`
+	// VersionID identifies the operation that produced the returned version of the document
+	VersionID string `json:"versionId,omitempty"`
+	// VersionTime is the ledger time of the operation that produced the returned version of the document
+	VersionTime uint64 `json:"versionTime,omitempty"`
+}
+
+// ResolutionOptions contains the options for document resolution. By default the latest version is resolved.
+type ResolutionOptions struct {
+	// VersionTime resolves the document as it was at the given ledger time (if not nil)
+	VersionTime *uint64
+	// VersionID resolves the version of the document that was produced by the given operation (if not empty)
+	VersionID string
+}
+
+// ResolutionOption is an option for document resolution
+type ResolutionOption func(opts *ResolutionOptions)
+
+// WithVersionTime resolves the document as it was at the given ledger time
+func WithVersionTime(versionTime uint64) ResolutionOption {
+	return func(opts *ResolutionOptions) {
+		opts.VersionTime = &versionTime
+	}
+}
+
+// WithVersionID resolves the version of the document that was produced by the operation with the given version ID
+func WithVersionID(versionID string) ResolutionOption {
+	return func(opts *ResolutionOptions) {
+		opts.VersionID = versionID
+	}
+}
+
+// GetResolutionOptions returns the resolution options that result from applying the given options
+func GetResolutionOptions(opts ...ResolutionOption) *ResolutionOptions {
+	options := &ResolutionOptions{}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// VersionID returns the version ID of the operation with the given transaction number and operation index
+func VersionID(txnNumber uint64, opIndex uint) string {
+	return fmt.Sprintf("%d-%d", txnNumber, opIndex)
+}
+
+// ParseVersionID returns the transaction number and operation index of the given version ID
+func ParseVersionID(versionID string) (txnNumber uint64, opIndex uint, err error) {
+	const twoParts = 2
+
+	parts := strings.Split(versionID, "-")
+	if len(parts) != twoParts {
+		return 0, 0, fmt.Errorf("invalid version ID [%s]: expecting <transaction number>-<operation index>", versionID)
+	}
+
+	txnNumber, err = strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid transaction number in version ID [%s]: %s", versionID, err)
+	}
+
+	index, err := strconv.ParseUint(parts[1], 10, strconv.IntSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid operation index in version ID [%s]: %s", versionID, err)
+	}
+
+	return txnNumber, uint(index), nil
 }
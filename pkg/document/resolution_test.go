@@ -0,0 +1,50 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package document
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetResolutionOptions(t *testing.T) {
+	options := GetResolutionOptions()
+	require.Nil(t, options.VersionTime)
+	require.Empty(t, options.VersionID)
+
+	options = GetResolutionOptions(WithVersionTime(0))
+	require.NotNil(t, options.VersionTime)
+	require.Equal(t, uint64(0), *options.VersionTime)
+
+	options = GetResolutionOptions(WithVersionID("5-1"))
+	require.Nil(t, options.VersionTime)
+	require.Equal(t, "5-1", options.VersionID)
+}
+
+func TestParseVersionID(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		txnNumber, opIndex, err := ParseVersionID(VersionID(12, 3))
+		require.NoError(t, err)
+		require.Equal(t, uint64(12), txnNumber)
+		require.Equal(t, uint(3), opIndex)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, _, err := ParseVersionID("12")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "expecting <transaction number>-<operation index>")
+
+		_, _, err = ParseVersionID("x-3")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid transaction number in version ID [x-3]")
+
+		_, _, err = ParseVersionID("12-y")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid operation index in version ID [12-y]")
+	})
+}
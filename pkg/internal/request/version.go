@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+)
+
+const (
+	// VersionTimeParam is the DID URL query parameter for resolving the document as it was at the given ledger time
+	VersionTimeParam = "versionTime"
+
+	// VersionIDParam is the DID URL query parameter for resolving the version of the document
+	// that was produced by the given operation (<transaction number>-<operation index>)
+	VersionIDParam = "versionId"
+)
+
+// GetVersion removes the version query parameters (versionTime or versionId) from the given DID URL and returns
+// the remaining DID URL along with the resolution options for the requested version
+func GetVersion(didURL string) (string, []document.ResolutionOption, error) {
+	pos := strings.Index(didURL, "?")
+	if pos == -1 {
+		return didURL, nil, nil
+	}
+
+	var params []string
+	var opts []document.ResolutionOption
+
+	for _, param := range strings.Split(didURL[pos+1:], "&") {
+		name, value := splitParam(param)
+
+		switch name {
+		case VersionTimeParam:
+			versionTime, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid %s [%s]", VersionTimeParam, value)
+			}
+
+			opts = append(opts, document.WithVersionTime(versionTime))
+		case VersionIDParam:
+			if _, _, err := document.ParseVersionID(value); err != nil {
+				return "", nil, err
+			}
+
+			opts = append(opts, document.WithVersionID(value))
+		default:
+			params = append(params, param)
+		}
+	}
+
+	if len(opts) > 1 {
+		return "", nil, errors.New("only one of versionTime and versionId may be specified")
+	}
+
+	if len(params) == 0 {
+		return didURL[:pos], opts, nil
+	}
+
+	return didURL[:pos+1] + strings.Join(params, "&"), opts, nil
+}
+
+func splitParam(param string) (string, string) {
+	const twoParts = 2
+
+	parts := strings.SplitN(param, "=", twoParts)
+	if len(parts) != twoParts {
+		return parts[0], ""
+	}
+
+	value, err := url.QueryUnescape(parts[1])
+	if err != nil {
+		return parts[0], parts[1]
+	}
+
+	return parts[0], value
+}
@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+)
+
+func TestGetVersion(t *testing.T) {
+	const testDID = "did:method:abc"
+
+	t.Run("no version", func(t *testing.T) {
+		did, opts, err := GetVersion(testDID)
+		require.NoError(t, err)
+		require.Equal(t, testDID, did)
+		require.Empty(t, opts)
+
+		did, opts, err = GetVersion(testDID + initialStateParam + "xyz.123")
+		require.NoError(t, err)
+		require.Equal(t, testDID+initialStateParam+"xyz.123", did)
+		require.Empty(t, opts)
+	})
+
+	t.Run("version time", func(t *testing.T) {
+		did, opts, err := GetVersion(testDID + "?versionTime=15")
+		require.NoError(t, err)
+		require.Equal(t, testDID, did)
+
+		options := document.GetResolutionOptions(opts...)
+		require.NotNil(t, options.VersionTime)
+		require.Equal(t, uint64(15), *options.VersionTime)
+	})
+
+	t.Run("version ID with initial state", func(t *testing.T) {
+		did, opts, err := GetVersion(testDID + "?versionId=5-1&-method-initial-state=xyz.123")
+		require.NoError(t, err)
+		require.Equal(t, testDID+initialStateParam+"xyz.123", did)
+		require.Equal(t, "5-1", document.GetResolutionOptions(opts...).VersionID)
+
+		did, opts, err = GetVersion(testDID + initialStateParam + "xyz.123&versionId=5-1")
+		require.NoError(t, err)
+		require.Equal(t, testDID+initialStateParam+"xyz.123", did)
+		require.Equal(t, "5-1", document.GetResolutionOptions(opts...).VersionID)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		did, opts, err := GetVersion(testDID + "?versionTime=abc")
+		require.Error(t, err)
+		require.Empty(t, did)
+		require.Nil(t, opts)
+		require.Contains(t, err.Error(), "invalid versionTime [abc]")
+
+		_, _, err = GetVersion(testDID + "?versionId=5")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid version ID [5]")
+
+		_, _, err = GetVersion(testDID + "?versionTime=15&versionId=5-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "only one of versionTime and versionId may be specified")
+	})
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package mocks
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
@@ -18,6 +19,7 @@ type MockBlockchainClient struct {
 	namespace string
 	anchors   []string
 	err       error
+	forkChan  chan txn.Fork
 }
 
 // NewMockBlockchainClient creates mock client
@@ -27,13 +29,13 @@ func NewMockBlockchainClient(err error) *MockBlockchainClient {
 
 // WriteAnchor writes the anchor file hash as a transaction to blockchain.
 func (m *MockBlockchainClient) WriteAnchor(anchorFileHash string) error {
+	m.Lock()
+	defer m.Unlock()
+
 	if m.err != nil {
 		return m.err
 	}
 
-	m.Lock()
-	defer m.Unlock()
-
 	m.anchors = append(m.anchors, anchorFileHash)
 
 	return nil
@@ -63,6 +65,14 @@ func (m *MockBlockchainClient) Read(sinceTransactionNumber int) (bool, *txn.Side
 	return moreTransactions, nil
 }
 
+// SetError injects an error into the mock client
+func (m *MockBlockchainClient) SetError(err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.err = err
+}
+
 // GetAnchors returns anchors
 func (m *MockBlockchainClient) GetAnchors() []string {
 	m.RLock()
@@ -70,3 +80,48 @@ func (m *MockBlockchainClient) GetAnchors() []string {
 
 	return m.anchors
 }
+
+// RegisterForFork returns the channel that receives the fork notifications of Reorg
+func (m *MockBlockchainClient) RegisterForFork() <-chan txn.Fork {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.forkChan == nil {
+		m.forkChan = make(chan txn.Fork, 10)
+	}
+
+	return m.forkChan
+}
+
+// Reorg simulates a ledger reorganization: the anchors after the given transaction number are replaced
+// by the given anchors and a fork notification is sent to the registered fork channel (if any).
+// An error is returned if the transaction number isn't the number of a transaction (the rollback point
+// is a transaction so at least the first transaction is kept).
+func (m *MockBlockchainClient) Reorg(sinceTransactionNumber int, anchors ...string) (txn.Fork, error) {
+	m.Lock()
+
+	if sinceTransactionNumber < 0 || sinceTransactionNumber >= len(m.anchors) {
+		m.Unlock()
+
+		return txn.Fork{}, fmt.Errorf("invalid transaction number [%d]", sinceTransactionNumber)
+	}
+
+	m.anchors = append(append([]string{}, m.anchors[:sinceTransactionNumber+1]...), anchors...)
+	forkChan := m.forkChan
+
+	m.Unlock()
+
+	fork := txn.Fork{
+		RollbackPoint: txn.Checkpoint{
+			TransactionTime:   uint64(sinceTransactionNumber),
+			TransactionNumber: uint64(sinceTransactionNumber),
+		},
+	}
+
+	// the notification is sent without holding the lock since the observer reads the ledger when it's notified
+	if forkChan != nil {
+		forkChan <- fork
+	}
+
+	return fork, nil
+}
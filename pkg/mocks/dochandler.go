@@ -101,6 +101,11 @@ func (m *MockDocumentHandler) ResolveDocument(idOrDocument string) (*document.Re
 		return nil, m.err
 	}
 
+	idOrDocument, _, err := request.GetVersion(idOrDocument)
+	if err != nil {
+		return nil, err
+	}
+
 	if strings.Contains(idOrDocument, request.GetInitialStateParam(m.namespace)) {
 		return m.resolveWithInitialState(idOrDocument)
 	}
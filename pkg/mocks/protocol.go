@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package mocks
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
@@ -21,6 +23,10 @@ const maxBatchFileSize = 20000
 // MockProtocolClient mocks protocol for testing purposes.
 type MockProtocolClient struct {
 	Protocol protocol.Protocol
+
+	// Versions are the protocol versions sorted by starting blockchain time.
+	// If no versions are set then Protocol applies to all transaction times.
+	Versions []protocol.Protocol
 }
 
 // NewMockProtocolClient creates mocks protocol client
@@ -45,6 +51,21 @@ func (m *MockProtocolClient) Current() protocol.Protocol {
 	return m.Protocol
 }
 
+// Get mocks getting protocol version based on transaction time
+func (m *MockProtocolClient) Get(transactionTime uint64) (protocol.Protocol, error) {
+	if len(m.Versions) == 0 {
+		return m.Protocol, nil
+	}
+
+	for i := len(m.Versions) - 1; i >= 0; i-- {
+		if transactionTime >= uint64(m.Versions[i].StartingBlockChainTime) {
+			return m.Versions[i], nil
+		}
+	}
+
+	return protocol.Protocol{}, fmt.Errorf("protocol parameters are not defined for transaction time [%d]", transactionTime)
+}
+
 // NewMockProtocolClientProvider creates new mock protocol client provider
 func NewMockProtocolClientProvider() *MockProtocolClientProvider {
 	m := make(map[string]protocol.Client)
@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mocks
+
+import (
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+// MockTxnOperationStore is a reference implementation of a transactional operation store. The operations of
+// a transaction are committed atomically and an operation whose idempotency key was already committed is ignored,
+// so a Sidetree transaction that is processed again (e.g. after a restart) doesn't store its operations twice.
+type MockTxnOperationStore struct {
+	mutex      sync.RWMutex
+	keys       map[string]bool
+	operations []*keyedOperation
+}
+
+type keyedOperation struct {
+	key string
+	op  *batch.Operation
+}
+
+// NewMockTxnOperationStore creates a new transactional operation store
+func NewMockTxnOperationStore() *MockTxnOperationStore {
+	return &MockTxnOperationStore{keys: make(map[string]bool)}
+}
+
+// Put stores the given operations without an idempotency key
+func (m *MockTxnOperationStore) Put(ops []*batch.Operation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, op := range ops {
+		m.operations = append(m.operations, &keyedOperation{op: op})
+	}
+
+	return nil
+}
+
+// DeleteAfter deletes all operations of the transactions after the given transaction
+// (along with their idempotency keys)
+func (m *MockTxnOperationStore) DeleteAfter(transactionTime, transactionNumber uint64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var remaining []*keyedOperation
+	for _, kop := range m.operations {
+		if kop.op.TransactionTime < transactionTime ||
+			kop.op.TransactionTime == transactionTime && kop.op.TransactionNumber <= transactionNumber {
+			remaining = append(remaining, kop)
+			continue
+		}
+
+		delete(m.keys, kop.key)
+	}
+
+	m.operations = remaining
+
+	return nil
+}
+
+// Begin starts a new store transaction
+func (m *MockTxnOperationStore) Begin() (batch.OperationStoreTxn, error) {
+	return &mockStoreTxn{store: m}, nil
+}
+
+// Operations returns the stored operations in the order that they were stored
+func (m *MockTxnOperationStore) Operations() []*batch.Operation {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ops := make([]*batch.Operation, len(m.operations))
+	for i, kop := range m.operations {
+		ops[i] = kop.op
+	}
+
+	return ops
+}
+
+type mockStoreTxn struct {
+	store  *MockTxnOperationStore
+	staged []*keyedOperation
+}
+
+// Put stages the given operation under the given idempotency key
+func (t *mockStoreTxn) Put(key string, op *batch.Operation) error {
+	t.staged = append(t.staged, &keyedOperation{key: key, op: op})
+
+	return nil
+}
+
+// Commit stores the staged operations whose idempotency keys weren't committed before
+func (t *mockStoreTxn) Commit() error {
+	t.store.mutex.Lock()
+	defer t.store.mutex.Unlock()
+
+	for _, kop := range t.staged {
+		if t.store.keys[kop.key] {
+			continue
+		}
+
+		t.store.keys[kop.key] = true
+		t.store.operations = append(t.store.operations, kop)
+	}
+
+	t.staged = nil
+
+	return nil
+}
+
+// Rollback discards the staged operations
+func (t *mockStoreTxn) Rollback() error {
+	t.staged = nil
+
+	return nil
+}
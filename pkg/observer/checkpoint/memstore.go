@@ -0,0 +1,61 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package checkpoint
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+// MemStore implements an in-memory store for the checkpoints of the observer
+type MemStore struct {
+	mutex       sync.RWMutex
+	checkpoints map[string]txn.Checkpoint
+}
+
+// NewMemStore returns a new in-memory checkpoint store
+func NewMemStore() *MemStore {
+	return &MemStore{checkpoints: make(map[string]txn.Checkpoint)}
+}
+
+// Put stores the checkpoint for the given namespace
+func (s *MemStore) Put(namespace string, checkpoint txn.Checkpoint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.checkpoints[namespace] = checkpoint
+
+	return nil
+}
+
+// Get returns the checkpoint for the given namespace
+func (s *MemStore) Get(namespace string) (txn.Checkpoint, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	checkpoint, ok := s.checkpoints[namespace]
+	if !ok {
+		return txn.Checkpoint{}, fmt.Errorf("checkpoint not found for namespace [%s]", namespace)
+	}
+
+	return checkpoint, nil
+}
+
+// GetAll returns the checkpoints of all namespaces
+func (s *MemStore) GetAll() (map[string]txn.Checkpoint, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	checkpoints := make(map[string]txn.Checkpoint, len(s.checkpoints))
+	for namespace, checkpoint := range s.checkpoints {
+		checkpoints[namespace] = checkpoint
+	}
+
+	return checkpoints, nil
+}
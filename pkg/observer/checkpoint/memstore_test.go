@@ -0,0 +1,42 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+func TestMemStore(t *testing.T) {
+	s := NewMemStore()
+
+	_, err := s.Get("ns1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checkpoint not found for namespace [ns1]")
+
+	all, err := s.GetAll()
+	require.NoError(t, err)
+	require.Empty(t, all)
+
+	require.NoError(t, s.Put("ns1", txn.Checkpoint{TransactionTime: 10, TransactionNumber: 2}))
+	require.NoError(t, s.Put("ns2", txn.Checkpoint{TransactionTime: 11, TransactionNumber: 3}))
+	require.NoError(t, s.Put("ns1", txn.Checkpoint{TransactionTime: 12, TransactionNumber: 4}))
+
+	checkpoint, err := s.Get("ns1")
+	require.NoError(t, err)
+	require.Equal(t, txn.Checkpoint{TransactionTime: 12, TransactionNumber: 4}, checkpoint)
+
+	all, err = s.GetAll()
+	require.NoError(t, err)
+	require.Equal(t, map[string]txn.Checkpoint{
+		"ns1": {TransactionTime: 12, TransactionNumber: 4},
+		"ns2": {TransactionTime: 11, TransactionNumber: 3},
+	}, all)
+}
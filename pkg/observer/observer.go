@@ -8,6 +8,8 @@ package observer
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -15,15 +17,63 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
 	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	"github.com/trustbloc/sidetree-core-go/pkg/observer/retry"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
+	"github.com/trustbloc/sidetree-core-go/pkg/txnhandler"
 )
 
 var logger = logrus.New()
 
+const (
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 5 * time.Minute
+	defaultRetryMaxAttempts    = 10
+)
+
+// RefusedTxnError is sent to the error channel when the observer refuses a transaction, i.e. when its batch files
+// are invalid or when it couldn't be processed after the maximum number of attempts
+type RefusedTxnError struct {
+	SidetreeTxn txn.SidetreeTxn
+	Attempts    uint
+	Reason      string
+}
+
+// Error returns the reason the transaction was refused
+func (e *RefusedTxnError) Error() string {
+	return fmt.Sprintf("anchor[%s] was refused after %d attempts: %s", e.SidetreeTxn.AnchorString, e.Attempts, e.Reason)
+}
+
 // Ledger interface to access ledger txn
 type Ledger interface {
 	RegisterForSidetreeTxn() <-chan []txn.SidetreeTxn
 }
 
+// LedgerReader reads Sidetree transactions from the ledger
+type LedgerReader interface {
+	// Read returns the Sidetree transaction that follows the given transaction number (if any)
+	// and whether there are more transactions
+	Read(sinceTransactionNumber int) (bool, *txn.SidetreeTxn)
+}
+
+// ForkNotifier notifies the observer when the ledger is reorganized
+type ForkNotifier interface {
+	RegisterForFork() <-chan txn.Fork
+}
+
+// CheckpointStore records the last fully processed Sidetree transaction for each namespace
+type CheckpointStore interface {
+	Put(namespace string, checkpoint txn.Checkpoint) error
+	GetAll() (map[string]txn.Checkpoint, error)
+}
+
+// RetryStore records the Sidetree transactions that failed to be processed and are pending retry
+type RetryStore interface {
+	Put(pending *txn.PendingTxn) error
+	Delete(transactionNumber uint64) error
+	// GetAll returns all pending transactions ordered by transaction number
+	GetAll() ([]*txn.PendingTxn, error)
+}
+
 // TxnOpsProvider defines an interface for retrieving(assembling) operations from batch files(chunk, map, anchor)
 type TxnOpsProvider interface {
 	// GetTxnOperations will read batch files(chunk, map, anchor) and assemble batch operations from those files
@@ -39,6 +89,24 @@ type DecompressionProvider interface {
 // OperationStore interface to access operation store
 type OperationStore interface {
 	Put(ops []*batch.Operation) error
+	// DeleteAfter deletes all operations of the transactions after the given transaction, i.e. the operations with
+	// a greater transaction time or with the same transaction time and a greater transaction number
+	DeleteAfter(transactionTime, transactionNumber uint64) error
+}
+
+// TransactionalOperationStore is implemented by operation stores that store the operations of a Sidetree
+// transaction atomically. Each operation is staged under its idempotency key (see IdempotencyKey)
+// and the operations that were already committed must be ignored, so that a transaction may safely be
+// processed again. (See mocks.MockTxnOperationStore for a reference implementation.)
+type TransactionalOperationStore interface {
+	OperationStore
+	Begin() (batch.OperationStoreTxn, error)
+}
+
+// IdempotencyKey returns the key that identifies an anchored operation, which is made up
+// of the transaction number and the index of the operation within the transaction
+func IdempotencyKey(op *batch.Operation) string {
+	return fmt.Sprintf("%d-%d", op.TransactionNumber, op.OperationIndex)
 }
 
 // OperationStoreProvider returns an operation store for the given namespace
@@ -56,6 +124,45 @@ type OperationFilterProvider interface {
 	Get(namespace string) (OperationFilter, error)
 }
 
+// OperationStatusStore records the state transitions of operations
+type OperationStatusStore interface {
+	Put(status *batch.OperationStatus) error
+}
+
+// ResolutionCache caches resolution results. It's invalidated whenever the operations of a document change.
+type ResolutionCache interface {
+	// Invalidate invalidates the cached resolution result of the document with the given unique suffix
+	Invalidate(namespace, uniqueSuffix string)
+	// InvalidateAll invalidates the cached resolution results of all documents of the given namespace
+	InvalidateAll(namespace string)
+}
+
+// DocumentStateUpdater updates the materialized state of the documents of a namespace
+type DocumentStateUpdater interface {
+	// UpdateState updates the state of the document with the given unique suffix after its operations were stored
+	UpdateState(uniqueSuffix string) error
+	// DeleteStateAfter deletes the state of the documents that was materialized from operations
+	// with a transaction time greater than the given transaction time
+	DeleteStateAfter(transactionTime uint64) error
+}
+
+// DocumentStateUpdaterProvider returns the document state updater for the given namespace
+type DocumentStateUpdaterProvider interface {
+	ForNamespace(namespace string) (DocumentStateUpdater, error)
+}
+
+// OperationListener is notified of the operations that were stored (e.g. the operation validator of
+// the batch writer, which tracks the anchored operations until they are stored)
+type OperationListener interface {
+	// Persisted is called after the given operations were stored
+	Persisted(ops []*batch.Operation)
+}
+
+// OperationListenerProvider returns the operation listener for the given namespace
+type OperationListenerProvider interface {
+	ForNamespace(namespace string) (OperationListener, error)
+}
+
 // Providers contains all of the providers required by the TxnProcessor
 type Providers struct {
 	Ledger                Ledger
@@ -63,6 +170,33 @@ type Providers struct {
 	OpStoreProvider       OperationStoreProvider
 	OpFilterProvider      OperationFilterProvider
 	DecompressionProvider DecompressionProvider
+	// OpStatusStore is optional. If set then the state transitions of the processed operations are recorded.
+	OpStatusStore OperationStatusStore
+	// CheckpointStore is optional. If set then the last processed transaction is recorded for each namespace.
+	CheckpointStore CheckpointStore
+	// LedgerReader is optional. If set then on startup the observer processes the transactions on the ledger
+	// since the earliest checkpoint (or all transactions if there are no checkpoints) before it processes
+	// live notifications.
+	LedgerReader LedgerReader
+	// ForkNotifier is optional. If set then the operations of the transactions that are no longer part of the
+	// canonical ledger are deleted when the ledger is reorganized and the new canonical transactions are
+	// processed (read from the ledger reader if provided, otherwise received as live notifications).
+	ForkNotifier ForkNotifier
+	// RetryStore is optional. It records the transactions that failed to be processed (e.g. since their batch
+	// files were temporarily unavailable in CAS) so that they are retried. An in-memory store is used by default.
+	// Note that if the checkpoint store is persistent then the retry store should also be persistent, otherwise
+	// the transactions that are pending retry are lost on restart.
+	RetryStore RetryStore
+	// ResolutionCache is optional. If set then the cached resolution result of a document is invalidated
+	// whenever operations are stored for the document (or deleted when the ledger is reorganized).
+	ResolutionCache ResolutionCache
+	// StateUpdaterProvider is optional. If set then the materialized state of a document is updated whenever
+	// operations are stored for the document (and deleted when the ledger is reorganized). Since documents are
+	// resolved from their state along with the operations that were stored since, failures are only logged.
+	StateUpdaterProvider DocumentStateUpdaterProvider
+	// OpListenerProvider is optional. If set then the operation listener of the namespace is notified
+	// whenever operations are stored.
+	OpListenerProvider OperationListenerProvider
 }
 
 // Observer receives transactions over a channel and processes them by storing them to an operation store
@@ -71,20 +205,265 @@ type Observer struct {
 
 	processor *TxnProcessor
 	stopCh    chan struct{}
+	// checkpoints contains the last processed transaction for each namespace. It's only
+	// maintained if a checkpoint store or ledger reader is provided.
+	checkpoints map[string]txn.Checkpoint
+	// namespaces contains the namespaces of the processed transactions
+	namespaces map[string]bool
+
+	retryStore RetryStore
+	// pending contains the transactions that are pending retry by transaction number. It's loaded from
+	// the retry store on first use and, like the retry store, it's only updated by the observer routine.
+	pending             map[uint64]*txn.PendingTxn
+	retryInitialBackoff time.Duration
+	retryMaxBackoff     time.Duration
+	retryMaxAttempts    uint
+
+	errChan chan<- error
+
+	fetchWorkers int
+
+	publisher *publisher
+}
+
+// Option is an observer option
+type Option func(o *Observer)
+
+// WithRetryBackoff sets the backoff for retrying transactions that failed to be processed. The backoff starts
+// at initialBackoff and is doubled after each attempt up to maxBackoff.
+func WithRetryBackoff(initialBackoff, maxBackoff time.Duration) Option {
+	return func(o *Observer) {
+		o.retryInitialBackoff = initialBackoff
+		o.retryMaxBackoff = maxBackoff
+	}
+}
+
+// WithRetryMaxAttempts sets the maximum number of times that processing a transaction is attempted. The
+// transaction is refused (and a RefusedTxnError is sent to the error channel) after the last attempt fails.
+func WithRetryMaxAttempts(maxAttempts uint) Option {
+	return func(o *Observer) {
+		o.retryMaxAttempts = maxAttempts
+	}
+}
+
+// WithErrorChannel sets the channel that receives a RefusedTxnError for each transaction that is refused.
+// The observer doesn't block on the channel, so errors are dropped if the channel is full.
+func WithErrorChannel(errChan chan<- error) Option {
+	return func(o *Observer) {
+		o.errChan = errChan
+	}
+}
+
+// WithFetchConcurrency sets the number of transactions whose batch files are retrieved from CAS concurrently.
+// The operations are still filtered and stored in ledger order. By default transactions are processed one at a time.
+func WithFetchConcurrency(workers int) Option {
+	return func(o *Observer) {
+		o.fetchWorkers = workers
+	}
 }
 
 // New returns a new observer
-func New(providers *Providers) *Observer {
-	return &Observer{
-		Providers: providers,
-		stopCh:    make(chan struct{}, 1),
-		processor: NewTxnProcessor(providers),
+func New(providers *Providers, opts ...Option) *Observer {
+	pub := newPublisher()
+
+	o := &Observer{
+		Providers:           providers,
+		stopCh:              make(chan struct{}, 1),
+		processor:           &TxnProcessor{Providers: providers, publisher: pub},
+		publisher:           pub,
+		namespaces:          make(map[string]bool),
+		retryStore:          providers.RetryStore,
+		retryInitialBackoff: defaultRetryInitialBackoff,
+		retryMaxBackoff:     defaultRetryMaxBackoff,
+		retryMaxAttempts:    defaultRetryMaxAttempts,
 	}
+
+	if o.retryStore == nil {
+		o.retryStore = retry.NewMemStore()
+	}
+
+	if providers.CheckpointStore != nil || providers.LedgerReader != nil {
+		o.checkpoints = make(map[string]txn.Checkpoint)
+	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
 }
 
-// Start starts observer routines
+// Subscribe returns a subscription to the events of the operations that are processed by the observer
+func (o *Observer) Subscribe(opts ...SubscriptionOption) *Subscription {
+	return o.publisher.subscribe(opts...)
+}
+
+// Unsubscribe cancels the given subscription and closes its events channel
+func (o *Observer) Unsubscribe(s *Subscription) {
+	o.publisher.unsubscribe(s)
+}
+
+// UnresolvedTxns returns the transactions that failed to be processed and are pending retry
+func (o *Observer) UnresolvedTxns() ([]*txn.PendingTxn, error) {
+	return o.retryStore.GetAll()
+}
+
+// Start starts observer routines. The observer registers for live notifications before it catches up
+// from the ledger so that no transactions are missed. Transactions that are received more than once
+// (during catch-up and as live notifications) are only processed once.
 func (o *Observer) Start() {
-	go o.listen(o.Ledger.RegisterForSidetreeTxn())
+	txnsCh := o.Ledger.RegisterForSidetreeTxn()
+
+	var forkCh <-chan txn.Fork
+	if o.ForkNotifier != nil {
+		forkCh = o.ForkNotifier.RegisterForFork()
+	}
+
+	go func() {
+		if !o.catchUp() {
+			return
+		}
+
+		o.listen(txnsCh, forkCh)
+	}()
+}
+
+// catchUp processes the transactions on the ledger since the earliest checkpoint.
+// Returns false if the observer was stopped while catching up.
+func (o *Observer) catchUp() bool {
+	if o.CheckpointStore != nil {
+		checkpoints, err := o.CheckpointStore.GetAll()
+		if err != nil {
+			logger.Errorf("Failed to load checkpoints: %s", err)
+		}
+
+		for namespace, checkpoint := range checkpoints {
+			o.checkpoints[namespace] = checkpoint
+			o.namespaces[namespace] = true
+		}
+	}
+
+	if o.LedgerReader == nil {
+		return true
+	}
+
+	since := o.earliestCheckpoint()
+
+	logger.Infof("Catching up from the ledger since transaction number [%d]", since)
+
+	return o.readFromLedger(since)
+}
+
+// readFromLedger processes the transactions on the ledger after the given transaction number.
+// Returns false if the observer was stopped while reading.
+func (o *Observer) readFromLedger(since int) bool {
+	if o.fetchWorkers > 1 {
+		return o.readFromLedgerConcurrently(since)
+	}
+
+	processed := 0
+
+	for {
+		select {
+		case <-o.stopCh:
+			logger.Infof("The observer has been stopped while reading from the ledger. Exiting.")
+			return false
+		default:
+		}
+
+		more, sidetreeTxn := o.LedgerReader.Read(since)
+		if sidetreeTxn == nil {
+			break
+		}
+
+		o.process([]txn.SidetreeTxn{*sidetreeTxn})
+
+		processed++
+		since = int(sidetreeTxn.TransactionNumber)
+
+		if !more {
+			break
+		}
+	}
+
+	logger.Infof("Read %d transactions from the ledger. Switching to live notifications.", processed)
+
+	return true
+}
+
+// readFromLedgerConcurrently processes the transactions on the ledger after the given transaction number while
+// the batch files of the transactions that follow are retrieved concurrently.
+// Returns false if the observer was stopped while reading.
+func (o *Observer) readFromLedgerConcurrently(since int) bool {
+	done := make(chan struct{})
+	defer close(done)
+
+	// the checkpoints are only updated by the observer routine so a copy is used to skip
+	// the transactions that were already processed before they are retrieved
+	checkpoints := make(map[string]txn.Checkpoint)
+	for namespace, checkpoint := range o.checkpoints {
+		checkpoints[namespace] = checkpoint
+	}
+
+	txnsCh := make(chan txn.SidetreeTxn)
+
+	go func() {
+		defer close(txnsCh)
+
+		for {
+			more, sidetreeTxn := o.LedgerReader.Read(since)
+			if sidetreeTxn == nil {
+				return
+			}
+
+			since = int(sidetreeTxn.TransactionNumber)
+
+			if !isProcessed(checkpoints, *sidetreeTxn) {
+				select {
+				case txnsCh <- *sidetreeTxn:
+				case <-done:
+					return
+				}
+			}
+
+			if !more {
+				return
+			}
+		}
+	}()
+
+	processed := 0
+
+	for fetched := range fetchOrdered(o.processor.fetch, txnsCh, o.fetchWorkers, done) {
+		select {
+		case <-o.stopCh:
+			logger.Infof("The observer has been stopped while reading from the ledger. Exiting.")
+			return false
+		default:
+		}
+
+		o.processFetched(fetched)
+
+		processed++
+	}
+
+	logger.Infof("Read %d transactions from the ledger. Switching to live notifications.", processed)
+
+	return true
+}
+
+// earliestCheckpoint returns the earliest transaction number of all checkpoints or -1 if there are no checkpoints
+func (o *Observer) earliestCheckpoint() int {
+	since := -1
+
+	for _, checkpoint := range o.checkpoints {
+		if since == -1 || int(checkpoint.TransactionNumber) < since {
+			since = int(checkpoint.TransactionNumber)
+		}
+	}
+
+	return since
 }
 
 // Stop stops the observer
@@ -92,13 +471,30 @@ func (o *Observer) Stop() {
 	o.stopCh <- struct{}{}
 }
 
-func (o *Observer) listen(txnsCh <-chan []txn.SidetreeTxn) {
+func (o *Observer) listen(txnsCh <-chan []txn.SidetreeTxn, forkCh <-chan txn.Fork) {
+	retryTicker := time.NewTicker(o.retryInitialBackoff)
+	defer retryTicker.Stop()
+
 	for {
 		select {
+		case <-retryTicker.C:
+			o.retryPending()
+
 		case <-o.stopCh:
 			logger.Infof("The observer has been stopped. Exiting.")
 			return
 
+		case fork, ok := <-forkCh:
+			if !ok {
+				logger.Warnf("Fork notification channel was closed.")
+				forkCh = nil
+				continue
+			}
+
+			if !o.rollback(fork) {
+				return
+			}
+
 		case txns, ok := <-txnsCh:
 			if !ok {
 				logger.Warnf("Notification channel was closed. Exiting.")
@@ -110,20 +506,475 @@ func (o *Observer) listen(txnsCh <-chan []txn.SidetreeTxn) {
 	}
 }
 
+// rollback deletes the operations of the transactions after the rollback point of the given fork and
+// processes the new canonical transactions from the ledger (if a ledger reader is provided).
+// Returns false if the observer was stopped while reading from the ledger.
+func (o *Observer) rollback(fork txn.Fork) bool {
+	point := fork.RollbackPoint
+
+	logger.Warnf("Ledger was reorganized. Rolling back to transaction time [%d] and transaction number [%d]", point.TransactionTime, point.TransactionNumber)
+
+	for namespace := range o.namespaces {
+		opStore, err := o.OpStoreProvider.ForNamespace(namespace)
+		if err != nil {
+			logger.Errorf("[%s] Failed to get operation store for rollback: %s", namespace, err)
+			continue
+		}
+
+		err = opStore.DeleteAfter(point.TransactionTime, point.TransactionNumber)
+
+		// the operations of any document may have been deleted
+		if o.ResolutionCache != nil {
+			o.ResolutionCache.InvalidateAll(namespace)
+		}
+
+		if err != nil {
+			logger.Errorf("[%s] Failed to delete operations after transaction time [%d] and transaction number [%d]: %s", namespace, point.TransactionTime, point.TransactionNumber, err)
+			continue
+		}
+
+		o.deleteStateAfter(namespace, point.TransactionTime)
+
+		checkpoint, ok := o.checkpoints[namespace]
+		if ok && isAfter(checkpoint.TransactionTime, checkpoint.TransactionNumber, point) {
+			o.putCheckpoint(namespace, point)
+		}
+	}
+
+	o.rollbackPending(point)
+
+	if o.LedgerReader == nil {
+		return true
+	}
+
+	return o.readFromLedger(int(point.TransactionNumber))
+}
+
+// deleteStateAfter deletes the materialized state of the documents of the given namespace that includes
+// operations after the given transaction time
+func (o *Observer) deleteStateAfter(namespace string, transactionTime uint64) {
+	if o.StateUpdaterProvider == nil {
+		return
+	}
+
+	updater, err := o.StateUpdaterProvider.ForNamespace(namespace)
+	if err != nil {
+		logger.Errorf("[%s] Failed to get document state updater for rollback: %s", namespace, err)
+		return
+	}
+
+	if err := updater.DeleteStateAfter(transactionTime); err != nil {
+		logger.Errorf("[%s] Failed to delete document states after transaction time [%d]: %s", namespace, transactionTime, err)
+	}
+}
+
 func (o *Observer) process(txns []txn.SidetreeTxn) {
+	if o.fetchWorkers > 1 {
+		o.processConcurrently(txns)
+		return
+	}
+
+	for _, txn := range txns {
+		if o.skip(txn) {
+			continue
+		}
+
+		txnOps, err := o.processor.fetch(txn)
+		o.commit(txn, txnOps, err)
+	}
+}
+
+// processConcurrently retrieves the batch files of the given transactions concurrently and processes them in order
+func (o *Observer) processConcurrently(txns []txn.SidetreeTxn) {
+	done := make(chan struct{})
+	defer close(done)
+
+	txnsCh := make(chan txn.SidetreeTxn, len(txns))
 	for _, txn := range txns {
-		err := o.processor.Process(txn)
+		if !o.skip(txn) {
+			txnsCh <- txn
+		}
+	}
+	close(txnsCh)
+
+	for fetched := range fetchOrdered(o.processor.fetch, txnsCh, o.fetchWorkers, done) {
+		o.processFetched(fetched)
+	}
+}
+
+// processFetched processes the given transaction whose batch files were already retrieved
+func (o *Observer) processFetched(fetched *fetchedTxn) {
+	if o.skip(fetched.txn) {
+		return
+	}
+
+	o.commit(fetched.txn, fetched.ops, fetched.err)
+}
+
+// skip returns true if the given transaction was already processed or is pending retry
+func (o *Observer) skip(sidetreeTxn txn.SidetreeTxn) bool {
+	o.namespaces[sidetreeTxn.Namespace] = true
+
+	if o.isProcessed(sidetreeTxn) {
+		logger.Debugf("Skipping anchor[%s] since it was already processed", sidetreeTxn.AnchorString)
+		return true
+	}
+
+	if o.isPending(sidetreeTxn) {
+		logger.Debugf("Skipping anchor[%s] since it's pending retry", sidetreeTxn.AnchorString)
+		return true
+	}
+
+	return false
+}
+
+// commit filters and stores the given operations of the transaction. The given error is the error
+// (if any) that occurred while retrieving the operations.
+func (o *Observer) commit(sidetreeTxn txn.SidetreeTxn, txnOps []*batch.Operation, err error) {
+	var rejected []string
+	if err == nil {
+		rejected, err = o.processor.processTxnOperations(txnOps, sidetreeTxn, nil)
+	}
+
+	if err != nil {
+		// the batch files of an invalid transaction will never be valid so the transaction is refused
+		if txnhandler.IsValidationError(err) || o.retryMaxAttempts == 1 {
+			o.refuse(sidetreeTxn, 1, err)
+			o.checkpoint(sidetreeTxn)
+			return
+		}
+
+		logger.Warnf("Failed to process anchor[%s]: %s. The transaction will be retried.", sidetreeTxn.AnchorString, err.Error())
+		o.addPending(sidetreeTxn, err)
+		return
+	}
+	logger.Debugf("Successfully processed anchor[%s]", sidetreeTxn.AnchorString)
+
+	o.addSubsequent(sidetreeTxn, rejected)
+	o.checkpoint(sidetreeTxn)
+}
+
+// addPending records the given transaction for retry
+func (o *Observer) addPending(sidetreeTxn txn.SidetreeTxn, err error) {
+	pending := &txn.PendingTxn{
+		SidetreeTxn: sidetreeTxn,
+		Attempts:    1,
+		LastError:   err.Error(),
+		NextRetry:   time.Now().Add(o.retryBackoff(1)),
+	}
+
+	if e := o.putPending(pending); e != nil {
+		logger.Errorf("[%s] Failed to record anchor[%s] for retry: %s", sidetreeTxn.Namespace, sidetreeTxn.AnchorString, e)
+	}
+}
+
+// isPending returns true if the given transaction is pending retry
+func (o *Observer) isPending(sidetreeTxn txn.SidetreeTxn) bool {
+	pending, err := o.pendingTxns()
+	if err != nil {
+		logger.Warnf("Failed to get pending transactions: %s", err)
+		return false
+	}
+
+	_, ok := pending[sidetreeTxn.TransactionNumber]
+
+	return ok
+}
+
+// addSubsequent records the given (processed) transaction as a subsequent transaction of the earlier transactions
+// of the same namespace that are pending retry along with the given suffixes of the operations that it rejected,
+// so that it's reprocessed for the rejected suffixes of a pending transaction once the pending transaction is processed.
+// Nothing is recorded if no operations were rejected.
+func (o *Observer) addSubsequent(sidetreeTxn txn.SidetreeTxn, rejected []string) {
+	if len(rejected) == 0 {
+		return
+	}
+
+	pendingTxns, err := o.sortedPendingTxns()
+	if err != nil {
+		logger.Warnf("Failed to get pending transactions: %s", err)
+		return
+	}
+
+	for _, pending := range pendingTxns {
+		if pending.Namespace != sidetreeTxn.Namespace || pending.TransactionNumber >= sidetreeTxn.TransactionNumber {
+			continue
+		}
+
+		pending.Subsequent = append(pending.Subsequent, txn.SubsequentTxn{SidetreeTxn: sidetreeTxn, Suffixes: rejected})
+
+		if err := o.putPending(pending); err != nil {
+			logger.Warnf("[%s] Failed to record subsequent anchor[%s] for pending anchor[%s]: %s", pending.Namespace, sidetreeTxn.AnchorString, pending.AnchorString, err)
+		}
+	}
+}
+
+// retryPending retries the pending transactions (in order) whose backoff has expired. Once a transaction is processed,
+// the subsequent transactions are reprocessed for the suffixes of the transaction that they rejected, since their
+// operations may have been rejected because the operations of the transaction were missing.
+func (o *Observer) retryPending() {
+	pendingTxns, err := o.sortedPendingTxns()
+	if err != nil {
+		logger.Warnf("Failed to get pending transactions: %s", err)
+		return
+	}
+
+	for _, pending := range pendingTxns {
+		if time.Now().Before(pending.NextRetry) {
+			continue
+		}
+
+		txnOps, rejected, err := o.processor.process(pending.SidetreeTxn, nil)
+		if err != nil && !txnhandler.IsValidationError(err) && pending.Attempts+1 < o.retryMaxAttempts {
+			pending.Attempts++
+			pending.LastError = err.Error()
+			pending.NextRetry = time.Now().Add(o.retryBackoff(pending.Attempts))
+
+			logger.Warnf("Failed to process anchor[%s] on attempt %d: %s. Retrying at %s", pending.AnchorString, pending.Attempts, err, pending.NextRetry)
+
+			if e := o.putPending(pending); e != nil {
+				logger.Errorf("[%s] Failed to record anchor[%s] for retry: %s", pending.Namespace, pending.AnchorString, e)
+			}
+
+			continue
+		}
+
+		if err := o.deletePending(pending.TransactionNumber); err != nil {
+			logger.Errorf("[%s] Failed to delete pending anchor[%s]: %s", pending.Namespace, pending.AnchorString, err)
+			continue
+		}
+
 		if err != nil {
-			logger.Warnf("Failed to process anchor[%s]: %s", txn.AnchorString, err.Error())
+			o.refuse(pending.SidetreeTxn, pending.Attempts+1, err)
+			continue
+		}
+
+		logger.Infof("Successfully processed anchor[%s] after %d attempts", pending.AnchorString, pending.Attempts+1)
+
+		o.reprocess(pending, txnOps)
+		o.addSubsequent(pending.SidetreeTxn, rejected)
+	}
+}
+
+// refuse logs the given transaction as refused and sends a RefusedTxnError to the error channel (if any).
+// The observer doesn't block on the error channel, so the error is dropped if the channel is full.
+func (o *Observer) refuse(sidetreeTxn txn.SidetreeTxn, attempts uint, err error) {
+	logger.Errorf("[%s] Refusing transaction for anchor[%s] after %d attempts: %s", sidetreeTxn.Namespace, sidetreeTxn.AnchorString, attempts, err)
+
+	if o.errChan == nil {
+		return
+	}
+
+	select {
+	case o.errChan <- &RefusedTxnError{SidetreeTxn: sidetreeTxn, Attempts: attempts, Reason: err.Error()}:
+	default:
+		logger.Warnf("[%s] error channel is full: dropping refusal of anchor[%s]", sidetreeTxn.Namespace, sidetreeTxn.AnchorString)
+	}
+}
+
+// reprocess reprocesses the subsequent transactions of the given pending transaction for the rejected suffixes
+// that are also suffixes of the given operations. Subsequent transactions without such suffixes aren't reprocessed.
+func (o *Observer) reprocess(pending *txn.PendingTxn, txnOps []*batch.Operation) {
+	txnSuffixes := make(map[string]bool)
+	for _, op := range txnOps {
+		txnSuffixes[op.UniqueSuffix] = true
+	}
+
+	for _, subsequent := range pending.Subsequent {
+		suffixes := make(map[string]bool)
+		for _, suffix := range subsequent.Suffixes {
+			if txnSuffixes[suffix] {
+				suffixes[suffix] = true
+			}
+		}
+
+		if len(suffixes) == 0 {
+			continue
+		}
+
+		logger.Debugf("Reprocessing anchor[%s] for %d suffixes of anchor[%s]", subsequent.AnchorString, len(suffixes), pending.AnchorString)
+
+		if _, _, err := o.processor.process(subsequent.SidetreeTxn, suffixes); err != nil {
+			logger.Warnf("Failed to reprocess anchor[%s] for the suffixes of anchor[%s]: %s", subsequent.AnchorString, pending.AnchorString, err)
+		}
+	}
+}
+
+// rollbackPending deletes the pending transactions after the given rollback point
+// along with the subsequent transactions after the rollback point
+func (o *Observer) rollbackPending(point txn.Checkpoint) {
+	pendingTxns, err := o.sortedPendingTxns()
+	if err != nil {
+		logger.Errorf("Failed to get pending transactions for rollback: %s", err)
+		return
+	}
+
+	for _, pending := range pendingTxns {
+		if isAfter(pending.TransactionTime, pending.TransactionNumber, point) {
+			if err := o.deletePending(pending.TransactionNumber); err != nil {
+				logger.Errorf("[%s] Failed to delete pending anchor[%s]: %s", pending.Namespace, pending.AnchorString, err)
+			}
+
+			continue
+		}
+
+		var subsequent []txn.SubsequentTxn
+		for _, s := range pending.Subsequent {
+			if !isAfter(s.TransactionTime, s.TransactionNumber, point) {
+				subsequent = append(subsequent, s)
+			}
+		}
+
+		if len(subsequent) == len(pending.Subsequent) {
 			continue
 		}
-		logger.Debugf("Successfully processed anchor[%s]", txn.AnchorString)
+
+		pending.Subsequent = subsequent
+
+		if err := o.putPending(pending); err != nil {
+			logger.Errorf("[%s] Failed to update pending anchor[%s]: %s", pending.Namespace, pending.AnchorString, err)
+		}
+	}
+}
+
+// pendingTxns returns the transactions that are pending retry by transaction number
+func (o *Observer) pendingTxns() (map[uint64]*txn.PendingTxn, error) {
+	if o.pending != nil {
+		return o.pending, nil
+	}
+
+	pendingTxns, err := o.retryStore.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	o.pending = make(map[uint64]*txn.PendingTxn, len(pendingTxns))
+	for _, pending := range pendingTxns {
+		o.pending[pending.TransactionNumber] = pending
+	}
+
+	return o.pending, nil
+}
+
+// sortedPendingTxns returns the transactions that are pending retry ordered by transaction number
+func (o *Observer) sortedPendingTxns() ([]*txn.PendingTxn, error) {
+	pending, err := o.pendingTxns()
+	if err != nil {
+		return nil, err
+	}
+
+	pendingTxns := make([]*txn.PendingTxn, 0, len(pending))
+	for _, p := range pending {
+		pendingTxns = append(pendingTxns, p)
+	}
+
+	sort.Slice(pendingTxns, func(i, j int) bool {
+		return pendingTxns[i].TransactionNumber < pendingTxns[j].TransactionNumber
+	})
+
+	return pendingTxns, nil
+}
+
+// putPending stores the given pending transaction and adds it to the pending transactions
+func (o *Observer) putPending(pending *txn.PendingTxn) error {
+	pendingTxns, err := o.pendingTxns()
+	if err != nil {
+		return err
+	}
+
+	if err := o.retryStore.Put(pending); err != nil {
+		return err
+	}
+
+	pendingTxns[pending.TransactionNumber] = pending
+
+	return nil
+}
+
+// deletePending deletes the pending transaction with the given transaction number
+func (o *Observer) deletePending(transactionNumber uint64) error {
+	pendingTxns, err := o.pendingTxns()
+	if err != nil {
+		return err
+	}
+
+	if err := o.retryStore.Delete(transactionNumber); err != nil {
+		return err
+	}
+
+	delete(pendingTxns, transactionNumber)
+
+	return nil
+}
+
+// retryBackoff returns the backoff after the given number of attempts
+func (o *Observer) retryBackoff(attempts uint) time.Duration {
+	backoff := o.retryInitialBackoff
+	for i := uint(1); i < attempts && backoff < o.retryMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > o.retryMaxBackoff {
+		backoff = o.retryMaxBackoff
+	}
+
+	return backoff
+}
+
+// isProcessed returns true if the given transaction is at or before the checkpoint of its namespace
+func (o *Observer) isProcessed(sidetreeTxn txn.SidetreeTxn) bool {
+	return isProcessed(o.checkpoints, sidetreeTxn)
+}
+
+// isProcessed returns true if the given transaction is at or before the checkpoint of its namespace
+func isProcessed(checkpoints map[string]txn.Checkpoint, sidetreeTxn txn.SidetreeTxn) bool {
+	checkpoint, ok := checkpoints[sidetreeTxn.Namespace]
+	if !ok {
+		return false
+	}
+
+	return !isAfter(sidetreeTxn.TransactionTime, sidetreeTxn.TransactionNumber, checkpoint)
+}
+
+// isAfter returns true if the transaction with the given transaction time and number is after the given checkpoint
+func isAfter(transactionTime, transactionNumber uint64, checkpoint txn.Checkpoint) bool {
+	if transactionTime != checkpoint.TransactionTime {
+		return transactionTime > checkpoint.TransactionTime
+	}
+
+	return transactionNumber > checkpoint.TransactionNumber
+}
+
+// checkpoint records the given transaction as the last processed transaction of its namespace.
+// Errors are only logged since the transaction was processed.
+func (o *Observer) checkpoint(sidetreeTxn txn.SidetreeTxn) {
+	if o.checkpoints == nil {
+		return
+	}
+
+	o.putCheckpoint(sidetreeTxn.Namespace, txn.Checkpoint{
+		TransactionTime:   sidetreeTxn.TransactionTime,
+		TransactionNumber: sidetreeTxn.TransactionNumber,
+	})
+}
+
+func (o *Observer) putCheckpoint(namespace string, checkpoint txn.Checkpoint) {
+	o.checkpoints[namespace] = checkpoint
+
+	if o.CheckpointStore == nil {
+		return
+	}
+
+	if err := o.CheckpointStore.Put(namespace, checkpoint); err != nil {
+		logger.Warnf("[%s] Failed to store checkpoint at transaction number [%d]: %s", namespace, checkpoint.TransactionNumber, err)
 	}
 }
 
 // TxnProcessor processes Sidetree transactions by persisting them to an operation store
 type TxnProcessor struct {
 	*Providers
+
+	publisher *publisher
 }
 
 // NewTxnProcessor returns a new document operation processor
@@ -135,17 +986,41 @@ func NewTxnProcessor(providers *Providers) *TxnProcessor {
 
 // Process persists all of the operations for the given anchor
 func (p *TxnProcessor) Process(sidetreeTxn txn.SidetreeTxn) error {
+	_, _, err := p.process(sidetreeTxn, nil)
+
+	return err
+}
+
+// process persists the operations for the given anchor and returns the operations of the transaction along with
+// the suffixes of the rejected operations. If suffixes is not nil then only the operations for the given suffixes are persisted.
+func (p *TxnProcessor) process(sidetreeTxn txn.SidetreeTxn, suffixes map[string]bool) ([]*batch.Operation, []string, error) {
 	logger.Debugf("processing sidetree txn:%+v", sidetreeTxn)
 
+	txnOps, err := p.fetch(sidetreeTxn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rejected, err := p.processTxnOperations(txnOps, sidetreeTxn, suffixes)
+
+	return txnOps, rejected, err
+}
+
+// fetch retrieves the operations of the given transaction from its batch files. It doesn't modify
+// any state so it may be called concurrently.
+func (p *TxnProcessor) fetch(sidetreeTxn txn.SidetreeTxn) ([]*batch.Operation, error) {
 	txnOps, err := p.TxnOpsProvider.GetTxnOperations(&sidetreeTxn)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve operations for anchor string[%s]: %s", sidetreeTxn.AnchorString, err)
+		return nil, errors.Wrapf(err, "failed to retrieve operations for anchor string[%s]", sidetreeTxn.AnchorString)
 	}
 
-	return p.processTxnOperations(txnOps, sidetreeTxn)
+	return txnOps, nil
 }
 
-func (p *TxnProcessor) processTxnOperations(txnOps []*batch.Operation, sidetreeTxn txn.SidetreeTxn) error {
+// processTxnOperations filters and stores the given operations of the transaction and returns the suffixes (in order)
+// whose operations were rejected by the operation filter. If suffixes is not nil then only the operations for the given
+// suffixes are processed.
+func (p *TxnProcessor) processTxnOperations(txnOps []*batch.Operation, sidetreeTxn txn.SidetreeTxn, suffixes map[string]bool) ([]string, error) {
 	logger.Debugf("processing %d transaction operations", len(txnOps))
 
 	batchSuffixes := make(map[string]bool)
@@ -158,41 +1033,229 @@ func (p *TxnProcessor) processTxnOperations(txnOps []*batch.Operation, sidetreeT
 			continue
 		}
 
+		batchSuffixes[op.UniqueSuffix] = true
+
+		if suffixes != nil && !suffixes[op.UniqueSuffix] {
+			continue
+		}
+
 		updatedOp := updateOperation(op, uint(index), sidetreeTxn)
 
 		logger.Debugf("updated operation with blockchain time: %s", updatedOp.ID)
 		ops = append(ops, updatedOp)
 
-		batchSuffixes[op.UniqueSuffix] = true
+		if suffixes == nil {
+			p.updateStatus(updatedOp, batch.OperationStateAnchored, sidetreeTxn)
+		}
 	}
 
+	// all of the operations are filtered before any of them are stored so that
+	// the transaction isn't partially stored if filtering fails
+	validOpsByNamespace := make(map[string][]*batch.Operation)
+	rejectedSuffixes := make(map[string]bool)
+
 	for suffix, mapping := range mapOperationsByUniqueSuffix(ops) {
 		logger.Debugf("Filtering operations for namespace [%s] and suffix [%s]", mapping.namespace, suffix)
 
 		opFilter, err := p.OpFilterProvider.Get(mapping.namespace)
 		if err != nil {
-			return errors.Wrapf(err, "error getting operation filter for namespace [%s]", mapping.namespace)
+			return nil, errors.Wrapf(err, "error getting operation filter for namespace [%s]", mapping.namespace)
 		}
 
 		validOps, err := opFilter.Filter(suffix, mapping.operations)
 		if err != nil {
-			return errors.Wrap(err, "error filtering invalid operations")
+			// the operations of the suffix can't be validated (e.g. since there's no create operation for the suffix)
+			// so they're rejected without affecting the operations of the other suffixes of the transaction
+			logger.Warnf("[%s] Rejecting %d operations for suffix[%s] of anchor[%s]: %s", mapping.namespace, len(mapping.operations), suffix, sidetreeTxn.AnchorString, err)
+
+			for _, op := range mapping.operations {
+				p.updateRejectedStatus(op, err.Error(), sidetreeTxn)
+			}
+
+			rejectedSuffixes[suffix] = true
+
+			continue
+		}
+
+		if len(validOps) < len(mapping.operations) {
+			rejectedSuffixes[suffix] = true
+		}
+
+		validOpsByNamespace[mapping.namespace] = append(validOpsByNamespace[mapping.namespace], validOps...)
+	}
+
+	accepted := make(map[*batch.Operation]bool)
+
+	for namespace, validOps := range validOpsByNamespace {
+		err := p.storeOperations(namespace, validOps, sidetreeTxn)
+
+		// the cache is also invalidated on failure since a non-transactional store may have stored some of the operations
+		p.invalidate(namespace, validOps)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.updateState(namespace, validOps)
+		p.notifyPersisted(namespace, validOps)
+
+		for _, op := range validOps {
+			p.updateStatus(op, batch.OperationStateAccepted, sidetreeTxn)
+			accepted[op] = true
+		}
+	}
+
+	p.publish(ops, accepted, suffixes != nil)
+
+	var rejected []string
+	for _, op := range ops {
+		if rejectedSuffixes[op.UniqueSuffix] {
+			rejected = append(rejected, op.UniqueSuffix)
+		}
+	}
+
+	return rejected, nil
+}
+
+// updateState updates the materialized state of the documents of the given (stored) operations
+func (p *TxnProcessor) updateState(namespace string, ops []*batch.Operation) {
+	if p.StateUpdaterProvider == nil || len(ops) == 0 {
+		return
+	}
+
+	updater, err := p.StateUpdaterProvider.ForNamespace(namespace)
+	if err != nil {
+		logger.Warnf("[%s] Failed to get document state updater: %s", namespace, err)
+		return
+	}
+
+	for _, op := range ops {
+		if err := updater.UpdateState(op.UniqueSuffix); err != nil {
+			logger.Warnf("[%s] Failed to update state of suffix[%s]: %s", namespace, op.UniqueSuffix, err)
+		}
+	}
+}
+
+// notifyPersisted notifies the operation listener of the given namespace that the given operations were stored
+func (p *TxnProcessor) notifyPersisted(namespace string, ops []*batch.Operation) {
+	if p.OpListenerProvider == nil || len(ops) == 0 {
+		return
+	}
+
+	listener, err := p.OpListenerProvider.ForNamespace(namespace)
+	if err != nil {
+		logger.Warnf("[%s] Failed to get operation listener: %s", namespace, err)
+		return
+	}
+
+	listener.Persisted(ops)
+}
+
+// invalidate invalidates the cached resolution results of the documents of the given operations
+func (p *TxnProcessor) invalidate(namespace string, ops []*batch.Operation) {
+	if p.ResolutionCache == nil {
+		return
+	}
+
+	for _, op := range ops {
+		p.ResolutionCache.Invalidate(namespace, op.UniqueSuffix)
+	}
+}
+
+// publish publishes the events of the given operations. When a transaction is reprocessed only the events
+// of the operations that were accepted are published, since the other operations were already published.
+func (p *TxnProcessor) publish(ops []*batch.Operation, accepted map[*batch.Operation]bool, reprocessed bool) {
+	var events []OperationEvent
+
+	for _, op := range ops {
+		if reprocessed && !accepted[op] {
+			continue
 		}
 
-		opStore, err := p.OpStoreProvider.ForNamespace(mapping.namespace)
+		events = append(events, OperationEvent{
+			Namespace:         op.Namespace,
+			UniqueSuffix:      op.UniqueSuffix,
+			Type:              op.Type,
+			TransactionTime:   op.TransactionTime,
+			TransactionNumber: op.TransactionNumber,
+			Accepted:          accepted[op],
+		})
+	}
+
+	p.publisher.publish(events)
+}
+
+// storeOperations stores the operations of the given transaction for the given namespace. The operations are stored
+// atomically (under their idempotency keys) if the operation store is a TransactionalOperationStore,
+// otherwise they are stored with a single Put.
+func (p *TxnProcessor) storeOperations(namespace string, ops []*batch.Operation, sidetreeTxn txn.SidetreeTxn) error {
+	opStore, err := p.OpStoreProvider.ForNamespace(namespace)
+	if err != nil {
+		return errors.Wrapf(err, "error getting operation store for namespace [%s]", namespace)
+	}
+
+	txnStore, ok := opStore.(TransactionalOperationStore)
+	if !ok {
+		err = opStore.Put(ops)
 		if err != nil {
-			return errors.Wrapf(err, "error getting operation store for namespace [%s]", mapping.namespace)
+			return errors.Wrapf(err, "failed to store operation from anchor string[%s]", sidetreeTxn.AnchorString)
 		}
 
-		err = opStore.Put(validOps)
+		return nil
+	}
+
+	storeTxn, err := txnStore.Begin()
+	if err != nil {
+		return errors.Wrapf(err, "failed to begin store transaction for anchor string[%s]", sidetreeTxn.AnchorString)
+	}
+
+	for _, op := range ops {
+		err = storeTxn.Put(IdempotencyKey(op), op)
 		if err != nil {
+			if e := storeTxn.Rollback(); e != nil {
+				logger.Warnf("[%s] Failed to roll back store transaction for anchor string[%s]: %s", namespace, sidetreeTxn.AnchorString, e)
+			}
+
 			return errors.Wrapf(err, "failed to store operation from anchor string[%s]", sidetreeTxn.AnchorString)
 		}
 	}
 
+	err = storeTxn.Commit()
+	if err != nil {
+		return errors.Wrapf(err, "failed to commit operations from anchor string[%s]", sidetreeTxn.AnchorString)
+	}
+
 	return nil
 }
 
+// updateStatus records the state transition of the operation. Status tracking doesn't affect
+// transaction processing so errors are only logged.
+func (p *TxnProcessor) updateStatus(op *batch.Operation, state batch.OperationState, sidetreeTxn txn.SidetreeTxn) {
+	p.putStatus(op, state, "", sidetreeTxn)
+}
+
+// updateRejectedStatus records that the operation was rejected for the given reason
+func (p *TxnProcessor) updateRejectedStatus(op *batch.Operation, reason string, sidetreeTxn txn.SidetreeTxn) {
+	p.putStatus(op, batch.OperationStateRejected, reason, sidetreeTxn)
+}
+
+func (p *TxnProcessor) putStatus(op *batch.Operation, state batch.OperationState, reason string, sidetreeTxn txn.SidetreeTxn) {
+	if p.OpStatusStore == nil {
+		return
+	}
+
+	status := opstatus.NewStatus(op, state)
+	status.AnchorString = sidetreeTxn.AnchorString
+	status.TransactionTime = sidetreeTxn.TransactionTime
+	status.TransactionNumber = sidetreeTxn.TransactionNumber
+	status.OperationIndex = op.OperationIndex
+	status.Reason = reason
+
+	if err := p.OpStatusStore.Put(status); err != nil {
+		logger.Warnf("[%s] failed to record status of operation for suffix[%s]: %s", sidetreeTxn.Namespace, op.UniqueSuffix, err)
+	}
+}
+
 func updateOperation(op *batch.Operation, index uint, sidetreeTxn txn.SidetreeTxn) *batch.Operation {
 	//  The logical blockchain time that this operation was anchored on the blockchain
 	op.TransactionTime = sidetreeTxn.TransactionTime
@@ -200,6 +1263,8 @@ func updateOperation(op *batch.Operation, index uint, sidetreeTxn txn.SidetreeTx
 	op.TransactionNumber = sidetreeTxn.TransactionNumber
 	// The index this operation was assigned to in the batch
 	op.OperationIndex = index
+	// The anchor string of the transaction this operation was batched within
+	op.AnchorString = sidetreeTxn.AnchorString
 
 	return op
 }
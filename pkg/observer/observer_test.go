@@ -19,6 +19,9 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
 	"github.com/trustbloc/sidetree-core-go/pkg/compression"
 	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/observer/checkpoint"
+	"github.com/trustbloc/sidetree-core-go/pkg/observer/retry"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 	"github.com/trustbloc/sidetree-core-go/pkg/txnhandler"
 )
 
@@ -55,6 +58,36 @@ func TestStartObserver(t *testing.T) {
 		rw.RUnlock()
 	})
 
+	t.Run("test invalid transaction", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		isCalled := false
+		var rw sync.RWMutex
+		readFunc := func(key string) ([]byte, error) {
+			rw.Lock()
+			isCalled = true
+			rw.Unlock()
+			return []byte("invalid"), nil
+		}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   txnhandler.NewOperationProvider(&mockDCAS{readFunc: readFunc}, mocks.NewMockProtocolClientProvider(), compression.New(compression.WithDefaultAlgorithms())),
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		o := New(providers)
+		require.NotNil(t, o)
+
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{{Namespace: mocks.DefaultNS, TransactionTime: 20, TransactionNumber: 2, AnchorString: "1.address"}}
+		time.Sleep(200 * time.Millisecond)
+		rw.RLock()
+		require.True(t, isCalled)
+		rw.RUnlock()
+	})
+
 	t.Run("test channel close", func(t *testing.T) {
 		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
 
@@ -106,164 +139,1577 @@ func TestStartObserver(t *testing.T) {
 	})
 }
 
-func TestTxnProcessor_Process(t *testing.T) {
-	t.Run("test error from txn operations provider", func(t *testing.T) {
+func TestCatchUp(t *testing.T) {
+	newLedgerReader := func(anchors ...string) *mocks.MockBlockchainClient {
+		bc := mocks.NewMockBlockchainClient(nil)
+		for _, anchor := range anchors {
+			require.NoError(t, bc.WriteAnchor(anchor))
+		}
+
+		return bc
+	}
+
+	t.Run("no checkpoints", func(t *testing.T) {
+		opsProvider := &mockTxnOpsProvider{}
+		checkpoints := checkpoint.NewMemStore()
+
 		providers := &Providers{
-			TxnOpsProvider:   &mockTxnOpsProvider{err: errors.New("txn operations provider error")},
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
 			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  checkpoints,
+			LedgerReader:     newLedgerReader("1.anchor0", "1.anchor1", "1.anchor2"),
 		}
 
-		p := NewTxnProcessor(providers)
-		err := p.Process(txn.SidetreeTxn{})
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "failed to retrieve operations for anchor string")
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		time.Sleep(200 * time.Millisecond)
+
+		require.Equal(t, []string{"1.anchor0", "1.anchor1", "1.anchor2"}, opsProvider.processedAnchors())
+
+		cp, err := checkpoints.Get(mocks.DefaultNS)
+		require.NoError(t, err)
+		require.Equal(t, txn.Checkpoint{TransactionTime: 2, TransactionNumber: 2}, cp)
 	})
-}
 
-func TestProcessTxnOperations(t *testing.T) {
-	t.Run("test error from operationStoreProvider ForNamespace", func(t *testing.T) {
-		errExpected := errors.New("injected store provider error")
+	t.Run("since checkpoint", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		opsProvider := &mockTxnOpsProvider{}
+
+		checkpoints := checkpoint.NewMemStore()
+		require.NoError(t, checkpoints.Put(mocks.DefaultNS, txn.Checkpoint{TransactionTime: 1, TransactionNumber: 1}))
 
 		providers := &Providers{
-			OpStoreProvider:  &mockOperationStoreProvider{err: errExpected},
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
 			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  checkpoints,
+			LedgerReader:     newLedgerReader("1.anchor0", "1.anchor1", "1.anchor2"),
 		}
 
-		p := NewTxnProcessor(providers)
-		err := p.processTxnOperations([]*batch.Operation{{ID: "doc:method:abc"}}, txn.SidetreeTxn{AnchorString: anchorString})
-		require.Error(t, err)
-		require.Contains(t, err.Error(), errExpected.Error())
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		// the live notifications include a transaction that was already read from the ledger
+		sidetreeTxnCh <- []txn.SidetreeTxn{
+			{Namespace: mocks.DefaultNS, TransactionTime: 2, TransactionNumber: 2, AnchorString: "1.anchor2"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 3, TransactionNumber: 3, AnchorString: "1.anchor3"},
+		}
+
+		time.Sleep(200 * time.Millisecond)
+
+		require.Equal(t, []string{"1.anchor2", "1.anchor3"}, opsProvider.processedAnchors())
+
+		cp, err := checkpoints.Get(mocks.DefaultNS)
+		require.NoError(t, err)
+		require.Equal(t, txn.Checkpoint{TransactionTime: 3, TransactionNumber: 3}, cp)
 	})
 
-	t.Run("test error from operationStore Put", func(t *testing.T) {
-		opStore := &mockOperationStore{putFunc: func(ops []*batch.Operation) error {
-			return fmt.Errorf("put error")
-		}}
+	t.Run("failed transaction is not checkpointed", func(t *testing.T) {
+		checkpoints := checkpoint.NewMemStore()
 
 		providers := &Providers{
-			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   &mockTxnOpsProvider{err: errors.New("CAS error")},
 			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  checkpoints,
+			LedgerReader:     newLedgerReader("1.anchor0"),
 		}
 
-		p := NewTxnProcessor(providers)
-		err := p.processTxnOperations([]*batch.Operation{{ID: "doc:method:abc"}}, txn.SidetreeTxn{AnchorString: anchorString})
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		time.Sleep(200 * time.Millisecond)
+
+		_, err := checkpoints.Get(mocks.DefaultNS)
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "failed to store operation from anchor string")
+		require.Contains(t, err.Error(), "checkpoint not found")
 	})
 
-	t.Run("test success", func(t *testing.T) {
+	t.Run("checkpoint store errors", func(t *testing.T) {
+		opsProvider := &mockTxnOpsProvider{}
+
 		providers := &Providers{
-			TxnOpsProvider:   &mockTxnOpsProvider{},
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   opsProvider,
 			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
 			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  &mockCheckpointStore{getErr: errors.New("get error"), putErr: errors.New("put error")},
+			LedgerReader:     newLedgerReader("1.anchor0", "1.anchor1"),
 		}
 
-		p := NewTxnProcessor(providers)
-		batchOps, err := p.TxnOpsProvider.GetTxnOperations(&txn.SidetreeTxn{AnchorString: anchorString})
-		require.NoError(t, err)
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
 
-		err = p.processTxnOperations(batchOps, txn.SidetreeTxn{AnchorString: anchorString})
-		require.NoError(t, err)
+		time.Sleep(200 * time.Millisecond)
+
+		// checkpoint store errors don't affect processing
+		require.Equal(t, []string{"1.anchor0", "1.anchor1"}, opsProvider.processedAnchors())
 	})
 
-	t.Run("success - multiple operations with same suffix in transaction operations", func(t *testing.T) {
-		mockOpsStore := &mockOperationStore{}
+	t.Run("stopped while catching up", func(t *testing.T) {
+		opsProvider := &mockTxnOpsProvider{}
+
 		providers := &Providers{
-			TxnOpsProvider:   &mockTxnOpsProvider{},
-			OpStoreProvider:  &mockOperationStoreProvider{opStore: mockOpsStore},
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   opsProvider,
 			OpFilterProvider: &NoopOperationFilterProvider{},
+			LedgerReader:     newLedgerReader("1.anchor0"),
 		}
 
-		p := NewTxnProcessor(providers)
-		batchOps, err := p.TxnOpsProvider.GetTxnOperations(&txn.SidetreeTxn{AnchorString: anchorString})
+		o := New(providers)
+		o.Stop()
+		o.Start()
+
+		time.Sleep(200 * time.Millisecond)
+
+		require.Empty(t, opsProvider.processedAnchors())
+	})
+}
+
+func TestFork(t *testing.T) {
+	t.Run("rollback and read canonical transactions from the ledger", func(t *testing.T) {
+		bc := mocks.NewMockBlockchainClient(nil)
+		for _, anchor := range []string{"1.anchor0", "1.anchor1", "1.anchor2"} {
+			require.NoError(t, bc.WriteAnchor(anchor))
+		}
+
+		opsProvider := &mockTxnOpsProvider{}
+		opStore := &memOperationStore{}
+		checkpoints := checkpoint.NewMemStore()
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  checkpoints,
+			LedgerReader:     bc,
+			ForkNotifier:     bc,
+		}
+
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		time.Sleep(200 * time.Millisecond)
+		require.Equal(t, []uint64{0, 1, 2}, opStore.transactionTimes())
+
+		// the last two transactions are replaced by three new transactions
+		_, err := bc.Reorg(0, "1.anchor1b", "1.anchor2b", "1.anchor3b")
 		require.NoError(t, err)
 
-		// add same operations again to create scenario where batch has multiple operations with same suffix
-		// only first operation will be processed, subsequent operations will be discarded
-		batchOps = append(batchOps, batchOps...)
+		time.Sleep(200 * time.Millisecond)
+
+		require.Equal(t, []uint64{0, 1, 2, 3}, opStore.transactionTimes())
+		require.Equal(t, []string{"1.anchor0", "1.anchor1", "1.anchor2", "1.anchor1b", "1.anchor2b", "1.anchor3b"}, opsProvider.processedAnchors())
 
-		err = p.processTxnOperations(batchOps, txn.SidetreeTxn{AnchorString: anchorString})
+		cp, err := checkpoints.Get(mocks.DefaultNS)
 		require.NoError(t, err)
+		require.Equal(t, txn.Checkpoint{TransactionTime: 3, TransactionNumber: 3}, cp)
 	})
-}
 
-func TestUpdateOperation(t *testing.T) {
-	t.Run("test success", func(t *testing.T) {
-		updatedOps := updateOperation(&batch.Operation{ID: "did:sidetree:abc"},
-			1, txn.SidetreeTxn{TransactionTime: 20, TransactionNumber: 2})
-		require.Equal(t, uint64(20), updatedOps.TransactionTime)
-		require.Equal(t, uint64(2), updatedOps.TransactionNumber)
-		require.Equal(t, uint(1), updatedOps.OperationIndex)
+	t.Run("rollback and receive canonical transactions as live notifications", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		forkCh := make(chan txn.Fork, 1)
+
+		opsProvider := &mockTxnOpsProvider{}
+		opStore := &memOperationStore{}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  checkpoint.NewMemStore(),
+			ForkNotifier:     &mockForkNotifier{forkCh: forkCh},
+		}
+
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{
+			{Namespace: mocks.DefaultNS, TransactionTime: 10, TransactionNumber: 1, AnchorString: "1.anchor1"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 11, TransactionNumber: 2, AnchorString: "1.anchor2"},
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, []uint64{10, 11}, opStore.transactionTimes())
+
+		forkCh <- txn.Fork{RollbackPoint: txn.Checkpoint{TransactionTime: 10, TransactionNumber: 1}}
+
+		// the new canonical transaction is processed since the checkpoint was rolled back
+		sidetreeTxnCh <- []txn.SidetreeTxn{
+			{Namespace: mocks.DefaultNS, TransactionTime: 11, TransactionNumber: 2, AnchorString: "1.anchor2b"},
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, []uint64{10, 11}, opStore.transactionTimes())
+		require.Equal(t, []string{"1.anchor1", "1.anchor2", "1.anchor2b"}, opsProvider.processedAnchors())
+
+		close(forkCh)
+		time.Sleep(100 * time.Millisecond)
 	})
-}
 
-type mockLedger struct {
-	registerForSidetreeTxnValue chan []txn.SidetreeTxn
-}
+	t.Run("operation store errors", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		forkCh := make(chan txn.Fork, 1)
 
-func (m mockLedger) RegisterForSidetreeTxn() <-chan []txn.SidetreeTxn {
-	return m.registerForSidetreeTxnValue
-}
+		var rw sync.RWMutex
+		deleted := false
 
-type mockDCAS struct {
-	readFunc func(key string) ([]byte, error)
-}
+		opStore := &mockOperationStore{deleteFunc: func(uint64, uint64) error {
+			rw.Lock()
+			deleted = true
+			rw.Unlock()
+			return errors.New("delete error")
+		}}
 
-func (m mockDCAS) Read(key string) ([]byte, error) {
-	if m.readFunc != nil {
-		return m.readFunc(key)
-	}
-	return nil, nil
-}
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   &mockTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			ForkNotifier:     &mockForkNotifier{forkCh: forkCh},
+		}
 
-func (m mockDCAS) Write(content []byte) (string, error) {
-	return "", errors.New("not implemented")
-}
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
 
-type mockOperationStore struct {
-	putFunc func(ops []*batch.Operation) error
-	getFunc func(suffix string) ([]*batch.Operation, error)
-}
+		sidetreeTxnCh <- []txn.SidetreeTxn{{Namespace: mocks.DefaultNS, TransactionTime: 10, TransactionNumber: 1, AnchorString: "1.anchor1"}}
+		time.Sleep(100 * time.Millisecond)
 
-func (m *mockOperationStore) Put(ops []*batch.Operation) error {
-	if m.putFunc != nil {
-		return m.putFunc(ops)
-	}
-	return nil
-}
+		forkCh <- txn.Fork{}
+		time.Sleep(100 * time.Millisecond)
 
-func (m *mockOperationStore) Get(suffix string) ([]*batch.Operation, error) {
-	if m.getFunc != nil {
-		return m.getFunc(suffix)
-	}
-	return nil, nil
-}
+		rw.RLock()
+		require.True(t, deleted)
+		rw.RUnlock()
 
-type mockOperationStoreProvider struct {
-	opStore OperationStore
-	err     error
-}
+		o.OpStoreProvider = &mockOperationStoreProvider{err: errors.New("provider error")}
+		forkCh <- txn.Fork{}
+		time.Sleep(100 * time.Millisecond)
+	})
 
-func (m *mockOperationStoreProvider) ForNamespace(string) (OperationStore, error) {
-	if m.err != nil {
-		return nil, m.err
-	}
+	t.Run("rollback within a transaction time", func(t *testing.T) {
+		opStore := &memOperationStore{}
+		require.NoError(t, opStore.Put([]*batch.Operation{
+			{UniqueSuffix: "abc", TransactionTime: 5, TransactionNumber: 1},
+			{UniqueSuffix: "def", TransactionTime: 5, TransactionNumber: 2},
+			{UniqueSuffix: "ghi", TransactionTime: 5, TransactionNumber: 3},
+			{UniqueSuffix: "jkl", TransactionTime: 6, TransactionNumber: 4},
+		}))
 
-	return m.opStore, nil
-}
+		checkpoints := checkpoint.NewMemStore()
 
-type mockTxnOpsProvider struct {
-	err error
+		o := New(&Providers{
+			OpStoreProvider: &mockOperationStoreProvider{opStore: opStore},
+			CheckpointStore: checkpoints,
+		})
+		o.namespaces[mocks.DefaultNS] = true
+		o.checkpoints[mocks.DefaultNS] = txn.Checkpoint{TransactionTime: 5, TransactionNumber: 3}
+
+		require.NoError(t, o.retryStore.Put(&txn.PendingTxn{SidetreeTxn: txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 5, TransactionNumber: 2}}))
+		require.NoError(t, o.retryStore.Put(&txn.PendingTxn{SidetreeTxn: txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 5, TransactionNumber: 3}}))
+
+		require.True(t, o.rollback(txn.Fork{RollbackPoint: txn.Checkpoint{TransactionTime: 5, TransactionNumber: 2}}))
+
+		// only the transactions after the rollback point are rolled back
+		require.Equal(t, []uint64{5, 5}, opStore.transactionTimes())
+		require.Equal(t, "def", opStore.ops[1].UniqueSuffix)
+
+		cp, err := checkpoints.Get(mocks.DefaultNS)
+		require.NoError(t, err)
+		require.Equal(t, txn.Checkpoint{TransactionTime: 5, TransactionNumber: 2}, cp)
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 1)
+		require.Equal(t, uint64(2), unresolved[0].TransactionNumber)
+	})
+
+	t.Run("invalid reorg", func(t *testing.T) {
+		bc := mocks.NewMockBlockchainClient(nil)
+		require.NoError(t, bc.WriteAnchor("1.anchor0"))
+
+		for _, since := range []int{-1, 1} {
+			_, err := bc.Reorg(since, "1.anchor1")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid transaction number")
+		}
+
+		require.Equal(t, []string{"1.anchor0"}, bc.GetAnchors())
+	})
 }
 
-func (m *mockTxnOpsProvider) GetTxnOperations(txn *txn.SidetreeTxn) ([]*batch.Operation, error) {
-	if m.err != nil {
-		return nil, m.err
-	}
+func TestRetry(t *testing.T) {
+	t.Run("retry until batch files are available", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		opsProvider := &flakyTxnOpsProvider{
+			failures: map[string]int{"1.anchor0": 2},
+			suffixes: map[string]string{"1.anchor3": "def", "1.anchor4": "ghi"},
+		}
+		opStore := &memOperationStore{}
 
-	op := &batch.Operation{
-		ID: "did:sidetree:abc",
-	}
+		providers := &Providers{
+			Ledger:          mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:  opsProvider,
+			OpStoreProvider: &mockOperationStoreProvider{opStore: opStore},
+			// the operations of abc and def are rejected until the operations of their create anchor are stored
+			OpFilterProvider: &dependentFilterProvider{
+				opStore:       opStore,
+				createAnchors: map[string]string{"abc": "1.anchor0", "def": "1.anchor9"},
+			},
+		}
 
-	return []*batch.Operation{op}, nil
+		o := New(providers, WithRetryBackoff(20*time.Millisecond, 40*time.Millisecond))
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{
+			{Namespace: mocks.DefaultNS, TransactionTime: 0, TransactionNumber: 0, AnchorString: "1.anchor0"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 1, TransactionNumber: 1, AnchorString: "1.anchor1"},
+			{Namespace: "did:other", TransactionTime: 2, TransactionNumber: 2, AnchorString: "1.anchor2"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 3, TransactionNumber: 3, AnchorString: "1.anchor3"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 4, TransactionNumber: 4, AnchorString: "1.anchor4"},
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 1)
+		require.Equal(t, "1.anchor0", unresolved[0].AnchorString)
+		require.Equal(t, uint(1), unresolved[0].Attempts)
+		require.Contains(t, unresolved[0].LastError, "CAS error")
+
+		// only the transactions of the same namespace that rejected operations are recorded
+		require.Equal(t, []txn.SubsequentTxn{
+			{
+				SidetreeTxn: txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 1, TransactionNumber: 1, AnchorString: "1.anchor1"},
+				Suffixes:    []string{"abc"},
+			},
+			{
+				SidetreeTxn: txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 3, TransactionNumber: 3, AnchorString: "1.anchor3"},
+				Suffixes:    []string{"def"},
+			},
+		}, unresolved[0].Subsequent)
+
+		time.Sleep(300 * time.Millisecond)
+
+		unresolved, err = o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Empty(t, unresolved)
+
+		// only the subsequent transaction that rejected a suffix of the retried transaction is reprocessed
+		require.Equal(t, []string{"1.anchor1", "1.anchor2", "1.anchor3", "1.anchor4", "1.anchor0", "1.anchor1"}, opsProvider.processedAnchors())
+		require.Equal(t, []uint64{4, 0, 1}, opStore.transactionTimes())
+	})
+
+	t.Run("invalid transaction is not retried", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+
+		var rw sync.RWMutex
+		reads := 0
+		readFunc := func(key string) ([]byte, error) {
+			rw.Lock()
+			defer rw.Unlock()
+
+			reads++
+			if reads == 1 {
+				return nil, errors.New("CAS error")
+			}
+
+			return []byte("invalid"), nil
+		}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   txnhandler.NewOperationProvider(&mockDCAS{readFunc: readFunc}, mocks.NewMockProtocolClientProvider(), compression.New(compression.WithDefaultAlgorithms())),
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		errChan := make(chan error, 10)
+
+		o := New(providers, WithRetryBackoff(20*time.Millisecond, 40*time.Millisecond), WithErrorChannel(errChan))
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{{Namespace: mocks.DefaultNS, TransactionTime: 0, TransactionNumber: 0, AnchorString: "1.anchor0"}}
+
+		time.Sleep(200 * time.Millisecond)
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Empty(t, unresolved)
+
+		rw.RLock()
+		require.Equal(t, 2, reads)
+		rw.RUnlock()
+
+		refused := receiveRefused(t, errChan)
+		require.Equal(t, "1.anchor0", refused.SidetreeTxn.AnchorString)
+		require.Equal(t, uint(2), refused.Attempts)
+	})
+
+	t.Run("transaction is refused after the maximum number of attempts", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		opsProvider := &flakyTxnOpsProvider{failures: map[string]int{"1.anchor0": 100}}
+		opStore := &memOperationStore{}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		errChan := make(chan error, 10)
+
+		o := New(providers, WithRetryBackoff(10*time.Millisecond, 10*time.Millisecond),
+			WithRetryMaxAttempts(3), WithErrorChannel(errChan))
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{
+			{Namespace: mocks.DefaultNS, TransactionTime: 0, TransactionNumber: 0, AnchorString: "1.anchor0"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 1, TransactionNumber: 1, AnchorString: "1.anchor1"},
+		}
+
+		refused := receiveRefused(t, errChan)
+		require.Equal(t, "1.anchor0", refused.SidetreeTxn.AnchorString)
+		require.Equal(t, uint(3), refused.Attempts)
+		require.Contains(t, refused.Reason, "CAS error")
+		require.Contains(t, refused.Error(), "anchor[1.anchor0] was refused after 3 attempts")
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Empty(t, unresolved)
+
+		time.Sleep(100 * time.Millisecond)
+
+		// the transaction isn't retried once it was refused
+		require.Equal(t, 3, opsProvider.attempts("1.anchor0"))
+		require.Equal(t, []uint64{1}, opStore.transactionTimes())
+	})
+
+	t.Run("pending transaction is skipped", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		opsProvider := &flakyTxnOpsProvider{failures: map[string]int{"1.anchor0": 100}}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxn := txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 0, TransactionNumber: 0, AnchorString: "1.anchor0"}
+		sidetreeTxnCh <- []txn.SidetreeTxn{sidetreeTxn}
+		sidetreeTxnCh <- []txn.SidetreeTxn{sidetreeTxn}
+
+		time.Sleep(100 * time.Millisecond)
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 1)
+		require.Equal(t, uint(1), unresolved[0].Attempts)
+		require.Equal(t, 1, opsProvider.attempts("1.anchor0"))
+	})
+
+	t.Run("rollback removes pending transactions", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		forkCh := make(chan txn.Fork, 1)
+		opsProvider := &flakyTxnOpsProvider{failures: map[string]int{"1.anchor1": 100, "1.anchor2": 100}}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   opsProvider,
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &memOperationStore{}},
+			OpFilterProvider: &mockOperationFilterProvider{rejectSuffix: "abc"},
+			ForkNotifier:     &mockForkNotifier{forkCh: forkCh},
+		}
+
+		o := New(providers)
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{
+			{Namespace: mocks.DefaultNS, TransactionTime: 10, TransactionNumber: 1, AnchorString: "1.anchor1"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 11, TransactionNumber: 2, AnchorString: "1.anchor2"},
+			{Namespace: mocks.DefaultNS, TransactionTime: 12, TransactionNumber: 3, AnchorString: "1.anchor3"},
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 2)
+		require.Len(t, unresolved[0].Subsequent, 1)
+
+		forkCh <- txn.Fork{RollbackPoint: txn.Checkpoint{TransactionTime: 10, TransactionNumber: 1}}
+
+		time.Sleep(100 * time.Millisecond)
+
+		unresolved, err = o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 1)
+		require.Equal(t, "1.anchor1", unresolved[0].AnchorString)
+		require.Empty(t, unresolved[0].Subsequent)
+	})
+
+	t.Run("pending transactions are loaded once", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		retryStore := &countingRetryStore{MemStore: retry.NewMemStore()}
+
+		require.NoError(t, retryStore.Put(&txn.PendingTxn{
+			SidetreeTxn: txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 0, TransactionNumber: 0, AnchorString: "1.anchor0"},
+			Attempts:    1,
+			NextRetry:   time.Now().Add(time.Hour),
+		}))
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   &flakyTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &memOperationStore{}},
+			OpFilterProvider: &mockOperationFilterProvider{rejectSuffix: "abc"},
+			RetryStore:       retryStore,
+		}
+
+		o := New(providers, WithRetryBackoff(10*time.Millisecond, 10*time.Millisecond))
+		o.Start()
+		defer o.Stop()
+
+		for i := uint64(0); i < 5; i++ {
+			sidetreeTxnCh <- []txn.SidetreeTxn{{Namespace: mocks.DefaultNS, TransactionTime: i, TransactionNumber: i, AnchorString: fmt.Sprintf("1.anchor%d", i)}}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		require.Equal(t, 1, retryStore.getAllCalls())
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 1)
+		require.Len(t, unresolved[0].Subsequent, 4)
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		o := New(&Providers{}, WithRetryBackoff(time.Second, 5*time.Second))
+
+		require.Equal(t, time.Second, o.retryBackoff(1))
+		require.Equal(t, 2*time.Second, o.retryBackoff(2))
+		require.Equal(t, 4*time.Second, o.retryBackoff(3))
+		require.Equal(t, 5*time.Second, o.retryBackoff(4))
+		require.Equal(t, 5*time.Second, o.retryBackoff(100))
+	})
+}
+
+func TestFetchConcurrency(t *testing.T) {
+	anchors := []string{"1.anchor0", "1.anchor1", "1.anchor2", "1.anchor3", "1.anchor4", "1.anchor5"}
+
+	t.Run("catch up from the ledger", func(t *testing.T) {
+		bc := mocks.NewMockBlockchainClient(nil)
+		for _, anchor := range anchors {
+			require.NoError(t, bc.WriteAnchor(anchor))
+		}
+
+		opStore := &memOperationStore{}
+		checkpoints := checkpoint.NewMemStore()
+		require.NoError(t, checkpoints.Put(mocks.DefaultNS, txn.Checkpoint{TransactionTime: 0, TransactionNumber: 0}))
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   &slowTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			CheckpointStore:  checkpoints,
+			LedgerReader:     bc,
+		}
+
+		o := New(providers, WithFetchConcurrency(3))
+		o.Start()
+		defer o.Stop()
+
+		time.Sleep(300 * time.Millisecond)
+
+		// the operations are stored in ledger order
+		require.Equal(t, []uint64{1, 2, 3, 4, 5}, opStore.transactionTimes())
+
+		cp, err := checkpoints.Get(mocks.DefaultNS)
+		require.NoError(t, err)
+		require.Equal(t, txn.Checkpoint{TransactionTime: 5, TransactionNumber: 5}, cp)
+	})
+
+	t.Run("live notifications", func(t *testing.T) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+		opStore := &memOperationStore{}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   &slowTxnOpsProvider{err: errors.New("CAS error"), errAnchor: "1.anchor3"},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		o := New(providers, WithFetchConcurrency(3))
+		o.Start()
+		defer o.Stop()
+
+		var txns []txn.SidetreeTxn
+		for i, anchor := range anchors {
+			txns = append(txns, txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: uint64(i), TransactionNumber: uint64(i), AnchorString: anchor})
+		}
+
+		sidetreeTxnCh <- txns
+
+		time.Sleep(300 * time.Millisecond)
+
+		require.Equal(t, []uint64{0, 1, 2, 4, 5}, opStore.transactionTimes())
+
+		unresolved, err := o.UnresolvedTxns()
+		require.NoError(t, err)
+		require.Len(t, unresolved, 1)
+		require.Equal(t, "1.anchor3", unresolved[0].AnchorString)
+	})
+
+	t.Run("stopped while catching up", func(t *testing.T) {
+		bc := mocks.NewMockBlockchainClient(nil)
+		for _, anchor := range anchors {
+			require.NoError(t, bc.WriteAnchor(anchor))
+		}
+
+		opStore := &memOperationStore{}
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: make(chan []txn.SidetreeTxn, 100)},
+			TxnOpsProvider:   &slowTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			LedgerReader:     bc,
+		}
+
+		o := New(providers, WithFetchConcurrency(3))
+		o.Stop()
+		o.Start()
+
+		time.Sleep(300 * time.Millisecond)
+
+		require.Empty(t, opStore.transactionTimes())
+	})
+}
+
+func TestTxnProcessor_Process(t *testing.T) {
+	t.Run("test error from txn operations provider", func(t *testing.T) {
+		providers := &Providers{
+			TxnOpsProvider:   &mockTxnOpsProvider{err: errors.New("txn operations provider error")},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		p := NewTxnProcessor(providers)
+		err := p.Process(txn.SidetreeTxn{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to retrieve operations for anchor string")
+		require.False(t, txnhandler.IsValidationError(err))
+	})
+
+	t.Run("test invalid transaction", func(t *testing.T) {
+		providers := &Providers{
+			TxnOpsProvider:   txnhandler.NewOperationProvider(&mockDCAS{}, mocks.NewMockProtocolClientProvider(), compression.New(compression.WithDefaultAlgorithms())),
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		p := NewTxnProcessor(providers)
+		err := p.Process(txn.SidetreeTxn{Namespace: mocks.DefaultNS, AnchorString: "abc.address"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to retrieve operations for anchor string[abc.address]")
+		require.True(t, txnhandler.IsValidationError(err))
+	})
+}
+
+func TestProcessTxnOperations(t *testing.T) {
+	t.Run("test error from operationStoreProvider ForNamespace", func(t *testing.T) {
+		errExpected := errors.New("injected store provider error")
+
+		providers := &Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{err: errExpected},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		p := NewTxnProcessor(providers)
+		_, err := p.processTxnOperations([]*batch.Operation{{ID: "doc:method:abc"}}, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), errExpected.Error())
+	})
+
+	t.Run("test error from operationStore Put", func(t *testing.T) {
+		opStore := &mockOperationStore{putFunc: func(ops []*batch.Operation) error {
+			return fmt.Errorf("put error")
+		}}
+
+		providers := &Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		p := NewTxnProcessor(providers)
+		_, err := p.processTxnOperations([]*batch.Operation{{ID: "doc:method:abc"}}, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store operation from anchor string")
+	})
+
+	t.Run("test success", func(t *testing.T) {
+		providers := &Providers{
+			TxnOpsProvider:   &mockTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		p := NewTxnProcessor(providers)
+		batchOps, err := p.TxnOpsProvider.GetTxnOperations(&txn.SidetreeTxn{AnchorString: anchorString})
+		require.NoError(t, err)
+
+		_, err = p.processTxnOperations(batchOps, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("success - operation status", func(t *testing.T) {
+		statusStore := opstatus.NewMemStore()
+
+		providers := &Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			OpStatusStore:    statusStore,
+		}
+
+		op := &batch.Operation{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: "did:sidetree"}
+
+		p := NewTxnProcessor(providers)
+		_, err := p.processTxnOperations([]*batch.Operation{op},
+			txn.SidetreeTxn{AnchorString: anchorString, TransactionTime: 20, TransactionNumber: 2}, nil)
+		require.NoError(t, err)
+
+		statuses, err := statusStore.Get(opstatus.TrackingID(op))
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+
+		require.Equal(t, batch.OperationStateAnchored, statuses[0].State)
+		require.Equal(t, anchorString, statuses[0].AnchorString)
+		require.Equal(t, uint64(20), statuses[0].TransactionTime)
+		require.Equal(t, uint64(2), statuses[0].TransactionNumber)
+
+		require.Equal(t, batch.OperationStateAccepted, statuses[1].State)
+	})
+
+	t.Run("success - operation status store error", func(t *testing.T) {
+		providers := &Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+			OpStatusStore:    &mockStatusStore{err: errors.New("status store error")},
+		}
+
+		p := NewTxnProcessor(providers)
+		_, err := p.processTxnOperations([]*batch.Operation{{ID: "did:sidetree:abc"}}, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("success - multiple operations with same suffix in transaction operations", func(t *testing.T) {
+		mockOpsStore := &mockOperationStore{}
+		providers := &Providers{
+			TxnOpsProvider:   &mockTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: mockOpsStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		}
+
+		p := NewTxnProcessor(providers)
+		batchOps, err := p.TxnOpsProvider.GetTxnOperations(&txn.SidetreeTxn{AnchorString: anchorString})
+		require.NoError(t, err)
+
+		// add same operations again to create scenario where batch has multiple operations with same suffix
+		// only first operation will be processed, subsequent operations will be discarded
+		batchOps = append(batchOps, batchOps...)
+
+		_, err = p.processTxnOperations(batchOps, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.NoError(t, err)
+	})
+}
+
+func TestProcessTxnOperations_Transactional(t *testing.T) {
+	newOps := func() []*batch.Operation {
+		return []*batch.Operation{
+			{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: mocks.DefaultNS},
+			{ID: "did:sidetree:def", UniqueSuffix: "def", Namespace: mocks.DefaultNS},
+			{ID: "did:sidetree:ghi", UniqueSuffix: "ghi", Namespace: mocks.DefaultNS},
+		}
+	}
+
+	sidetreeTxn := txn.SidetreeTxn{AnchorString: anchorString, TransactionTime: 20, TransactionNumber: 2}
+
+	t.Run("success", func(t *testing.T) {
+		opStore := newTxnOperationStore()
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		})
+
+		rejected, err := p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Empty(t, rejected)
+		require.Len(t, opStore.committed, 3)
+		require.Contains(t, opStore.committed, "2-1")
+
+		// processing the transaction again doesn't store the operations twice
+		_, err = p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Len(t, opStore.committed, 3)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		opStore := mocks.NewMockTxnOperationStore()
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		})
+
+		_, err := p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Len(t, opStore.Operations(), 3)
+
+		// the transaction is processed again (e.g. the observer was restarted before the checkpoint was stored)
+		_, err = p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Len(t, opStore.Operations(), 3)
+
+		var keys []string
+		for _, op := range opStore.Operations() {
+			keys = append(keys, IdempotencyKey(op))
+		}
+
+		require.ElementsMatch(t, []string{"2-0", "2-1", "2-2"}, keys)
+
+		// the operations of a later transaction have different keys
+		laterTxn := txn.SidetreeTxn{AnchorString: anchorString, TransactionTime: 21, TransactionNumber: 3}
+
+		_, err = p.processTxnOperations(newOps(), laterTxn, nil)
+		require.NoError(t, err)
+		require.Len(t, opStore.Operations(), 6)
+
+		// the later transaction is stored again after its operations were deleted (e.g. due to a ledger reorg)
+		require.NoError(t, opStore.DeleteAfter(20, 2))
+		require.Len(t, opStore.Operations(), 3)
+
+		_, err = p.processTxnOperations(newOps(), laterTxn, nil)
+		require.NoError(t, err)
+		require.Len(t, opStore.Operations(), 6)
+	})
+
+	t.Run("filter error", func(t *testing.T) {
+		opStore := newTxnOperationStore()
+		statusStore := opstatus.NewMemStore()
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &mockOperationFilterProvider{errSuffix: "def"},
+			OpStatusStore:    statusStore,
+		})
+
+		ops := newOps()
+
+		// only the operations of the suffix that couldn't be filtered are rejected
+		rejected, err := p.processTxnOperations(ops, sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"def"}, rejected)
+		require.Len(t, opStore.committed, 2)
+		require.Contains(t, opStore.committed, "2-0")
+		require.Contains(t, opStore.committed, "2-2")
+
+		statuses, err := statusStore.Get(opstatus.TrackingID(ops[1]))
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+		require.Equal(t, batch.OperationStateRejected, statuses[1].State)
+		require.Equal(t, "filter error", statuses[1].Reason)
+		require.Equal(t, uint64(20), statuses[1].TransactionTime)
+	})
+
+	t.Run("begin error", func(t *testing.T) {
+		opStore := newTxnOperationStore()
+		opStore.beginErr = errors.New("begin error")
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		})
+
+		_, err := p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to begin store transaction")
+	})
+
+	t.Run("put error", func(t *testing.T) {
+		opStore := newTxnOperationStore()
+		opStore.putErr = errors.New("put error")
+		opStore.rollbackErr = errors.New("rollback error")
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		})
+
+		_, err := p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store operation from anchor string")
+		require.Empty(t, opStore.committed)
+		require.True(t, opStore.rolledBack)
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		opStore := newTxnOperationStore()
+		opStore.commitErr = errors.New("commit error")
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: opStore},
+			OpFilterProvider: &NoopOperationFilterProvider{},
+		})
+
+		_, err := p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to commit operations from anchor string")
+		require.Empty(t, opStore.committed)
+
+		// the transaction is stored in full when it's processed again
+		opStore.commitErr = nil
+
+		_, err = p.processTxnOperations(newOps(), sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Len(t, opStore.committed, 3)
+	})
+}
+
+func TestResolutionCache(t *testing.T) {
+	t.Run("operations are stored", func(t *testing.T) {
+		ops := []*batch.Operation{
+			{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: mocks.DefaultNS},
+			{ID: "did:sidetree:def", UniqueSuffix: "def", Namespace: mocks.DefaultNS},
+			{ID: "did:sidetree:ghi", UniqueSuffix: "ghi", Namespace: mocks.DefaultNS},
+		}
+
+		cache := &mockResolutionCache{}
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: &mockOperationFilterProvider{rejectSuffix: "def"},
+			ResolutionCache:  cache,
+		})
+
+		sidetreeTxn := txn.SidetreeTxn{AnchorString: anchorString, TransactionTime: 20, TransactionNumber: 2}
+
+		rejected, err := p.processTxnOperations(ops, sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"def"}, rejected)
+
+		// the rejected operation wasn't stored
+		require.ElementsMatch(t, []string{mocks.DefaultNS + ":abc", mocks.DefaultNS + ":ghi"}, cache.invalidated)
+		require.Empty(t, cache.invalidatedAll)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		cache := &mockResolutionCache{}
+
+		o := New(&Providers{
+			OpStoreProvider: &mockOperationStoreProvider{opStore: &memOperationStore{}},
+			ResolutionCache: cache,
+		})
+		o.namespaces[mocks.DefaultNS] = true
+
+		require.True(t, o.rollback(txn.Fork{RollbackPoint: txn.Checkpoint{TransactionTime: 1, TransactionNumber: 1}}))
+		require.Equal(t, []string{mocks.DefaultNS}, cache.invalidatedAll)
+	})
+}
+
+func TestDocumentStateUpdater(t *testing.T) {
+	t.Run("operations are stored", func(t *testing.T) {
+		ops := []*batch.Operation{
+			{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: mocks.DefaultNS},
+			{ID: "did:sidetree:def", UniqueSuffix: "def", Namespace: mocks.DefaultNS},
+			{ID: "did:sidetree:ghi", UniqueSuffix: "ghi", Namespace: mocks.DefaultNS},
+		}
+
+		updater := &mockStateUpdater{err: errors.New("update error")}
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:      &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider:     &mockOperationFilterProvider{rejectSuffix: "def"},
+			StateUpdaterProvider: &mockStateUpdaterProvider{updater: updater},
+		})
+
+		sidetreeTxn := txn.SidetreeTxn{AnchorString: anchorString, TransactionTime: 20, TransactionNumber: 2}
+
+		// state update errors are only logged
+		_, err := p.processTxnOperations(ops, sidetreeTxn, nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"abc", "ghi"}, updater.updated)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		updater := &mockStateUpdater{}
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:      &mockOperationStoreProvider{opStore: &mockOperationStore{putFunc: func([]*batch.Operation) error { return errors.New("put error") }}},
+			OpFilterProvider:     &NoopOperationFilterProvider{},
+			StateUpdaterProvider: &mockStateUpdaterProvider{updater: updater},
+		})
+
+		ops := []*batch.Operation{{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: mocks.DefaultNS}}
+
+		_, err := p.processTxnOperations(ops, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.Error(t, err)
+		require.Empty(t, updater.updated)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:      &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider:     &NoopOperationFilterProvider{},
+			StateUpdaterProvider: &mockStateUpdaterProvider{err: errors.New("provider error")},
+		})
+
+		ops := []*batch.Operation{{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: mocks.DefaultNS}}
+
+		_, err := p.processTxnOperations(ops, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		updater := &mockStateUpdater{}
+
+		o := New(&Providers{
+			OpStoreProvider:      &mockOperationStoreProvider{opStore: &memOperationStore{}},
+			StateUpdaterProvider: &mockStateUpdaterProvider{updater: updater},
+		})
+		o.namespaces[mocks.DefaultNS] = true
+
+		require.True(t, o.rollback(txn.Fork{RollbackPoint: txn.Checkpoint{TransactionTime: 5, TransactionNumber: 5}}))
+		require.Equal(t, []uint64{5}, updater.deletedAfter)
+	})
+}
+
+func TestOperationListener(t *testing.T) {
+	ops := []*batch.Operation{
+		{ID: "did:sidetree:abc", UniqueSuffix: "abc", Namespace: mocks.DefaultNS},
+		{ID: "did:sidetree:def", UniqueSuffix: "def", Namespace: mocks.DefaultNS},
+	}
+
+	t.Run("operations are stored", func(t *testing.T) {
+		listener := &mockOperationListener{}
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:    &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider:   &mockOperationFilterProvider{rejectSuffix: "def"},
+			OpListenerProvider: &mockOperationListenerProvider{listener: listener},
+		})
+
+		_, err := p.processTxnOperations(ops, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.NoError(t, err)
+		require.Len(t, listener.persisted, 1)
+		require.Equal(t, "abc", listener.persisted[0].UniqueSuffix)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		listener := &mockOperationListener{}
+
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:    &mockOperationStoreProvider{opStore: &mockOperationStore{putFunc: func([]*batch.Operation) error { return errors.New("put error") }}},
+			OpFilterProvider:   &NoopOperationFilterProvider{},
+			OpListenerProvider: &mockOperationListenerProvider{listener: listener},
+		})
+
+		_, err := p.processTxnOperations(ops, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.Error(t, err)
+		require.Empty(t, listener.persisted)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		p := NewTxnProcessor(&Providers{
+			OpStoreProvider:    &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider:   &NoopOperationFilterProvider{},
+			OpListenerProvider: &mockOperationListenerProvider{err: errors.New("provider error")},
+		})
+
+		_, err := p.processTxnOperations(ops, txn.SidetreeTxn{AnchorString: anchorString}, nil)
+		require.NoError(t, err)
+	})
+}
+
+func TestUpdateOperation(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		updatedOps := updateOperation(&batch.Operation{ID: "did:sidetree:abc"},
+			1, txn.SidetreeTxn{TransactionTime: 20, TransactionNumber: 2, AnchorString: "1.anchor"})
+		require.Equal(t, uint64(20), updatedOps.TransactionTime)
+		require.Equal(t, uint64(2), updatedOps.TransactionNumber)
+		require.Equal(t, uint(1), updatedOps.OperationIndex)
+		require.Equal(t, "1.anchor", updatedOps.AnchorString)
+	})
+}
+
+type mockLedger struct {
+	registerForSidetreeTxnValue chan []txn.SidetreeTxn
+}
+
+func (m mockLedger) RegisterForSidetreeTxn() <-chan []txn.SidetreeTxn {
+	return m.registerForSidetreeTxnValue
+}
+
+type mockDCAS struct {
+	readFunc func(key string) ([]byte, error)
+}
+
+func (m mockDCAS) Read(key string) ([]byte, error) {
+	if m.readFunc != nil {
+		return m.readFunc(key)
+	}
+	return nil, nil
+}
+
+func (m mockDCAS) Write(content []byte) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+type mockOperationStore struct {
+	putFunc    func(ops []*batch.Operation) error
+	getFunc    func(suffix string) ([]*batch.Operation, error)
+	deleteFunc func(transactionTime, transactionNumber uint64) error
+}
+
+func (m *mockOperationStore) Put(ops []*batch.Operation) error {
+	if m.putFunc != nil {
+		return m.putFunc(ops)
+	}
+	return nil
+}
+
+func (m *mockOperationStore) Get(suffix string) ([]*batch.Operation, error) {
+	if m.getFunc != nil {
+		return m.getFunc(suffix)
+	}
+	return nil, nil
+}
+
+func (m *mockOperationStore) DeleteAfter(transactionTime, transactionNumber uint64) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(transactionTime, transactionNumber)
+	}
+	return nil
+}
+
+// memOperationStore stores operations in memory
+type memOperationStore struct {
+	mutex sync.Mutex
+	ops   []*batch.Operation
+}
+
+func (m *memOperationStore) Put(ops []*batch.Operation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ops = append(m.ops, ops...)
+
+	return nil
+}
+
+func (m *memOperationStore) DeleteAfter(transactionTime, transactionNumber uint64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var ops []*batch.Operation
+	for _, op := range m.ops {
+		if !isAfter(op.TransactionTime, op.TransactionNumber, txn.Checkpoint{TransactionTime: transactionTime, TransactionNumber: transactionNumber}) {
+			ops = append(ops, op)
+		}
+	}
+
+	m.ops = ops
+
+	return nil
+}
+
+func (m *memOperationStore) hasAnchor(anchor string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, op := range m.ops {
+		if op.AnchorString == anchor {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *memOperationStore) transactionTimes() []uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var times []uint64
+	for _, op := range m.ops {
+		times = append(times, op.TransactionTime)
+	}
+
+	return times
+}
+
+type mockOperationStoreProvider struct {
+	opStore OperationStore
+	err     error
+}
+
+func (m *mockOperationStoreProvider) ForNamespace(string) (OperationStore, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.opStore, nil
+}
+
+type mockTxnOpsProvider struct {
+	err     error
+	mutex   sync.Mutex
+	anchors []string
+}
+
+func (m *mockTxnOpsProvider) GetTxnOperations(txn *txn.SidetreeTxn) ([]*batch.Operation, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.mutex.Lock()
+	m.anchors = append(m.anchors, txn.AnchorString)
+	m.mutex.Unlock()
+
+	op := &batch.Operation{
+		ID: "did:sidetree:abc",
+	}
+
+	return []*batch.Operation{op}, nil
+}
+
+type mockStatusStore struct {
+	err error
+}
+
+func (m *mockStatusStore) Put(*batch.OperationStatus) error {
+	return m.err
+}
+
+func (m *mockTxnOpsProvider) processedAnchors() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.anchors
+}
+
+type mockCheckpointStore struct {
+	putErr error
+	getErr error
+}
+
+func (m *mockCheckpointStore) Put(string, txn.Checkpoint) error {
+	return m.putErr
+}
+
+func (m *mockCheckpointStore) GetAll() (map[string]txn.Checkpoint, error) {
+	return nil, m.getErr
+}
+
+type mockForkNotifier struct {
+	forkCh chan txn.Fork
+}
+
+func (m *mockForkNotifier) RegisterForFork() <-chan txn.Fork {
+	return m.forkCh
+}
+
+func receiveRefused(t *testing.T, errChan <-chan error) *RefusedTxnError {
+	select {
+	case err := <-errChan:
+		refused, ok := err.(*RefusedTxnError)
+		require.True(t, ok)
+
+		return refused
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for refused transaction")
+	}
+
+	return nil
+}
+
+// flakyTxnOpsProvider fails to provide the operations of an anchor for the given number of attempts
+type flakyTxnOpsProvider struct {
+	mutex    sync.Mutex
+	failures map[string]int
+	// suffixes contains the suffix of the operation of each anchor (abc by default)
+	suffixes map[string]string
+	calls    map[string]int
+	anchors  []string
+}
+
+func (m *flakyTxnOpsProvider) GetTxnOperations(sidetreeTxn *txn.SidetreeTxn) ([]*batch.Operation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.calls == nil {
+		m.calls = make(map[string]int)
+	}
+
+	m.calls[sidetreeTxn.AnchorString]++
+
+	if m.calls[sidetreeTxn.AnchorString] <= m.failures[sidetreeTxn.AnchorString] {
+		return nil, errors.New("CAS error")
+	}
+
+	m.anchors = append(m.anchors, sidetreeTxn.AnchorString)
+
+	suffix, ok := m.suffixes[sidetreeTxn.AnchorString]
+	if !ok {
+		suffix = "abc"
+	}
+
+	return []*batch.Operation{{ID: "did:sidetree:" + suffix, UniqueSuffix: suffix, Namespace: sidetreeTxn.Namespace}}, nil
+}
+
+func (m *flakyTxnOpsProvider) processedAnchors() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.anchors
+}
+
+func (m *flakyTxnOpsProvider) attempts(anchor string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.calls[anchor]
+}
+
+// countingRetryStore counts the calls to GetAll
+type countingRetryStore struct {
+	*retry.MemStore
+
+	mutex sync.Mutex
+	calls int
+}
+
+func (m *countingRetryStore) GetAll() ([]*txn.PendingTxn, error) {
+	m.mutex.Lock()
+	m.calls++
+	m.mutex.Unlock()
+
+	return m.MemStore.GetAll()
+}
+
+func (m *countingRetryStore) getAllCalls() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.calls
+}
+
+// slowTxnOpsProvider takes longer to provide the operations of earlier transactions
+type slowTxnOpsProvider struct {
+	err       error
+	errAnchor string
+}
+
+func (m *slowTxnOpsProvider) GetTxnOperations(sidetreeTxn *txn.SidetreeTxn) ([]*batch.Operation, error) {
+	time.Sleep(time.Duration(10-sidetreeTxn.TransactionNumber) * 5 * time.Millisecond)
+
+	if sidetreeTxn.AnchorString == m.errAnchor {
+		return nil, m.err
+	}
+
+	return []*batch.Operation{{ID: "did:sidetree:abc", UniqueSuffix: "abc"}}, nil
+}
+
+// txnOperationStore is a transactional operation store that stores operations by their idempotency key
+type txnOperationStore struct {
+	mockOperationStore
+
+	committed   map[string]*batch.Operation
+	beginErr    error
+	putErr      error
+	commitErr   error
+	rollbackErr error
+	rolledBack  bool
+}
+
+func newTxnOperationStore() *txnOperationStore {
+	return &txnOperationStore{committed: make(map[string]*batch.Operation)}
+}
+
+func (m *txnOperationStore) Begin() (batch.OperationStoreTxn, error) {
+	if m.beginErr != nil {
+		return nil, m.beginErr
+	}
+
+	return &mockOperationStoreTxn{store: m}, nil
+}
+
+type mockOperationStoreTxn struct {
+	store  *txnOperationStore
+	staged map[string]*batch.Operation
+}
+
+func (m *mockOperationStoreTxn) Put(key string, op *batch.Operation) error {
+	if m.store.putErr != nil {
+		return m.store.putErr
+	}
+
+	if m.staged == nil {
+		m.staged = make(map[string]*batch.Operation)
+	}
+
+	m.staged[key] = op
+
+	return nil
+}
+
+func (m *mockOperationStoreTxn) Commit() error {
+	if m.store.commitErr != nil {
+		return m.store.commitErr
+	}
+
+	for key, op := range m.staged {
+		m.store.committed[key] = op
+	}
+
+	return nil
+}
+
+func (m *mockOperationStoreTxn) Rollback() error {
+	m.store.rolledBack = true
+	m.staged = nil
+
+	return m.store.rollbackErr
+}
+
+// mockOperationFilterProvider returns a filter that fails for one suffix and rejects the operations of another
+type mockOperationFilterProvider struct {
+	errSuffix    string
+	rejectSuffix string
+}
+
+func (m *mockOperationFilterProvider) Get(string) (OperationFilter, error) {
+	return m, nil
+}
+
+func (m *mockOperationFilterProvider) Filter(uniqueSuffix string, ops []*batch.Operation) ([]*batch.Operation, error) {
+	if uniqueSuffix == m.errSuffix {
+		return nil, errors.New("filter error")
+	}
+
+	if uniqueSuffix == m.rejectSuffix {
+		return nil, nil
+	}
+
+	return ops, nil
+}
+
+// dependentFilterProvider returns a filter that rejects the operations of a suffix until the operations
+// of the create anchor of the suffix were stored (or the operations are operations of the create anchor)
+type dependentFilterProvider struct {
+	opStore       *memOperationStore
+	createAnchors map[string]string
+}
+
+func (m *dependentFilterProvider) Get(string) (OperationFilter, error) {
+	return m, nil
+}
+
+func (m *dependentFilterProvider) Filter(uniqueSuffix string, ops []*batch.Operation) ([]*batch.Operation, error) {
+	createAnchor, ok := m.createAnchors[uniqueSuffix]
+	if !ok || ops[0].AnchorString == createAnchor || m.opStore.hasAnchor(createAnchor) {
+		return ops, nil
+	}
+
+	return nil, nil
+}
+
+type mockResolutionCache struct {
+	invalidated    []string
+	invalidatedAll []string
+}
+
+func (m *mockResolutionCache) Invalidate(namespace, uniqueSuffix string) {
+	m.invalidated = append(m.invalidated, namespace+":"+uniqueSuffix)
+}
+
+func (m *mockResolutionCache) InvalidateAll(namespace string) {
+	m.invalidatedAll = append(m.invalidatedAll, namespace)
+}
+
+type mockStateUpdaterProvider struct {
+	updater *mockStateUpdater
+	err     error
+}
+
+func (m *mockStateUpdaterProvider) ForNamespace(string) (DocumentStateUpdater, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.updater, nil
+}
+
+type mockStateUpdater struct {
+	updated      []string
+	deletedAfter []uint64
+	err          error
+}
+
+func (m *mockStateUpdater) UpdateState(uniqueSuffix string) error {
+	m.updated = append(m.updated, uniqueSuffix)
+
+	return m.err
+}
+
+func (m *mockStateUpdater) DeleteStateAfter(transactionTime uint64) error {
+	m.deletedAfter = append(m.deletedAfter, transactionTime)
+
+	return m.err
+}
+
+type mockOperationListenerProvider struct {
+	listener *mockOperationListener
+	err      error
+}
+
+func (m *mockOperationListenerProvider) ForNamespace(string) (OperationListener, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.listener, nil
+}
+
+type mockOperationListener struct {
+	persisted []*batch.Operation
+}
+
+func (m *mockOperationListener) Persisted(ops []*batch.Operation) {
+	m.persisted = append(m.persisted, ops...)
 }
@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+// fetchedTxn is a Sidetree transaction along with the operations that were retrieved from its batch files
+type fetchedTxn struct {
+	txn txn.SidetreeTxn
+	ops []*batch.Operation
+	err error
+}
+
+type fetchFunc func(sidetreeTxn txn.SidetreeTxn) ([]*batch.Operation, error)
+
+// fetchOrdered retrieves the operations of the transactions from the given channel using (up to) the given number
+// of concurrent workers. The fetched transactions are returned in the same order as the transactions were received,
+// so that they may be processed in ledger order. Closing done stops the pipeline.
+func fetchOrdered(fetch fetchFunc, txns <-chan txn.SidetreeTxn, workers int, done <-chan struct{}) <-chan *fetchedTxn {
+	// results holds the results of the transactions that are being fetched (in order). Its capacity
+	// bounds how far the workers may get ahead of the transaction that is processed next.
+	results := make(chan chan *fetchedTxn, workers)
+	sem := make(chan struct{}, workers)
+	out := make(chan *fetchedTxn)
+
+	go func() {
+		defer close(results)
+
+		for sidetreeTxn := range txns {
+			result := make(chan *fetchedTxn, 1)
+
+			select {
+			case results <- result:
+			case <-done:
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			go func(sidetreeTxn txn.SidetreeTxn) {
+				defer func() { <-sem }()
+
+				ops, err := fetch(sidetreeTxn)
+				result <- &fetchedTxn{txn: sidetreeTxn, ops: ops, err: err}
+			}(sidetreeTxn)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		for result := range results {
+			var fetched *fetchedTxn
+
+			select {
+			case fetched = <-result:
+			case <-done:
+				return
+			}
+
+			select {
+			case out <- fetched:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+func TestFetchOrdered(t *testing.T) {
+	const numTxns = 20
+
+	newTxns := func() <-chan txn.SidetreeTxn {
+		txnsCh := make(chan txn.SidetreeTxn, numTxns)
+		for i := 0; i < numTxns; i++ {
+			txnsCh <- txn.SidetreeTxn{TransactionNumber: uint64(i)}
+		}
+		close(txnsCh)
+
+		return txnsCh
+	}
+
+	t.Run("results are in order", func(t *testing.T) {
+		var mutex sync.Mutex
+		active, maxActive := 0, 0
+
+		fetch := func(sidetreeTxn txn.SidetreeTxn) ([]*batch.Operation, error) {
+			mutex.Lock()
+			active++
+			if active > maxActive {
+				maxActive = active
+			}
+			mutex.Unlock()
+
+			// earlier transactions take longer to fetch
+			time.Sleep(time.Duration(numTxns-sidetreeTxn.TransactionNumber) * time.Millisecond)
+
+			mutex.Lock()
+			active--
+			mutex.Unlock()
+
+			if sidetreeTxn.TransactionNumber%5 == 0 {
+				return nil, errors.New("CAS error")
+			}
+
+			return []*batch.Operation{{TransactionNumber: sidetreeTxn.TransactionNumber}}, nil
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+
+		var next uint64
+		for fetched := range fetchOrdered(fetch, newTxns(), 4, done) {
+			require.Equal(t, next, fetched.txn.TransactionNumber)
+
+			if next%5 == 0 {
+				require.Error(t, fetched.err)
+			} else {
+				require.NoError(t, fetched.err)
+				require.Len(t, fetched.ops, 1)
+				require.Equal(t, next, fetched.ops[0].TransactionNumber)
+			}
+
+			next++
+		}
+
+		require.Equal(t, uint64(numTxns), next)
+
+		mutex.Lock()
+		require.True(t, maxActive > 1)
+		require.True(t, maxActive <= 4)
+		mutex.Unlock()
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		fetch := func(sidetreeTxn txn.SidetreeTxn) ([]*batch.Operation, error) {
+			time.Sleep(time.Millisecond)
+			return nil, nil
+		}
+
+		done := make(chan struct{})
+
+		results := fetchOrdered(fetch, newTxns(), 2, done)
+		<-results
+		close(done)
+
+		// the results channel is closed once the pipeline stops
+		for range results {
+		}
+	})
+}
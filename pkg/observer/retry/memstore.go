@@ -0,0 +1,68 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retry
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+// MemStore implements an in-memory store for the Sidetree transactions that are pending retry
+type MemStore struct {
+	mutex sync.RWMutex
+	txns  map[uint64]txn.PendingTxn
+}
+
+// NewMemStore returns a new in-memory pending transaction store
+func NewMemStore() *MemStore {
+	return &MemStore{txns: make(map[uint64]txn.PendingTxn)}
+}
+
+// Put stores the pending transaction. An existing transaction with the same transaction number is replaced.
+func (s *MemStore) Put(pending *txn.PendingTxn) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p := *pending
+	p.Subsequent = append([]txn.SubsequentTxn(nil), pending.Subsequent...)
+
+	s.txns[pending.TransactionNumber] = p
+
+	return nil
+}
+
+// Delete deletes the pending transaction with the given transaction number
+func (s *MemStore) Delete(transactionNumber uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.txns, transactionNumber)
+
+	return nil
+}
+
+// GetAll returns all pending transactions ordered by transaction number
+func (s *MemStore) GetAll() ([]*txn.PendingTxn, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	txns := make([]*txn.PendingTxn, 0, len(s.txns))
+	for _, pending := range s.txns {
+		p := pending
+		p.Subsequent = append([]txn.SubsequentTxn(nil), pending.Subsequent...)
+
+		txns = append(txns, &p)
+	}
+
+	sort.Slice(txns, func(i, j int) bool {
+		return txns[i].TransactionNumber < txns[j].TransactionNumber
+	})
+
+	return txns, nil
+}
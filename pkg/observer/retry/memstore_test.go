@@ -0,0 +1,50 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+func TestMemStore(t *testing.T) {
+	s := NewMemStore()
+
+	txns, err := s.GetAll()
+	require.NoError(t, err)
+	require.Empty(t, txns)
+
+	txn1 := &txn.PendingTxn{SidetreeTxn: txn.SidetreeTxn{TransactionNumber: 5, AnchorString: "anchor5"}, Attempts: 1}
+	txn2 := &txn.PendingTxn{SidetreeTxn: txn.SidetreeTxn{TransactionNumber: 2, AnchorString: "anchor2"}, Attempts: 1}
+
+	require.NoError(t, s.Put(txn1))
+	require.NoError(t, s.Put(txn2))
+
+	txns, err = s.GetAll()
+	require.NoError(t, err)
+	require.Equal(t, []*txn.PendingTxn{txn2, txn1}, txns)
+
+	// the stored transactions are copies
+	txns[0].Subsequent = append(txns[0].Subsequent, txn.SubsequentTxn{SidetreeTxn: txn.SidetreeTxn{TransactionNumber: 3}})
+	txns[0].Attempts = 2
+
+	txns, err = s.GetAll()
+	require.NoError(t, err)
+	require.Empty(t, txns[0].Subsequent)
+	require.Equal(t, uint(1), txns[0].Attempts)
+
+	txn2.Attempts = 2
+	require.NoError(t, s.Put(txn2))
+	require.NoError(t, s.Delete(5))
+
+	txns, err = s.GetAll()
+	require.NoError(t, err)
+	require.Equal(t, []*txn.PendingTxn{txn2}, txns)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+const defaultEventBufferSize = 100
+
+// OperationEvent is published to subscribers when the observer processes an anchored operation
+type OperationEvent struct {
+	Namespace         string
+	UniqueSuffix      string
+	Type              batch.OperationType
+	TransactionTime   uint64
+	TransactionNumber uint64
+	// Accepted is true if the operation passed the operation filter and was stored
+	Accepted bool
+}
+
+// SubscriptionOption is a subscription option
+type SubscriptionOption func(s *Subscription)
+
+// WithNamespace only delivers the events of the given namespace
+func WithNamespace(namespace string) SubscriptionOption {
+	return func(s *Subscription) {
+		s.namespace = namespace
+	}
+}
+
+// WithSuffixes only delivers the events of the given unique suffixes
+func WithSuffixes(suffixes ...string) SubscriptionOption {
+	return func(s *Subscription) {
+		s.suffixes = make(map[string]bool)
+		for _, suffix := range suffixes {
+			s.suffixes[suffix] = true
+		}
+	}
+}
+
+// WithBufferSize sets the number of events that are buffered for the subscriber
+func WithBufferSize(size int) SubscriptionOption {
+	return func(s *Subscription) {
+		s.bufferSize = size
+	}
+}
+
+// WithBlocking makes the observer wait for the subscriber when its buffer is full. Note that a slow subscriber
+// then slows down the observer. By default events are dropped when the buffer is full (see Dropped).
+func WithBlocking() SubscriptionOption {
+	return func(s *Subscription) {
+		s.blocking = true
+	}
+}
+
+// Subscription delivers the operation events that match its filter
+type Subscription struct {
+	// dropped is accessed atomically so it's the first field to guarantee 64-bit alignment
+	dropped uint64
+
+	namespace  string
+	suffixes   map[string]bool
+	bufferSize int
+	blocking   bool
+
+	events chan OperationEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Events returns the channel that the events are delivered to. The channel is closed when the
+// subscription is cancelled.
+func (s *Subscription) Events() <-chan OperationEvent {
+	return s.events
+}
+
+// Dropped returns the number of events that were dropped because the subscriber's buffer was full.
+// A subscriber that has missed events should re-resolve the documents that it's interested in.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) matches(event *OperationEvent) bool {
+	if s.namespace != "" && s.namespace != event.Namespace {
+		return false
+	}
+
+	return s.suffixes == nil || s.suffixes[event.UniqueSuffix]
+}
+
+func (s *Subscription) deliver(event OperationEvent) {
+	if s.blocking {
+		select {
+		case s.events <- event:
+		case <-s.done:
+		}
+
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		logger.Warnf("Dropping event for suffix[%s] since the subscriber's buffer is full", event.UniqueSuffix)
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// publisher delivers operation events to the subscribers
+type publisher struct {
+	mutex         sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+func newPublisher() *publisher {
+	return &publisher{subscriptions: make(map[*Subscription]struct{})}
+}
+
+func (p *publisher) subscribe(opts ...SubscriptionOption) *Subscription {
+	s := &Subscription{
+		bufferSize: defaultEventBufferSize,
+		done:       make(chan struct{}),
+	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.events = make(chan OperationEvent, s.bufferSize)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.subscriptions[s] = struct{}{}
+
+	return s
+}
+
+func (p *publisher) unsubscribe(s *Subscription) {
+	// a blocked delivery to the subscription is released before the lock is acquired
+	s.once.Do(func() { close(s.done) })
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.subscriptions[s]; !ok {
+		return
+	}
+
+	delete(p.subscriptions, s)
+	close(s.events)
+}
+
+func (p *publisher) publish(events []OperationEvent) {
+	if p == nil || len(events) == 0 {
+		return
+	}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for i := range events {
+		event := &events[i]
+
+		for s := range p.subscriptions {
+			if s.matches(event) {
+				s.deliver(*event)
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+)
+
+func TestPublisher(t *testing.T) {
+	events := []OperationEvent{
+		{Namespace: "did:sidetree", UniqueSuffix: "abc", Type: batch.OperationTypeCreate, Accepted: true},
+		{Namespace: "did:sidetree", UniqueSuffix: "def", Type: batch.OperationTypeUpdate},
+		{Namespace: "did:other", UniqueSuffix: "abc", Type: batch.OperationTypeUpdate, Accepted: true},
+	}
+
+	t.Run("filters", func(t *testing.T) {
+		p := newPublisher()
+
+		all := p.subscribe()
+		byNamespace := p.subscribe(WithNamespace("did:sidetree"))
+		bySuffix := p.subscribe(WithSuffixes("abc"))
+		both := p.subscribe(WithNamespace("did:other"), WithSuffixes("abc", "xyz"))
+
+		p.publish(events)
+
+		require.Equal(t, events, receive(all, 3))
+		require.Equal(t, events[:2], receive(byNamespace, 2))
+		require.Equal(t, []OperationEvent{events[0], events[2]}, receive(bySuffix, 2))
+		require.Equal(t, events[2:], receive(both, 1))
+	})
+
+	t.Run("events are dropped when the buffer is full", func(t *testing.T) {
+		p := newPublisher()
+
+		s := p.subscribe(WithBufferSize(1))
+
+		p.publish(events)
+
+		require.Equal(t, events[:1], receive(s, 1))
+		require.Equal(t, uint64(2), s.Dropped())
+	})
+
+	t.Run("blocking", func(t *testing.T) {
+		p := newPublisher()
+
+		s := p.subscribe(WithBufferSize(1), WithBlocking())
+
+		published := make(chan struct{})
+		go func() {
+			p.publish(events)
+			close(published)
+		}()
+
+		require.Equal(t, events, receive(s, 3))
+		<-published
+		require.Zero(t, s.Dropped())
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		p := newPublisher()
+
+		s := p.subscribe(WithBufferSize(1), WithBlocking())
+
+		published := make(chan struct{})
+		go func() {
+			p.publish(events)
+			close(published)
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+
+		// a blocked delivery is released when the subscription is cancelled
+		p.unsubscribe(s)
+		<-published
+
+		for range s.Events() {
+		}
+
+		// unsubscribing again is a no-op
+		p.unsubscribe(s)
+		p.publish(events)
+	})
+}
+
+func TestObserver_Subscribe(t *testing.T) {
+	newObserver := func(filterProvider OperationFilterProvider) (*Observer, chan []txn.SidetreeTxn) {
+		sidetreeTxnCh := make(chan []txn.SidetreeTxn, 100)
+
+		providers := &Providers{
+			Ledger:           mockLedger{registerForSidetreeTxnValue: sidetreeTxnCh},
+			TxnOpsProvider:   &flakyTxnOpsProvider{},
+			OpStoreProvider:  &mockOperationStoreProvider{opStore: &mockOperationStore{}},
+			OpFilterProvider: filterProvider,
+		}
+
+		return New(providers), sidetreeTxnCh
+	}
+
+	sidetreeTxn := txn.SidetreeTxn{Namespace: mocks.DefaultNS, TransactionTime: 20, TransactionNumber: 2, AnchorString: "1.anchor"}
+
+	t.Run("accepted", func(t *testing.T) {
+		o, sidetreeTxnCh := newObserver(&NoopOperationFilterProvider{})
+
+		s := o.Subscribe(WithNamespace(mocks.DefaultNS), WithSuffixes("abc"))
+
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{sidetreeTxn}
+
+		require.Equal(t, []OperationEvent{{Namespace: mocks.DefaultNS, UniqueSuffix: "abc", TransactionTime: 20, TransactionNumber: 2, Accepted: true}}, receive(s, 1))
+
+		o.Unsubscribe(s)
+
+		_, ok := <-s.Events()
+		require.False(t, ok)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		o, sidetreeTxnCh := newObserver(&mockOperationFilterProvider{rejectSuffix: "abc"})
+
+		s := o.Subscribe()
+		defer o.Unsubscribe(s)
+
+		o.Start()
+		defer o.Stop()
+
+		sidetreeTxnCh <- []txn.SidetreeTxn{sidetreeTxn}
+
+		require.Equal(t, []OperationEvent{{Namespace: mocks.DefaultNS, UniqueSuffix: "abc", TransactionTime: 20, TransactionNumber: 2}}, receive(s, 1))
+	})
+}
+
+func receive(s *Subscription, n int) []OperationEvent {
+	var events []OperationEvent
+
+	for len(events) < n {
+		select {
+		case event := <-s.Events():
+			events = append(events, event)
+		case <-time.After(time.Second):
+			return events
+		}
+	}
+
+	return events
+}
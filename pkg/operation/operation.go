@@ -16,6 +16,21 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
 )
 
+// Parser parses operations using the current protocol version
+type Parser struct {
+	client protocol.Client
+}
+
+// NewParser returns new operation parser
+func NewParser(client protocol.Client) *Parser {
+	return &Parser{client: client}
+}
+
+// Parse parses and validates operation
+func (p *Parser) Parse(namespace string, operationBuffer []byte) (*batch.Operation, error) {
+	return ParseOperation(namespace, operationBuffer, p.client.Current())
+}
+
 // ParseOperation parses and validates operation
 func ParseOperation(namespace string, operationBuffer []byte, protocol protocol.Protocol) (*batch.Operation, error) {
 	schema := &operationSchema{}
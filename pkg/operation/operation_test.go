@@ -13,6 +13,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
 )
 
 const namespace = "did:sidetree"
@@ -83,6 +85,31 @@ func TestGetOperation(t *testing.T) {
 	})
 }
 
+func TestParser_Parse(t *testing.T) {
+	pc := mocks.NewMockProtocolClient()
+
+	parser := NewParser(pc)
+	require.NotNil(t, parser)
+
+	t.Run("success", func(t *testing.T) {
+		operation, err := getCreateRequestBytes()
+		require.NoError(t, err)
+
+		op, err := parser.Parse(namespace, operation)
+		require.NoError(t, err)
+		require.NotNil(t, op)
+		require.Equal(t, namespace, op.Namespace)
+		require.Equal(t, namespace+docutil.NamespaceDelimiter+op.UniqueSuffix, op.ID)
+	})
+
+	t.Run("error - unsupported operation type", func(t *testing.T) {
+		op, err := parser.Parse(namespace, getUnsupportedRequest())
+		require.Error(t, err)
+		require.Nil(t, op)
+		require.Contains(t, err.Error(), "not implemented")
+	})
+}
+
 func getUnsupportedRequest() []byte {
 	schema := &operationSchema{
 		Operation: "unsupported",
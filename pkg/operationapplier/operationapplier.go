@@ -0,0 +1,273 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operationapplier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	internal "github.com/trustbloc/sidetree-core-go/pkg/internal/jws"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+// Applier applies operations to the resolution model. The protocol parameters that are used to validate
+// an operation are the ones that apply at the operation's transaction time.
+type Applier struct {
+	pc       protocol.Client
+	composer protocol.DocumentComposer
+}
+
+// New returns new operation applier
+func New(pc protocol.Client, composer protocol.DocumentComposer) *Applier {
+	return &Applier{pc: pc, composer: composer}
+}
+
+// Apply validates the given operation against the resolution model and returns the new resolution model
+func (s *Applier) Apply(operation *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	switch operation.Type {
+	case batch.OperationTypeCreate:
+		return s.applyCreateOperation(operation, rm)
+	case batch.OperationTypeUpdate:
+		return s.applyUpdateOperation(operation, rm)
+	case batch.OperationTypeDeactivate:
+		return s.applyDeactivateOperation(operation, rm)
+	case batch.OperationTypeRecover:
+		return s.applyRecoverOperation(operation, rm)
+	default:
+		return nil, errors.New("operation type not supported for process operation")
+	}
+}
+
+func (s *Applier) applyCreateOperation(operation *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	log.Debugf("Applying create operation: %+v", operation)
+
+	if rm.Doc != nil {
+		return nil, errors.New("create has to be the first operation")
+	}
+
+	doc, err := s.composer.ApplyPatches(make(document.Document), operation.Delta.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.ResolutionModel{
+		Doc:                            doc,
+		LastOperationTransactionTime:   operation.TransactionTime,
+		LastOperationTransactionNumber: operation.TransactionNumber,
+		UpdateCommitment:               operation.Delta.UpdateCommitment,
+		RecoveryCommitment:             operation.SuffixData.RecoveryCommitment,
+	}, nil
+}
+
+func (s *Applier) applyUpdateOperation(operation *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) { //nolint:dupl
+	log.Debugf("Applying update operation: %+v", operation)
+
+	if rm.Doc == nil {
+		return nil, errors.New("update cannot be first operation")
+	}
+
+	jwsParts, err := parseSignedData(operation.SignedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var signedDataModel model.UpdateSignedDataModel
+	err = json.Unmarshal(jwsParts.Payload, &signedDataModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signed data model while applying update: %s", err.Error())
+	}
+
+	p, err := s.pc.Get(operation.TransactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	updateCommitment, err := commitment.Calculate(signedDataModel.UpdateKey, p.HashAlgorithmInMultiHashCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify that update commitments match
+	if updateCommitment != rm.UpdateCommitment {
+		return nil, fmt.Errorf("commitment generated from update key doesn't match update commitment: [%s][%s]", updateCommitment, rm.UpdateCommitment)
+	}
+
+	// verify the delta against the signed delta hash
+	err = isValidHash(operation.EncodedDelta, signedDataModel.DeltaHash)
+	if err != nil {
+		return nil, fmt.Errorf("update delta doesn't match delta hash: %s", err.Error())
+	}
+
+	// verify signature
+	_, err = internal.VerifyJWS(operation.SignedData, signedDataModel.UpdateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check signature: %s", err.Error())
+	}
+
+	doc, err := s.composer.ApplyPatches(rm.Doc, operation.Delta.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.ResolutionModel{
+		Doc:                            doc,
+		LastOperationTransactionTime:   operation.TransactionTime,
+		LastOperationTransactionNumber: operation.TransactionNumber,
+		UpdateCommitment:               operation.Delta.UpdateCommitment,
+		RecoveryCommitment:             rm.RecoveryCommitment}, nil
+}
+
+func parseSignedData(compactJWS string) (*internal.JSONWebSignature, error) {
+	if compactJWS == "" {
+		return nil, errors.New("missing signed data")
+	}
+
+	return internal.ParseJWS(compactJWS)
+}
+
+func (s *Applier) applyDeactivateOperation(operation *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	log.Debugf("Applying deactivate operation: %+v", operation)
+
+	if rm.Doc == nil {
+		return nil, errors.New("deactivate can only be applied to an existing document")
+	}
+
+	jwsParts, err := parseSignedData(operation.SignedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var signedDataModel model.DeactivateSignedDataModel
+	err = json.Unmarshal(jwsParts.Payload, &signedDataModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signed data model while applying deactivate: %s", err.Error())
+	}
+
+	// verify signed did suffix against actual did suffix
+	if operation.UniqueSuffix != signedDataModel.DidSuffix {
+		return nil, errors.New("did suffix doesn't match signed value")
+	}
+
+	p, err := s.pc.Get(operation.TransactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCommitment, err := commitment.Calculate(signedDataModel.RecoveryKey, p.HashAlgorithmInMultiHashCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify that recovery commitments match
+	if recoveryCommitment != rm.RecoveryCommitment {
+		return nil, fmt.Errorf("commitment generated from recovery key doesn't match recovery commitment: [%s][%s]", recoveryCommitment, rm.RecoveryCommitment)
+	}
+
+	// verify signature
+	_, err = internal.VerifyJWS(operation.SignedData, signedDataModel.RecoveryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check signature: %s", err.Error())
+	}
+
+	return &protocol.ResolutionModel{
+		Doc:                            nil,
+		LastOperationTransactionTime:   operation.TransactionTime,
+		LastOperationTransactionNumber: operation.TransactionNumber,
+		UpdateCommitment:               "",
+		RecoveryCommitment:             ""}, nil
+}
+
+func (s *Applier) applyRecoverOperation(operation *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) { //nolint:dupl
+	log.Debugf("Applying recover operation: %+v", operation)
+
+	if rm.Doc == nil {
+		return nil, errors.New("recover can only be applied to an existing document")
+	}
+
+	jwsParts, err := parseSignedData(operation.SignedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var signedDataModel model.RecoverSignedDataModel
+	err = json.Unmarshal(jwsParts.Payload, &signedDataModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signed data model while applying recover: %s", err.Error())
+	}
+
+	p, err := s.pc.Get(operation.TransactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCommitment, err := commitment.Calculate(signedDataModel.RecoveryKey, p.HashAlgorithmInMultiHashCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify that recovery commitments match
+	if recoveryCommitment != rm.RecoveryCommitment {
+		return nil, fmt.Errorf("commitment generated from recovery key doesn't match recovery commitment: [%s][%s]", recoveryCommitment, rm.RecoveryCommitment)
+	}
+
+	// verify the delta against the signed delta hash
+	err = isValidHash(operation.EncodedDelta, signedDataModel.DeltaHash)
+	if err != nil {
+		return nil, fmt.Errorf("recover delta doesn't match delta hash: %s", err.Error())
+	}
+
+	// verify signature
+	_, err = internal.VerifyJWS(operation.SignedData, signedDataModel.RecoveryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check signature: %s", err.Error())
+	}
+
+	doc, err := s.composer.ApplyPatches(make(document.Document), operation.Delta.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.ResolutionModel{
+		Doc:                            doc,
+		LastOperationTransactionTime:   operation.TransactionTime,
+		LastOperationTransactionNumber: operation.TransactionNumber,
+		UpdateCommitment:               operation.Delta.UpdateCommitment,
+		RecoveryCommitment:             signedDataModel.RecoveryCommitment}, nil
+}
+
+func isValidHash(encodedContent, encodedMultihash string) error {
+	content, err := docutil.DecodeString(encodedContent)
+	if err != nil {
+		return err
+	}
+
+	code, err := docutil.GetMultihashCode(encodedMultihash)
+	if err != nil {
+		return err
+	}
+
+	computedMultihash, err := docutil.ComputeMultihash(uint(code), content)
+	if err != nil {
+		return err
+	}
+
+	encodedComputedMultihash := docutil.EncodeToString(computedMultihash)
+
+	if encodedComputedMultihash != encodedMultihash {
+		return errors.New("supplied hash doesn't match original content")
+	}
+
+	return nil
+}
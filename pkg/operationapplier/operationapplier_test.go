@@ -0,0 +1,147 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operationapplier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/composer"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/patch"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/helper"
+)
+
+const sha2_256 = 18
+
+func TestApplier_Apply(t *testing.T) {
+	pc := mocks.NewMockProtocolClient()
+
+	t.Run("create success", func(t *testing.T) {
+		createOp := getCreateOperation(t)
+
+		applier := New(pc, composer.New())
+
+		rm, err := applier.Apply(createOp, &protocol.ResolutionModel{})
+		require.NoError(t, err)
+		require.NotNil(t, rm.Doc)
+		require.Equal(t, createOp.Delta.UpdateCommitment, rm.UpdateCommitment)
+		require.Equal(t, createOp.SuffixData.RecoveryCommitment, rm.RecoveryCommitment)
+
+		rm, err = applier.Apply(createOp, rm)
+		require.Error(t, err)
+		require.Nil(t, rm)
+		require.Contains(t, err.Error(), "create has to be the first operation")
+	})
+
+	t.Run("document composer error", func(t *testing.T) {
+		applier := New(pc, &mockComposer{err: errors.New("composer error")})
+
+		rm, err := applier.Apply(getCreateOperation(t), &protocol.ResolutionModel{})
+		require.Error(t, err)
+		require.Nil(t, rm)
+		require.Contains(t, err.Error(), "composer error")
+	})
+
+	t.Run("update is first operation error", func(t *testing.T) {
+		applier := New(pc, composer.New())
+
+		rm, err := applier.Apply(&batch.Operation{Type: batch.OperationTypeUpdate}, &protocol.ResolutionModel{})
+		require.Error(t, err)
+		require.Nil(t, rm)
+		require.Contains(t, err.Error(), "update cannot be first operation")
+	})
+
+	t.Run("protocol not defined for transaction time error", func(t *testing.T) {
+		v := pc.Protocol
+		v.StartingBlockChainTime = 100
+
+		versioned := mocks.NewMockProtocolClient()
+		versioned.Versions = []protocol.Protocol{v}
+
+		applier := New(versioned, composer.New())
+
+		rm, err := applier.Apply(getCreateOperation(t), &protocol.ResolutionModel{})
+		require.NoError(t, err)
+
+		rm, err = applier.Apply(&batch.Operation{
+			Type:         batch.OperationTypeDeactivate,
+			UniqueSuffix: "suffix",
+			SignedData:   "eyJhbGciOiJFUzI1NiJ9.eyJkaWRfc3VmZml4Ijoic3VmZml4In0.c2ln",
+		}, rm)
+		require.Error(t, err)
+		require.Nil(t, rm)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [0]")
+	})
+
+	t.Run("operation type not supported error", func(t *testing.T) {
+		applier := New(pc, composer.New())
+
+		rm, err := applier.Apply(&batch.Operation{Type: "invalid"}, &protocol.ResolutionModel{})
+		require.Error(t, err)
+		require.Nil(t, rm)
+		require.Contains(t, err.Error(), "operation type not supported for process operation")
+	})
+}
+
+func TestIsValidHashErrors(t *testing.T) {
+	multihash, err := docutil.ComputeMultihash(sha2_256, []byte("test"))
+	require.NoError(t, err)
+
+	encodedMultihash := docutil.EncodeToString(multihash)
+
+	err = isValidHash("hello", encodedMultihash)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "illegal base64 data at input byte 4")
+
+	err = isValidHash(docutil.EncodeToString([]byte("content")), string(multihash))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "illegal base64 data at input byte 0")
+
+	err = isValidHash(docutil.EncodeToString([]byte("content")), encodedMultihash)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "supplied hash doesn't match original content")
+}
+
+func getCreateOperation(t *testing.T) *batch.Operation {
+	multihash, err := docutil.ComputeMultihash(sha2_256, []byte("commitment"))
+	require.NoError(t, err)
+
+	c := docutil.EncodeToString(multihash)
+
+	request, err := helper.NewCreateRequest(&helper.CreateRequestInfo{
+		OpaqueDocument:     `{"test":"value"}`,
+		RecoveryCommitment: c,
+		UpdateCommitment:   c,
+		MultihashCode:      sha2_256,
+	})
+	require.NoError(t, err)
+
+	op, err := operation.ParseCreateOperation(request, mocks.NewMockProtocolClient().Protocol)
+	require.NoError(t, err)
+
+	return op
+}
+
+type mockComposer struct {
+	err error
+}
+
+func (m *mockComposer) ApplyPatches(doc document.Document, patches []patch.Patch) (document.Document, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return doc, nil
+}
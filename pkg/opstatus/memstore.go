@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opstatus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+const defaultMaxOperations = 1000
+
+// MemStore implements an in-memory store for the state transitions of operations. The store keeps the state
+// transitions of (up to) the maximum number of the most recently tracked operations per namespace.
+type MemStore struct {
+	mutex    sync.RWMutex
+	statuses map[string][]*batch.OperationStatus
+	// trackingIDs holds the tracking IDs of the operations of each unique suffix (in the order they were first put)
+	trackingIDs map[string][]string
+	// namespaceIDs holds the tracking IDs of the operations of each namespace (in the order they were first put)
+	namespaceIDs  map[string][]string
+	maxOperations int
+}
+
+// Option is an option for the operation status store
+type Option func(s *MemStore)
+
+// WithMaxOperations sets the maximum number of operations whose state transitions are kept per namespace
+// (zero for no limit)
+func WithMaxOperations(maxOperations int) Option {
+	return func(s *MemStore) {
+		s.maxOperations = maxOperations
+	}
+}
+
+// NewMemStore returns a new in-memory operation status store
+func NewMemStore(opts ...Option) *MemStore {
+	s := &MemStore{
+		statuses:      make(map[string][]*batch.OperationStatus),
+		trackingIDs:   make(map[string][]string),
+		namespaceIDs:  make(map[string][]string),
+		maxOperations: defaultMaxOperations,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Put records the state transition of an operation. The state transitions of the oldest operation of
+// the namespace are dropped if the store already holds the maximum number of operations for the namespace.
+func (s *MemStore) Put(status *batch.OperationStatus) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.statuses[status.TrackingID]; !ok {
+		s.trackingIDs[status.UniqueSuffix] = append(s.trackingIDs[status.UniqueSuffix], status.TrackingID)
+		s.namespaceIDs[status.Namespace] = append(s.namespaceIDs[status.Namespace], status.TrackingID)
+	}
+
+	s.statuses[status.TrackingID] = append(s.statuses[status.TrackingID], status)
+
+	s.evict(status.Namespace)
+
+	return nil
+}
+
+// evict drops the state transitions of the oldest operations of the given namespace that exceed the maximum
+func (s *MemStore) evict(namespace string) {
+	ids := s.namespaceIDs[namespace]
+	if s.maxOperations <= 0 || len(ids) <= s.maxOperations {
+		return
+	}
+
+	for _, trackingID := range ids[:len(ids)-s.maxOperations] {
+		uniqueSuffix := s.statuses[trackingID][0].UniqueSuffix
+
+		delete(s.statuses, trackingID)
+
+		s.trackingIDs[uniqueSuffix] = remove(s.trackingIDs[uniqueSuffix], trackingID)
+		if len(s.trackingIDs[uniqueSuffix]) == 0 {
+			delete(s.trackingIDs, uniqueSuffix)
+		}
+	}
+
+	s.namespaceIDs[namespace] = append([]string(nil), ids[len(ids)-s.maxOperations:]...)
+}
+
+// Get returns the state transitions of the operation with the given tracking ID (oldest first)
+func (s *MemStore) Get(trackingID string) ([]*batch.OperationStatus, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	statuses, ok := s.statuses[trackingID]
+	if !ok {
+		return nil, fmt.Errorf("status not found for tracking ID [%s]", trackingID)
+	}
+
+	result := make([]*batch.OperationStatus, len(statuses))
+	copy(result, statuses)
+
+	return result, nil
+}
+
+// GetBySuffix returns the state transitions of the operations of the given unique suffix
+// (grouped by operation and oldest first)
+func (s *MemStore) GetBySuffix(uniqueSuffix string) ([]*batch.OperationStatus, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var result []*batch.OperationStatus
+	for _, trackingID := range s.trackingIDs[uniqueSuffix] {
+		result = append(result, s.statuses[trackingID]...)
+	}
+
+	return result, nil
+}
+
+func remove(ids []string, id string) []string {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,49 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package opstatus tracks the lifecycle of operations from the time they are accepted by the document handler
+// until they are anchored and validated by the observer.
+//
+// Each operation is identified by a tracking ID that is computed from the operation's content that is stored
+// in the batch files, so that the same tracking ID is computed for the operation before it is batched and after
+// it is assembled from the batch files by the observer.
+package opstatus
+
+import (
+	"crypto/sha256"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+// TrackingID returns the tracking ID of the given operation
+func TrackingID(op *batch.Operation) string {
+	content := strings.Join([]string{
+		string(op.Type),
+		op.UniqueSuffix,
+		op.EncodedSuffixData,
+		op.SignedData,
+		op.EncodedDelta,
+	}, ".")
+
+	hash := sha256.Sum256([]byte(content))
+
+	return docutil.EncodeToString(hash[:])
+}
+
+// NewStatus returns the status of the given operation in the given state
+func NewStatus(op *batch.Operation, state batch.OperationState) *batch.OperationStatus {
+	return &batch.OperationStatus{
+		TrackingID:   TrackingID(op),
+		Namespace:    op.Namespace,
+		UniqueSuffix: op.UniqueSuffix,
+		Type:         op.Type,
+		State:        state,
+		Time:         time.Now(),
+	}
+}
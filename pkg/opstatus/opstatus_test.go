@@ -0,0 +1,155 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opstatus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+func TestTrackingID(t *testing.T) {
+	op := &batch.Operation{
+		Type:            batch.OperationTypeUpdate,
+		Namespace:       "did:sidetree",
+		UniqueSuffix:    "suffix",
+		SignedData:      "signed",
+		EncodedDelta:    "delta",
+		OperationBuffer: []byte("request"),
+	}
+
+	id := TrackingID(op)
+	require.NotEmpty(t, id)
+
+	// the operation assembled from batch files has the same tracking ID
+	anchored := &batch.Operation{
+		Type:              batch.OperationTypeUpdate,
+		Namespace:         "did:sidetree",
+		UniqueSuffix:      "suffix",
+		SignedData:        "signed",
+		EncodedDelta:      "delta",
+		TransactionTime:   10,
+		TransactionNumber: 2,
+	}
+	require.Equal(t, id, TrackingID(anchored))
+
+	anchored.EncodedDelta = "other"
+	require.NotEqual(t, id, TrackingID(anchored))
+}
+
+func TestNewStatus(t *testing.T) {
+	op := &batch.Operation{
+		Type:         batch.OperationTypeCreate,
+		Namespace:    "did:sidetree",
+		UniqueSuffix: "suffix",
+	}
+
+	status := NewStatus(op, batch.OperationStateQueued)
+	require.Equal(t, TrackingID(op), status.TrackingID)
+	require.Equal(t, "did:sidetree", status.Namespace)
+	require.Equal(t, "suffix", status.UniqueSuffix)
+	require.Equal(t, batch.OperationTypeCreate, status.Type)
+	require.Equal(t, batch.OperationStateQueued, status.State)
+	require.False(t, status.Time.IsZero())
+}
+
+func TestMemStore(t *testing.T) {
+	op := &batch.Operation{Type: batch.OperationTypeCreate, UniqueSuffix: "suffix"}
+
+	s := NewMemStore()
+
+	statuses, err := s.Get(TrackingID(op))
+	require.Error(t, err)
+	require.Nil(t, statuses)
+	require.Contains(t, err.Error(), "status not found for tracking ID")
+
+	queued := NewStatus(op, batch.OperationStateQueued)
+	batched := NewStatus(op, batch.OperationStateBatched)
+
+	require.NoError(t, s.Put(queued))
+	require.NoError(t, s.Put(batched))
+
+	statuses, err = s.Get(TrackingID(op))
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationStatus{queued, batched}, statuses)
+
+	update := NewStatus(&batch.Operation{Type: batch.OperationTypeUpdate, UniqueSuffix: "suffix"}, batch.OperationStateRejected)
+	other := NewStatus(&batch.Operation{Type: batch.OperationTypeCreate, UniqueSuffix: "other"}, batch.OperationStateQueued)
+
+	require.NoError(t, s.Put(update))
+	require.NoError(t, s.Put(other))
+
+	anchored := NewStatus(op, batch.OperationStateAnchored)
+	require.NoError(t, s.Put(anchored))
+
+	statuses, err = s.GetBySuffix("suffix")
+	require.NoError(t, err)
+	require.Equal(t, []*batch.OperationStatus{queued, batched, anchored, update}, statuses)
+
+	statuses, err = s.GetBySuffix("none")
+	require.NoError(t, err)
+	require.Empty(t, statuses)
+}
+
+func TestMemStore_MaxOperations(t *testing.T) {
+	t.Run("oldest operations are evicted", func(t *testing.T) {
+		s := NewMemStore(WithMaxOperations(2))
+
+		var ops []*batch.Operation
+		for _, suffix := range []string{"abc", "def", "abc"} {
+			op := &batch.Operation{Type: batch.OperationTypeUpdate, Namespace: "ns1", UniqueSuffix: suffix, EncodedDelta: fmt.Sprintf("delta%d", len(ops))}
+			ops = append(ops, op)
+
+			require.NoError(t, s.Put(NewStatus(op, batch.OperationStateQueued)))
+		}
+
+		// the operations of other namespaces aren't affected
+		other := &batch.Operation{Type: batch.OperationTypeCreate, Namespace: "ns2", UniqueSuffix: "ghi"}
+		require.NoError(t, s.Put(NewStatus(other, batch.OperationStateQueued)))
+
+		// the oldest operation of the namespace was evicted
+		_, err := s.Get(TrackingID(ops[0]))
+		require.Error(t, err)
+
+		for _, op := range append(ops[1:], other) {
+			statuses, err := s.Get(TrackingID(op))
+			require.NoError(t, err)
+			require.Len(t, statuses, 1)
+		}
+
+		statuses, err := s.GetBySuffix("abc")
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		require.Equal(t, TrackingID(ops[2]), statuses[0].TrackingID)
+
+		// additional state transitions of an operation don't count against the limit
+		require.NoError(t, s.Put(NewStatus(ops[1], batch.OperationStateBatched)))
+
+		statuses, err = s.Get(TrackingID(ops[1]))
+		require.NoError(t, err)
+		require.Len(t, statuses, 2)
+
+		_, err = s.Get(TrackingID(ops[2]))
+		require.NoError(t, err)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		s := NewMemStore(WithMaxOperations(0))
+
+		for i := 0; i < 2000; i++ {
+			op := &batch.Operation{Type: batch.OperationTypeCreate, Namespace: "ns1", UniqueSuffix: fmt.Sprintf("suffix%d", i)}
+			require.NoError(t, s.Put(NewStatus(op, batch.OperationStateQueued)))
+		}
+
+		statuses, err := s.GetBySuffix("suffix0")
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+	})
+}
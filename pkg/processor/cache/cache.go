@@ -0,0 +1,242 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cache caches the resolution results of the operation processor. Resolving a document loads all of
+// its operations from the operation store and applies them, so the latest resolution result of frequently
+// resolved documents is cached until the operations of the document change.
+//
+// The cache is bounded in size (the least recently used results are evicted) and the results expire after
+// a TTL. The cache must be invalidated whenever operations are stored for a document, which is done by the
+// observer when the cache is set as its resolution cache.
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const (
+	defaultMaxSize = 1000
+	defaultTTL     = 10 * time.Minute
+)
+
+// OperationProcessor resolves documents
+type OperationProcessor interface {
+	Resolve(uniqueSuffix string, opts ...document.ResolutionOption) (*document.ResolutionResult, error)
+}
+
+// Cache caches the resolution results of the operation processors of one or more namespaces
+type Cache struct {
+	maxSize int
+	ttl     time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	// lru contains the entries ordered by last access (most recently used first)
+	lru *list.List
+	// generation is incremented on every invalidation so that a result that was resolved
+	// while the cache was invalidated isn't cached
+	generation uint64
+
+	now func() time.Time
+}
+
+type entry struct {
+	key            string
+	docBytes       []byte
+	methodMetadata document.MethodMetadata
+	expiry         time.Time
+}
+
+// Option is a cache option
+type Option func(c *Cache)
+
+// WithMaxSize sets the maximum number of cached resolution results
+func WithMaxSize(maxSize int) Option {
+	return func(c *Cache) {
+		c.maxSize = maxSize
+	}
+}
+
+// WithTTL sets the time after which a cached resolution result expires
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// New returns a new resolution cache
+func New(opts ...Option) *Cache {
+	c := &Cache{
+		maxSize: defaultMaxSize,
+		ttl:     defaultTTL,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Wrap returns an operation processor for the given namespace that resolves documents using the given
+// operation processor and caches the results
+func (c *Cache) Wrap(namespace string, processor OperationProcessor) *Processor {
+	return &Processor{
+		namespace: namespace,
+		processor: processor,
+		cache:     c,
+	}
+}
+
+// Invalidate removes the cached resolution result of the document with the given unique suffix
+func (c *Cache) Invalidate(namespace, uniqueSuffix string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+
+	if e, ok := c.entries[key(namespace, uniqueSuffix)]; ok {
+		c.remove(e)
+	}
+}
+
+// InvalidateAll removes the cached resolution results of all documents of the given namespace
+func (c *Cache) InvalidateAll(namespace string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+
+	prefix := namespace + docutil.NamespaceDelimiter
+
+	for k, e := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			c.remove(e)
+		}
+	}
+}
+
+// Len returns the number of cached resolution results
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *Cache) get(k string) (*document.ResolutionResult, uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, c.generation, false
+	}
+
+	ent := e.Value.(*entry)
+
+	if !c.now().Before(ent.expiry) {
+		c.remove(e)
+		return nil, c.generation, false
+	}
+
+	// the cached document is decoded for every caller since the resolution result may be modified
+	doc, err := document.FromBytes(ent.docBytes)
+	if err != nil {
+		log.Warnf("Failed to decode cached document [%s]: %s", k, err)
+		c.remove(e)
+
+		return nil, c.generation, false
+	}
+
+	c.lru.MoveToFront(e)
+
+	return &document.ResolutionResult{Document: doc, MethodMetadata: ent.methodMetadata}, c.generation, true
+}
+
+func (c *Cache) put(k string, result *document.ResolutionResult, generation uint64) {
+	docBytes, err := result.Document.Bytes()
+	if err != nil {
+		log.Warnf("Failed to encode document [%s] for the cache: %s", k, err)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		log.Debugf("Not caching document [%s] since the cache was invalidated while it was resolved", k)
+		return
+	}
+
+	if e, ok := c.entries[k]; ok {
+		c.remove(e)
+	}
+
+	c.entries[k] = c.lru.PushFront(&entry{
+		key:            k,
+		docBytes:       docBytes,
+		methodMetadata: result.MethodMetadata,
+		expiry:         c.now().Add(c.ttl),
+	})
+
+	for c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*entry).key)
+}
+
+// Processor is an operation processor that caches the latest resolution result of documents
+type Processor struct {
+	namespace string
+	processor OperationProcessor
+	cache     *Cache
+}
+
+// Resolve returns the cached resolution result of the document with the given unique suffix or resolves
+// (and caches) it if it isn't cached. Previous versions of documents are not cached.
+func (p *Processor) Resolve(uniqueSuffix string, opts ...document.ResolutionOption) (*document.ResolutionResult, error) {
+	if len(opts) > 0 {
+		return p.processor.Resolve(uniqueSuffix, opts...)
+	}
+
+	k := key(p.namespace, uniqueSuffix)
+
+	result, generation, ok := p.cache.get(k)
+	if ok {
+		log.Debugf("Resolved document [%s] from the cache", k)
+		return result, nil
+	}
+
+	result, err := p.processor.Resolve(uniqueSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cache.put(k, result, generation)
+
+	return result, nil
+}
+
+func key(namespace, uniqueSuffix string) string {
+	return namespace + docutil.NamespaceDelimiter + uniqueSuffix
+}
@@ -0,0 +1,227 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+)
+
+const namespace = "did:sidetree"
+
+func TestProcessor_Resolve(t *testing.T) {
+	t.Run("cached", func(t *testing.T) {
+		mp := newMockProcessor()
+		p := New().Wrap(namespace, mp)
+
+		result, err := p.Resolve("abc")
+		require.NoError(t, err)
+		require.Equal(t, "abc", result.Document["test"])
+		require.Equal(t, "commitment", result.MethodMetadata.UpdateCommitment)
+
+		// modifying the result doesn't modify the cached result
+		result.Document["test"] = "modified"
+
+		result, err = p.Resolve("abc")
+		require.NoError(t, err)
+		require.Equal(t, "abc", result.Document["test"])
+		require.Equal(t, "commitment", result.MethodMetadata.UpdateCommitment)
+
+		require.Equal(t, 1, mp.calls("abc"))
+	})
+
+	t.Run("namespaces", func(t *testing.T) {
+		c := New()
+
+		mp1 := newMockProcessor()
+		mp2 := newMockProcessor()
+
+		_, err := c.Wrap(namespace, mp1).Resolve("abc")
+		require.NoError(t, err)
+		_, err = c.Wrap("did:other", mp2).Resolve("abc")
+		require.NoError(t, err)
+
+		require.Equal(t, 1, mp1.calls("abc"))
+		require.Equal(t, 1, mp2.calls("abc"))
+		require.Equal(t, 2, c.Len())
+	})
+
+	t.Run("errors aren't cached", func(t *testing.T) {
+		mp := newMockProcessor()
+		mp.err = errors.New("not found")
+
+		p := New().Wrap(namespace, mp)
+
+		for i := 0; i < 2; i++ {
+			result, err := p.Resolve("abc")
+			require.Error(t, err)
+			require.Nil(t, result)
+		}
+
+		require.Equal(t, 2, mp.calls("abc"))
+	})
+
+	t.Run("versions aren't cached", func(t *testing.T) {
+		mp := newMockProcessor()
+		p := New().Wrap(namespace, mp)
+
+		for i := 0; i < 2; i++ {
+			_, err := p.Resolve("abc", document.WithVersionID("1-0"))
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 2, mp.calls("abc"))
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		mp := newMockProcessor()
+
+		now := time.Now()
+
+		c := New(WithTTL(time.Minute))
+		c.now = func() time.Time { return now }
+
+		p := c.Wrap(namespace, mp)
+
+		_, err := p.Resolve("abc")
+		require.NoError(t, err)
+
+		now = now.Add(59 * time.Second)
+
+		_, err = p.Resolve("abc")
+		require.NoError(t, err)
+		require.Equal(t, 1, mp.calls("abc"))
+
+		now = now.Add(time.Second)
+
+		_, err = p.Resolve("abc")
+		require.NoError(t, err)
+		require.Equal(t, 2, mp.calls("abc"))
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		mp := newMockProcessor()
+
+		c := New(WithMaxSize(2))
+		p := c.Wrap(namespace, mp)
+
+		for _, suffix := range []string{"abc", "def", "abc", "ghi"} {
+			_, err := p.Resolve(suffix)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 2, c.Len())
+
+		// the least recently used result was evicted
+		for _, suffix := range []string{"abc", "ghi", "def"} {
+			_, err := p.Resolve(suffix)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 1, mp.calls("abc"))
+		require.Equal(t, 2, mp.calls("def"))
+		require.Equal(t, 1, mp.calls("ghi"))
+	})
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	t.Run("invalidate", func(t *testing.T) {
+		mp := newMockProcessor()
+
+		c := New()
+		p := c.Wrap(namespace, mp)
+
+		for _, suffix := range []string{"abc", "def"} {
+			_, err := p.Resolve(suffix)
+			require.NoError(t, err)
+		}
+
+		c.Invalidate(namespace, "abc")
+		c.Invalidate("did:other", "def")
+		require.Equal(t, 1, c.Len())
+
+		for _, suffix := range []string{"abc", "def"} {
+			_, err := p.Resolve(suffix)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 2, mp.calls("abc"))
+		require.Equal(t, 1, mp.calls("def"))
+	})
+
+	t.Run("invalidate all", func(t *testing.T) {
+		c := New()
+
+		_, err := c.Wrap(namespace, newMockProcessor()).Resolve("abc")
+		require.NoError(t, err)
+		_, err = c.Wrap(namespace+":test", newMockProcessor()).Resolve("abc")
+		require.NoError(t, err)
+		_, err = c.Wrap("did:other", newMockProcessor()).Resolve("abc")
+		require.NoError(t, err)
+
+		c.InvalidateAll(namespace)
+		require.Equal(t, 1, c.Len())
+	})
+
+	t.Run("invalidated while resolving", func(t *testing.T) {
+		c := New()
+
+		mp := newMockProcessor()
+		mp.resolving = func() { c.Invalidate(namespace, "abc") }
+
+		p := c.Wrap(namespace, mp)
+
+		_, err := p.Resolve("abc")
+		require.NoError(t, err)
+
+		// the result may be stale so it wasn't cached
+		require.Zero(t, c.Len())
+	})
+}
+
+type mockProcessor struct {
+	mutex     sync.Mutex
+	resolved  map[string]int
+	err       error
+	resolving func()
+}
+
+func newMockProcessor() *mockProcessor {
+	return &mockProcessor{resolved: make(map[string]int)}
+}
+
+func (m *mockProcessor) Resolve(uniqueSuffix string, _ ...document.ResolutionOption) (*document.ResolutionResult, error) {
+	m.mutex.Lock()
+	m.resolved[uniqueSuffix]++
+	m.mutex.Unlock()
+
+	if m.resolving != nil {
+		m.resolving()
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &document.ResolutionResult{
+		Document:       document.Document{"test": uniqueSuffix},
+		MethodMetadata: document.MethodMetadata{UpdateCommitment: "commitment"},
+	}, nil
+}
+
+func (m *mockProcessor) calls(uniqueSuffix string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.resolved[uniqueSuffix]
+}
@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package docstate
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+)
+
+// MemStore implements an in-memory store for the materialized state of documents
+type MemStore struct {
+	mutex  sync.RWMutex
+	states map[string]*protocol.DocumentState
+}
+
+// NewMemStore returns a new in-memory document state store
+func NewMemStore() *MemStore {
+	return &MemStore{states: make(map[string]*protocol.DocumentState)}
+}
+
+// Put stores the state of a document. An existing state for the same unique suffix is replaced.
+func (s *MemStore) Put(state *protocol.DocumentState) error {
+	st, err := copyState(state)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.states[state.UniqueSuffix] = st
+
+	return nil
+}
+
+// Get returns the state of the document with the given unique suffix or nil if there's no state for the document
+func (s *MemStore) Get(uniqueSuffix string) (*protocol.DocumentState, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	state, ok := s.states[uniqueSuffix]
+	if !ok {
+		return nil, nil
+	}
+
+	return copyState(state)
+}
+
+// DeleteAfter deletes the state of the documents that were materialized from
+// operations with a transaction time greater than the given transaction time
+func (s *MemStore) DeleteAfter(transactionTime uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for uniqueSuffix, state := range s.states {
+		if state.MaxTransactionTime > transactionTime {
+			delete(s.states, uniqueSuffix)
+		}
+	}
+
+	return nil
+}
+
+// copyState returns a deep copy of the given state so that the stored state isn't modified by the caller
+func copyState(state *protocol.DocumentState) (*protocol.DocumentState, error) {
+	st := *state
+	st.AppliedOperations = append([]string(nil), state.AppliedOperations...)
+	st.UnappliedOperations = append([]string(nil), state.UnappliedOperations...)
+
+	if state.Doc == nil {
+		return &st, nil
+	}
+
+	docBytes, err := state.Doc.Bytes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to copy document of suffix [%s]", state.UniqueSuffix)
+	}
+
+	st.Doc, err = document.FromBytes(docBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to copy document of suffix [%s]", state.UniqueSuffix)
+	}
+
+	return &st, nil
+}
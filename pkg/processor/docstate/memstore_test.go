@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package docstate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+)
+
+func TestMemStore(t *testing.T) {
+	newState := func(uniqueSuffix string, maxTransactionTime uint64) *protocol.DocumentState {
+		return &protocol.DocumentState{
+			ResolutionModel: protocol.ResolutionModel{
+				Doc:              document.Document{"test": "value"},
+				UpdateCommitment: "commitment",
+			},
+			UniqueSuffix:        uniqueSuffix,
+			AppliedOperations:   []string{"1-0"},
+			UnappliedOperations: []string{"2-0"},
+			MaxTransactionTime:  maxTransactionTime,
+		}
+	}
+
+	t.Run("put and get", func(t *testing.T) {
+		s := NewMemStore()
+
+		state, err := s.Get("abc")
+		require.NoError(t, err)
+		require.Nil(t, state)
+
+		state = newState("abc", 10)
+		require.NoError(t, s.Put(state))
+
+		// modifying the state doesn't modify the stored state
+		state.Doc["test"] = "modified"
+		state.AppliedOperations[0] = "modified"
+
+		stored, err := s.Get("abc")
+		require.NoError(t, err)
+		require.Equal(t, newState("abc", 10), stored)
+
+		stored.Doc["test"] = "modified"
+
+		stored, err = s.Get("abc")
+		require.NoError(t, err)
+		require.Equal(t, "value", stored.Doc["test"])
+	})
+
+	t.Run("deactivated", func(t *testing.T) {
+		s := NewMemStore()
+
+		state := newState("abc", 10)
+		state.Doc = nil
+
+		require.NoError(t, s.Put(state))
+
+		stored, err := s.Get("abc")
+		require.NoError(t, err)
+		require.Nil(t, stored.Doc)
+	})
+
+	t.Run("delete after", func(t *testing.T) {
+		s := NewMemStore()
+
+		require.NoError(t, s.Put(newState("abc", 10)))
+		require.NoError(t, s.Put(newState("def", 20)))
+
+		require.NoError(t, s.DeleteAfter(10))
+
+		state, err := s.Get("abc")
+		require.NoError(t, err)
+		require.NotNil(t, state)
+
+		state, err = s.Get("def")
+		require.NoError(t, err)
+		require.Nil(t, state)
+	})
+}
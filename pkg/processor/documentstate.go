@@ -0,0 +1,182 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processor
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+// DocumentStateStore stores the materialized state of documents
+type DocumentStateStore interface {
+	// Get returns the state of the document with the given unique suffix or nil if there's no state for the document
+	Get(uniqueSuffix string) (*protocol.DocumentState, error)
+	Put(state *protocol.DocumentState) error
+	// DeleteAfter deletes the state of the documents that were materialized from
+	// operations with a transaction time greater than the given transaction time
+	DeleteAfter(transactionTime uint64) error
+}
+
+// WithDocumentStateStore sets the store that holds the materialized state of documents. The latest version
+// of a document is then resolved by applying the operations that were stored since its state was materialized
+// to its state (instead of applying all of its operations). The state of a document is updated by UpdateState.
+func WithDocumentStateStore(store DocumentStateStore) Option {
+	return func(opts *OperationProcessor) {
+		opts.stateStore = store
+	}
+}
+
+// UpdateState materializes the state of the document with the given unique suffix from its stored
+// operations. It's called by the observer after it stores operations for the document.
+func (s *OperationProcessor) UpdateState(uniqueSuffix string) error {
+	if s.stateStore == nil {
+		return nil
+	}
+
+	ops, err := s.store.Get(uniqueSuffix)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get operations for suffix [%s]", uniqueSuffix)
+	}
+
+	state, err := s.currentState(uniqueSuffix, ops, make(map[*batch.Operation]error))
+	if err != nil {
+		return err
+	}
+
+	if len(state.AppliedOperations) == 0 {
+		log.Debugf("[%s] No operations were applied for suffix [%s]. The state isn't stored.", s.name, uniqueSuffix)
+		return nil
+	}
+
+	if err := s.stateStore.Put(state); err != nil {
+		return errors.Wrapf(err, "failed to store state for suffix [%s]", uniqueSuffix)
+	}
+
+	return nil
+}
+
+// DeleteStateAfter deletes the state of the documents that was materialized from operations with a transaction
+// time greater than the given transaction time. It's called by the observer when the ledger is reorganized.
+func (s *OperationProcessor) DeleteStateAfter(transactionTime uint64) error {
+	if s.stateStore == nil {
+		return nil
+	}
+
+	return s.stateStore.DeleteAfter(transactionTime)
+}
+
+// currentState returns the state that results from applying the given operations (of the document with the given
+// unique suffix) to the stored state of the document. The stored state is only used if it's still valid for the
+// given operations, otherwise all of the operations are applied.
+func (s *OperationProcessor) currentState(uniqueSuffix string, ops []*batch.Operation, rejected map[*batch.Operation]error) (*protocol.DocumentState, error) {
+	state, err := s.stateStore.Get(uniqueSuffix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get state for suffix [%s]", uniqueSuffix)
+	}
+
+	if state != nil && !isValidState(state, ops) {
+		log.Debugf("[%s] The stored state of suffix [%s] is out of date. Applying all operations.", s.name, uniqueSuffix)
+		state = nil
+	}
+
+	if state == nil {
+		state = &protocol.DocumentState{UniqueSuffix: uniqueSuffix}
+	}
+
+	applied := toSet(state.AppliedOperations)
+
+	var candidates []*batch.Operation
+	for _, op := range ops {
+		if !applied[versionID(op)] {
+			candidates = append(candidates, op)
+		}
+	}
+
+	// no operations may be applied after the document was deactivated
+	deactivated := len(state.AppliedOperations) > 0 && state.Doc == nil
+
+	newState := &protocol.DocumentState{
+		ResolutionModel:    state.ResolutionModel,
+		UniqueSuffix:       uniqueSuffix,
+		AppliedOperations:  state.AppliedOperations,
+		MaxTransactionTime: state.MaxTransactionTime,
+	}
+
+	var newlyApplied []*batch.Operation
+	if !deactivated && len(candidates) > 0 {
+		var rm *protocol.ResolutionModel
+
+		newlyApplied, rm = s.applyOperationsFrom(&state.ResolutionModel, candidates, rejected, nil)
+		if len(newlyApplied) > 0 {
+			newState.ResolutionModel = *rm
+		}
+	}
+
+	for _, op := range newlyApplied {
+		newState.AppliedOperations = append(newState.AppliedOperations, versionID(op))
+	}
+
+	for _, op := range candidates {
+		if !contains(newlyApplied, op) {
+			newState.UnappliedOperations = append(newState.UnappliedOperations, versionID(op))
+		}
+	}
+
+	for _, op := range ops {
+		if op.TransactionTime > newState.MaxTransactionTime {
+			newState.MaxTransactionTime = op.TransactionTime
+		}
+	}
+
+	return newState, nil
+}
+
+// isValidState returns true if the given state may be used as the starting point for applying the given operations,
+// i.e. all of the applied operations are still stored and all of the operations that weren't considered when the
+// state was materialized come after the applied operations (in ledger order). Otherwise the operations may have
+// been applied in a different order.
+func isValidState(state *protocol.DocumentState, ops []*batch.Operation) bool {
+	applied := toSet(state.AppliedOperations)
+	unapplied := toSet(state.UnappliedOperations)
+
+	var appliedOps, newOps []*batch.Operation
+
+	for _, op := range ops {
+		switch v := versionID(op); {
+		case applied[v]:
+			appliedOps = append(appliedOps, op)
+		case !unapplied[v]:
+			newOps = append(newOps, op)
+		}
+	}
+
+	if len(appliedOps) != len(state.AppliedOperations) {
+		return false
+	}
+
+	for _, newOp := range newOps {
+		for _, op := range appliedOps {
+			if isBefore(newOp, op) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
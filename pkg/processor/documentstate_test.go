@@ -0,0 +1,278 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/composer"
+	"github.com/trustbloc/sidetree-core-go/pkg/document"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/operationapplier"
+	"github.com/trustbloc/sidetree-core-go/pkg/processor/docstate"
+)
+
+func TestDocumentState(t *testing.T) {
+	recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pc := mocks.NewMockProtocolClient()
+
+	newProcessor := func(store OperationStoreClient) (*OperationProcessor, *countingApplier, *docstate.MemStore) {
+		stateStore := docstate.NewMemStore()
+		applier := &countingApplier{applier: operationapplier.New(pc, composer.New())}
+
+		return New("test", store, pc, WithOperationApplier(applier), WithDocumentStateStore(stateStore)), applier, stateStore
+	}
+
+	t.Run("resolve from state", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp1.TransactionTime = 10
+		require.NoError(t, store.Put(updateOp1))
+
+		p, applier, stateStore := newProcessor(store)
+
+		require.NoError(t, p.UpdateState(uniqueSuffix))
+
+		state, err := stateStore.Get(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, []string{"0-0", "1-0"}, state.AppliedOperations)
+		require.Empty(t, state.UnappliedOperations)
+		require.Equal(t, uint64(10), state.MaxTransactionTime)
+		require.Equal(t, "special1", document.DidDocumentFromJSONLDObject(state.Doc)["test"])
+
+		// an operation is stored after the state was updated
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+		updateOp2.TransactionTime = 20
+		require.NoError(t, store.Put(updateOp2))
+
+		applier.applied = 0
+
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special2", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+		require.Equal(t, "2-0", result.MethodMetadata.VersionID)
+		require.Equal(t, uint64(20), result.MethodMetadata.VersionTime)
+
+		// only the new operation was applied
+		require.Equal(t, 1, applier.applied)
+
+		// previous versions are resolved from the operations
+		result, err = p.Resolve(uniqueSuffix, document.WithVersionID("1-0"))
+		require.NoError(t, err)
+		require.Equal(t, "special1", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+	})
+
+	t.Run("operations anchored out of order", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp1.TransactionTime = 20
+
+		// the second update is anchored before the update that it follows
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+		updateOp2.TransactionTime = 10
+		require.NoError(t, store.Put(updateOp2))
+
+		p, _, stateStore := newProcessor(store)
+
+		require.NoError(t, p.UpdateState(uniqueSuffix))
+
+		state, err := stateStore.Get(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, []string{"0-0"}, state.AppliedOperations)
+		require.Equal(t, []string{"2-0"}, state.UnappliedOperations)
+
+		require.NoError(t, store.Put(updateOp1))
+
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special2", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+
+		require.NoError(t, p.UpdateState(uniqueSuffix))
+
+		state, err = stateStore.Get(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, []string{"0-0", "1-0", "2-0"}, state.AppliedOperations)
+		require.Empty(t, state.UnappliedOperations)
+	})
+
+	t.Run("out of date state", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp.TransactionTime = 20
+		require.NoError(t, store.Put(updateOp))
+
+		p, applier, _ := newProcessor(store)
+
+		require.NoError(t, p.UpdateState(uniqueSuffix))
+
+		// a competing update that was anchored before the applied update is stored late (e.g. after a retry)
+		competingOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 5)
+		require.NoError(t, err)
+		competingOp.TransactionTime = 15
+		require.NoError(t, store.Put(competingOp))
+
+		applier.applied = 0
+
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special5", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+
+		// all operations were applied
+		require.Equal(t, 2, applier.applied)
+	})
+
+	t.Run("deactivated", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		deactivateOp, err := getDeactivateOperation(recoveryKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(deactivateOp))
+
+		p, _, stateStore := newProcessor(store)
+
+		require.NoError(t, p.UpdateState(uniqueSuffix))
+
+		state, err := stateStore.Get(uniqueSuffix)
+		require.NoError(t, err)
+		require.Nil(t, state.Doc)
+
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(updateOp))
+
+		result, err := p.Resolve(uniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, result)
+		require.Contains(t, err.Error(), "document was deactivated")
+	})
+
+	t.Run("missing create", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+
+		updateOp, _, err := getUpdateOperation(updateKey, dummyUniqueSuffix, 1)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(updateOp))
+
+		p, _, stateStore := newProcessor(store)
+
+		require.NoError(t, p.UpdateState(dummyUniqueSuffix))
+
+		state, err := stateStore.Get(dummyUniqueSuffix)
+		require.NoError(t, err)
+		require.Nil(t, state)
+
+		result, err := p.Resolve(dummyUniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, result)
+		require.Contains(t, err.Error(), "missing create operation")
+	})
+
+	t.Run("delete state after", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp.TransactionTime = 10
+		require.NoError(t, store.Put(updateOp))
+
+		p, _, stateStore := newProcessor(store)
+
+		require.NoError(t, p.UpdateState(uniqueSuffix))
+		require.NoError(t, p.DeleteStateAfter(5))
+
+		state, err := stateStore.Get(uniqueSuffix)
+		require.NoError(t, err)
+		require.Nil(t, state)
+	})
+
+	t.Run("no state store", func(t *testing.T) {
+		p := New("test", mocks.NewMockOperationStore(nil), pc)
+
+		require.NoError(t, p.UpdateState(dummyUniqueSuffix))
+		require.NoError(t, p.DeleteStateAfter(5))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		p := New("test", store, pc, WithDocumentStateStore(&mockStateStore{err: errors.New("state store error")}))
+
+		result, err := p.Resolve(uniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, result)
+		require.Contains(t, err.Error(), "failed to get state for suffix")
+
+		err = p.UpdateState(uniqueSuffix)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get state for suffix")
+
+		p = New("test", store, pc, WithDocumentStateStore(&mockStateStore{putErr: errors.New("put error")}))
+
+		err = p.UpdateState(uniqueSuffix)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store state for suffix")
+
+		p = New("test", mocks.NewMockOperationStore(errors.New("operation store error")), pc, WithDocumentStateStore(docstate.NewMemStore()))
+
+		err = p.UpdateState(uniqueSuffix)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get operations for suffix")
+	})
+}
+
+// countingApplier counts the operations that were applied
+type countingApplier struct {
+	applier protocol.OperationApplier
+	applied int
+}
+
+func (m *countingApplier) Apply(op *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	result, err := m.applier.Apply(op, rm)
+	if err == nil {
+		m.applied++
+	}
+
+	return result, err
+}
+
+type mockStateStore struct {
+	err    error
+	putErr error
+}
+
+func (m *mockStateStore) Get(string) (*protocol.DocumentState, error) {
+	return nil, m.err
+}
+
+func (m *mockStateStore) Put(*protocol.DocumentState) error {
+	return m.putErr
+}
+
+func (m *mockStateStore) DeleteAfter(uint64) error {
+	return m.err
+}
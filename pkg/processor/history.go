@@ -0,0 +1,260 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+// OperationStatusReader is implemented by operation status stores that can return the state transitions
+// of the operations of a document. If the operation status store (see WithOperationStatusStore) implements
+// this interface then the operations that were rejected by the operation filter are included in the history.
+type OperationStatusReader interface {
+	// GetBySuffix returns the state transitions of the operations of the given unique suffix (oldest first)
+	GetBySuffix(uniqueSuffix string) ([]*batch.OperationStatus, error)
+}
+
+// History returns the anchored operations of the document with the given unique suffix (in ledger order)
+// along with whether each operation was applied when the document was resolved, the reason it wasn't
+// applied and the commitments of the document before and after the operation. Operations that were
+// rejected before they were anchored aren't included (see RejectedBeforeAnchoring).
+func (s *OperationProcessor) History(uniqueSuffix string) ([]*batch.OperationHistoryEntry, error) {
+	ops, err := s.store.Get(uniqueSuffix)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, errors.Wrapf(err, "failed to get operations for suffix [%s]", uniqueSuffix)
+	}
+
+	entries := s.operationHistory(ops)
+
+	filtered, err := s.filteredHistory(uniqueSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = append(entries, filtered...)
+
+	if len(entries) == 0 {
+		return nil, errors.Errorf("history not found for suffix [%s]", uniqueSuffix)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].TransactionTime != entries[j].TransactionTime {
+			return entries[i].TransactionTime < entries[j].TransactionTime
+		}
+
+		if entries[i].TransactionNumber != entries[j].TransactionNumber {
+			return entries[i].TransactionNumber < entries[j].TransactionNumber
+		}
+
+		return entries[i].OperationIndex < entries[j].OperationIndex
+	})
+
+	return entries, nil
+}
+
+// RejectedBeforeAnchoring returns the status of each operation of the given unique suffix that was rejected
+// before it was anchored (e.g. by the batch writer). Nil is returned if the operation status store
+// (see WithOperationStatusStore) doesn't implement OperationStatusReader.
+func (s *OperationProcessor) RejectedBeforeAnchoring(uniqueSuffix string) ([]*batch.OperationStatus, error) {
+	rejected, err := s.rejectedStatuses(uniqueSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*batch.OperationStatus
+
+	for _, status := range rejected {
+		if status.AnchorString == "" {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+// operationHistory applies the given operations (see applyOperations) and returns an entry for each operation
+// (in ledger order). The commitments of an operation that wasn't applied are the commitments of the document
+// when the operation was last tried.
+func (s *OperationProcessor) operationHistory(ops []*batch.Operation) []*batch.OperationHistoryEntry {
+	sorted := make([]*batch.Operation, len(ops))
+	copy(sorted, ops)
+
+	sortOperations(sorted)
+
+	remaining := make([]*batch.Operation, len(sorted))
+	copy(remaining, sorted)
+
+	entries := make(map[*batch.Operation]*batch.OperationHistoryEntry)
+	tried := make(map[*batch.Operation]*batch.Commitments)
+	rejected := make(map[*batch.Operation]error)
+	rm := &protocol.ResolutionModel{}
+
+	for {
+		next, m := s.nextOperation(remaining, rm, rejected)
+
+		// the operations before the next operation were tried (and rejected) in the current document state
+		markTried(tried, remaining, next, rm)
+
+		if next == -1 {
+			break
+		}
+
+		op := remaining[next]
+
+		entry := newHistoryEntry(op)
+		entry.Applied = true
+		entry.CommitmentsBefore = commitments(rm)
+		entry.CommitmentsAfter = commitments(m)
+
+		entries[op] = entry
+		rm = m
+
+		remaining = append(remaining[:next], remaining[next+1:]...)
+
+		if rm.Doc == nil {
+			break
+		}
+	}
+
+	deactivated := len(entries) > 0 && rm.Doc == nil
+
+	history := make([]*batch.OperationHistoryEntry, len(sorted))
+	for i, op := range sorted {
+		entry, ok := entries[op]
+		if !ok {
+			entry = newHistoryEntry(op)
+			entry.Reason = notAppliedReason(rejected[op], deactivated)
+			entry.CommitmentsBefore = tried[op]
+			entry.CommitmentsAfter = tried[op]
+		}
+
+		history[i] = entry
+	}
+
+	return history
+}
+
+// filteredHistory returns an entry for each anchored operation of the given unique suffix that was rejected
+// by the operation filter (and was therefore never stored)
+func (s *OperationProcessor) filteredHistory(uniqueSuffix string) ([]*batch.OperationHistoryEntry, error) {
+	rejected, err := s.rejectedStatuses(uniqueSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*batch.OperationHistoryEntry
+
+	for _, status := range rejected {
+		// operations that were rejected before they were anchored aren't part of the history
+		if status.AnchorString == "" {
+			continue
+		}
+
+		entries = append(entries, &batch.OperationHistoryEntry{
+			Type:              status.Type,
+			AnchorString:      status.AnchorString,
+			TransactionTime:   status.TransactionTime,
+			TransactionNumber: status.TransactionNumber,
+			OperationIndex:    status.OperationIndex,
+			Reason:            status.Reason,
+		})
+	}
+
+	return entries, nil
+}
+
+// rejectedStatuses returns the current status of each operation of the given unique suffix that was rejected
+func (s *OperationProcessor) rejectedStatuses(uniqueSuffix string) ([]*batch.OperationStatus, error) {
+	reader, ok := s.statusStore.(OperationStatusReader)
+	if !ok {
+		return nil, nil
+	}
+
+	statuses, err := reader.GetBySuffix(uniqueSuffix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get operation statuses for suffix [%s]", uniqueSuffix)
+	}
+
+	// the current status of each operation is its last state transition
+	var trackingIDs []string
+	current := make(map[string]*batch.OperationStatus)
+
+	for _, status := range statuses {
+		if _, ok := current[status.TrackingID]; !ok {
+			trackingIDs = append(trackingIDs, status.TrackingID)
+		}
+
+		current[status.TrackingID] = status
+	}
+
+	var rejected []*batch.OperationStatus
+
+	for _, trackingID := range trackingIDs {
+		status := current[trackingID]
+		if status.State == batch.OperationStateRejected {
+			rejected = append(rejected, status)
+		}
+	}
+
+	return rejected, nil
+}
+
+// markTried records the given commitments for the operations (before the operation with index next
+// or all operations if next is -1) that nextOperation tried to apply to the given resolution model
+func markTried(tried map[*batch.Operation]*batch.Commitments, ops []*batch.Operation, next int, rm *protocol.ResolutionModel) {
+	if next == -1 {
+		next = len(ops)
+	}
+
+	for _, op := range ops[:next] {
+		// operations other than create aren't tried until the document was created
+		if rm.Doc == nil && op.Type != batch.OperationTypeCreate {
+			continue
+		}
+
+		tried[op] = commitments(rm)
+	}
+}
+
+func notAppliedReason(err error, deactivated bool) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case deactivated:
+		return "document was deactivated"
+	default:
+		return "missing create operation"
+	}
+}
+
+func newHistoryEntry(op *batch.Operation) *batch.OperationHistoryEntry {
+	return &batch.OperationHistoryEntry{
+		Type:              op.Type,
+		AnchorString:      op.AnchorString,
+		TransactionTime:   op.TransactionTime,
+		TransactionNumber: op.TransactionNumber,
+		OperationIndex:    op.OperationIndex,
+	}
+}
+
+// commitments returns the commitments of the given resolution model (nil if it doesn't have any)
+func commitments(rm *protocol.ResolutionModel) *batch.Commitments {
+	if rm.UpdateCommitment == "" && rm.RecoveryCommitment == "" {
+		return nil
+	}
+
+	return &batch.Commitments{
+		UpdateCommitment:   rm.UpdateCommitment,
+		RecoveryCommitment: rm.RecoveryCommitment,
+	}
+}
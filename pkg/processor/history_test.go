@@ -0,0 +1,289 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
+)
+
+func TestHistory(t *testing.T) {
+	recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pc := mocks.NewMockProtocolClient()
+
+	t.Run("success", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		ops, err := store.Get(uniqueSuffix)
+		require.NoError(t, err)
+		ops[0].AnchorString = "0.anchor"
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp1.AnchorString = "1.anchor"
+
+		// the competing update reveals the same update key as the first update
+		competingOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		competingOp.OperationIndex = 1
+
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		deactivateOp, err := getDeactivateOperation(recoveryKey, uniqueSuffix, 3)
+		require.NoError(t, err)
+
+		lateOp, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 4)
+		require.NoError(t, err)
+
+		for _, op := range []*batch.Operation{lateOp, deactivateOp, updateOp2, competingOp, updateOp1} {
+			require.NoError(t, store.Put(op))
+		}
+
+		history, err := New("test", store, pc).History(uniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, history, 6)
+
+		create, update1, competing, update2, deactivate, late := history[0], history[1], history[2], history[3], history[4], history[5]
+
+		updateCommitment, err := getCommitment(updateKey)
+		require.NoError(t, err)
+
+		require.Equal(t, batch.OperationTypeCreate, create.Type)
+		require.Equal(t, "0.anchor", create.AnchorString)
+		require.True(t, create.Applied)
+		require.Nil(t, create.CommitmentsBefore)
+		require.NotNil(t, create.CommitmentsAfter)
+		require.Equal(t, updateCommitment, create.CommitmentsAfter.UpdateCommitment)
+
+		require.Equal(t, batch.OperationTypeUpdate, update1.Type)
+		require.Equal(t, "1.anchor", update1.AnchorString)
+		require.Equal(t, uint64(1), update1.TransactionNumber)
+		require.True(t, update1.Applied)
+		require.Empty(t, update1.Reason)
+		require.Equal(t, create.CommitmentsAfter, update1.CommitmentsBefore)
+		require.NotEqual(t, update1.CommitmentsBefore.UpdateCommitment, update1.CommitmentsAfter.UpdateCommitment)
+		require.Equal(t, create.CommitmentsAfter.RecoveryCommitment, update1.CommitmentsAfter.RecoveryCommitment)
+
+		// the competing update was last tried after the second update was applied
+		require.Equal(t, uint(1), competing.OperationIndex)
+		require.False(t, competing.Applied)
+		require.Contains(t, competing.Reason, "commitment generated from update key doesn't match update commitment")
+		require.Equal(t, update2.CommitmentsAfter, competing.CommitmentsBefore)
+		require.Equal(t, competing.CommitmentsBefore, competing.CommitmentsAfter)
+
+		require.True(t, update2.Applied)
+		require.Equal(t, update1.CommitmentsAfter, update2.CommitmentsBefore)
+
+		require.Equal(t, batch.OperationTypeDeactivate, deactivate.Type)
+		require.True(t, deactivate.Applied)
+		require.Equal(t, update2.CommitmentsAfter, deactivate.CommitmentsBefore)
+		require.Nil(t, deactivate.CommitmentsAfter)
+
+		require.False(t, late.Applied)
+		require.Equal(t, "document was deactivated", late.Reason)
+		require.Nil(t, late.CommitmentsBefore)
+		require.Nil(t, late.CommitmentsAfter)
+	})
+
+	t.Run("missing create operation", func(t *testing.T) {
+		updateOp, _, err := getUpdateOperation(updateKey, dummyUniqueSuffix, 1)
+		require.NoError(t, err)
+
+		store := mocks.NewMockOperationStore(nil)
+		require.NoError(t, store.Put(updateOp))
+
+		history, err := New("test", store, pc).History(dummyUniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.False(t, history[0].Applied)
+		require.Equal(t, "missing create operation", history[0].Reason)
+		require.Nil(t, history[0].CommitmentsBefore)
+	})
+
+	t.Run("rejected by the operation filter", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp.TransactionTime = 10
+		require.NoError(t, store.Put(updateOp))
+
+		statusStore := opstatus.NewMemStore()
+
+		rejectedOp, _, err := getUpdateOperation(recoveryKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		rejected := opstatus.NewStatus(rejectedOp, batch.OperationStateRejected)
+		rejected.AnchorString = "5.anchor"
+		rejected.TransactionTime = 5
+		rejected.Reason = "failed to check signature"
+		require.NoError(t, statusStore.Put(rejected))
+
+		// the statuses of operations that were accepted are ignored
+		require.NoError(t, statusStore.Put(opstatus.NewStatus(updateOp, batch.OperationStateRejected)))
+		require.NoError(t, statusStore.Put(opstatus.NewStatus(updateOp, batch.OperationStateAccepted)))
+
+		history, err := New("test", store, pc, WithOperationStatusStore(statusStore)).History(uniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+
+		require.Equal(t, batch.OperationTypeCreate, history[0].Type)
+		require.True(t, history[0].Applied)
+
+		require.Equal(t, batch.OperationTypeUpdate, history[1].Type)
+		require.Equal(t, "5.anchor", history[1].AnchorString)
+		require.Equal(t, uint64(5), history[1].TransactionTime)
+		require.False(t, history[1].Applied)
+		require.Equal(t, "failed to check signature", history[1].Reason)
+		require.Nil(t, history[1].CommitmentsBefore)
+
+		require.Equal(t, uint64(10), history[2].TransactionTime)
+		require.True(t, history[2].Applied)
+	})
+
+	t.Run("only rejected by the operation filter", func(t *testing.T) {
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+
+		rejected := opstatus.NewStatus(createOp, batch.OperationStateRejected)
+		rejected.AnchorString = "1.anchor"
+
+		statusStore := opstatus.NewMemStore()
+		require.NoError(t, statusStore.Put(rejected))
+
+		history, err := New("test", mocks.NewMockOperationStore(nil), pc, WithOperationStatusStore(statusStore)).History(createOp.UniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.False(t, history[0].Applied)
+	})
+
+	t.Run("rejected in the same transaction", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp.AnchorString = "5.anchor"
+		updateOp.TransactionTime = 5
+		updateOp.TransactionNumber = 5
+		updateOp.OperationIndex = 2
+		require.NoError(t, store.Put(updateOp))
+
+		statusStore := opstatus.NewMemStore()
+
+		for _, index := range []uint{3, 1} {
+			rejectedOp, _, err := getUpdateOperation(recoveryKey, uniqueSuffix, index+10)
+			require.NoError(t, err)
+
+			rejected := opstatus.NewStatus(rejectedOp, batch.OperationStateRejected)
+			rejected.AnchorString = "5.anchor"
+			rejected.TransactionTime = 5
+			rejected.TransactionNumber = 5
+			rejected.OperationIndex = index
+			require.NoError(t, statusStore.Put(rejected))
+		}
+
+		history, err := New("test", store, pc, WithOperationStatusStore(statusStore)).History(uniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+
+		// the operations of a transaction are ordered by their index in the batch
+		require.Equal(t, batch.OperationTypeCreate, history[0].Type)
+		require.Equal(t, uint(1), history[1].OperationIndex)
+		require.False(t, history[1].Applied)
+		require.Equal(t, uint(2), history[2].OperationIndex)
+		require.True(t, history[2].Applied)
+		require.Equal(t, uint(3), history[3].OperationIndex)
+		require.False(t, history[3].Applied)
+	})
+
+	t.Run("rejected before anchoring", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+
+		rejected := opstatus.NewStatus(updateOp, batch.OperationStateRejected)
+		rejected.Reason = "invalid operation"
+
+		statusStore := opstatus.NewMemStore()
+		require.NoError(t, statusStore.Put(rejected))
+
+		p := New("test", store, pc, WithOperationStatusStore(statusStore))
+
+		// the operation isn't part of the history since it was never anchored
+		history, err := p.History(uniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, batch.OperationTypeCreate, history[0].Type)
+
+		statuses, err := p.RejectedBeforeAnchoring(uniqueSuffix)
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		require.Equal(t, batch.OperationTypeUpdate, statuses[0].Type)
+		require.Equal(t, "invalid operation", statuses[0].Reason)
+
+		statuses, err = New("test", store, pc).RejectedBeforeAnchoring(uniqueSuffix)
+		require.NoError(t, err)
+		require.Empty(t, statuses)
+
+		statuses, err = New("test", store, pc, WithOperationStatusStore(&mockStatusReader{err: errors.New("status store error")})).RejectedBeforeAnchoring(uniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, statuses)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		history, err := New("test", mocks.NewMockOperationStore(nil), pc).History(dummyUniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, history)
+		require.Contains(t, err.Error(), "history not found for suffix [dummy]")
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		history, err := New("test", mocks.NewMockOperationStore(errors.New("store error")), pc).History(dummyUniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, history)
+		require.Contains(t, err.Error(), "failed to get operations for suffix [dummy]: store error")
+	})
+
+	t.Run("status store error", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		statusStore := &mockStatusReader{err: errors.New("status store error")}
+
+		history, err := New("test", store, pc, WithOperationStatusStore(statusStore)).History(uniqueSuffix)
+		require.Error(t, err)
+		require.Nil(t, history)
+		require.Contains(t, err.Error(), "failed to get operation statuses for suffix")
+	})
+}
+
+type mockStatusReader struct {
+	err error
+}
+
+func (m *mockStatusReader) Put(*batch.OperationStatus) error {
+	return nil
+}
+
+func (m *mockStatusReader) GetBySuffix(string) ([]*batch.OperationStatus, error) {
+	return nil, m.err
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package processor
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 )
 
 // OperationValidationFilter filters out invalid operations.
@@ -21,86 +23,140 @@ type OperationValidationFilter struct {
 	*OperationProcessor
 }
 
+// OperationStatusStore defines interface for recording the state transitions of operations
+type OperationStatusStore interface {
+	Put(status *batch.OperationStatus) error
+}
+
+// WithOperationStatusStore sets the store that the operation filter uses to record rejected operations
+// along with the reason for the rejection. If the store implements OperationStatusReader then History
+// includes the rejected operations.
+func WithOperationStatusStore(store OperationStatusStore) Option {
+	return func(opts *OperationProcessor) {
+		opts.statusStore = store
+	}
+}
+
 // NewOperationFilter returns new operation filter with the given name. (Note that name is only used for logging.)
-func NewOperationFilter(name string, store OperationStoreClient, pc protocol.Client) *OperationValidationFilter {
+func NewOperationFilter(name string, store OperationStoreClient, pc protocol.Client, opts ...Option) *OperationValidationFilter {
 	return &OperationValidationFilter{
-		OperationProcessor: New(name, store, pc),
+		OperationProcessor: New(name, store, pc, opts...),
 	}
 }
 
 // Filter filters out the invalid operations and returns only the valid ones
 func (s *OperationValidationFilter) Filter(uniqueSuffix string, newOps []*batch.Operation) ([]*batch.Operation, error) {
+	// reasons for rejecting operations
+	rejected := make(map[*batch.Operation]string)
+
+	validNewOps, persistedOps, err := s.filter(uniqueSuffix, newOps, rejected)
+	if err != nil {
+		return nil, err
+	}
+
+	s.updateStatus(newOps, validNewOps, persistedOps, rejected)
+
+	return validNewOps, nil
+}
+
+// filter returns the valid new operations along with the new operations that were already persisted
+func (s *OperationValidationFilter) filter(uniqueSuffix string, newOps []*batch.Operation, rejected map[*batch.Operation]string) ([]*batch.Operation, []*batch.Operation, error) {
 	log.Debugf("[%s] Validating operations for unique suffix [%s]...", s.name, uniqueSuffix)
 
-	newOps = s.filterInvalidSuffix(uniqueSuffix, newOps)
+	newOps = s.filterInvalidSuffix(uniqueSuffix, newOps, rejected)
 
 	ops, err := s.store.Get(uniqueSuffix)
 	if err != nil {
 		if !strings.Contains(err.Error(), "not found") {
-			return nil, err
+			return nil, nil, err
 		}
 
 		log.Debugf("[%s] Unique suffix not found in the store [%s]", s.name, uniqueSuffix)
 	}
 
+	// A transaction may be processed again (e.g. after an earlier transaction was retried)
+	// so the operations that were already persisted are skipped
+	newOps, persistedOps := s.filterPersisted(newOps, ops)
+
 	// Combine the existing (persistet) operations with the new operations
 	ops = append(ops, newOps...)
 
-	// Sort the operations by transaction time/number
-	sortOperations(ops)
-
 	log.Debugf("[%s] Found %d operations for unique suffix [%s]: %+v", s.name, len(ops), uniqueSuffix, ops)
 
-	// split operations info 'full' and 'update' operations
-	fullOps, updateOps := splitOperations(ops)
-	if len(fullOps) == 0 {
-		return nil, errors.New("missing create operation")
+	if !containsCreate(ops) {
+		return nil, nil, errors.New("missing create operation")
 	}
 
-	// apply 'full' operations first
-	validFullOps, rm := s.getValidOperations(fullOps, &resolutionModel{})
+	// the new operations are valid if they're part of the commitment chain of the document
+	reasons := make(map[*batch.Operation]error)
 
-	var validUpdateOps []*batch.Operation
-	if rm.Doc == nil {
+	applied, rm := s.applyOperations(ops, reasons)
+	if len(applied) > 0 && rm.Doc == nil {
 		log.Debugf("[%s] Document was deactivated [%s]", s.name, uniqueSuffix)
-	} else {
-		// next apply update ops since last 'full' transaction
-		validUpdateOps, _ = s.getValidOperations(getOpsWithTxnGreaterThan(updateOps, rm.LastOperationTransactionTime, rm.LastOperationTransactionNumber), rm)
 	}
 
 	var validNewOps []*batch.Operation
-	for _, op := range append(validFullOps, validUpdateOps...) {
+	for _, op := range applied {
 		if contains(newOps, op) {
 			validNewOps = append(validNewOps, op)
 		}
 	}
 
-	return validNewOps, nil
+	for _, op := range newOps {
+		if contains(validNewOps, op) {
+			continue
+		}
+
+		if err, ok := reasons[op]; ok {
+			log.Infof("[%s] Rejecting invalid operation {ID: %s, UniqueSuffix: %s, Type: %s, TransactionTime: %d, TransactionNumber: %d}. Reason: %s", s.name, op.ID, op.UniqueSuffix, op.Type, op.TransactionTime, op.TransactionNumber, err)
+			rejected[op] = err.Error()
+		}
+	}
+
+	return validNewOps, persistedOps, nil
 }
 
-func (s *OperationValidationFilter) getValidOperations(ops []*batch.Operation, rm *resolutionModel) ([]*batch.Operation, *resolutionModel) {
-	var validOps []*batch.Operation
+func containsCreate(ops []*batch.Operation) bool {
 	for _, op := range ops {
-		m, err := s.applyOperation(op, rm)
-		if err != nil {
-			log.Infof("[%s] Rejecting invalid operation {ID: %s, UniqueSuffix: %s, Type: %s, TransactionTime: %d, TransactionNumber: %d}. Reason: %s", s.name, op.ID, op.UniqueSuffix, op.Type, op.TransactionTime, op.TransactionNumber, err)
-			continue
+		if op.Type == batch.OperationTypeCreate {
+			return true
 		}
+	}
+
+	return false
+}
+
+// filterPersisted returns the new operations that haven't been persisted yet along with the ones that were already persisted
+func (s *OperationValidationFilter) filterPersisted(newOps, persisted []*batch.Operation) ([]*batch.Operation, []*batch.Operation) {
+	keys := make(map[string]bool)
+	for _, op := range persisted {
+		keys[persistedKey(op)] = true
+	}
 
-		validOps = append(validOps, op)
-		rm = m
+	var filtered, persistedOps []*batch.Operation
+	for _, op := range newOps {
+		if keys[persistedKey(op)] {
+			log.Debugf("[%s] Skipping operation {UniqueSuffix: %s, Type: %s, TransactionTime: %d, TransactionNumber: %d} since it was already persisted", s.name, op.UniqueSuffix, op.Type, op.TransactionTime, op.TransactionNumber)
+			persistedOps = append(persistedOps, op)
+			continue
+		}
 
-		log.Debugf("[%s] After applying op %+v, New doc: %s", s.name, op, rm.Doc)
+		filtered = append(filtered, op)
 	}
 
-	return validOps, rm
+	return filtered, persistedOps
+}
+
+func persistedKey(op *batch.Operation) string {
+	return fmt.Sprintf("%s-%d-%d", opstatus.TrackingID(op), op.TransactionTime, op.TransactionNumber)
 }
 
-func (s *OperationValidationFilter) filterInvalidSuffix(uniqueSuffix string, ops []*batch.Operation) []*batch.Operation {
+func (s *OperationValidationFilter) filterInvalidSuffix(uniqueSuffix string, ops []*batch.Operation, rejected map[*batch.Operation]string) []*batch.Operation {
 	var filtered []*batch.Operation
 	for _, op := range ops {
 		if op.UniqueSuffix != uniqueSuffix {
 			log.Infof("[%s] Rejecting invalid operation {ID: %s, UniqueSuffix: %s Type: %s, TransactionTime: %d, TransactionNumber: %d}. Reason: operation's unique suffix is not set to [%s]", s.name, op.ID, op.UniqueSuffix, op.Type, op.TransactionTime, op.TransactionNumber, uniqueSuffix)
+			rejected[op] = fmt.Sprintf("operation's unique suffix is not set to [%s]", uniqueSuffix)
 			continue
 		}
 
@@ -110,6 +166,36 @@ func (s *OperationValidationFilter) filterInvalidSuffix(uniqueSuffix string, ops
 	return filtered
 }
 
+// updateStatus records the new operations that were rejected. Status tracking doesn't affect
+// filtering so errors are only logged.
+func (s *OperationValidationFilter) updateStatus(newOps, validNewOps, persistedOps []*batch.Operation, rejected map[*batch.Operation]string) {
+	if s.statusStore == nil {
+		return
+	}
+
+	for _, op := range newOps {
+		if contains(validNewOps, op) || contains(persistedOps, op) {
+			continue
+		}
+
+		reason, ok := rejected[op]
+		if !ok {
+			reason = "operation does not apply to the current document state"
+		}
+
+		status := opstatus.NewStatus(op, batch.OperationStateRejected)
+		status.AnchorString = op.AnchorString
+		status.TransactionTime = op.TransactionTime
+		status.TransactionNumber = op.TransactionNumber
+		status.OperationIndex = op.OperationIndex
+		status.Reason = reason
+
+		if err := s.statusStore.Put(status); err != nil {
+			log.Warnf("[%s] failed to record status of operation for suffix [%s]: %s", s.name, op.UniqueSuffix, err)
+		}
+	}
+}
+
 func contains(ops []*batch.Operation, op *batch.Operation) bool {
 	for _, o := range ops {
 		if o == op {
@@ -17,6 +17,7 @@ import (
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 )
 
 func TestOperationFilter_Filter(t *testing.T) {
@@ -123,11 +124,126 @@ func TestOperationFilter_Filter(t *testing.T) {
 		deactivateOp, err := getDeactivateOperation(recoveryKey, createOp1.UniqueSuffix, 2)
 		require.NoError(t, err)
 
-		// The create should be discarded (since there's already a create) and update should be discarded since the document was deactivated
+		// The create should be discarded (since there's already a create). The update precedes the deactivate
+		// in ledger order so both are valid: the update is applied and then the document is deactivated.
 		filter := NewOperationFilter("test", store, pc)
 		validOps, err := filter.Filter(createOp1.UniqueSuffix, []*batch.Operation{createOp2, updateOp, deactivateOp})
 		require.NoError(t, err)
+		require.Len(t, validOps, 2)
+		require.True(t, validOps[0] == updateOp)
+		require.True(t, validOps[1] == deactivateOp)
+	})
+
+	t.Run("Update after deactivate operation", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+		store.Validate = false
+
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(createOp))
+
+		deactivateOp, err := getDeactivateOperation(recoveryKey, createOp.UniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp, _, err := getUpdateOperation(updateKey, createOp.UniqueSuffix, 2)
+		require.NoError(t, err)
+
+		// The update should be discarded since the document was deactivated before it
+		filter := NewOperationFilter("test", store, pc)
+		validOps, err := filter.Filter(createOp.UniqueSuffix, []*batch.Operation{updateOp, deactivateOp})
+		require.NoError(t, err)
 		require.Len(t, validOps, 1)
 		require.True(t, validOps[0] == deactivateOp)
 	})
+	t.Run("Operations already persisted", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+		store.Validate = false
+
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+		updateOp, _, err := getUpdateOperation(updateKey, createOp.UniqueSuffix, 1)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(createOp))
+		require.NoError(t, store.Put(updateOp))
+
+		statusStore := opstatus.NewMemStore()
+
+		// the same operations are processed again (e.g. when a transaction is reprocessed)
+		createCopy := *createOp
+		updateCopy := *updateOp
+
+		filter := NewOperationFilter("test", store, pc, WithOperationStatusStore(statusStore))
+		validOps, err := filter.Filter(createOp.UniqueSuffix, []*batch.Operation{&createCopy, &updateCopy})
+		require.NoError(t, err)
+		require.Empty(t, validOps)
+
+		// the skipped operations are not rejected
+		_, err = statusStore.Get(opstatus.TrackingID(updateOp))
+		require.Error(t, err)
+	})
+
+	t.Run("Rejected operation status", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+		store.Validate = false
+
+		createOp1, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+		err = store.Put(createOp1)
+		require.Nil(t, err)
+
+		// a duplicate create that was anchored in a later transaction
+		createOp2, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+		createOp2.TransactionNumber = 1
+		updateOp1, _, err := getUpdateOperation(updateKey, "123456", 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(updateKey, createOp1.UniqueSuffix, 1)
+		require.NoError(t, err)
+
+		statusStore := opstatus.NewMemStore()
+
+		filter := NewOperationFilter("test", store, pc, WithOperationStatusStore(statusStore))
+		validOps, err := filter.Filter(createOp1.UniqueSuffix, []*batch.Operation{createOp2, updateOp1, updateOp2})
+		require.NoError(t, err)
+		require.Len(t, validOps, 1)
+
+		statuses, err := statusStore.Get(opstatus.TrackingID(updateOp1))
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		require.Equal(t, batch.OperationStateRejected, statuses[0].State)
+		require.Contains(t, statuses[0].Reason, "operation's unique suffix is not set to")
+
+		statuses, err = statusStore.Get(opstatus.TrackingID(createOp2))
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		require.Equal(t, batch.OperationStateRejected, statuses[0].State)
+		require.NotEmpty(t, statuses[0].Reason)
+
+		// valid operations are not recorded by the filter
+		_, err = statusStore.Get(opstatus.TrackingID(updateOp2))
+		require.Error(t, err)
+	})
+
+	t.Run("Rejected operation status store error", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+		store.Validate = false
+
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+		updateOp, _, err := getUpdateOperation(updateKey, "123456", 1)
+		require.NoError(t, err)
+
+		filter := NewOperationFilter("test", store, pc, WithOperationStatusStore(&mockStatusStore{err: errors.New("store error")}))
+		validOps, err := filter.Filter(createOp.UniqueSuffix, []*batch.Operation{createOp, updateOp})
+		require.NoError(t, err)
+		require.Len(t, validOps, 1)
+	})
+}
+
+type mockStatusStore struct {
+	err error
+}
+
+func (m *mockStatusStore) Put(*batch.OperationStatus) error {
+	return m.err
 }
@@ -0,0 +1,293 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processor
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
+)
+
+// OperationValidator validates new operations against the current state of their documents before they are
+// anchored, using the same commitment and signature checks that the operation filter applies after they are anchored.
+//
+// The operations that were anchored by the batch writer may not have been persisted to the operation store yet,
+// so the validator keeps track of them (see Anchored) and applies them on top of the persisted operations
+// until they are persisted (see Persisted) or found in the operation store. Pending operations are dropped if their
+// anchor is abandoned (see Abandoned) or if they aren't persisted within the pending timeout (e.g. because they
+// were rejected by the observer). Expired operations are swept whenever new operations are anchored.
+type OperationValidator struct {
+	*OperationProcessor
+
+	pc             protocol.Client
+	mutex          sync.Mutex
+	pending        map[string][]*pendingOperation
+	pendingTimeout time.Duration
+}
+
+// pendingOperation is an operation that was anchored but not yet persisted to the operation store
+type pendingOperation struct {
+	*batch.Operation
+	expiry time.Time
+}
+
+const defaultPendingTimeout = time.Hour
+
+// ValidatorOption is an option for operation validator
+type ValidatorOption func(v *OperationValidator)
+
+// WithPendingTimeout sets the time after which an anchored operation that wasn't persisted to the operation store
+// is no longer applied when validating new operations
+func WithPendingTimeout(timeout time.Duration) ValidatorOption {
+	return func(v *OperationValidator) {
+		v.pendingTimeout = timeout
+	}
+}
+
+// WithProcessorOptions sets the options of the operation processor that applies the operations
+func WithProcessorOptions(opts ...Option) ValidatorOption {
+	return func(v *OperationValidator) {
+		for _, opt := range opts {
+			opt(v.OperationProcessor)
+		}
+	}
+}
+
+// documentState contains the state of a document that new operations are validated against
+type documentState struct {
+	rm      *protocol.ResolutionModel
+	created bool
+}
+
+// NewOperationValidator returns new operation validator with the given name. (Note that name is only used for logging.)
+func NewOperationValidator(name string, store OperationStoreClient, pc protocol.Client, opts ...ValidatorOption) *OperationValidator {
+	v := &OperationValidator{
+		OperationProcessor: New(name, store, pc),
+		pc:                 pc,
+		pending:            make(map[string][]*pendingOperation),
+		pendingTimeout:     defaultPendingTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Validate validates the given operations (in order) against the current state of their documents. Operations for
+// the same document must form a consistent chain, i.e. each operation is validated against the document state
+// that results from the valid operations that precede it. The invalid operations are returned along with the
+// reason they are invalid.
+func (v *OperationValidator) Validate(ops []*batch.Operation) (map[*batch.Operation]error, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	invalid := make(map[*batch.Operation]error)
+	states := make(map[string]*documentState)
+
+	for _, op := range ops {
+		state, ok := states[op.UniqueSuffix]
+		if !ok {
+			var err error
+			state, err = v.getState(op.UniqueSuffix)
+			if err != nil {
+				return nil, err
+			}
+
+			states[op.UniqueSuffix] = state
+		}
+
+		rm, err := v.apply(v.pendingOperation(op), state)
+		if err != nil {
+			log.Infof("[%s] Rejecting invalid operation {UniqueSuffix: %s, Type: %s} before it is anchored. Reason: %s", v.name, op.UniqueSuffix, op.Type, err)
+			invalid[op] = err
+			continue
+		}
+
+		state.rm = rm
+		state.created = true
+	}
+
+	return invalid, nil
+}
+
+// Anchored notifies the validator that the given operations were anchored so that new operations
+// are validated against them until they are persisted to the operation store
+func (v *OperationValidator) Anchored(ops []*batch.Operation) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	now := time.Now()
+
+	v.removeExpired(now)
+
+	expiry := now.Add(v.pendingTimeout)
+
+	for _, op := range ops {
+		v.pending[op.UniqueSuffix] = append(v.pending[op.UniqueSuffix], &pendingOperation{
+			Operation: v.pendingOperation(op),
+			expiry:    expiry,
+		})
+	}
+}
+
+// Abandoned notifies the validator that the given (previously anchored) operations will not be persisted
+// since their anchor was abandoned, so new operations are no longer validated against them
+func (v *OperationValidator) Abandoned(ops []*batch.Operation) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for _, op := range ops {
+		v.removePending(op.UniqueSuffix, func(pending *pendingOperation) bool {
+			return opstatus.TrackingID(pending.Operation) == opstatus.TrackingID(op)
+		})
+	}
+}
+
+// Persisted notifies the validator that the given (previously anchored) operations were persisted to
+// the operation store, so they no longer need to be tracked. (The observer notifies its operation
+// listener once operations are stored.)
+func (v *OperationValidator) Persisted(ops []*batch.Operation) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for _, op := range ops {
+		trackingID := opstatus.TrackingID(op)
+
+		v.removePending(op.UniqueSuffix, func(pending *pendingOperation) bool {
+			return opstatus.TrackingID(pending.Operation) == trackingID
+		})
+	}
+}
+
+// getState returns the state of the document after the persisted operations and the pending (anchored
+// but not yet persisted) operations have been applied
+func (v *OperationValidator) getState(uniqueSuffix string) (*documentState, error) {
+	ops, err := v.store.Get(uniqueSuffix)
+	if err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			return nil, err
+		}
+
+		log.Debugf("[%s] Unique suffix not found in the store [%s]", v.name, uniqueSuffix)
+	}
+
+	pending := v.removePersisted(uniqueSuffix, ops)
+
+	applied, rm := v.applyOperations(ops, make(map[*batch.Operation]error))
+
+	state := &documentState{rm: rm, created: len(applied) > 0}
+	state.rm = v.applyValid(pending, state)
+
+	return state, nil
+}
+
+// applyValid applies the given operations to the document state and skips the ones that are invalid
+func (v *OperationValidator) applyValid(ops []*batch.Operation, state *documentState) *protocol.ResolutionModel {
+	for _, op := range ops {
+		rm, err := v.apply(op, state)
+		if err != nil {
+			log.Debugf("[%s] Skipping invalid operation {UniqueSuffix: %s, Type: %s}: %s", v.name, op.UniqueSuffix, op.Type, err)
+			continue
+		}
+
+		state.rm = rm
+		state.created = true
+	}
+
+	return state.rm
+}
+
+func (v *OperationValidator) apply(op *batch.Operation, state *documentState) (*protocol.ResolutionModel, error) {
+	if op.Type != batch.OperationTypeCreate && state.rm.Doc == nil {
+		if state.created {
+			return nil, errors.New("document was deactivated")
+		}
+
+		return nil, errors.New("missing create operation")
+	}
+
+	return v.applier.Apply(op, state.rm)
+}
+
+// removePersisted removes the pending operations that were persisted to the operation store (or have expired)
+// and returns the ones that remain pending
+func (v *OperationValidator) removePersisted(uniqueSuffix string, persisted []*batch.Operation) []*batch.Operation {
+	trackingIDs := make(map[string]bool)
+	for _, op := range persisted {
+		trackingIDs[opstatus.TrackingID(op)] = true
+	}
+
+	now := time.Now()
+
+	return v.removePending(uniqueSuffix, func(op *pendingOperation) bool {
+		return trackingIDs[opstatus.TrackingID(op.Operation)] || v.expired(op, now)
+	})
+}
+
+// removeExpired removes the pending operations (for all suffixes) that weren't persisted in time
+func (v *OperationValidator) removeExpired(now time.Time) {
+	for uniqueSuffix := range v.pending {
+		v.removePending(uniqueSuffix, func(op *pendingOperation) bool {
+			return v.expired(op, now)
+		})
+	}
+}
+
+func (v *OperationValidator) expired(op *pendingOperation, now time.Time) bool {
+	if !now.After(op.expiry) {
+		return false
+	}
+
+	log.Warnf("[%s] Anchored operation {UniqueSuffix: %s, Type: %s} wasn't persisted in time. It's no longer applied when validating new operations.", v.name, op.UniqueSuffix, op.Type)
+
+	return true
+}
+
+// removePending removes the pending operations for the given suffix that match the given function
+// and returns the ones that remain pending
+func (v *OperationValidator) removePending(uniqueSuffix string, remove func(op *pendingOperation) bool) []*batch.Operation {
+	pending, ok := v.pending[uniqueSuffix]
+	if !ok {
+		return nil
+	}
+
+	var remaining []*pendingOperation
+	var ops []*batch.Operation
+	for _, op := range pending {
+		if !remove(op) {
+			remaining = append(remaining, op)
+			ops = append(ops, op.Operation)
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(v.pending, uniqueSuffix)
+	} else {
+		v.pending[uniqueSuffix] = remaining
+	}
+
+	return ops
+}
+
+// pendingOperation returns a copy of the given operation that will be validated using the
+// current protocol version, since its transaction time is not known until it's anchored
+func (v *OperationValidator) pendingOperation(op *batch.Operation) *batch.Operation {
+	pending := *op
+	pending.TransactionTime = uint64(v.pc.Current().StartingBlockChainTime)
+	pending.TransactionNumber = 0
+
+	return &pending
+}
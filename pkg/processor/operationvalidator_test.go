@@ -0,0 +1,265 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package processor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
+)
+
+func TestOperationValidator_Validate(t *testing.T) {
+	recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pc := mocks.NewMockProtocolClient()
+
+	t.Run("Store error", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(errors.New("injected store error"))
+
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+		invalid, err := validator.Validate([]*batch.Operation{createOp})
+		require.EqualError(t, err, "injected store error")
+		require.Nil(t, invalid)
+	})
+
+	t.Run("Create operation", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+		invalid, err := validator.Validate([]*batch.Operation{createOp})
+		require.NoError(t, err)
+		require.Empty(t, invalid)
+	})
+
+	t.Run("Missing create operation", func(t *testing.T) {
+		store := mocks.NewMockOperationStore(nil)
+
+		updateOp, _, err := getUpdateOperation(updateKey, "unknown", 1)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+		invalid, err := validator.Validate([]*batch.Operation{updateOp})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.EqualError(t, invalid[updateOp], "missing create operation")
+	})
+
+	t.Run("Stale update key", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		staleKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		updateOp, _, err := getUpdateOperation(staleKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+		invalid, err := validator.Validate([]*batch.Operation{updateOp})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.Contains(t, invalid[updateOp].Error(), "commitment")
+	})
+
+	t.Run("Operations in batch form a chain", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+		// the update key was already used by the first update
+		updateOp3, _, err := getUpdateOperation(updateKey, uniqueSuffix, 3)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+		invalid, err := validator.Validate([]*batch.Operation{updateOp1, updateOp2, updateOp3})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.NotNil(t, invalid[updateOp3])
+	})
+
+	t.Run("Document was deactivated", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		deactivateOp, err := getDeactivateOperation(recoveryKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(deactivateOp))
+
+		recoverOp, _, err := getRecoverOperation(recoveryKey, updateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+		invalid, err := validator.Validate([]*batch.Operation{recoverOp})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.EqualError(t, invalid[recoverOp], "document was deactivated")
+	})
+
+	t.Run("Anchored operations", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+
+		invalid, err := validator.Validate([]*batch.Operation{updateOp1})
+		require.NoError(t, err)
+		require.Empty(t, invalid)
+
+		validator.Anchored([]*batch.Operation{updateOp1})
+
+		// the second update is validated against the first update even though it hasn't been persisted yet
+		invalid, err = validator.Validate([]*batch.Operation{updateOp2})
+		require.NoError(t, err)
+		require.Empty(t, invalid)
+		require.Len(t, validator.pending[uniqueSuffix], 1)
+
+		// the anchored operation is no longer pending after it has been persisted
+		require.NoError(t, store.Put(updateOp1))
+
+		invalid, err = validator.Validate([]*batch.Operation{updateOp2})
+		require.NoError(t, err)
+		require.Empty(t, invalid)
+		require.Empty(t, validator.pending)
+	})
+
+	t.Run("Abandoned operations", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+
+		validator.Anchored([]*batch.Operation{updateOp1})
+		validator.Abandoned([]*batch.Operation{updateOp1})
+		require.Empty(t, validator.pending)
+
+		// the second update is no longer validated against the first update since its anchor was abandoned
+		invalid, err := validator.Validate([]*batch.Operation{updateOp2})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.Contains(t, invalid[updateOp2].Error(), "commitment")
+
+		// the first update is valid again
+		invalid, err = validator.Validate([]*batch.Operation{updateOp1})
+		require.NoError(t, err)
+		require.Empty(t, invalid)
+	})
+
+	t.Run("Expired operations", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc, WithPendingTimeout(50*time.Millisecond))
+
+		validator.Anchored([]*batch.Operation{updateOp1})
+
+		invalid, err := validator.Validate([]*batch.Operation{updateOp2})
+		require.NoError(t, err)
+		require.Empty(t, invalid)
+
+		// the first update was never persisted (e.g. it was rejected by the observer)
+		time.Sleep(100 * time.Millisecond)
+
+		invalid, err = validator.Validate([]*batch.Operation{updateOp2})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.Empty(t, validator.pending)
+	})
+
+	t.Run("Expired operations are swept when operations are anchored", func(t *testing.T) {
+		const suffixes = 10
+
+		validator := NewOperationValidator("test", mocks.NewMockOperationStore(nil), pc, WithPendingTimeout(50*time.Millisecond))
+
+		for i := 0; i < suffixes; i++ {
+			validator.Anchored([]*batch.Operation{{UniqueSuffix: fmt.Sprintf("suffix-%d", i), Type: batch.OperationTypeUpdate}})
+		}
+
+		require.Len(t, validator.pending, suffixes)
+
+		// none of the suffixes are validated again
+		time.Sleep(100 * time.Millisecond)
+
+		op := &batch.Operation{UniqueSuffix: "other", Type: batch.OperationTypeUpdate}
+		validator.Anchored([]*batch.Operation{op})
+
+		require.Len(t, validator.pending, 1)
+		require.Contains(t, validator.pending, "other")
+
+		validator.Persisted([]*batch.Operation{op})
+		require.Empty(t, validator.pending)
+	})
+
+	t.Run("Persisted operations", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		validator := NewOperationValidator("test", store, pc)
+
+		validator.Anchored([]*batch.Operation{updateOp1, updateOp2})
+		require.Len(t, validator.pending[uniqueSuffix], 2)
+
+		// the observer stores the operations with their transaction time
+		persisted := *updateOp1
+		persisted.TransactionTime = 10
+		persisted.TransactionNumber = 5
+
+		validator.Persisted([]*batch.Operation{&persisted})
+		require.Len(t, validator.pending[uniqueSuffix], 1)
+		require.Equal(t, opstatus.TrackingID(updateOp2), opstatus.TrackingID(validator.pending[uniqueSuffix][0].Operation))
+	})
+
+	t.Run("Processor options", func(t *testing.T) {
+		createOp, err := getCreateOperation(recoveryKey, updateKey)
+		require.NoError(t, err)
+
+		applier := &mockApplier{err: errors.New("apply error")}
+
+		validator := NewOperationValidator("test", mocks.NewMockOperationStore(nil), pc,
+			WithProcessorOptions(WithOperationApplier(applier)))
+
+		invalid, err := validator.Validate([]*batch.Operation{createOp})
+		require.NoError(t, err)
+		require.Len(t, invalid, 1)
+		require.EqualError(t, invalid[createOp], "apply error")
+	})
+}
@@ -7,7 +7,6 @@ SPDX-License-Identifier: Apache-2.0
 package processor
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -16,20 +15,21 @@ import (
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
-	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
 	"github.com/trustbloc/sidetree-core-go/pkg/composer"
 	"github.com/trustbloc/sidetree-core-go/pkg/document"
-	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
-	internal "github.com/trustbloc/sidetree-core-go/pkg/internal/jws"
-	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+	"github.com/trustbloc/sidetree-core-go/pkg/operationapplier"
 )
 
 // OperationProcessor will process document operations in chronological order and create final document during resolution.
 // It uses operation store client to retrieve all operations that are related to requested document.
 type OperationProcessor struct {
-	name  string
-	store OperationStoreClient
-	pc    protocol.Client
+	name    string
+	store   OperationStoreClient
+	applier protocol.OperationApplier
+	// statusStore is used by the operation filter to record rejected operations (and by History to read them)
+	statusStore OperationStatusStore
+	// stateStore is optional. If set then the latest version of documents is resolved from their materialized state.
+	stateStore DocumentStateStore
 }
 
 // OperationStoreClient defines interface for retrieving all operations related to document
@@ -38,347 +38,247 @@ type OperationStoreClient interface {
 	Get(uniqueSuffix string) ([]*batch.Operation, error)
 }
 
+// Option is an option for operation processor
+type Option func(opts *OperationProcessor)
+
 // New returns new operation processor with the given name. (Note that name is only used for logging.)
-func New(name string, store OperationStoreClient, pc protocol.Client) *OperationProcessor {
-	return &OperationProcessor{name: name, store: store, pc: pc}
+// By default, operations are applied using the protocol parameters (from the given protocol client)
+// that apply at the operation's transaction time.
+func New(name string, store OperationStoreClient, pc protocol.Client, opts ...Option) *OperationProcessor {
+	s := &OperationProcessor{
+		name:    name,
+		store:   store,
+		applier: operationapplier.New(pc, composer.New()),
+	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// WithOperationApplier sets the operation applier, e.g. an applier that selects the
+// protocol version implementation based on the operation's transaction time
+func WithOperationApplier(applier protocol.OperationApplier) Option {
+	return func(opts *OperationProcessor) {
+		opts.applier = applier
+	}
 }
 
 // Resolve document based on the given unique suffix
 // Parameters:
 // uniqueSuffix - unique portion of ID to resolve. for example "abc123" in "did:sidetree:abc123"
-func (s *OperationProcessor) Resolve(uniqueSuffix string) (*document.ResolutionResult, error) {
+// opts - resolution options, e.g. to resolve a previous version of the document (by default the latest version is resolved)
+func (s *OperationProcessor) Resolve(uniqueSuffix string, opts ...document.ResolutionOption) (*document.ResolutionResult, error) {
+	options := document.GetResolutionOptions(opts...)
+
 	ops, err := s.store.Get(uniqueSuffix)
 	if err != nil {
 		return nil, err
 	}
 
-	sortOperations(ops)
-
 	log.Debugf("[%s] Found %d operations for unique suffix [%s]: %+v", s.name, len(ops), uniqueSuffix, ops)
 
-	rm := &resolutionModel{}
-
-	// split operations info 'full' and 'update' operations
-	fullOps, updateOps := splitOperations(ops)
-	if len(fullOps) == 0 {
-		return nil, errors.New("missing create operation")
+	if options.VersionTime == nil && options.VersionID == "" && s.stateStore != nil {
+		return s.resolveFromState(uniqueSuffix, ops)
 	}
 
-	// apply 'full' operations first
-	rm, err = s.applyOperations(fullOps, rm)
-	if err != nil {
-		return nil, err
+	if options.VersionTime != nil {
+		ops = getOpsUntil(ops, *options.VersionTime)
+		if len(ops) == 0 {
+			return nil, fmt.Errorf("document not found at version time %d", *options.VersionTime)
+		}
 	}
 
-	if rm.Doc == nil {
-		return nil, errors.New("document was deactivated")
-	}
+	rejected := make(map[*batch.Operation]error)
 
-	// next apply update ops since last 'full' transaction
-	rm, err = s.applyOperations(getOpsWithTxnGreaterThan(updateOps, rm.LastOperationTransactionTime, rm.LastOperationTransactionNumber), rm)
-	if err != nil {
-		return nil, err
+	applied, rm := s.applyOperationsFrom(&protocol.ResolutionModel{}, ops, rejected, isVersion(options.VersionID))
+	if len(applied) == 0 {
+		return nil, createError(ops, rejected)
 	}
 
-	return &document.ResolutionResult{
-		Document: rm.Doc,
-		MethodMetadata: document.MethodMetadata{
-			RecoveryCommitment: rm.RecoveryCommitment,
-			UpdateCommitment:   rm.UpdateCommitment,
-		},
-	}, nil
-}
+	last := applied[len(applied)-1]
 
-func splitOperations(ops []*batch.Operation) (fullOps, updateOps []*batch.Operation) {
-	for _, op := range ops {
-		if op.Type == batch.OperationTypeUpdate {
-			updateOps = append(updateOps, op)
-		} else { // Create, Recover, deactivate
-			fullOps = append(fullOps, op)
-		}
+	if options.VersionID != "" && versionID(last) != options.VersionID {
+		return nil, fmt.Errorf("version [%s] not found", options.VersionID)
 	}
 
-	return fullOps, updateOps
+	return resolutionResult(rm, versionID(last))
 }
 
-// pre-condition: operations have to be sorted
-func getOpsWithTxnGreaterThan(ops []*batch.Operation, txnTime, txnNumber uint64) []*batch.Operation {
-	for index, op := range ops {
-		if op.TransactionTime < txnTime {
-			continue
-		}
+// resolveFromState resolves the latest version of the document from its materialized state (if any)
+// and the given operations that weren't considered when the state was materialized
+func (s *OperationProcessor) resolveFromState(uniqueSuffix string, ops []*batch.Operation) (*document.ResolutionResult, error) {
+	rejected := make(map[*batch.Operation]error)
 
-		if op.TransactionTime > txnTime {
-			return ops[index:]
-		}
-
-		if op.TransactionNumber > txnNumber {
-			return ops[index:]
-		}
+	state, err := s.currentState(uniqueSuffix, ops, rejected)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
-
-func (s *OperationProcessor) applyOperations(ops []*batch.Operation, rm *resolutionModel) (*resolutionModel, error) {
-	var err error
-
-	for _, op := range ops {
-		if rm, err = s.applyOperation(op, rm); err != nil {
-			return nil, err
-		}
-
-		log.Debugf("[%s] After applying op %+v, New doc: %s", s.name, op, rm.Doc)
+	if len(state.AppliedOperations) == 0 {
+		return nil, createError(ops, rejected)
 	}
 
-	return rm, nil
-}
-
-type resolutionModel struct {
-	Doc                            document.Document
-	LastOperationTransactionTime   uint64
-	LastOperationTransactionNumber uint64
-	UpdateCommitment               string
-	RecoveryCommitment             string
+	return resolutionResult(&state.ResolutionModel, state.AppliedOperations[len(state.AppliedOperations)-1])
 }
 
-func (s *OperationProcessor) applyOperation(operation *batch.Operation, rm *resolutionModel) (*resolutionModel, error) {
-	switch operation.Type {
-	case batch.OperationTypeCreate:
-		return s.applyCreateOperation(operation, rm)
-	case batch.OperationTypeUpdate:
-		return s.applyUpdateOperation(operation, rm)
-	case batch.OperationTypeDeactivate:
-		return s.applyDeactivateOperation(operation, rm)
-	case batch.OperationTypeRecover:
-		return s.applyRecoverOperation(operation, rm)
-	default:
-		return nil, errors.New("operation type not supported for process operation")
-	}
-}
-
-func (s *OperationProcessor) applyCreateOperation(operation *batch.Operation, rm *resolutionModel) (*resolutionModel, error) {
-	log.Debugf("[%s] Applying create operation: %+v", s.name, operation)
-
-	if rm.Doc != nil {
-		return nil, errors.New("create has to be the first operation")
-	}
-
-	doc, err := composer.ApplyPatches(make(document.Document), operation.Delta.Patches)
-	if err != nil {
-		return nil, err
+func resolutionResult(rm *protocol.ResolutionModel, versionID string) (*document.ResolutionResult, error) {
+	if rm.Doc == nil {
+		return nil, errors.New("document was deactivated")
 	}
 
-	return &resolutionModel{
-		Doc:                            doc,
-		LastOperationTransactionTime:   operation.TransactionTime,
-		LastOperationTransactionNumber: operation.TransactionNumber,
-		UpdateCommitment:               operation.Delta.UpdateCommitment,
-		RecoveryCommitment:             operation.SuffixData.RecoveryCommitment,
+	return &document.ResolutionResult{
+		Document: rm.Doc,
+		MethodMetadata: document.MethodMetadata{
+			RecoveryCommitment: rm.RecoveryCommitment,
+			UpdateCommitment:   rm.UpdateCommitment,
+			VersionID:          versionID,
+			VersionTime:        rm.LastOperationTransactionTime,
+		},
 	}, nil
 }
 
-func (s *OperationProcessor) applyUpdateOperation(operation *batch.Operation, rm *resolutionModel) (*resolutionModel, error) { //nolint:dupl
-	log.Debugf("[%s] Applying update operation: %+v", s.name, operation)
-
-	if rm.Doc == nil {
-		return nil, errors.New("update cannot be first operation")
-	}
-
-	jwsParts, err := parseSignedData(operation.SignedData)
-	if err != nil {
-		return nil, err
-	}
+// applyOperations applies the given operations by following the commitment chain of the document. The first valid
+// create operation (in ledger order) is applied first. Then the next operation to be applied is the first operation
+// (in ledger order) that is valid for the current document state, i.e. an update whose reveal value matches the
+// current update commitment or a recover or deactivate whose reveal value matches the current recovery commitment.
+// This is repeated until no more operations can be applied (or the document is deactivated).
+//
+// The applied operations are returned (in the order they were applied) along with the resulting resolution model.
+// The reason that each operation could not be applied (when it was last tried) is added to rejected.
+func (s *OperationProcessor) applyOperations(ops []*batch.Operation, rejected map[*batch.Operation]error) ([]*batch.Operation, *protocol.ResolutionModel) {
+	return s.applyOperationsFrom(&protocol.ResolutionModel{}, ops, rejected, nil)
+}
 
-	var signedDataModel model.UpdateSignedDataModel
-	err = json.Unmarshal(jwsParts.Payload, &signedDataModel)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal signed data model while applying update: %s", err.Error())
-	}
+// applyOperationsFrom applies the given operations (see applyOperations) to the given resolution model and stops
+// after applying the operation for which last returns true (if last is not nil)
+func (s *OperationProcessor) applyOperationsFrom(rm *protocol.ResolutionModel, ops []*batch.Operation, rejected map[*batch.Operation]error, last func(op *batch.Operation) bool) ([]*batch.Operation, *protocol.ResolutionModel) {
+	remaining := make([]*batch.Operation, len(ops))
+	copy(remaining, ops)
 
-	// TODO: protocol should be calculated based on transaction number
-	p := s.pc.Current()
+	sortOperations(remaining)
 
-	updateCommitment, err := commitment.Calculate(signedDataModel.UpdateKey, p.HashAlgorithmInMultiHashCode)
-	if err != nil {
-		return nil, err
-	}
+	var applied []*batch.Operation
+	for {
+		next, m := s.nextOperation(remaining, rm, rejected)
+		if next == -1 {
+			break
+		}
 
-	// verify that update commitments match
-	if updateCommitment != rm.UpdateCommitment {
-		return nil, fmt.Errorf("commitment generated from update key doesn't match update commitment: [%s][%s]", updateCommitment, rm.UpdateCommitment)
-	}
+		op := remaining[next]
 
-	// verify the delta against the signed delta hash
-	err = isValidHash(operation.EncodedDelta, signedDataModel.DeltaHash)
-	if err != nil {
-		return nil, fmt.Errorf("update delta doesn't match delta hash: %s", err.Error())
-	}
+		applied = append(applied, op)
+		rm = m
 
-	// verify signature
-	_, err = internal.VerifyJWS(operation.SignedData, signedDataModel.UpdateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check signature: %s", err.Error())
-	}
+		log.Debugf("[%s] After applying op %+v, New doc: %s", s.name, op, rm.Doc)
 
-	doc, err := composer.ApplyPatches(rm.Doc, operation.Delta.Patches)
-	if err != nil {
-		return nil, err
-	}
+		remaining = append(remaining[:next], remaining[next+1:]...)
 
-	return &resolutionModel{
-		Doc:                            doc,
-		LastOperationTransactionTime:   operation.TransactionTime,
-		LastOperationTransactionNumber: operation.TransactionNumber,
-		UpdateCommitment:               operation.Delta.UpdateCommitment,
-		RecoveryCommitment:             rm.RecoveryCommitment}, nil
-}
+		if rm.Doc == nil {
+			log.Debugf("[%s] Document was deactivated", s.name)
+			break
+		}
 
-func parseSignedData(compactJWS string) (*internal.JSONWebSignature, error) {
-	if compactJWS == "" {
-		return nil, errors.New("missing signed data")
+		if last != nil && last(op) {
+			break
+		}
 	}
 
-	return internal.ParseJWS(compactJWS)
+	return applied, rm
 }
 
-func (s *OperationProcessor) applyDeactivateOperation(operation *batch.Operation, rm *resolutionModel) (*resolutionModel, error) {
-	log.Debugf("[%s] Applying deactivate operation: %+v", s.name, operation)
-
-	if rm.Doc == nil {
-		return nil, errors.New("deactivate can only be applied to an existing document")
-	}
-
-	jwsParts, err := parseSignedData(operation.SignedData)
-	if err != nil {
-		return nil, err
-	}
-
-	var signedDataModel model.DeactivateSignedDataModel
-	err = json.Unmarshal(jwsParts.Payload, &signedDataModel)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal signed data model while applying deactivate: %s", err.Error())
-	}
-
-	// verify signed did suffix against actual did suffix
-	if operation.UniqueSuffix != signedDataModel.DidSuffix {
-		return nil, errors.New("did suffix doesn't match signed value")
-	}
-
-	// TODO: protocol should be calculated based on transaction number
-	p := s.pc.Current()
-
-	recoveryCommitment, err := commitment.Calculate(signedDataModel.RecoveryKey, p.HashAlgorithmInMultiHashCode)
-	if err != nil {
-		return nil, err
-	}
+// nextOperation returns the index of the first of the given (sorted) operations that can be applied to the
+// given resolution model along with the resulting resolution model. -1 is returned if none can be applied.
+func (s *OperationProcessor) nextOperation(ops []*batch.Operation, rm *protocol.ResolutionModel, rejected map[*batch.Operation]error) (int, *protocol.ResolutionModel) {
+	for i, op := range ops {
+		// the first operation has to be a create operation
+		if rm.Doc == nil && op.Type != batch.OperationTypeCreate {
+			continue
+		}
 
-	// verify that recovery commitments match
-	if recoveryCommitment != rm.RecoveryCommitment {
-		return nil, fmt.Errorf("commitment generated from recovery key doesn't match recovery commitment: [%s][%s]", recoveryCommitment, rm.RecoveryCommitment)
-	}
+		m, err := s.applier.Apply(op, rm)
+		if err != nil {
+			log.Debugf("[%s] Operation {UniqueSuffix: %s, Type: %s, TransactionTime: %d, TransactionNumber: %d} can't be applied: %s", s.name, op.UniqueSuffix, op.Type, op.TransactionTime, op.TransactionNumber, err)
+			rejected[op] = err
+			continue
+		}
 
-	// verify signature
-	_, err = internal.VerifyJWS(operation.SignedData, signedDataModel.RecoveryKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check signature: %s", err.Error())
+		return i, m
 	}
 
-	return &resolutionModel{
-		Doc:                            nil,
-		LastOperationTransactionTime:   operation.TransactionTime,
-		LastOperationTransactionNumber: operation.TransactionNumber,
-		UpdateCommitment:               "",
-		RecoveryCommitment:             ""}, nil
+	return -1, nil
 }
 
-func (s *OperationProcessor) applyRecoverOperation(operation *batch.Operation, rm *resolutionModel) (*resolutionModel, error) { //nolint:dupl
-	log.Debugf("[%s] Applying recover operation: %+v", s.name, operation)
+// createError returns the reason why none of the given operations could be applied
+func createError(ops []*batch.Operation, rejected map[*batch.Operation]error) error {
+	var err error
 
-	if rm.Doc == nil {
-		return nil, errors.New("recover can only be applied to an existing document")
-	}
+	for _, op := range ops {
+		if op.Type != batch.OperationTypeCreate {
+			continue
+		}
 
-	jwsParts, err := parseSignedData(operation.SignedData)
-	if err != nil {
-		return nil, err
+		if e, ok := rejected[op]; ok {
+			err = e
+		}
 	}
 
-	var signedDataModel model.RecoverSignedDataModel
-	err = json.Unmarshal(jwsParts.Payload, &signedDataModel)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal signed data model while applying recover: %s", err.Error())
+	if err == nil {
+		return errors.New("missing create operation")
 	}
 
-	// TODO: protocol should be calculated based on transaction number
-	p := s.pc.Current()
+	return err
+}
 
-	recoveryCommitment, err := commitment.Calculate(signedDataModel.RecoveryKey, p.HashAlgorithmInMultiHashCode)
-	if err != nil {
-		return nil, err
-	}
+// getOpsUntil returns the operations that were anchored at or before the given ledger time
+func getOpsUntil(ops []*batch.Operation, txnTime uint64) []*batch.Operation {
+	var filtered []*batch.Operation
 
-	// verify that recovery commitments match
-	if recoveryCommitment != rm.RecoveryCommitment {
-		return nil, fmt.Errorf("commitment generated from recovery key doesn't match recovery commitment: [%s][%s]", recoveryCommitment, rm.RecoveryCommitment)
+	for _, op := range ops {
+		if op.TransactionTime <= txnTime {
+			filtered = append(filtered, op)
+		}
 	}
 
-	// verify the delta against the signed delta hash
-	err = isValidHash(operation.EncodedDelta, signedDataModel.DeltaHash)
-	if err != nil {
-		return nil, fmt.Errorf("recover delta doesn't match delta hash: %s", err.Error())
-	}
+	return filtered
+}
 
-	// verify signature
-	_, err = internal.VerifyJWS(operation.SignedData, signedDataModel.RecoveryKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check signature: %s", err.Error())
+// isVersion returns a function that returns true for the operation with the given version ID
+// (nil is returned if the version ID is empty)
+func isVersion(id string) func(op *batch.Operation) bool {
+	if id == "" {
+		return nil
 	}
 
-	doc, err := composer.ApplyPatches(make(document.Document), operation.Delta.Patches)
-	if err != nil {
-		return nil, err
+	return func(op *batch.Operation) bool {
+		return versionID(op) == id
 	}
-
-	return &resolutionModel{
-		Doc:                            doc,
-		LastOperationTransactionTime:   operation.TransactionTime,
-		LastOperationTransactionNumber: operation.TransactionNumber,
-		UpdateCommitment:               operation.Delta.UpdateCommitment,
-		RecoveryCommitment:             signedDataModel.RecoveryCommitment}, nil
 }
 
-func isValidHash(encodedContent, encodedMultihash string) error {
-	content, err := docutil.DecodeString(encodedContent)
-	if err != nil {
-		return err
-	}
+func versionID(op *batch.Operation) string {
+	return document.VersionID(op.TransactionNumber, op.OperationIndex)
+}
 
-	code, err := docutil.GetMultihashCode(encodedMultihash)
-	if err != nil {
-		return err
-	}
+// sortOperations sorts the given operations in ledger order, i.e. by transaction time,
+// transaction number and operation index
+func sortOperations(ops []*batch.Operation) {
+	sort.SliceStable(ops, func(i, j int) bool {
+		return isBefore(ops[i], ops[j])
+	})
+}
 
-	computedMultihash, err := docutil.ComputeMultihash(uint(code), content)
-	if err != nil {
-		return err
+// isBefore returns true if op1 comes before op2 in ledger order
+func isBefore(op1, op2 *batch.Operation) bool {
+	if op1.TransactionTime != op2.TransactionTime {
+		return op1.TransactionTime < op2.TransactionTime
 	}
 
-	encodedComputedMultihash := docutil.EncodeToString(computedMultihash)
-
-	if encodedComputedMultihash != encodedMultihash {
-		return errors.New("supplied hash doesn't match original content")
+	if op1.TransactionNumber != op2.TransactionNumber {
+		return op1.TransactionNumber < op2.TransactionNumber
 	}
 
-	return nil
-}
-
-func sortOperations(ops []*batch.Operation) {
-	sort.Slice(ops, func(i, j int) bool {
-		if ops[i].TransactionTime < ops[j].TransactionTime {
-			return true
-		}
-
-		return ops[i].TransactionNumber < ops[j].TransactionNumber
-	})
+	return op1.OperationIndex < op2.OperationIndex
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
 	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
 	"github.com/trustbloc/sidetree-core-go/pkg/document"
 	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
@@ -99,6 +100,24 @@ func TestResolve(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "expected array")
 	})
+
+	t.Run("operation applier option", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		p := New("test", store, pc, WithOperationApplier(&mockApplier{err: errors.New("applier error")}))
+		doc, err := p.Resolve(uniqueSuffix)
+		require.Nil(t, doc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "applier error")
+	})
+}
+
+type mockApplier struct {
+	err error
+}
+
+func (m *mockApplier) Apply(*batch.Operation, *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	return nil, m.err
 }
 
 func TestUpdateDocument(t *testing.T) {
@@ -142,6 +161,64 @@ func TestUpdateDocument(t *testing.T) {
 		require.Equal(t, "special2", didDoc["test"])
 	})
 
+	t.Run("protocol version is selected by transaction time", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		v1 := pc.Protocol
+		v2 := pc.Protocol
+		v2.StartingBlockChainTime = 100
+		v2.HashAlgorithmInMultiHashCode = 55
+
+		versionedPC := mocks.NewMockProtocolClient()
+		versionedPC.Versions = []protocol.Protocol{v1, v2}
+
+		// update anchored before the new protocol version applies the original hash algorithm
+		op, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		op.TransactionTime = 99
+		require.NoError(t, store.Put(op))
+
+		p := New("test", store, versionedPC)
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special1", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+
+		// update anchored after the new protocol version applies the new (unsupported) hash algorithm
+		op, _, err = getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+		op.TransactionTime = 100
+		require.NoError(t, store.Put(op))
+
+		result, err = p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special1", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+
+		// the second update is not applied
+		requireRejected(t, p, store, uniqueSuffix, op, "algorithm not supported")
+	})
+
+	t.Run("protocol version not defined for transaction time error", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		v := pc.Protocol
+		v.StartingBlockChainTime = 10
+
+		versionedPC := mocks.NewMockProtocolClient()
+		versionedPC.Versions = []protocol.Protocol{v}
+
+		op, _, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(op))
+
+		p := New("test", store, versionedPC)
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, op, "protocol parameters are not defined for transaction time [0]")
+	})
+
 	t.Run("missing signed data error", func(t *testing.T) {
 		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
 
@@ -155,9 +232,11 @@ func TestUpdateDocument(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "missing signed data")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, updateOp, "missing signed data")
 	})
 
 	t.Run("unmarshal signed data model error", func(t *testing.T) {
@@ -178,9 +257,11 @@ func TestUpdateDocument(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "failed to unmarshal signed data model while applying update")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, updateOp, "failed to unmarshal signed data model while applying update")
 	})
 
 	t.Run("invalid update commitment error", func(t *testing.T) {
@@ -193,9 +274,11 @@ func TestUpdateDocument(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "commitment generated from update key doesn't match update commitment")
-		require.Nil(t, doc)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, updateOp, "commitment generated from update key doesn't match update commitment")
 	})
 
 	t.Run("invalid signature error", func(t *testing.T) {
@@ -214,9 +297,11 @@ func TestUpdateDocument(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "ecdsa: invalid signature")
-		require.Nil(t, doc)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, updateOp, "ecdsa: invalid signature")
 	})
 
 	t.Run("delta hash doesn't match delta error", func(t *testing.T) {
@@ -232,9 +317,11 @@ func TestUpdateDocument(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "update delta doesn't match delta hash")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, updateOp, "update delta doesn't match delta hash")
 	})
 }
 
@@ -280,9 +367,18 @@ func TestProcessOperation(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(createOp.UniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Equal(t, "create has to be the first operation", err.Error())
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the second create is not applied
+		ops, err := store.Get(createOp.UniqueSuffix)
+		require.NoError(t, err)
+
+		rejected := make(map[*batch.Operation]error)
+
+		applied, _ := p.applyOperations(ops, rejected)
+		require.Len(t, applied, 1)
+		require.EqualError(t, rejected[createOp], "create has to be the first operation")
 	})
 
 	t.Run("recover after deactivate error", func(t *testing.T) {
@@ -301,7 +397,7 @@ func TestProcessOperation(t *testing.T) {
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "recover can only be applied to an existing document")
+		require.Contains(t, err.Error(), "document was deactivated")
 		require.Nil(t, doc)
 	})
 
@@ -311,37 +407,24 @@ func TestProcessOperation(t *testing.T) {
 		createOp, err := getCreateOperation(recoveryKey, updateKey)
 		require.NoError(t, err)
 
-		createOp.Type = "invalid"
-
 		// store create operation
 		err = store.Put(createOp)
 		require.Nil(t, err)
 
-		p := New("test", store, pc)
-		doc, err := p.Resolve(createOp.UniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Equal(t, "operation type not supported for process operation", err.Error())
-	})
-}
-
-func TestIsValidHashErrors(t *testing.T) {
-	multihash, err := docutil.ComputeMultihash(sha2_256, []byte("test"))
-	require.NoError(t, err)
-
-	encodedMultihash := docutil.EncodeToString(multihash)
+		invalidOp := *createOp
+		invalidOp.Type = "invalid"
+		invalidOp.TransactionNumber = 1
 
-	err = isValidHash("hello", encodedMultihash)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "illegal base64 data at input byte 4")
+		err = store.Put(&invalidOp)
+		require.Nil(t, err)
 
-	err = isValidHash(docutil.EncodeToString([]byte("content")), string(multihash))
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "illegal base64 data at input byte 0")
+		p := New("test", store, pc)
+		doc, err := p.Resolve(createOp.UniqueSuffix)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
 
-	err = isValidHash(docutil.EncodeToString([]byte("content")), encodedMultihash)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "supplied hash doesn't match original content")
+		requireRejected(t, p, store, createOp.UniqueSuffix, &invalidOp, "operation type not supported for process operation")
+	})
 }
 
 func TestDeactivate(t *testing.T) {
@@ -374,7 +457,7 @@ func TestDeactivate(t *testing.T) {
 		require.Contains(t, err.Error(), "document was deactivated")
 		require.Nil(t, doc)
 
-		// deactivate same document again - error
+		// deactivate same document again - the document remains deactivated
 		deactivateOp, err = getDeactivateOperation(recoveryKey, uniqueSuffix, 2)
 		require.NoError(t, err)
 		err = store.Put(deactivateOp)
@@ -382,7 +465,7 @@ func TestDeactivate(t *testing.T) {
 
 		doc, err = p.Resolve(uniqueSuffix)
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "deactivate can only be applied to an existing document")
+		require.Contains(t, err.Error(), "document was deactivated")
 		require.Nil(t, doc)
 	})
 
@@ -397,7 +480,7 @@ func TestDeactivate(t *testing.T) {
 		p := New("test", store, pc)
 		doc, err := p.Resolve(dummyUniqueSuffix)
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "deactivate can only be applied to an existing document")
+		require.Contains(t, err.Error(), "missing create operation")
 		require.Nil(t, doc)
 	})
 
@@ -414,9 +497,11 @@ func TestDeactivate(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "missing signed data")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, deactivateOp, "missing signed data")
 	})
 
 	t.Run("unmarshal signed data model error", func(t *testing.T) {
@@ -437,9 +522,11 @@ func TestDeactivate(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "failed to unmarshal signed data model while applying deactivate")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, deactivateOp, "failed to unmarshal signed data model while applying deactivate")
 	})
 
 	t.Run("invalid signature error", func(t *testing.T) {
@@ -457,9 +544,11 @@ func TestDeactivate(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "ecdsa: invalid signature")
-		require.Nil(t, doc)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, deactivateOp, "ecdsa: invalid signature")
 	})
 
 	t.Run("did suffix doesn't match signed value error", func(t *testing.T) {
@@ -483,9 +572,11 @@ func TestDeactivate(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "did suffix doesn't match signed value")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, deactivateOp, "did suffix doesn't match signed value")
 	})
 
 	t.Run("deactivate recovery reveal value doesn't match recovery commitment", func(t *testing.T) {
@@ -509,9 +600,11 @@ func TestDeactivate(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "commitment generated from recovery key doesn't match recovery commitment")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, deactivateOp, "commitment generated from recovery key doesn't match recovery commitment")
 	})
 }
 
@@ -567,9 +660,11 @@ func TestRecover(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "missing signed data")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, recoverOp, "missing signed data")
 	})
 
 	t.Run("unmarshal signed data model error", func(t *testing.T) {
@@ -590,9 +685,11 @@ func TestRecover(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "failed to unmarshal signed data model while applying recover")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, recoverOp, "failed to unmarshal signed data model while applying recover")
 	})
 
 	t.Run("invalid signature error", func(t *testing.T) {
@@ -610,9 +707,11 @@ func TestRecover(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "ecdsa: invalid signature")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, recoverOp, "ecdsa: invalid signature")
 	})
 
 	t.Run("invalid recovery commitment error", func(t *testing.T) {
@@ -636,9 +735,11 @@ func TestRecover(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "commitment generated from recovery key doesn't match recovery commitment")
-		require.Nil(t, doc)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, op, "commitment generated from recovery key doesn't match recovery commitment")
 	})
 	t.Run("delta hash doesn't match delta error", func(t *testing.T) {
 		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
@@ -653,33 +754,208 @@ func TestRecover(t *testing.T) {
 
 		p := New("test", store, pc)
 		doc, err := p.Resolve(uniqueSuffix)
-		require.Error(t, err)
-		require.Nil(t, doc)
-		require.Contains(t, err.Error(), "recover delta doesn't match delta hash")
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+
+		// the invalid operation is not applied
+		requireRejected(t, p, store, uniqueSuffix, recoverOp, "recover delta doesn't match delta hash")
 	})
 }
 
-func TestOpsWithTxnGreaterThan(t *testing.T) {
-	op1 := &batch.Operation{
-		TransactionTime:   1,
-		TransactionNumber: 1,
-	}
+func TestResolve_CommitmentChain(t *testing.T) {
+	recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
 
-	op2 := &batch.Operation{
-		TransactionTime:   1,
-		TransactionNumber: 2,
-	}
+	updateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pc := mocks.NewMockProtocolClient()
+
+	t.Run("operations anchored out of order", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		// the second update is anchored before the update that it follows
+		updateOp2.TransactionNumber = 1
+		updateOp1.TransactionNumber = 2
+
+		require.NoError(t, store.Put(updateOp2))
+		require.NoError(t, store.Put(updateOp1))
+
+		p := New("test", store, pc)
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special2", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+	})
+
+	t.Run("competing updates", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		// both updates reveal the same update key
+		updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+		competingOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 5)
+		require.NoError(t, err)
+		competingOp.TransactionNumber = 1
+		competingOp.OperationIndex = 1
+
+		updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Put(updateOp2))
+		require.NoError(t, store.Put(competingOp))
+		require.NoError(t, store.Put(updateOp1))
+
+		p := New("test", store, pc)
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special2", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+
+		// the tie is broken by ledger order
+		requireRejected(t, p, store, uniqueSuffix, competingOp, "commitment generated from update key doesn't match update commitment")
+	})
+
+	t.Run("competing recover and update", func(t *testing.T) {
+		store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+		recoverOp, _, err := getRecoverOperation(recoveryKey, updateKey, uniqueSuffix, 1)
+		require.NoError(t, err)
+
+		// the update reveals the update key that was replaced by the recovery
+		updateOp, _, err := getUpdateOperation(updateKey, uniqueSuffix, 2)
+		require.NoError(t, err)
 
-	ops := []*batch.Operation{op1, op2}
+		require.NoError(t, store.Put(updateOp))
+		require.NoError(t, store.Put(recoverOp))
 
-	txns := getOpsWithTxnGreaterThan(ops, 0, 0)
-	require.Equal(t, 2, len(txns))
+		p := New("test", store, pc)
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+
+		docBytes, err := result.Document.Bytes()
+		require.NoError(t, err)
+		require.Contains(t, string(docBytes), "recovered")
+		require.NotContains(t, string(docBytes), "special2")
+	})
+}
+
+func TestResolve_Version(t *testing.T) {
+	recoveryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	updateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pc := mocks.NewMockProtocolClient()
+
+	store, uniqueSuffix := getDefaultStore(recoveryKey, updateKey)
+
+	ops, err := store.Get(uniqueSuffix)
+	require.NoError(t, err)
+	ops[0].TransactionTime = 10
+
+	updateOp1, nextUpdateKey, err := getUpdateOperation(updateKey, uniqueSuffix, 1)
+	require.NoError(t, err)
+	updateOp1.TransactionTime = 20
+	require.NoError(t, store.Put(updateOp1))
+
+	updateOp2, _, err := getUpdateOperation(nextUpdateKey, uniqueSuffix, 2)
+	require.NoError(t, err)
+	updateOp2.TransactionTime = 30
+	updateOp2.OperationIndex = 3
+	require.NoError(t, store.Put(updateOp2))
+
+	p := New("test", store, pc)
+
+	t.Run("latest version", func(t *testing.T) {
+		result, err := p.Resolve(uniqueSuffix)
+		require.NoError(t, err)
+		require.Equal(t, "special2", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+		require.Equal(t, "2-3", result.MethodMetadata.VersionID)
+		require.Equal(t, uint64(30), result.MethodMetadata.VersionTime)
+	})
+
+	t.Run("version time", func(t *testing.T) {
+		result, err := p.Resolve(uniqueSuffix, document.WithVersionTime(25))
+		require.NoError(t, err)
+		require.Equal(t, "special1", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+		require.Equal(t, "1-0", result.MethodMetadata.VersionID)
+		require.Equal(t, uint64(20), result.MethodMetadata.VersionTime)
+
+		result, err = p.Resolve(uniqueSuffix, document.WithVersionTime(10))
+		require.NoError(t, err)
+		require.Nil(t, document.DidDocumentFromJSONLDObject(result.Document)["test"])
+		require.Equal(t, uint64(10), result.MethodMetadata.VersionTime)
+	})
+
+	t.Run("version time before create", func(t *testing.T) {
+		result, err := p.Resolve(uniqueSuffix, document.WithVersionTime(5))
+		require.Error(t, err)
+		require.Nil(t, result)
+		require.Contains(t, err.Error(), "document not found at version time 5")
+	})
+
+	t.Run("version ID", func(t *testing.T) {
+		result, err := p.Resolve(uniqueSuffix, document.WithVersionID("1-0"))
+		require.NoError(t, err)
+		require.Equal(t, "special1", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+		require.Equal(t, "1-0", result.MethodMetadata.VersionID)
+
+		result, err = p.Resolve(uniqueSuffix, document.WithVersionID("2-3"))
+		require.NoError(t, err)
+		require.Equal(t, "special2", document.DidDocumentFromJSONLDObject(result.Document)["test"])
+	})
+
+	t.Run("version ID not found", func(t *testing.T) {
+		result, err := p.Resolve(uniqueSuffix, document.WithVersionID("2-0"))
+		require.Error(t, err)
+		require.Nil(t, result)
+		require.Contains(t, err.Error(), "version [2-0] not found")
+	})
+
+	t.Run("version ID of a deactivate operation", func(t *testing.T) {
+		deactivateOp, err := getDeactivateOperation(recoveryKey, uniqueSuffix, 3)
+		require.NoError(t, err)
+		deactivateOp.TransactionTime = 40
+
+		store, _ := getDefaultStore(recoveryKey, updateKey)
+		require.NoError(t, store.Put(deactivateOp))
+
+		result, err := New("test", store, pc).Resolve(uniqueSuffix, document.WithVersionID("3-0"))
+		require.Error(t, err)
+		require.Nil(t, result)
+		require.Contains(t, err.Error(), "document was deactivated")
+	})
+}
+
+// requireRejected requires that the given operation is not part of the commitment chain of the document
+// and that it was rejected for the given reason
+func requireRejected(t *testing.T, p *OperationProcessor, store OperationStoreClient, uniqueSuffix string, op *batch.Operation, reason string) {
+	ops, err := store.Get(uniqueSuffix)
+	require.NoError(t, err)
+
+	rejected := make(map[*batch.Operation]error)
+
+	applied, _ := p.applyOperations(ops, rejected)
+	require.NotContains(t, applied, op)
+	require.Error(t, rejected[op])
+	require.Contains(t, rejected[op].Error(), reason)
+}
 
-	txns = getOpsWithTxnGreaterThan(ops, 2, 1)
-	require.Equal(t, 0, len(txns))
+func TestSortOperations(t *testing.T) {
+	op1 := &batch.Operation{TransactionTime: 1, TransactionNumber: 5}
+	op2 := &batch.Operation{TransactionTime: 2, TransactionNumber: 1}
+	op3 := &batch.Operation{TransactionTime: 2, TransactionNumber: 3, OperationIndex: 0}
+	op4 := &batch.Operation{TransactionTime: 2, TransactionNumber: 3, OperationIndex: 1}
+	op5 := &batch.Operation{TransactionTime: 3, TransactionNumber: 0}
 
-	txns = getOpsWithTxnGreaterThan(ops, 1, 1)
-	require.Equal(t, 1, len(txns))
+	ops := []*batch.Operation{op5, op4, op2, op3, op1}
+	sortOperations(ops)
+	require.Equal(t, []*batch.Operation{op1, op2, op3, op4, op5}, ops)
 }
 
 func getUpdateOperation(privateKey *ecdsa.PrivateKey, uniqueSuffix string, operationNumber uint) (*batch.Operation, *ecdsa.PrivateKey, error) {
@@ -0,0 +1,30 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package diddochandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/dochandler"
+)
+
+// StatusHandler returns the status of DID operations
+type StatusHandler struct {
+	*handler
+}
+
+// NewStatusHandler returns a new DID operation status handler
+func NewStatusHandler(basePath string, store dochandler.OperationStatusReader) *StatusHandler {
+	return &StatusHandler{
+		handler: newHandler(
+			fmt.Sprintf("%s/operations/{id}/status", basePath),
+			http.MethodGet,
+			dochandler.NewStatusHandler(store).Status,
+		),
+	}
+}
@@ -0,0 +1,30 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package diddochandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
+)
+
+func TestStatusHandler_Status(t *testing.T) {
+	handler := NewStatusHandler(basePath, opstatus.NewMemStore())
+	require.Equal(t, basePath+"/operations/{id}/status", handler.Path())
+	require.Equal(t, http.MethodGet, handler.Method())
+	require.NotNil(t, handler.Handler())
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/document/operations/status", nil)
+	handler.Handler()(rw, req)
+	require.Equal(t, http.StatusBadRequest, rw.Code)
+	require.Contains(t, rw.Body.String(), "tracking ID is required")
+}
@@ -8,6 +8,7 @@ package dochandler
 
 import (
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -78,7 +79,7 @@ func (o *ResolveHandler) doResolve(id string) (*document.ResolutionResult, error
 }
 
 var getID = func(namespace string, req *http.Request) string {
-	return mux.Vars(req)["id"] + getInitialState(namespace, req)
+	return addVersion(mux.Vars(req)["id"]+getInitialState(namespace, req), req)
 }
 
 func getInitialState(namespace string, req *http.Request) string {
@@ -91,3 +92,22 @@ func getInitialState(namespace string, req *http.Request) string {
 
 	return ""
 }
+
+// addVersion adds the version query parameters of the request (if any) to the given DID URL
+func addVersion(didURL string, req *http.Request) string {
+	for _, param := range []string{request.VersionTimeParam, request.VersionIDParam} {
+		value := req.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+
+		delimiter := "?"
+		if strings.Contains(didURL, "?") {
+			delimiter = "&"
+		}
+
+		didURL += delimiter + param + "=" + url.QueryEscape(value)
+	}
+
+	return didURL
+}
@@ -181,6 +181,19 @@ func TestGetInitialState(t *testing.T) {
 	require.Equal(t, "?-sidetree-initial-state=abc", initialState)
 }
 
+func TestAddVersion(t *testing.T) {
+	const id = "did:sidetree:abc"
+
+	req := httptest.NewRequest(http.MethodGet, "/document", nil)
+	require.Equal(t, id, addVersion(id, req))
+
+	req = httptest.NewRequest(http.MethodGet, "/document?versionTime=15", nil)
+	require.Equal(t, id+"?versionTime=15", addVersion(id, req))
+
+	req = httptest.NewRequest(http.MethodGet, "/document?-sidetree-initial-state=abc&versionId=5-1", nil)
+	require.Equal(t, id+"?-sidetree-initial-state=abc&versionId=5-1", addVersion(id+getInitialState(namespace, req), req))
+}
+
 func getCreateRequest() (*model.CreateRequest, error) {
 	delta, err := getDelta()
 	if err != nil {
@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dochandler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+// OperationStatusReader returns the state transitions of an operation
+type OperationStatusReader interface {
+	Get(trackingID string) ([]*batch.OperationStatus, error)
+}
+
+// StatusHandler returns the status of operations
+type StatusHandler struct {
+	store OperationStatusReader
+}
+
+// NewStatusHandler returns a new operation status handler
+func NewStatusHandler(store OperationStatusReader) *StatusHandler {
+	return &StatusHandler{
+		store: store,
+	}
+}
+
+// Status returns the status of the operation with the tracking ID in the request
+func (h *StatusHandler) Status(rw http.ResponseWriter, req *http.Request) {
+	trackingID := getTrackingID(req)
+	logger.Debugf("Getting status for tracking ID [%s]", trackingID)
+
+	response, err := h.doStatus(trackingID)
+	if err != nil {
+		common.WriteError(rw, err.(*common.HTTPError).Status(), err)
+		return
+	}
+
+	common.WriteResponse(rw, http.StatusOK, response)
+}
+
+func (h *StatusHandler) doStatus(trackingID string) (*model.OperationStatus, error) {
+	if trackingID == "" {
+		return nil, common.NewHTTPError(http.StatusBadRequest, errors.New("tracking ID is required"))
+	}
+
+	statuses, err := h.store.Get(trackingID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, common.NewHTTPError(http.StatusNotFound, errors.New("operation not found"))
+		}
+
+		logger.Errorf("internal server error:  %s", err.Error())
+		return nil, common.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if len(statuses) == 0 {
+		return nil, common.NewHTTPError(http.StatusNotFound, errors.New("operation not found"))
+	}
+
+	current := statuses[len(statuses)-1]
+
+	response := &model.OperationStatus{
+		TrackingID:   trackingID,
+		UniqueSuffix: current.UniqueSuffix,
+		Type:         model.OperationType(current.Type),
+		State:        string(current.State),
+		History:      make([]*model.OperationStateTransition, len(statuses)),
+	}
+
+	for i, status := range statuses {
+		response.History[i] = &model.OperationStateTransition{
+			State:             string(status.State),
+			Time:              status.Time,
+			AnchorString:      status.AnchorString,
+			TransactionTime:   status.TransactionTime,
+			TransactionNumber: status.TransactionNumber,
+			Reason:            status.Reason,
+		}
+	}
+
+	return response, nil
+}
+
+var getTrackingID = func(req *http.Request) string {
+	return mux.Vars(req)["id"]
+}
@@ -0,0 +1,95 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dochandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+func TestStatusHandler_Status(t *testing.T) {
+	op := &batch.Operation{
+		Type:         batch.OperationTypeUpdate,
+		Namespace:    namespace,
+		UniqueSuffix: "suffix",
+		SignedData:   "signed",
+		EncodedDelta: "delta",
+	}
+	trackingID := opstatus.TrackingID(op)
+
+	t.Run("Success", func(t *testing.T) {
+		store := opstatus.NewMemStore()
+		require.NoError(t, store.Put(opstatus.NewStatus(op, batch.OperationStateQueued)))
+
+		batched := opstatus.NewStatus(op, batch.OperationStateBatched)
+		batched.AnchorString = "anchor"
+		require.NoError(t, store.Put(batched))
+
+		getTrackingID = func(req *http.Request) string { return trackingID }
+
+		rw := httptest.NewRecorder()
+		NewStatusHandler(store).Status(rw, httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		var status model.OperationStatus
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+		require.Equal(t, trackingID, status.TrackingID)
+		require.Equal(t, "suffix", status.UniqueSuffix)
+		require.Equal(t, model.OperationTypeUpdate, status.Type)
+		require.Equal(t, string(batch.OperationStateBatched), status.State)
+		require.Len(t, status.History, 2)
+		require.Equal(t, string(batch.OperationStateQueued), status.History[0].State)
+		require.Equal(t, "anchor", status.History[1].AnchorString)
+	})
+	t.Run("Not found", func(t *testing.T) {
+		getTrackingID = func(req *http.Request) string { return trackingID }
+
+		rw := httptest.NewRecorder()
+		NewStatusHandler(opstatus.NewMemStore()).Status(rw, httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Equal(t, "operation not found", rw.Body.String())
+	})
+	t.Run("Missing tracking ID", func(t *testing.T) {
+		getTrackingID = func(req *http.Request) string { return "" }
+
+		rw := httptest.NewRecorder()
+		NewStatusHandler(opstatus.NewMemStore()).Status(rw, httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+	t.Run("Store error", func(t *testing.T) {
+		getTrackingID = func(req *http.Request) string { return trackingID }
+
+		rw := httptest.NewRecorder()
+		NewStatusHandler(&mockStatusReader{err: errors.New("store error")}).Status(rw, httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Equal(t, "store error", rw.Body.String())
+	})
+	t.Run("No statuses", func(t *testing.T) {
+		getTrackingID = func(req *http.Request) string { return trackingID }
+
+		rw := httptest.NewRecorder()
+		NewStatusHandler(&mockStatusReader{}).Status(rw, httptest.NewRequest(http.MethodGet, "/status", nil))
+		require.Equal(t, http.StatusNotFound, rw.Code)
+	})
+}
+
+type mockStatusReader struct {
+	err error
+}
+
+func (m *mockStatusReader) Get(string) ([]*batch.OperationStatus, error) {
+	return nil, m.err
+}
@@ -14,9 +14,14 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
 	"github.com/trustbloc/sidetree-core-go/pkg/document"
 	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/opstatus"
 	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
 )
 
+// TrackingIDHeader is the response header that contains the tracking ID of the operation.
+// The tracking ID may be used to query the status of the operation.
+const TrackingIDHeader = "Sidetree-Tracking-ID"
+
 // Processor processes document operations
 type Processor interface {
 	Namespace() string
@@ -44,29 +49,30 @@ func (h *UpdateHandler) Update(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	response, err := h.doUpdate(request)
+	response, trackingID, err := h.doUpdate(request)
 	if err != nil {
 		common.WriteError(rw, err.(*common.HTTPError).Status(), err)
 		return
 	}
+	rw.Header().Set(TrackingIDHeader, trackingID)
 	common.WriteResponse(rw, http.StatusOK, response)
 }
 
-func (h *UpdateHandler) doUpdate(request []byte) (*document.ResolutionResult, error) {
+func (h *UpdateHandler) doUpdate(request []byte) (*document.ResolutionResult, string, error) {
 	operation, err := h.getOperation(request)
 	if err != nil {
 		logger.Warnf("operation validation error: %s", err.Error())
-		return nil, common.NewHTTPError(http.StatusBadRequest, err)
+		return nil, "", common.NewHTTPError(http.StatusBadRequest, err)
 	}
 
 	// operation has been validated, now process it
 	result, err := h.processor.ProcessOperation(operation)
 	if err != nil {
 		logger.Errorf("internal server error:  %s", err.Error())
-		return nil, common.NewHTTPError(http.StatusInternalServerError, err)
+		return nil, "", common.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return result, nil
+	return result, opstatus.TrackingID(operation), nil
 }
 
 func (h *UpdateHandler) getOperation(operationBuffer []byte) (*batch.Operation, error) {
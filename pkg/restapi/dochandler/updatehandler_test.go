@@ -64,6 +64,7 @@ func TestUpdateHandler_Update(t *testing.T) {
 		handler.Update(rw, req)
 		require.Equal(t, http.StatusOK, rw.Code)
 		require.Equal(t, "application/did+ld+json", rw.Header().Get("content-type"))
+		require.NotEmpty(t, rw.Header().Get(TrackingIDHeader))
 
 		body, err := ioutil.ReadAll(rw.Body)
 		require.NoError(t, err)
@@ -111,6 +112,7 @@ func TestUpdateHandler_Update(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/document", bytes.NewReader(getUnsupportedRequest()))
 		handler.Update(rw, req)
 		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Empty(t, rw.Header().Get(TrackingIDHeader))
 	})
 	t.Run("Bad Request", func(t *testing.T) {
 		rw := httptest.NewRecorder()
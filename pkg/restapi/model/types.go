@@ -6,6 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 
 package model
 
+import (
+	"time"
+)
+
 // OperationType is the operation type
 // swagger:model OperationType
 type OperationType string
@@ -23,3 +27,98 @@ const (
 	// OperationTypeRecover captures enum value "recover"
 	OperationTypeRecover OperationType = "recover"
 )
+
+// RejectedOperation contains an operation that was rejected by the batch writer
+// swagger:model RejectedOperation
+type RejectedOperation struct {
+	// UniqueSuffix is the unique suffix of the DID
+	UniqueSuffix string `json:"uniqueSuffix"`
+
+	// Operation is the original operation request
+	Operation []byte `json:"operation"`
+
+	// Reason is the reason the operation was rejected
+	Reason string `json:"reason"`
+}
+
+// OperationStatus contains the current state and the state transitions of an operation
+// swagger:model OperationStatus
+type OperationStatus struct {
+	// TrackingID identifies the operation throughout its lifecycle
+	TrackingID string `json:"trackingId"`
+
+	// UniqueSuffix is the unique suffix of the DID
+	UniqueSuffix string `json:"uniqueSuffix"`
+
+	// Type is the operation type
+	Type OperationType `json:"type"`
+
+	// State is the current state of the operation
+	State string `json:"state"`
+
+	// History contains the state transitions of the operation (oldest first)
+	History []*OperationStateTransition `json:"history"`
+}
+
+// OperationStateTransition contains a state transition of an operation
+// swagger:model OperationStateTransition
+type OperationStateTransition struct {
+	// State is the state of the operation after the transition
+	State string `json:"state"`
+
+	// Time is the time of the transition
+	Time time.Time `json:"time"`
+
+	// AnchorString is the anchor string of the batch that contains the operation
+	AnchorString string `json:"anchorString,omitempty"`
+
+	// TransactionTime is the logical blockchain time that the operation was anchored on the blockchain
+	TransactionTime uint64 `json:"transactionTime,omitempty"`
+
+	// TransactionNumber is the transaction number of the transaction the operation was batched within
+	TransactionNumber uint64 `json:"transactionNumber,omitempty"`
+
+	// Reason is the reason the operation was rejected
+	Reason string `json:"reason,omitempty"`
+}
+
+// OperationHistoryEntry describes an anchored operation of a DID and the outcome of applying it
+// swagger:model OperationHistoryEntry
+type OperationHistoryEntry struct {
+	// Type is the operation type
+	Type OperationType `json:"type"`
+
+	// AnchorString is the anchor string of the transaction the operation was batched within
+	AnchorString string `json:"anchorString"`
+
+	// TransactionTime is the logical blockchain time that the operation was anchored on the blockchain
+	TransactionTime uint64 `json:"transactionTime"`
+
+	// TransactionNumber is the transaction number of the transaction the operation was batched within
+	TransactionNumber uint64 `json:"transactionNumber"`
+
+	// OperationIndex is the index of the operation in the batch
+	OperationIndex uint `json:"operationIndex"`
+
+	// Applied is true if the operation was applied to the document
+	Applied bool `json:"applied"`
+
+	// Reason is the reason the operation wasn't applied
+	Reason string `json:"reason,omitempty"`
+
+	// CommitmentsBefore contains the commitments of the document before the operation was applied
+	CommitmentsBefore *Commitments `json:"commitmentsBefore,omitempty"`
+
+	// CommitmentsAfter contains the commitments of the document after the operation was applied
+	CommitmentsAfter *Commitments `json:"commitmentsAfter,omitempty"`
+}
+
+// Commitments contains the update and recovery commitments of a document
+// swagger:model Commitments
+type Commitments struct {
+	// UpdateCommitment is the commitment of the next update operation
+	UpdateCommitment string `json:"updateCommitment"`
+
+	// RecoveryCommitment is the commitment of the next recover or deactivate operation
+	RecoveryCommitment string `json:"recoveryCommitment"`
+}
@@ -0,0 +1,114 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operationhandler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+// HistoryProvider returns the operation history of a document
+type HistoryProvider interface {
+	History(uniqueSuffix string) ([]*batch.OperationHistoryEntry, error)
+}
+
+// HistoryHandler returns the operation history of a DID, i.e. its anchored operations (in ledger order)
+// along with whether each operation was applied, the reason it wasn't applied and the commitments of
+// the document before and after the operation
+type HistoryHandler struct {
+	*handler
+
+	namespace string
+	provider  HistoryProvider
+}
+
+// NewHistoryHandler returns a new handler for the operation history of DIDs
+func NewHistoryHandler(basePath, namespace string, provider HistoryProvider) *HistoryHandler {
+	h := &HistoryHandler{
+		namespace: namespace,
+		provider:  provider,
+	}
+
+	h.handler = newHandler(
+		fmt.Sprintf("%s/identifiers/{id}/history", basePath),
+		http.MethodGet,
+		h.getHistory,
+	)
+
+	return h
+}
+
+func (h *HistoryHandler) getHistory(rw http.ResponseWriter, req *http.Request) {
+	id := getID(req)
+	logger.Debugf("Getting operation history for ID [%s]", id)
+
+	response, err := h.doGetHistory(id)
+	if err != nil {
+		common.WriteError(rw, err.(*common.HTTPError).Status(), err)
+		return
+	}
+
+	common.WriteResponse(rw, http.StatusOK, response)
+}
+
+func (h *HistoryHandler) doGetHistory(id string) ([]*model.OperationHistoryEntry, error) {
+	uniqueSuffix := strings.TrimPrefix(id, h.namespace+":")
+	if uniqueSuffix == id || uniqueSuffix == "" {
+		logger.Errorf("DID ID [%s] does not start with supported namespace [%s]", id, h.namespace)
+		return nil, common.NewHTTPError(http.StatusBadRequest, errors.New("must start with supported namespace"))
+	}
+
+	entries, err := h.provider.History(uniqueSuffix)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, common.NewHTTPError(http.StatusNotFound, errors.New("document not found"))
+		}
+
+		logger.Errorf("failed to get operation history for ID [%s]: %s", id, err)
+		return nil, common.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := make([]*model.OperationHistoryEntry, len(entries))
+	for i, entry := range entries {
+		response[i] = &model.OperationHistoryEntry{
+			Type:              model.OperationType(entry.Type),
+			AnchorString:      entry.AnchorString,
+			TransactionTime:   entry.TransactionTime,
+			TransactionNumber: entry.TransactionNumber,
+			OperationIndex:    entry.OperationIndex,
+			Applied:           entry.Applied,
+			Reason:            entry.Reason,
+			CommitmentsBefore: toCommitments(entry.CommitmentsBefore),
+			CommitmentsAfter:  toCommitments(entry.CommitmentsAfter),
+		}
+	}
+
+	return response, nil
+}
+
+func toCommitments(c *batch.Commitments) *model.Commitments {
+	if c == nil {
+		return nil
+	}
+
+	return &model.Commitments{
+		UpdateCommitment:   c.UpdateCommitment,
+		RecoveryCommitment: c.RecoveryCommitment,
+	}
+}
+
+var getID = func(req *http.Request) string {
+	return mux.Vars(req)["id"]
+}
@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operationhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	id := namespace + ":suffix"
+
+	getHistory := func(h *HistoryHandler, id string) *httptest.ResponseRecorder {
+		restore := getID
+		defer func() { getID = restore }()
+
+		getID = func(*http.Request) string { return id }
+
+		rw := httptest.NewRecorder()
+		h.Handler()(rw, httptest.NewRequest(http.MethodGet, basePath+"/identifiers/"+id+"/history", nil))
+
+		return rw
+	}
+
+	t.Run("success", func(t *testing.T) {
+		provider := &mockHistoryProvider{entries: []*batch.OperationHistoryEntry{
+			{
+				Type:              batch.OperationTypeCreate,
+				AnchorString:      "1.anchor",
+				TransactionTime:   10,
+				TransactionNumber: 1,
+				Applied:           true,
+				CommitmentsAfter:  &batch.Commitments{UpdateCommitment: "u1", RecoveryCommitment: "r1"},
+			},
+			{
+				Type:              batch.OperationTypeUpdate,
+				AnchorString:      "2.anchor",
+				TransactionTime:   20,
+				TransactionNumber: 2,
+				OperationIndex:    3,
+				Reason:            "commitment generated from update key doesn't match update commitment",
+				CommitmentsBefore: &batch.Commitments{UpdateCommitment: "u1", RecoveryCommitment: "r1"},
+				CommitmentsAfter:  &batch.Commitments{UpdateCommitment: "u1", RecoveryCommitment: "r1"},
+			},
+		}}
+
+		h := NewHistoryHandler(basePath, namespace, provider)
+		require.Equal(t, basePath+"/identifiers/{id}/history", h.Path())
+		require.Equal(t, http.MethodGet, h.Method())
+		require.NotNil(t, h.Handler())
+
+		rw := getHistory(h, id)
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "suffix", provider.uniqueSuffix)
+
+		var entries []*model.OperationHistoryEntry
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &entries))
+		require.Len(t, entries, 2)
+
+		require.Equal(t, model.OperationTypeCreate, entries[0].Type)
+		require.Equal(t, "1.anchor", entries[0].AnchorString)
+		require.Equal(t, uint64(10), entries[0].TransactionTime)
+		require.Equal(t, uint64(1), entries[0].TransactionNumber)
+		require.True(t, entries[0].Applied)
+		require.Nil(t, entries[0].CommitmentsBefore)
+		require.Equal(t, &model.Commitments{UpdateCommitment: "u1", RecoveryCommitment: "r1"}, entries[0].CommitmentsAfter)
+
+		require.Equal(t, model.OperationTypeUpdate, entries[1].Type)
+		require.Equal(t, uint(3), entries[1].OperationIndex)
+		require.False(t, entries[1].Applied)
+		require.Equal(t, "commitment generated from update key doesn't match update commitment", entries[1].Reason)
+		require.Equal(t, entries[1].CommitmentsBefore, entries[1].CommitmentsAfter)
+	})
+
+	t.Run("unsupported namespace", func(t *testing.T) {
+		h := NewHistoryHandler(basePath, namespace, &mockHistoryProvider{})
+
+		rw := getHistory(h, "did:other:suffix")
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+		require.Equal(t, "must start with supported namespace", rw.Body.String())
+
+		rw = getHistory(h, namespace+":")
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		h := NewHistoryHandler(basePath, namespace, &mockHistoryProvider{err: errors.New("history not found for suffix [suffix]")})
+
+		rw := getHistory(h, id)
+		require.Equal(t, http.StatusNotFound, rw.Code)
+		require.Equal(t, "document not found", rw.Body.String())
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		h := NewHistoryHandler(basePath, namespace, &mockHistoryProvider{err: errors.New("store error")})
+
+		rw := getHistory(h, id)
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Equal(t, "store error", rw.Body.String())
+	})
+}
+
+type mockHistoryProvider struct {
+	entries      []*batch.OperationHistoryEntry
+	err          error
+	uniqueSuffix string
+}
+
+func (m *mockHistoryProvider) History(uniqueSuffix string) ([]*batch.OperationHistoryEntry, error) {
+	m.uniqueSuffix = uniqueSuffix
+
+	return m.entries, m.err
+}
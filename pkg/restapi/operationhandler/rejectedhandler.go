@@ -0,0 +1,66 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operationhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/common"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+// DeadLetterStore returns the operations that were rejected by the batch writer
+type DeadLetterStore interface {
+	Get(namespace string) ([]*batch.RejectedOperation, error)
+}
+
+// RejectedHandler returns the operations that were rejected by the batch writer for a namespace
+type RejectedHandler struct {
+	*handler
+
+	namespace string
+	store     DeadLetterStore
+}
+
+// NewRejectedHandler returns a new handler for the operations that were rejected by the batch writer
+func NewRejectedHandler(basePath, namespace string, store DeadLetterStore) *RejectedHandler {
+	h := &RejectedHandler{
+		namespace: namespace,
+		store:     store,
+	}
+
+	h.handler = newHandler(
+		fmt.Sprintf("%s/operations/rejected", basePath),
+		http.MethodGet,
+		h.getRejected,
+	)
+
+	return h
+}
+
+func (h *RejectedHandler) getRejected(rw http.ResponseWriter, _ *http.Request) {
+	ops, err := h.store.Get(h.namespace)
+	if err != nil {
+		logger.Errorf("failed to get rejected operations for namespace [%s]: %s", h.namespace, err)
+		common.WriteError(rw, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	response := make([]*model.RejectedOperation, len(ops))
+	for i, op := range ops {
+		response[i] = &model.RejectedOperation{
+			UniqueSuffix: op.UniqueSuffix,
+			Operation:    op.Data,
+			Reason:       op.Reason,
+		}
+	}
+
+	common.WriteResponse(rw, http.StatusOK, response)
+}
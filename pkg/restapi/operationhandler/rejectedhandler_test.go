@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operationhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/batch/deadletter"
+	"github.com/trustbloc/sidetree-core-go/pkg/restapi/model"
+)
+
+const (
+	namespace = "did:sidetree"
+	basePath  = "/sidetree/0.0.1"
+)
+
+func TestRejectedHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		store := deadletter.NewMemStore()
+		require.NoError(t, store.Put(&batch.RejectedOperation{
+			OperationInfo: &batch.OperationInfo{Namespace: namespace, UniqueSuffix: "suffix", Data: []byte("{}")},
+			Reason:        "operation type [] not implemented",
+		}))
+
+		h := NewRejectedHandler(basePath, namespace, store)
+		require.Equal(t, basePath+"/operations/rejected", h.Path())
+		require.Equal(t, http.MethodGet, h.Method())
+		require.NotNil(t, h.Handler())
+
+		rw := httptest.NewRecorder()
+		h.Handler()(rw, httptest.NewRequest(http.MethodGet, h.Path(), nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		var ops []*model.RejectedOperation
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &ops))
+		require.Len(t, ops, 1)
+		require.Equal(t, "suffix", ops[0].UniqueSuffix)
+		require.Equal(t, []byte("{}"), ops[0].Operation)
+		require.Equal(t, "operation type [] not implemented", ops[0].Reason)
+	})
+
+	t.Run("no rejected operations", func(t *testing.T) {
+		h := NewRejectedHandler(basePath, namespace, deadletter.NewMemStore())
+
+		rw := httptest.NewRecorder()
+		h.Handler()(rw, httptest.NewRequest(http.MethodGet, h.Path(), nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.Equal(t, "[]\n", rw.Body.String())
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		h := NewRejectedHandler(basePath, namespace, &mockDeadLetterStore{err: errors.New("store error")})
+
+		rw := httptest.NewRecorder()
+		h.Handler()(rw, httptest.NewRequest(http.MethodGet, h.Path(), nil))
+		require.Equal(t, http.StatusInternalServerError, rw.Code)
+		require.Equal(t, "store error", rw.Body.String())
+	})
+}
+
+type mockDeadLetterStore struct {
+	err error
+}
+
+func (s *mockDeadLetterStore) Get(string) ([]*batch.RejectedOperation, error) {
+	return nil, s.err
+}
@@ -0,0 +1,36 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txnhandler
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ValidationError is returned when the anchor string or the batch files (anchor, map, chunk) of a Sidetree
+// transaction are invalid. Since batch files are immutable, a transaction with invalid batch files can never be
+// processed and should be refused rather than retried.
+type ValidationError struct {
+	cause error
+}
+
+// Error returns the reason the transaction is invalid
+func (e *ValidationError) Error() string {
+	return e.cause.Error()
+}
+
+// IsValidationError returns true if the given error (or its cause) is a ValidationError
+func IsValidationError(err error) bool {
+	_, ok := errors.Cause(err).(*ValidationError)
+
+	return ok
+}
+
+func newValidationError(format string, args ...interface{}) error {
+	return &ValidationError{cause: fmt.Errorf(format, args...)}
+}
@@ -35,27 +35,68 @@ func NewOperationHandler(cas cas.Client, p protocol.Client, cp compressionProvid
 }
 
 // PrepareTxnFiles will create batch files(chunk, map, anchor) from batch operations,
-// store those files in CAS and return anchor string
-func (h *OperationHandler) PrepareTxnFiles(ops []*batch.Operation) (string, error) {
+// store those files in CAS and return anchor string along with the number of operations in the batch.
+// If the anchor or map file of all of the operations would exceed the maximum file size then the batch is made up
+// of the largest number of operations (from the start of ops) whose files don't exceed it. The remaining operations
+// should be included in a later batch.
+func (h *OperationHandler) PrepareTxnFiles(ops []*batch.Operation) (string, int, error) {
+	n := len(ops)
+
+	for {
+		anchorString, fit, err := h.prepareTxnFiles(ops[:n])
+		if err != nil {
+			return "", 0, err
+		}
+
+		if fit == n {
+			return anchorString, n, nil
+		}
+
+		// the batch files of fewer operations are smaller, so fit decreases until the files of the batch fit
+		log.Infof("batch files of %d operations exceed maximum file size: creating batch files of %d operations", n, fit)
+
+		n = fit
+	}
+}
+
+// prepareTxnFiles creates the batch files of the given operations and returns the anchor string. If the map or anchor
+// file would exceed the maximum file size then no anchor string is returned, along with the (estimated) number
+// of operations whose files fit.
+func (h *OperationHandler) prepareTxnFiles(ops []*batch.Operation) (string, int, error) {
 	deactivateOps := getOperations(batch.OperationTypeDeactivate, ops)
 
 	// special case: if all ops are deactivate don't create chunk and map files
 	mapFileAddr := ""
 	if len(deactivateOps) != len(ops) {
-		chunkFileAddr, err := h.createChunkFile(ops)
+		chunkFileAddrs, err := h.createChunkFiles(ops)
 		if err != nil {
-			return "", err
+			return "", 0, err
+		}
+
+		// the chunk files of fewer operations are no more than these, so the map file size is not underestimated
+		n, content, err := h.fillBatchFile(len(ops), h.protocol.Current().MaxMapFileSize, "map", func(n int) interface{} {
+			return models.CreateMapFile(chunkFileAddrs, ops[:n])
+		})
+		if err != nil || n < len(ops) {
+			return "", n, err
 		}
 
-		mapFileAddr, err = h.createMapFile([]string{chunkFileAddr}, ops)
+		mapFileAddr, err = h.writeToCAS(content, "map")
 		if err != nil {
-			return "", err
+			return "", 0, err
 		}
 	}
 
-	anchorAddr, err := h.createAnchorFile(mapFileAddr, ops)
+	n, content, err := h.fillBatchFile(len(ops), h.protocol.Current().MaxAnchorFileSize, "anchor", func(n int) interface{} {
+		return models.CreateAnchorFile(mapFileAddr, ops[:n])
+	})
+	if err != nil || n < len(ops) {
+		return "", n, err
+	}
+
+	anchorAddr, err := h.writeToCAS(content, "anchor")
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 
 	ad := AnchorData{
@@ -63,48 +104,128 @@ func (h *OperationHandler) PrepareTxnFiles(ops []*batch.Operation) (string, erro
 		AnchorAddress:      anchorAddr,
 	}
 
-	return ad.GetAnchorString(), nil
+	return ad.GetAnchorString(), len(ops), nil
 }
 
-// createAnchorFile will create anchor file from operations and map file and write it to CAS
-// returns anchor file address
-func (h *OperationHandler) createAnchorFile(mapAddress string, ops []*batch.Operation) (string, error) {
-	anchorFile := models.CreateAnchorFile(mapAddress, ops)
+// createChunkFiles will split operation deltas across as many chunk files as required by the maximum
+// chunk file size and write them to CAS
+// returns chunk file addresses (in the order of the deltas)
+func (h *OperationHandler) createChunkFiles(ops []*batch.Operation) ([]string, error) {
+	deltas := models.CreateChunkFile(ops).Deltas
+	maxSize := h.protocol.Current().MaxChunkFileSize
+
+	var addresses []string
+	for len(deltas) > 0 {
+		n, content, err := h.fillChunkFile(deltas, maxSize)
+		if err != nil {
+			return nil, err
+		}
+
+		address, err := h.writeToCAS(content, "chunk")
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, address)
+		deltas = deltas[n:]
+	}
+
+	log.Debugf("created %d chunk files", len(addresses))
+
+	return addresses, nil
+}
+
+// fillChunkFile finds the largest number of the given deltas (from the start) whose compressed chunk file
+// does not exceed the given maximum size. Returns the number of deltas and the compressed chunk file.
+func (h *OperationHandler) fillChunkFile(deltas []string, maxSize uint) (int, []byte, error) {
+	n, content, err := h.fillFile(len(deltas), maxSize, "chunk", func(n int) interface{} {
+		return &models.ChunkFile{Deltas: deltas[:n]}
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if n == 0 {
+		return 0, nil, fmt.Errorf("delta size exceeds maximum chunk file size %d", maxSize)
+	}
 
-	return h.writeModelToCAS(anchorFile, "anchor")
+	return n, content, nil
 }
 
-// createChunkFile will create chunk file from operations and write it to CAS
-// returns chunk file address
-func (h *OperationHandler) createChunkFile(ops []*batch.Operation) (string, error) {
-	chunkFile := models.CreateChunkFile(ops)
+// fillBatchFile finds the largest number of operations (up to num) whose compressed map or anchor file does not exceed
+// the given maximum size. Returns the number of operations and the compressed file.
+func (h *OperationHandler) fillBatchFile(num int, maxSize uint, alias string, model func(n int) interface{}) (int, []byte, error) {
+	n, content, err := h.fillFile(num, maxSize, alias, model)
+	if err != nil {
+		return 0, nil, err
+	}
 
-	return h.writeModelToCAS(chunkFile, "chunk")
+	if n == 0 {
+		return 0, nil, fmt.Errorf("operation exceeds maximum %s file size %d", alias, maxSize)
+	}
+
+	return n, content, nil
 }
 
-// createMapFile will create map file from operations and chunk file URIs and write it to CAS
-// returns map file address
-func (h *OperationHandler) createMapFile(uri []string, ops []*batch.Operation) (string, error) {
-	mapFile := models.CreateMapFile(uri, ops)
+// fillFile finds the largest number of entries (up to num) whose compressed file does not exceed the given maximum
+// size, where model returns the file model of the given number of entries. Returns the number of entries
+// (zero if not even a single entry fits) and the compressed file.
+func (h *OperationHandler) fillFile(num int, maxSize uint, alias string, model func(n int) interface{}) (int, []byte, error) {
+	content, err := h.compressModel(model(num), alias)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if uint(len(content)) <= maxSize {
+		return num, content, nil
+	}
+
+	// binary search for the largest number of entries that fit (the compressed size grows with the number of entries)
+	var fit []byte
+	low, high := 0, num-1
+
+	for low < high {
+		mid := (low + high + 1) / 2
+
+		content, err = h.compressModel(model(mid), alias)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if uint(len(content)) <= maxSize {
+			low = mid
+			fit = content
+		} else {
+			high = mid - 1
+		}
+	}
 
-	return h.writeModelToCAS(mapFile, "map")
+	return low, fit, nil
 }
 
 func (h *OperationHandler) writeModelToCAS(model interface{}, alias string) (string, error) {
-	bytes, err := docutil.MarshalCanonical(model)
+	compressedBytes, err := h.compressModel(model, alias)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal %s file: %s", alias, err.Error())
+		return "", err
 	}
 
-	log.Debugf("%s file: %s", alias, string(bytes))
+	return h.writeToCAS(compressedBytes, alias)
+}
 
-	compressedBytes, err := h.cp.Compress(h.protocol.Current().CompressionAlgorithm, bytes)
+func (h *OperationHandler) compressModel(model interface{}, alias string) ([]byte, error) {
+	bytes, err := docutil.MarshalCanonical(model)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to marshal %s file: %s", alias, err.Error())
 	}
 
+	log.Debugf("%s file: %s", alias, string(bytes))
+
+	return h.cp.Compress(h.protocol.Current().CompressionAlgorithm, bytes)
+}
+
+func (h *OperationHandler) writeToCAS(content []byte, alias string) (string, error) {
 	// make file available in CAS
-	address, err := h.cas.Write(compressedBytes)
+	address, err := h.cas.Write(content)
 	if err != nil {
 		return "", fmt.Errorf("failed to store %s file: %s", alias, err.Error())
 	}
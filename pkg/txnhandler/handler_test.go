@@ -57,9 +57,10 @@ func TestOperationHandler_PrepareTxnFiles(t *testing.T) {
 			mocks.NewMockProtocolClient(),
 			compression)
 
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, n, err := handler.PrepareTxnFiles(ops)
 		require.NoError(t, err)
 		require.NotEmpty(t, anchorString)
+		require.Equal(t, len(ops), n)
 
 		anchorData, err := ParseAnchorData(anchorString)
 		require.NoError(t, err)
@@ -118,7 +119,7 @@ func TestOperationHandler_PrepareTxnFiles(t *testing.T) {
 			mocks.NewMockProtocolClient(),
 			compression)
 
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
 		require.Error(t, err)
 		require.Empty(t, anchorString)
 		require.Contains(t, err.Error(), "failed to store chunk file: CAS error")
@@ -132,13 +133,184 @@ func TestOperationHandler_PrepareTxnFiles(t *testing.T) {
 			mocks.NewMockProtocolClient(),
 			compression)
 
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
 		require.Error(t, err)
 		require.Empty(t, anchorString)
 		require.Contains(t, err.Error(), "failed to store anchor file: CAS error")
 	})
 }
 
+func TestOperationHandler_PrepareTxnFiles_MultipleChunks(t *testing.T) {
+	cp := compression.New(compression.WithDefaultAlgorithms())
+	ops := getTestOperations(3, 3, 1, 2)
+	deltas := models.CreateChunkFile(ops).Deltas
+
+	pc := mocks.NewMockProtocolClient()
+	cas := mocks.NewMockCasClient(nil)
+	handler := NewOperationHandler(cas, pc, cp)
+
+	// the maximum chunk file size fits the largest delta but not all of them
+	var maxSize int
+	for _, delta := range deltas {
+		content, err := handler.compressModel(&models.ChunkFile{Deltas: []string{delta}}, "chunk")
+		require.NoError(t, err)
+
+		if len(content) > maxSize {
+			maxSize = len(content)
+		}
+	}
+
+	pc.Protocol.MaxChunkFileSize = uint(maxSize)
+
+	t.Run("success", func(t *testing.T) {
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
+		require.NoError(t, err)
+
+		anchorData, err := ParseAnchorData(anchorString)
+		require.NoError(t, err)
+
+		af := &models.AnchorFile{}
+		readModel(t, cas, anchorData.AnchorAddress, af)
+
+		mf := &models.MapFile{}
+		readModel(t, cas, af.MapFileHash, mf)
+		require.True(t, len(mf.Chunks) > 1)
+
+		var chunkDeltas []string
+		for _, chunk := range mf.Chunks {
+			bytes, err := cas.Read(chunk.ChunkFileURI)
+			require.NoError(t, err)
+			require.True(t, len(bytes) <= maxSize)
+
+			cf := &models.ChunkFile{}
+			readModel(t, cas, chunk.ChunkFileURI, cf)
+			require.NotEmpty(t, cf.Deltas)
+
+			chunkDeltas = append(chunkDeltas, cf.Deltas...)
+		}
+
+		// deltas are split across chunk files in order
+		require.Equal(t, deltas, chunkDeltas)
+	})
+
+	t.Run("error - delta exceeds maximum chunk file size", func(t *testing.T) {
+		pc := mocks.NewMockProtocolClient()
+		pc.Protocol.MaxChunkFileSize = uint(maxSize - 1)
+
+		handler := NewOperationHandler(mocks.NewMockCasClient(nil), pc, cp)
+
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
+		require.Error(t, err)
+		require.Empty(t, anchorString)
+		require.Contains(t, err.Error(), "delta size exceeds maximum chunk file size")
+	})
+
+	t.Run("error - compression error", func(t *testing.T) {
+		pc := mocks.NewMockProtocolClient()
+		pc.Protocol.CompressionAlgorithm = "invalid"
+
+		handler := NewOperationHandler(mocks.NewMockCasClient(nil), pc, cp)
+
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
+		require.Error(t, err)
+		require.Empty(t, anchorString)
+		require.Contains(t, err.Error(), "compression algorithm 'invalid' not supported")
+	})
+}
+
+func TestOperationHandler_PrepareTxnFiles_MaxFileSize(t *testing.T) {
+	cp := compression.New(compression.WithDefaultAlgorithms())
+
+	t.Run("map file exceeds maximum size", func(t *testing.T) {
+		ops := getTestOperations(0, 10, 0, 0)
+
+		pc := mocks.NewMockProtocolClient()
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		// the map file of all of the operations doesn't fit
+		content, err := handler.compressModel(models.CreateMapFile([]string{"chunk"}, ops), "map")
+		require.NoError(t, err)
+
+		pc.Protocol.MaxMapFileSize = uint(len(content) - 1)
+
+		anchorString, n, err := handler.PrepareTxnFiles(ops)
+		require.NoError(t, err)
+		require.True(t, n > 0 && n < len(ops))
+
+		anchorData, err := ParseAnchorData(anchorString)
+		require.NoError(t, err)
+		require.Equal(t, n, anchorData.NumberOfOperations)
+
+		af := &models.AnchorFile{}
+		readModel(t, cas, anchorData.AnchorAddress, af)
+
+		bytes, err := cas.Read(af.MapFileHash)
+		require.NoError(t, err)
+		require.True(t, uint(len(bytes)) <= pc.Protocol.MaxMapFileSize)
+
+		mf := &models.MapFile{}
+		readModel(t, cas, af.MapFileHash, mf)
+		require.Len(t, mf.Operations.Update, n)
+
+		for i, op := range mf.Operations.Update {
+			require.Equal(t, ops[i].UniqueSuffix, op.DidSuffix)
+		}
+	})
+
+	t.Run("anchor file exceeds maximum size", func(t *testing.T) {
+		ops := getTestOperations(0, 0, 10, 0)
+
+		pc := mocks.NewMockProtocolClient()
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		content, err := handler.compressModel(models.CreateAnchorFile("", ops), "anchor")
+		require.NoError(t, err)
+
+		pc.Protocol.MaxAnchorFileSize = uint(len(content) - 1)
+
+		anchorString, n, err := handler.PrepareTxnFiles(ops)
+		require.NoError(t, err)
+		require.True(t, n > 0 && n < len(ops))
+
+		anchorData, err := ParseAnchorData(anchorString)
+		require.NoError(t, err)
+		require.Equal(t, n, anchorData.NumberOfOperations)
+
+		bytes, err := cas.Read(anchorData.AnchorAddress)
+		require.NoError(t, err)
+		require.True(t, uint(len(bytes)) <= pc.Protocol.MaxAnchorFileSize)
+
+		af := &models.AnchorFile{}
+		readModel(t, cas, anchorData.AnchorAddress, af)
+		require.Len(t, af.Operations.Deactivate, n)
+	})
+
+	t.Run("error - operation exceeds maximum map file size", func(t *testing.T) {
+		pc := mocks.NewMockProtocolClient()
+		pc.Protocol.MaxMapFileSize = 10
+
+		handler := NewOperationHandler(mocks.NewMockCasClient(nil), pc, cp)
+
+		anchorString, n, err := handler.PrepareTxnFiles(getTestOperations(0, 2, 0, 0))
+		require.Error(t, err)
+		require.Empty(t, anchorString)
+		require.Zero(t, n)
+		require.Contains(t, err.Error(), "operation exceeds maximum map file size 10")
+	})
+}
+
+func readModel(t *testing.T, cas *mocks.MockCasClient, address string, model interface{}) {
+	bytes, err := cas.Read(address)
+	require.NoError(t, err)
+
+	content, err := compression.New(compression.WithDefaultAlgorithms()).Decompress(compressionAlgorithm, bytes)
+	require.NoError(t, err)
+
+	require.NoError(t, json.Unmarshal(content, model))
+}
+
 func TestWriteModelToCAS(t *testing.T) {
 	handler := NewOperationHandler(
 		mocks.NewMockCasClient(nil),
@@ -7,7 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package models
 
 import (
-	"encoding/json"
+	"fmt"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 )
@@ -71,16 +71,53 @@ func getCreateOperations(ops []*batch.Operation) []CreateOperation {
 }
 
 // ParseAnchorFile will parse anchor model from content
-func ParseAnchorFile(content []byte) (*AnchorFile, error) {
+func ParseAnchorFile(content []byte, opts ...ParseOption) (*AnchorFile, error) {
 	af, err := getAnchorFile(content)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: Verify anchor file - issue-294
+	if err := af.validate(getParseOptions(opts...).validateAddress); err != nil {
+		return nil, err
+	}
+
 	return af, nil
 }
 
+// validate checks the structure of the anchor file
+func (af *AnchorFile) validate(validateAddress AddressValidator) error {
+	if af.MapFileHash != "" {
+		if err := validateAddress(af.MapFileHash); err != nil {
+			return fmt.Errorf("invalid map file hash: %s", err)
+		}
+	} else {
+		// only deactivate operations may be anchored without a map file
+		if len(af.Operations.Create) > 0 || len(af.Operations.Recover) > 0 {
+			return fmt.Errorf("missing map file hash for create and recover operations")
+		}
+
+		if len(af.Operations.Deactivate) == 0 {
+			return fmt.Errorf("anchor file doesn't contain any operations")
+		}
+	}
+
+	if len(af.Operations.Update) > 0 {
+		return fmt.Errorf("update operations are not allowed in anchor file")
+	}
+
+	for i, op := range af.Operations.Create {
+		if op.SuffixData == "" {
+			return fmt.Errorf("missing suffix data for create operation at index %d", i)
+		}
+	}
+
+	if err := validateSignedOperations("recover", af.Operations.Recover); err != nil {
+		return err
+	}
+
+	return validateSignedOperations("deactivate", af.Operations.Deactivate)
+}
+
 // getAnchorFile creates new anchor file struct from bytes
 var getAnchorFile = func(bytes []byte) (*AnchorFile, error) {
 	return unmarshalAnchorFile(bytes)
@@ -89,7 +126,7 @@ var getAnchorFile = func(bytes []byte) (*AnchorFile, error) {
 // unmarshalAnchorFile creates new anchor file struct from bytes
 func unmarshalAnchorFile(bytes []byte) (*AnchorFile, error) {
 	file := &AnchorFile{}
-	err := json.Unmarshal(bytes, file)
+	err := unmarshalStrict(bytes, file)
 	if err != nil {
 		return nil, err
 	}
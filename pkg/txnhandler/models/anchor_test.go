@@ -9,6 +9,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -81,3 +82,80 @@ func generateOperation(num int, opType batch.OperationType) *batch.Operation {
 		SignedData:        "signed-data",
 	}
 }
+
+func TestParseAnchorFile_Validation(t *testing.T) {
+	t.Run("success - deactivate only", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"Operations":{"deactivate":[{"did_suffix":"suffix","signed_data":"jws"}]}}`))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(af.Operations.Deactivate))
+	})
+
+	t.Run("error - unknown field", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"address","Operations":{},"other":"value"}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), `unknown field "other"`)
+	})
+
+	t.Run("error - trailing content", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"address","Operations":{}}{}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "unexpected content after batch file")
+	})
+
+	t.Run("error - invalid map file hash", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"map/address","Operations":{}}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "invalid map file hash: address[map/address] contains invalid characters")
+	})
+
+	t.Run("map file hash with address validator", func(t *testing.T) {
+		validator := NewAddressValidator(12, regexp.MustCompile(`^[a-z/]+$`))
+
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"map/address","Operations":{}}`), WithAddressValidator(validator))
+		require.NoError(t, err)
+		require.Equal(t, "map/address", af.MapFileHash)
+
+		af, err = ParseAnchorFile([]byte(`{"mapFileHash":"map/address/long","Operations":{}}`), WithAddressValidator(validator))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "invalid map file hash: address length 16 exceeds maximum length 12")
+	})
+
+	t.Run("error - missing map file hash", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"Operations":{"create":[{"suffix_data":"data"}]}}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "missing map file hash for create and recover operations")
+	})
+
+	t.Run("error - update operations", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"address","Operations":{"update":[{"did_suffix":"suffix","signed_data":"jws"}]}}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "update operations are not allowed in anchor file")
+	})
+
+	t.Run("error - missing suffix data", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"address","Operations":{"create":[{}]}}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "missing suffix data for create operation at index 0")
+	})
+
+	t.Run("error - missing did suffix", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"mapFileHash":"address","Operations":{"recover":[{"signed_data":"jws"}]}}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "missing did suffix for recover operation at index 0")
+	})
+
+	t.Run("error - missing signed data", func(t *testing.T) {
+		af, err := ParseAnchorFile([]byte(`{"Operations":{"deactivate":[{"did_suffix":"suffix"}]}}`))
+		require.Error(t, err)
+		require.Nil(t, af)
+		require.Contains(t, err.Error(), "missing signed data for deactivate operation at index 0")
+	})
+}
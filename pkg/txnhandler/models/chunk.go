@@ -7,7 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package models
 
 import (
-	"encoding/json"
+	"fmt"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 )
@@ -37,10 +37,28 @@ func ParseChunkFile(content []byte) (*ChunkFile, error) {
 		return nil, err
 	}
 
-	// TODO: Verify chunk file - issue-296
+	if err := cf.validate(); err != nil {
+		return nil, err
+	}
+
 	return cf, nil
 }
 
+// validate checks the structure of the chunk file
+func (cf *ChunkFile) validate() error {
+	if len(cf.Deltas) == 0 {
+		return fmt.Errorf("chunk file doesn't contain any deltas")
+	}
+
+	for i, delta := range cf.Deltas {
+		if delta == "" {
+			return fmt.Errorf("empty delta at index %d", i)
+		}
+	}
+
+	return nil
+}
+
 func getDeltas(filter batch.OperationType, ops []*batch.Operation) []string {
 	var deltas []string
 	for _, op := range ops {
@@ -60,7 +78,7 @@ var getChunkFile = func(bytes []byte) (*ChunkFile, error) {
 // unmarshal chunk file bytes into chunk file model
 func unmarshalChunkFile(bytes []byte) (*ChunkFile, error) {
 	file := &ChunkFile{}
-	err := json.Unmarshal(bytes, file)
+	err := unmarshalStrict(bytes, file)
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +43,19 @@ func TestParseChunkFile(t *testing.T) {
 
 	require.Equal(t, createOpsNum+updateOpsNum+recoverOpsNum, len(parsed.Deltas))
 }
+
+func TestParseChunkFile_Validation(t *testing.T) {
+	t.Run("error - unknown field", func(t *testing.T) {
+		cf, err := ParseChunkFile([]byte(`{"deltas":["delta"],"other":true}`))
+		require.Error(t, err)
+		require.Nil(t, cf)
+		require.Contains(t, err.Error(), `unknown field "other"`)
+	})
+
+	t.Run("error - empty delta", func(t *testing.T) {
+		cf, err := ParseChunkFile([]byte(`{"deltas":["delta",""]}`))
+		require.Error(t, err)
+		require.Nil(t, cf)
+		require.Contains(t, err.Error(), "empty delta at index 1")
+	})
+}
@@ -7,7 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package models
 
 import (
-	"encoding/json"
+	"fmt"
 
 	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
 )
@@ -35,16 +35,38 @@ func CreateMapFile(uri []string, ops []*batch.Operation) *MapFile {
 }
 
 // ParseMapFile will parse map file model from content
-func ParseMapFile(content []byte) (*MapFile, error) {
+func ParseMapFile(content []byte, opts ...ParseOption) (*MapFile, error) {
 	mf, err := getMapFile(content)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: Verify map file - issue-295
+	if err := mf.validate(getParseOptions(opts...).validateAddress); err != nil {
+		return nil, err
+	}
+
 	return mf, nil
 }
 
+// validate checks the structure of the map file
+func (mf *MapFile) validate(validateAddress AddressValidator) error {
+	if len(mf.Chunks) == 0 {
+		return fmt.Errorf("map file doesn't reference any chunk files")
+	}
+
+	for i, chunk := range mf.Chunks {
+		if err := validateAddress(chunk.ChunkFileURI); err != nil {
+			return fmt.Errorf("invalid chunk file URI at index %d: %s", i, err)
+		}
+	}
+
+	if len(mf.Operations.Create) > 0 || len(mf.Operations.Recover) > 0 || len(mf.Operations.Deactivate) > 0 {
+		return fmt.Errorf("only update operations are allowed in map file")
+	}
+
+	return validateSignedOperations("update", mf.Operations.Update)
+}
+
 func getChunks(uris []string) []Chunk {
 	var chunks []Chunk
 	for _, uri := range uris {
@@ -80,7 +102,7 @@ var getMapFile = func(bytes []byte) (*MapFile, error) {
 // unmarshal map file bytes into map file model
 func unmarshalMapFile(bytes []byte) (*MapFile, error) {
 	file := &MapFile{}
-	err := json.Unmarshal(bytes, file)
+	err := unmarshalStrict(bytes, file)
 	if err != nil {
 		return nil, err
 	}
@@ -8,6 +8,7 @@ package models
 
 import (
 	"encoding/json"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -52,3 +53,47 @@ func TestHandler_ParseMapFile(t *testing.T) {
 	require.Equal(t, 0, len(parsed.Operations.Deactivate))
 	require.Equal(t, 0, len(parsed.Operations.Recover))
 }
+
+func TestParseMapFile_Validation(t *testing.T) {
+	t.Run("error - unknown field", func(t *testing.T) {
+		mf, err := ParseMapFile([]byte(`{"chunks":[{"chunk_file_uri":"address","size":10}]}`))
+		require.Error(t, err)
+		require.Nil(t, mf)
+		require.Contains(t, err.Error(), `unknown field "size"`)
+	})
+
+	t.Run("error - invalid chunk file URI", func(t *testing.T) {
+		mf, err := ParseMapFile([]byte(`{"chunks":[{"chunk_file_uri":""}]}`))
+		require.Error(t, err)
+		require.Nil(t, mf)
+		require.Contains(t, err.Error(), "invalid chunk file URI at index 0: address is empty")
+	})
+
+	t.Run("chunk file URI with address validator", func(t *testing.T) {
+		content := []byte(`{"chunks":[{"chunk_file_uri":"ipfs://QmWd5PH6vyRH5kMdzZRPBnf952dbR4av3Bd7B2wBqMaAcf"}]}`)
+
+		mf, err := ParseMapFile(content)
+		require.Error(t, err)
+		require.Nil(t, mf)
+		require.Contains(t, err.Error(), "contains invalid characters")
+
+		mf, err = ParseMapFile(content,
+			WithAddressValidator(NewAddressValidator(200, regexp.MustCompile(`^ipfs://[A-Za-z0-9]+$`))))
+		require.NoError(t, err)
+		require.Equal(t, "ipfs://QmWd5PH6vyRH5kMdzZRPBnf952dbR4av3Bd7B2wBqMaAcf", mf.Chunks[0].ChunkFileURI)
+	})
+
+	t.Run("error - operations other than update", func(t *testing.T) {
+		mf, err := ParseMapFile([]byte(`{"chunks":[{"chunk_file_uri":"address"}],"Operations":{"create":[{"suffix_data":"data"}]}}`))
+		require.Error(t, err)
+		require.Nil(t, mf)
+		require.Contains(t, err.Error(), "only update operations are allowed in map file")
+	})
+
+	t.Run("error - missing signed data", func(t *testing.T) {
+		mf, err := ParseMapFile([]byte(`{"chunks":[{"chunk_file_uri":"address"}],"Operations":{"update":[{"did_suffix":"suffix"}]}}`))
+		require.Error(t, err)
+		require.Nil(t, mf)
+		require.Contains(t, err.Error(), "missing signed data for update operation at index 0")
+	})
+}
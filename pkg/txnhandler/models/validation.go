@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// maxAddressLength is the default maximum length of a CAS address (file hash or URI) in a batch file
+const maxAddressLength = 100
+
+// addressPattern matches CAS addresses (base58, base32 or URL-safe base64 encoded hashes)
+var addressPattern = regexp.MustCompile(`^[A-Za-z0-9_\-=]+$`)
+
+// AddressValidator checks the format of a CAS address (file hash or URI) that is referenced by a batch file
+type AddressValidator func(address string) error
+
+// NewAddressValidator returns an address validator for CAS addresses of the given maximum length
+// that match the given pattern. The address format depends on the CAS, e.g. a CAS that is addressed
+// by URI requires a pattern that allows the URI scheme and path.
+func NewAddressValidator(maxLength int, pattern *regexp.Regexp) AddressValidator {
+	return func(address string) error {
+		if address == "" {
+			return fmt.Errorf("address is empty")
+		}
+
+		if len(address) > maxLength {
+			return fmt.Errorf("address length %d exceeds maximum length %d", len(address), maxLength)
+		}
+
+		if !pattern.MatchString(address) {
+			return fmt.Errorf("address[%s] contains invalid characters", address)
+		}
+
+		return nil
+	}
+}
+
+// ValidateAddress checks the format of a CAS address that is referenced by a batch file against the default
+// address format (an encoded hash of up to 100 characters)
+var ValidateAddress = NewAddressValidator(maxAddressLength, addressPattern)
+
+// ParseOption is an option for parsing batch files
+type ParseOption func(opts *parseOptions)
+
+type parseOptions struct {
+	validateAddress AddressValidator
+}
+
+// WithAddressValidator sets the validator for the CAS addresses that are referenced by the batch file
+func WithAddressValidator(validator AddressValidator) ParseOption {
+	return func(opts *parseOptions) {
+		opts.validateAddress = validator
+	}
+}
+
+func getParseOptions(opts ...ParseOption) *parseOptions {
+	options := &parseOptions{validateAddress: ValidateAddress}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// unmarshalStrict unmarshals batch file content into the given model. Unknown fields are not allowed.
+func unmarshalStrict(content []byte, model interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(model); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return fmt.Errorf("unexpected content after batch file")
+	}
+
+	return nil
+}
+
+func validateSignedOperations(opType string, ops []SignedOperation) error {
+	for i, op := range ops {
+		if op.DidSuffix == "" {
+			return fmt.Errorf("missing did suffix for %s operation at index %d", opType, i)
+		}
+
+		if op.SignedData == "" {
+			return fmt.Errorf("missing signed data for %s operation at index %d", opType, i)
+		}
+	}
+
+	return nil
+}
@@ -7,8 +7,6 @@ SPDX-License-Identifier: Apache-2.0
 package txnhandler
 
 import (
-	"fmt"
-
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
@@ -31,71 +29,131 @@ type decompressionProvider interface {
 
 // OperationProvider assembles batch operations from batch files
 type OperationProvider struct {
-	cas DCAS
-	pcp protocol.ClientProvider
-	dp  decompressionProvider
+	cas             DCAS
+	pcp             protocol.ClientProvider
+	dp              decompressionProvider
+	validateAddress models.AddressValidator
+}
+
+// Option is an option for the operation provider
+type Option func(h *OperationProvider)
+
+// WithAddressValidator sets the validator for the CAS addresses in the anchor string and batch files.
+// The validator must accept the address format of the CAS. (The default only accepts encoded hashes.)
+func WithAddressValidator(validator models.AddressValidator) Option {
+	return func(h *OperationProvider) {
+		h.validateAddress = validator
+	}
 }
 
 // NewOperationProvider returns new operation provider
-func NewOperationProvider(cas DCAS, pcp protocol.ClientProvider, dp decompressionProvider) *OperationProvider {
-	return &OperationProvider{cas: cas, pcp: pcp, dp: dp}
+func NewOperationProvider(cas DCAS, pcp protocol.ClientProvider, dp decompressionProvider, opts ...Option) *OperationProvider {
+	h := &OperationProvider{cas: cas, pcp: pcp, dp: dp, validateAddress: models.ValidateAddress}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
-// GetTxnOperations will read batch files(Chunk, map, anchor) and assemble batch operations from those files
+// GetTxnOperations will read batch files(Chunk, map, anchor) and assemble batch operations from those files.
+// A ValidationError is returned if the anchor string or the batch files are invalid.
 func (h *OperationProvider) GetTxnOperations(txn *txn.SidetreeTxn) ([]*batch.Operation, error) {
 	// ParseAnchorData anchor address and number of operations from anchor string
 	anchorData, err := ParseAnchorData(txn.AnchorString)
 	if err != nil {
-		return nil, err
+		return nil, &ValidationError{cause: err}
 	}
 
-	pc, err := h.pcp.ForNamespace(txn.Namespace)
+	if err := h.validateAddress(anchorData.AnchorAddress); err != nil {
+		return nil, newValidationError("invalid anchor address in anchor string[%s]: %s", txn.AnchorString, err)
+	}
+
+	p, err := h.getProtocol(txn)
 	if err != nil {
 		return nil, err
 	}
 
-	af, err := h.getAnchorFile(anchorData.AnchorAddress, pc.Current())
+	af, err := h.getAnchorFile(anchorData.AnchorAddress, *p)
 	if err != nil {
 		return nil, err
 	}
 
+	var txnOps []*batch.Operation
+
 	if af.MapFileHash == "" {
 		// if there's no map file that means that we have only deactivate operations in the batch
 		anchorOps, e := h.parseAnchorOperations(af, txn)
 		if e != nil {
-			return nil, fmt.Errorf("parse anchor operations: %s", e.Error())
+			return nil, errors.Wrap(e, "parse anchor operations")
+		}
+
+		txnOps = anchorOps.Deactivate
+	} else {
+		mf, e := h.getMapFile(af.MapFileHash, *p)
+		if e != nil {
+			return nil, e
 		}
 
-		return anchorOps.Deactivate, nil
+		deltas, e := h.getChunkDeltas(mf, *p)
+		if e != nil {
+			return nil, e
+		}
+
+		txnOps, e = h.assembleBatchOperations(af, mf, deltas, txn)
+		if e != nil {
+			return nil, e
+		}
 	}
 
-	mf, err := h.getMapFile(af.MapFileHash, pc.Current())
-	if err != nil {
-		return nil, err
+	if len(txnOps) != anchorData.NumberOfOperations {
+		return nil, newValidationError("number of txn ops[%d] doesn't match anchor string num of ops[%d]", len(txnOps), anchorData.NumberOfOperations)
 	}
 
-	chunkAddress := mf.Chunks[0].ChunkFileURI
-	cf, err := h.getChunkFile(chunkAddress, pc.Current())
-	if err != nil {
+	if err := checkForDuplicates(txnOps); err != nil {
 		return nil, err
 	}
 
-	txnOps, err := h.assembleBatchOperations(af, mf, cf, txn)
-	if err != nil {
-		return nil, err
+	return txnOps, nil
+}
+
+// getChunkDeltas will download all chunk files referenced by the map file and return their deltas in order
+func (h *OperationProvider) getChunkDeltas(mf *models.MapFile, p protocol.Protocol) ([]string, error) {
+	var deltas []string
+	for _, chunk := range mf.Chunks {
+		cf, err := h.getChunkFile(chunk.ChunkFileURI, p)
+		if err != nil {
+			return nil, err
+		}
+
+		deltas = append(deltas, cf.Deltas...)
 	}
 
-	if len(txnOps) != anchorData.NumberOfOperations {
-		return nil, fmt.Errorf("number of txn ops[%d] doesn't match anchor string num of ops[%d]", len(txnOps), anchorData.NumberOfOperations)
+	log.Debugf("successfully read %d deltas from %d chunk files", len(deltas), len(mf.Chunks))
+
+	return deltas, nil
+}
+
+// checkForDuplicates returns a ValidationError if more than one operation in the batch is for the same unique suffix
+func checkForDuplicates(ops []*batch.Operation) error {
+	suffixes := make(map[string]batch.OperationType)
+
+	for _, op := range ops {
+		if opType, ok := suffixes[op.UniqueSuffix]; ok {
+			return newValidationError("duplicate suffix[%s] found in batch operations: %s and %s", op.UniqueSuffix, opType, op.Type)
+		}
+
+		suffixes[op.UniqueSuffix] = op.Type
 	}
 
-	return txnOps, nil
+	return nil
 }
 
-func (h *OperationProvider) assembleBatchOperations(af *models.AnchorFile, mf *models.MapFile, cf *models.ChunkFile, txn *txn.SidetreeTxn) ([]*batch.Operation, error) {
+func (h *OperationProvider) assembleBatchOperations(af *models.AnchorFile, mf *models.MapFile, deltas []string, txn *txn.SidetreeTxn) ([]*batch.Operation, error) {
 	anchorOps, err := h.parseAnchorOperations(af, txn)
 	if err != nil {
-		return nil, fmt.Errorf("parse anchor operations: %s", err.Error())
+		return nil, errors.Wrap(err, "parse anchor operations")
 	}
 
 	log.Debugf("successfully parsed anchor operations: create[%d], recover[%d], deactivate[%d]",
@@ -111,17 +169,21 @@ func (h *OperationProvider) assembleBatchOperations(af *models.AnchorFile, mf *m
 	operations = append(operations, mapOps.Update...)
 	operations = append(operations, anchorOps.Deactivate...)
 
-	// TODO: Add checks here to makes sure that file sizes match - part of validation tickets
+	p, err := h.getProtocol(txn)
+	if err != nil {
+		return nil, err
+	}
 
-	for i, delta := range cf.Deltas {
-		p, err := h.getProtocol(operations[i].Namespace)
-		if err != nil {
-			return nil, err
-		}
+	// deactivate operations don't have deltas
+	numOpsWithDelta := len(operations) - len(anchorOps.Deactivate)
+	if len(deltas) != numOpsWithDelta {
+		return nil, newValidationError("number of deltas[%d] in chunk files doesn't match number of operations with deltas[%d]", len(deltas), numOpsWithDelta)
+	}
 
+	for i, delta := range deltas {
 		deltaModel, err := operation.ParseDelta(delta, p.HashAlgorithmInMultiHashCode)
 		if err != nil {
-			return nil, fmt.Errorf("parse delta: %s", err.Error())
+			return nil, newValidationError("parse delta: %s", err.Error())
 		}
 
 		operations[i].EncodedDelta = delta
@@ -138,12 +200,11 @@ func (h *OperationProvider) getAnchorFile(address string, p protocol.Protocol) (
 		return nil, errors.Wrapf(err, "error reading anchor file[%s]", address)
 	}
 
-	af, err := models.ParseAnchorFile(content)
+	af, err := models.ParseAnchorFile(content, models.WithAddressValidator(h.validateAddress))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse content for anchor file[%s]", address)
+		return nil, errors.Wrapf(&ValidationError{cause: err}, "failed to parse content for anchor file[%s]", address)
 	}
 
-	// TODO: verify anchor file - issue-294
 	return af, nil
 }
 
@@ -154,12 +215,11 @@ func (h *OperationProvider) getMapFile(address string, p protocol.Protocol) (*mo
 		return nil, errors.Wrapf(err, "error reading map file[%s]", address)
 	}
 
-	mf, err := models.ParseMapFile(content)
+	mf, err := models.ParseMapFile(content, models.WithAddressValidator(h.validateAddress))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse content for map file[%s]", address)
+		return nil, errors.Wrapf(&ValidationError{cause: err}, "failed to parse content for map file[%s]", address)
 	}
 
-	// TODO: verify map file - issue-295
 	return mf, nil
 }
 
@@ -172,10 +232,9 @@ func (h *OperationProvider) getChunkFile(address string, p protocol.Protocol) (*
 
 	cf, err := models.ParseChunkFile(content)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse content for chunk file[%s]", address)
+		return nil, errors.Wrapf(&ValidationError{cause: err}, "failed to parse content for chunk file[%s]", address)
 	}
 
-	// TODO: verify chunk file - issue-296
 	return cf, nil
 }
 
@@ -186,12 +245,12 @@ func (h *OperationProvider) readFromCAS(address, alg string, maxSize uint) ([]by
 	}
 
 	if len(bytes) > int(maxSize) {
-		return nil, fmt.Errorf("content[%s] size %d exceeded maximum size %d", address, len(bytes), maxSize)
+		return nil, newValidationError("content[%s] size %d exceeded maximum size %d", address, len(bytes), maxSize)
 	}
 
 	content, err := h.dp.Decompress(alg, bytes)
 	if err != nil {
-		return nil, errors.Wrapf(err, "decompress CAS content[%s] using '%s'", address, alg)
+		return nil, errors.Wrapf(&ValidationError{cause: err}, "decompress CAS content[%s] using '%s'", address, alg)
 	}
 
 	return content, nil
@@ -208,7 +267,7 @@ type anchorOperations struct {
 func (h *OperationProvider) parseAnchorOperations(af *models.AnchorFile, txn *txn.SidetreeTxn) (*anchorOperations, error) { //nolint: funlen
 	log.Debugf("parsing anchor operations for anchor address: %s", txn.AnchorString)
 
-	p, err := h.getProtocol(txn.Namespace)
+	p, err := h.getProtocol(txn)
 	if err != nil {
 		return nil, err
 	}
@@ -219,12 +278,12 @@ func (h *OperationProvider) parseAnchorOperations(af *models.AnchorFile, txn *tx
 	for _, op := range af.Operations.Create {
 		suffix, err := docutil.CalculateUniqueSuffix(op.SuffixData, p.HashAlgorithmInMultiHashCode)
 		if err != nil {
-			return nil, err
+			return nil, &ValidationError{cause: err}
 		}
 
 		suffixModel, err := operation.ParseSuffixData(op.SuffixData, p.HashAlgorithmInMultiHashCode)
 		if err != nil {
-			return nil, err
+			return nil, &ValidationError{cause: err}
 		}
 
 		// TODO: they are assembling operation buffer in reference implementation (might be easier for version manager)
@@ -277,14 +336,20 @@ func (h *OperationProvider) parseAnchorOperations(af *models.AnchorFile, txn *tx
 	}, nil
 }
 
-func (h *OperationProvider) getProtocol(namespace string) (*protocol.Protocol, error) {
-	pc, err := h.pcp.ForNamespace(namespace)
+// getProtocol returns the protocol version that applies at the time of the given Sidetree transaction.
+// A ValidationError is returned if no protocol applies to the transaction since that won't change on retry.
+func (h *OperationProvider) getProtocol(txn *txn.SidetreeTxn) (*protocol.Protocol, error) {
+	// the protocol client of the namespace may not be available yet so the transaction may be retried
+	pc, err := h.pcp.ForNamespace(txn.Namespace)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "failed to get protocol client for namespace [%s]", txn.Namespace)
 	}
 
-	// TODO: get protocol based on Sidetree transaction - for now use current
-	p := pc.Current()
+	// the transaction isn't valid if no protocol applies at its transaction time
+	p, err := pc.Get(txn.TransactionTime)
+	if err != nil {
+		return nil, &ValidationError{cause: err}
+	}
 
 	return &p, nil
 }
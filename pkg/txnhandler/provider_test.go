@@ -9,6 +9,7 @@ package txnhandler
 import (
 	"encoding/json"
 	"errors"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -47,7 +48,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 
 		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
 
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
 		require.NoError(t, err)
 		require.NotEmpty(t, anchorString)
 
@@ -64,6 +65,199 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		require.Equal(t, createOpsNum+updateOpsNum+deactivateOpsNum+recoverOpsNum, len(txnOps))
 	})
 
+	t.Run("success - multiple chunk files", func(t *testing.T) {
+		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
+		deltas := models.CreateChunkFile(ops).Deltas
+
+		chunkPC := mocks.NewMockProtocolClient()
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, chunkPC, cp)
+
+		// the deltas don't fit in a single chunk file
+		content, err := handler.compressModel(&models.ChunkFile{Deltas: deltas[:2]}, "chunk")
+		require.NoError(t, err)
+		chunkPC.Protocol.MaxChunkFileSize = uint(len(content))
+
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
+		require.NoError(t, err)
+
+		pcp := mocks.NewMockProtocolClientProvider()
+		pcp.ProtocolClients[mocks.DefaultNS] = chunkPC
+
+		provider := NewOperationProvider(cas, pcp, cp)
+
+		ad, err := ParseAnchorData(anchorString)
+		require.NoError(t, err)
+		af, err := provider.getAnchorFile(ad.AnchorAddress, chunkPC.Protocol)
+		require.NoError(t, err)
+		mf, err := provider.getMapFile(af.MapFileHash, chunkPC.Protocol)
+		require.NoError(t, err)
+		require.True(t, len(mf.Chunks) > 1)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         mocks.DefaultNS,
+			AnchorString:      anchorString,
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, createOpsNum+updateOpsNum+deactivateOpsNum+recoverOpsNum, len(txnOps))
+
+		// deltas are assigned to operations in the order of the chunk files
+		for i, delta := range deltas {
+			require.Equal(t, delta, txnOps[i].EncodedDelta)
+		}
+	})
+
+	t.Run("error - number of deltas doesn't match operations", func(t *testing.T) {
+		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
+		deltas := models.CreateChunkFile(ops).Deltas
+
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		// the second chunk file is missing a delta
+		chunk1, err := handler.writeModelToCAS(&models.ChunkFile{Deltas: deltas[:2]}, "chunk")
+		require.NoError(t, err)
+		chunk2, err := handler.writeModelToCAS(&models.ChunkFile{Deltas: deltas[2 : len(deltas)-1]}, "chunk")
+		require.NoError(t, err)
+
+		mapAddr, err := handler.writeModelToCAS(models.CreateMapFile([]string{chunk1, chunk2}, ops), "map")
+		require.NoError(t, err)
+
+		anchorAddr, err := handler.writeModelToCAS(models.CreateAnchorFile(mapAddr, ops), "anchor")
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, mocks.NewMockProtocolClientProvider(), cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      (&AnchorData{NumberOfOperations: len(ops), AnchorAddress: anchorAddr}).GetAnchorString(),
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.Contains(t, err.Error(), "number of deltas[6] in chunk files doesn't match number of operations with deltas[7]")
+		require.True(t, IsValidationError(err))
+	})
+
+	t.Run("error - map file without chunk files", func(t *testing.T) {
+		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
+
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		mapAddr, err := handler.writeModelToCAS(models.CreateMapFile(nil, ops), "map")
+		require.NoError(t, err)
+
+		anchorAddr, err := handler.writeModelToCAS(models.CreateAnchorFile(mapAddr, ops), "anchor")
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, mocks.NewMockProtocolClientProvider(), cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      (&AnchorData{NumberOfOperations: len(ops), AnchorAddress: anchorAddr}).GetAnchorString(),
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.Contains(t, err.Error(), "map file doesn't reference any chunk files")
+	})
+
+	t.Run("error - chunk file not found", func(t *testing.T) {
+		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
+
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		mapAddr, err := handler.writeModelToCAS(models.CreateMapFile([]string{"chunk"}, ops), "map")
+		require.NoError(t, err)
+
+		anchorAddr, err := handler.writeModelToCAS(models.CreateAnchorFile(mapAddr, ops), "anchor")
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, mocks.NewMockProtocolClientProvider(), cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      (&AnchorData{NumberOfOperations: len(ops), AnchorAddress: anchorAddr}).GetAnchorString(),
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.Contains(t, err.Error(), "error reading chunk file[chunk]")
+	})
+
+	t.Run("success - protocol version is selected by transaction time", func(t *testing.T) {
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
+
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
+		require.NoError(t, err)
+
+		v1 := pc.Protocol
+		v2 := pc.Protocol
+		v2.StartingBlockChainTime = 100
+		v2.CompressionAlgorithm = "invalid"
+
+		versioned := mocks.NewMockProtocolClient()
+		versioned.Versions = []protocol.Protocol{v1, v2}
+
+		pcp := mocks.NewMockProtocolClientProvider()
+		pcp.ProtocolClients[mocks.DefaultNS] = versioned
+
+		provider := NewOperationProvider(cas, pcp, cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         mocks.DefaultNS,
+			AnchorString:      anchorString,
+			TransactionNumber: 1,
+			TransactionTime:   99,
+		})
+		require.NoError(t, err)
+		require.Equal(t, createOpsNum+updateOpsNum+deactivateOpsNum+recoverOpsNum, len(txnOps))
+
+		txnOps, err = provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         mocks.DefaultNS,
+			AnchorString:      anchorString,
+			TransactionNumber: 2,
+			TransactionTime:   100,
+		})
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.Contains(t, err.Error(), "compression algorithm 'invalid' not supported")
+	})
+
+	t.Run("error - protocol version not defined for transaction time", func(t *testing.T) {
+		v := pc.Protocol
+		v.StartingBlockChainTime = 100
+
+		versioned := mocks.NewMockProtocolClient()
+		versioned.Versions = []protocol.Protocol{v}
+
+		pcp := mocks.NewMockProtocolClientProvider()
+		pcp.ProtocolClients[mocks.DefaultNS] = versioned
+
+		provider := NewOperationProvider(mocks.NewMockCasClient(nil), pcp, cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         mocks.DefaultNS,
+			AnchorString:      "1" + delimiter + "anchor",
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [1]")
+		require.True(t, IsValidationError(err))
+	})
+
 	t.Run("error - number of operations doesn't match", func(t *testing.T) {
 		cas := mocks.NewMockCasClient(nil)
 		handler := NewOperationHandler(cas, pc, cp)
@@ -71,7 +265,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
 
 		// anchor string has 9 operations "9.anchorAddress"
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
 		require.NoError(t, err)
 		require.NotEmpty(t, anchorString)
 
@@ -93,6 +287,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, txnOps)
 		require.Contains(t, err.Error(), "number of txn ops[9] doesn't match anchor string num of ops[7]")
+		require.True(t, IsValidationError(err))
 	})
 
 	t.Run("error - read from CAS error", func(t *testing.T) {
@@ -111,6 +306,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, txnOps)
 		require.Contains(t, err.Error(), "error reading anchor file[anchor]: retrieve CAS content[anchor]: CAS error")
+		require.False(t, IsValidationError(err))
 	})
 
 	t.Run("error - parse anchor operations error", func(t *testing.T) {
@@ -119,7 +315,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 
 		ops := getTestOperations(createOpsNum, updateOpsNum, deactivateOpsNum, recoverOpsNum)
 
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
 		require.NoError(t, err)
 		require.NotEmpty(t, anchorString)
 
@@ -140,6 +336,45 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, txnOps)
 		require.Contains(t, err.Error(), "parse anchor operations: algorithm not supported")
+		require.True(t, IsValidationError(err))
+	})
+
+	t.Run("error - malformed suffix data in anchor file", func(t *testing.T) {
+		cas := mocks.NewMockCasClient(nil)
+		provider := NewOperationProvider(cas, mocks.NewMockProtocolClientProvider(), cp)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		anchorString, _, err := handler.PrepareTxnFiles(generateOperations(1, batch.OperationTypeCreate))
+		require.NoError(t, err)
+
+		ad, err := ParseAnchorData(anchorString)
+		require.NoError(t, err)
+
+		// replace the suffix data of the create operation in the anchor file with malformed data
+		af, err := provider.getAnchorFile(ad.AnchorAddress, pc.Protocol)
+		require.NoError(t, err)
+
+		af.Operations.Create[0].SuffixData = "invalid"
+
+		anchorBytes, err := json.Marshal(af)
+		require.NoError(t, err)
+
+		content, err := cp.Compress(compressionAlgorithm, anchorBytes)
+		require.NoError(t, err)
+
+		anchor, err := cas.Write(content)
+		require.NoError(t, err)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      "1" + delimiter + anchor,
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.Contains(t, err.Error(), "parse anchor operations")
+		require.True(t, IsValidationError(err))
 	})
 
 	t.Run("error - parse anchor data error", func(t *testing.T) {
@@ -154,6 +389,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, txnOps)
 		require.Contains(t, err.Error(), "parse anchor data[abc.anchor] failed")
+		require.True(t, IsValidationError(err))
 	})
 
 	t.Run("success - deactivate only", func(t *testing.T) {
@@ -165,7 +401,7 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		cas := mocks.NewMockCasClient(nil)
 		handler := NewOperationHandler(cas, pc, cp)
 
-		anchorString, err := handler.PrepareTxnFiles(ops)
+		anchorString, _, err := handler.PrepareTxnFiles(ops)
 		require.NoError(t, err)
 		require.NotEmpty(t, anchorString)
 
@@ -182,6 +418,65 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		require.Equal(t, deactivateOpsNum, len(txnOps))
 	})
 
+	t.Run("error - invalid anchor address", func(t *testing.T) {
+		provider := NewOperationProvider(mocks.NewMockCasClient(nil), mocks.NewMockProtocolClientProvider(), cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      "1" + delimiter + "anchor/address",
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.True(t, IsValidationError(err))
+		require.Contains(t, err.Error(), "invalid anchor address in anchor string")
+	})
+
+	t.Run("anchor address with address validator", func(t *testing.T) {
+		provider := NewOperationProvider(mocks.NewMockCasClient(nil), mocks.NewMockProtocolClientProvider(), cp,
+			WithAddressValidator(models.NewAddressValidator(100, regexp.MustCompile(`^[a-z]+/[a-z]+$`))))
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      "1" + delimiter + "anchor/address",
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+
+		// the anchor address is accepted so the anchor file is read from CAS (where it doesn't exist)
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.NotContains(t, err.Error(), "invalid anchor address in anchor string")
+		require.Contains(t, err.Error(), "error reading anchor file")
+	})
+
+	t.Run("error - duplicate suffix in batch operations", func(t *testing.T) {
+		op, err := generateOperation(1, batch.OperationTypeDeactivate)
+		require.NoError(t, err)
+
+		cas := mocks.NewMockCasClient(nil)
+		handler := NewOperationHandler(cas, pc, cp)
+
+		anchorString, _, err := handler.PrepareTxnFiles([]*batch.Operation{op, op})
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, mocks.NewMockProtocolClientProvider(), cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      anchorString,
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+
+		require.Error(t, err)
+		require.Nil(t, txnOps)
+		require.True(t, IsValidationError(err))
+		require.Contains(t, err.Error(), "duplicate suffix["+op.UniqueSuffix+"] found in batch operations")
+	})
+
 	t.Run("error - protocol client not found for namespace", func(t *testing.T) {
 		const createOpsNum = 2
 
@@ -209,6 +504,29 @@ func TestHandler_GetTxnOperations(t *testing.T) {
 		})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "protocol client not found for namespace [did:sidetree]")
+		require.False(t, IsValidationError(err))
+		require.Nil(t, txnOps)
+	})
+
+	t.Run("error - protocol not defined for transaction time", func(t *testing.T) {
+		pc := mocks.NewMockProtocolClient()
+		pc.Versions = []protocol.Protocol{pc.Protocol}
+		pc.Versions[0].StartingBlockChainTime = 10
+
+		pcp := mocks.NewMockProtocolClientProvider()
+		pcp.ProtocolClients[mocks.DefaultNS] = pc
+
+		provider := NewOperationProvider(mocks.NewMockCasClient(nil), pcp, cp)
+
+		txnOps, err := provider.GetTxnOperations(&txn.SidetreeTxn{
+			Namespace:         defaultNS,
+			AnchorString:      "1" + delimiter + "anchor",
+			TransactionNumber: 1,
+			TransactionTime:   1,
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [1]")
+		require.True(t, IsValidationError(err))
 		require.Nil(t, txnOps)
 	})
 }
@@ -219,7 +537,7 @@ func TestHandler_GetAnchorFile(t *testing.T) {
 	p := protocol.Protocol{MaxAnchorFileSize: maxFileSize, CompressionAlgorithm: compressionAlgorithm}
 
 	cas := mocks.NewMockCasClient(nil)
-	content, err := cp.Compress(compressionAlgorithm, []byte("{}"))
+	content, err := cp.Compress(compressionAlgorithm, []byte(`{"mapFileHash":"address","Operations":{}}`))
 	require.NoError(t, err)
 	address, err := cas.Write(content)
 	require.NoError(t, err)
@@ -255,6 +573,20 @@ func TestHandler_GetAnchorFile(t *testing.T) {
 		require.Nil(t, file)
 		require.Contains(t, err.Error(), "failed to parse content for anchor file")
 	})
+
+	t.Run("error - invalid anchor file", func(t *testing.T) {
+		content, err := cp.Compress(compressionAlgorithm, []byte("{}"))
+		require.NoError(t, err)
+		address, err := cas.Write(content)
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, pcp, cp)
+		file, err := provider.getAnchorFile(address, p)
+		require.Error(t, err)
+		require.Nil(t, file)
+		require.True(t, IsValidationError(err))
+		require.Contains(t, err.Error(), "anchor file doesn't contain any operations")
+	})
 }
 
 func TestHandler_GetMapFile(t *testing.T) {
@@ -263,7 +595,7 @@ func TestHandler_GetMapFile(t *testing.T) {
 	p := protocol.Protocol{MaxMapFileSize: maxFileSize, CompressionAlgorithm: compressionAlgorithm}
 
 	cas := mocks.NewMockCasClient(nil)
-	content, err := cp.Compress(compressionAlgorithm, []byte("{}"))
+	content, err := cp.Compress(compressionAlgorithm, []byte(`{"chunks":[{"chunk_file_uri":"address"}]}`))
 	require.NoError(t, err)
 	address, err := cas.Write(content)
 	require.NoError(t, err)
@@ -299,6 +631,20 @@ func TestHandler_GetMapFile(t *testing.T) {
 		require.Nil(t, file)
 		require.Contains(t, err.Error(), "failed to parse content for map file")
 	})
+
+	t.Run("error - invalid map file", func(t *testing.T) {
+		content, err := cp.Compress(compressionAlgorithm, []byte("{}"))
+		require.NoError(t, err)
+		address, err := cas.Write(content)
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, pcp, cp)
+		file, err := provider.getMapFile(address, p)
+		require.Error(t, err)
+		require.Nil(t, file)
+		require.True(t, IsValidationError(err))
+		require.Contains(t, err.Error(), "map file doesn't reference any chunk files")
+	})
 }
 
 func TestHandler_GetChunkFile(t *testing.T) {
@@ -307,7 +653,7 @@ func TestHandler_GetChunkFile(t *testing.T) {
 	p := protocol.Protocol{MaxChunkFileSize: maxFileSize, CompressionAlgorithm: compressionAlgorithm}
 
 	cas := mocks.NewMockCasClient(nil)
-	content, err := cp.Compress(compressionAlgorithm, []byte("{}"))
+	content, err := cp.Compress(compressionAlgorithm, []byte(`{"deltas":["delta"]}`))
 	require.NoError(t, err)
 	address, err := cas.Write(content)
 	require.NoError(t, err)
@@ -342,6 +688,20 @@ func TestHandler_GetChunkFile(t *testing.T) {
 		require.Nil(t, file)
 		require.Contains(t, err.Error(), "failed to parse content for chunk file")
 	})
+
+	t.Run("error - invalid chunk file", func(t *testing.T) {
+		content, err := cp.Compress(compressionAlgorithm, []byte("{}"))
+		require.NoError(t, err)
+		address, err := cas.Write(content)
+		require.NoError(t, err)
+
+		provider := NewOperationProvider(cas, pcp, cp)
+		file, err := provider.getChunkFile(address, p)
+		require.Error(t, err)
+		require.Nil(t, file)
+		require.True(t, IsValidationError(err))
+		require.Contains(t, err.Error(), "chunk file doesn't contain any deltas")
+	})
 }
 
 func TestHandler_readFromCAS(t *testing.T) {
@@ -379,6 +739,7 @@ func TestHandler_readFromCAS(t *testing.T) {
 		require.Error(t, err)
 		require.Nil(t, file)
 		require.Contains(t, err.Error(), "exceeded maximum size 20")
+		require.True(t, IsValidationError(err))
 	})
 
 	t.Run("error - decompression error", func(t *testing.T) {
@@ -0,0 +1,105 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+// Client implements protocol client for a set of protocol versions. The versions are sorted by
+// starting blockchain time and the version that applies at a given transaction time is the latest
+// version that started at or before that time.
+type Client struct {
+	// lastLedgerTime is accessed atomically so it's the first field to guarantee 64-bit alignment
+	lastLedgerTime uint64
+
+	protocols  []protocol.Protocol
+	ledgerTime LedgerTimeProvider
+}
+
+// NewClient returns a new protocol client for the given protocol versions. The given ledger time
+// provider determines the version that is currently in force (see Current).
+func NewClient(ledgerTime LedgerTimeProvider, protocols ...protocol.Protocol) (*Client, error) {
+	if ledgerTime == nil {
+		return nil, errors.New("ledger time provider is required")
+	}
+
+	if len(protocols) == 0 {
+		return nil, errors.New("at least one protocol version is required")
+	}
+
+	sorted := make([]protocol.Protocol, len(protocols))
+	copy(sorted, protocols)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartingBlockChainTime < sorted[j].StartingBlockChainTime
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].StartingBlockChainTime == sorted[i-1].StartingBlockChainTime {
+			return nil, fmt.Errorf("duplicate protocol version for starting blockchain time [%d]", sorted[i].StartingBlockChainTime)
+		}
+	}
+
+	return &Client{protocols: sorted, ledgerTime: ledgerTime}, nil
+}
+
+// Current returns the version of protocol that is in force at the current ledger time. (A version
+// that starts at a later time isn't returned until the ledger reaches its starting blockchain time.)
+// If the ledger time can't be determined then the ledger time that was last determined is used.
+func (c *Client) Current() protocol.Protocol {
+	p, err := c.current()
+	if err != nil {
+		// the ledger hasn't reached the first version yet
+		return c.protocols[0]
+	}
+
+	return p
+}
+
+// current returns the version of protocol that applies at the current ledger time
+func (c *Client) current() (protocol.Protocol, error) {
+	ledgerTime, err := c.ledgerTime.LedgerTime()
+	if err != nil {
+		ledgerTime = atomic.LoadUint64(&c.lastLedgerTime)
+
+		log.Warnf("Failed to get ledger time: %s. Using last ledger time [%d].", err, ledgerTime)
+	} else {
+		atomic.StoreUint64(&c.lastLedgerTime, ledgerTime)
+	}
+
+	return c.Get(ledgerTime)
+}
+
+// Get returns the version of protocol that applies at the given transaction time
+func (c *Client) Get(transactionTime uint64) (protocol.Protocol, error) {
+	// find the first version that starts after the given transaction time
+	i := sort.Search(len(c.protocols), func(i int) bool {
+		return uint64(c.protocols[i].StartingBlockChainTime) > transactionTime
+	})
+
+	if i == 0 {
+		return protocol.Protocol{}, fmt.Errorf("protocol parameters are not defined for transaction time [%d]", transactionTime)
+	}
+
+	return c.protocols[i-1], nil
+}
+
+// Versions returns all protocol versions sorted by starting blockchain time
+func (c *Client) Versions() []protocol.Protocol {
+	versions := make([]protocol.Protocol, len(c.protocols))
+	copy(versions, c.protocols)
+
+	return versions
+}
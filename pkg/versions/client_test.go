@@ -0,0 +1,129 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+func TestNewClient(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		c, err := NewClient(NewLedgerClock(0), protocol.Protocol{StartingBlockChainTime: 100}, protocol.Protocol{StartingBlockChainTime: 0})
+		require.NoError(t, err)
+		require.NotNil(t, c)
+
+		versions := c.Versions()
+		require.Len(t, versions, 2)
+		require.Equal(t, uint(0), versions[0].StartingBlockChainTime)
+		require.Equal(t, uint(100), versions[1].StartingBlockChainTime)
+	})
+
+	t.Run("error - no ledger time provider", func(t *testing.T) {
+		c, err := NewClient(nil, protocol.Protocol{})
+		require.Error(t, err)
+		require.Nil(t, c)
+		require.Contains(t, err.Error(), "ledger time provider is required")
+	})
+
+	t.Run("error - no versions", func(t *testing.T) {
+		c, err := NewClient(NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, c)
+		require.Contains(t, err.Error(), "at least one protocol version is required")
+	})
+
+	t.Run("error - duplicate starting time", func(t *testing.T) {
+		c, err := NewClient(NewLedgerClock(0), protocol.Protocol{StartingBlockChainTime: 10}, protocol.Protocol{StartingBlockChainTime: 10})
+		require.Error(t, err)
+		require.Nil(t, c)
+		require.Contains(t, err.Error(), "duplicate protocol version for starting blockchain time [10]")
+	})
+}
+
+func TestClient_Get(t *testing.T) {
+	v1 := protocol.Protocol{StartingBlockChainTime: 10, MaxOperationsPerBatch: 1}
+	v2 := protocol.Protocol{StartingBlockChainTime: 100, MaxOperationsPerBatch: 2}
+	v3 := protocol.Protocol{StartingBlockChainTime: 500, MaxOperationsPerBatch: 3}
+
+	c, err := NewClient(NewLedgerClock(0), v3, v1, v2)
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		tests := []struct {
+			txnTime  uint64
+			expected protocol.Protocol
+		}{
+			{txnTime: 10, expected: v1},
+			{txnTime: 99, expected: v1},
+			{txnTime: 100, expected: v2},
+			{txnTime: 499, expected: v2},
+			{txnTime: 500, expected: v3},
+			{txnTime: 10000, expected: v3},
+		}
+
+		for _, test := range tests {
+			p, err := c.Get(test.txnTime)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, p)
+		}
+	})
+
+	t.Run("error - transaction time before first version", func(t *testing.T) {
+		p, err := c.Get(9)
+		require.Error(t, err)
+		require.Empty(t, p)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [9]")
+	})
+}
+
+func TestClient_Current(t *testing.T) {
+	v1 := protocol.Protocol{StartingBlockChainTime: 10, MaxOperationsPerBatch: 1}
+	v2 := protocol.Protocol{StartingBlockChainTime: 100, MaxOperationsPerBatch: 2}
+
+	t.Run("version in force at ledger time", func(t *testing.T) {
+		clock := NewLedgerClock(0)
+
+		c, err := NewClient(clock, v2, v1)
+		require.NoError(t, err)
+
+		// the first version applies until the ledger reaches it
+		require.Equal(t, v1, c.Current())
+
+		clock.Set(99)
+		require.Equal(t, v1, c.Current())
+
+		// a later version isn't current until the ledger reaches its starting time
+		clock.Set(100)
+		require.Equal(t, v2, c.Current())
+	})
+
+	t.Run("ledger time error", func(t *testing.T) {
+		lt := &mockLedgerTime{time: 100}
+
+		c, err := NewClient(lt, v1, v2)
+		require.NoError(t, err)
+		require.Equal(t, v2, c.Current())
+
+		// the last ledger time is used
+		lt.err = errors.New("ledger error")
+		require.Equal(t, v2, c.Current())
+	})
+}
+
+type mockLedgerTime struct {
+	time uint64
+	err  error
+}
+
+func (m *mockLedgerTime) LedgerTime() (uint64, error) {
+	return m.time, m.err
+}
@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/compression"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+// Config contains the protocol versions of all namespaces
+type Config struct {
+	Namespaces []NamespaceConfig `json:"namespaces" yaml:"namespaces"`
+}
+
+// NamespaceConfig contains the protocol versions of a namespace
+type NamespaceConfig struct {
+	Namespace string           `json:"namespace" yaml:"namespace"`
+	Protocols []ProtocolConfig `json:"protocols" yaml:"protocols"`
+}
+
+// ProtocolConfig contains the parameters of a protocol version
+type ProtocolConfig struct {
+	StartingBlockChainTime       uint   `json:"startingBlockChainTime" yaml:"startingBlockChainTime"`
+	HashAlgorithmInMultiHashCode uint   `json:"hashAlgorithmInMultiHashCode" yaml:"hashAlgorithmInMultiHashCode"`
+	MaxOperationsPerBatch        uint   `json:"maxOperationsPerBatch" yaml:"maxOperationsPerBatch"`
+	MaxDeltaByteSize             uint   `json:"maxDeltaByteSize" yaml:"maxDeltaByteSize"`
+	CompressionAlgorithm         string `json:"compressionAlgorithm" yaml:"compressionAlgorithm"`
+	MaxAnchorFileSize            uint   `json:"maxAnchorFileSize" yaml:"maxAnchorFileSize"`
+	MaxMapFileSize               uint   `json:"maxMapFileSize" yaml:"maxMapFileSize"`
+	MaxChunkFileSize             uint   `json:"maxChunkFileSize" yaml:"maxChunkFileSize"`
+}
+
+// NewClientProviderFromFile loads the protocol versions of all namespaces from the given JSON or YAML
+// file (determined by the file extension) and returns a protocol client provider for them.
+// Compression algorithms are validated against the given compression registry. The given ledger time
+// provider determines the version that is currently in force.
+func NewClientProviderFromFile(path string, registry *compression.Registry, ledgerTime LedgerTimeProvider) (*ClientProvider, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read protocol config file [%s]: %s", path, err.Error())
+	}
+
+	cfg, err := ParseConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClientProviderFromConfig(cfg, registry, ledgerTime)
+}
+
+// ParseConfig parses protocol config in the given format (json, yaml or yml)
+func ParseConfig(data []byte, format string) (*Config, error) {
+	cfg := &Config{}
+
+	var err error
+
+	switch strings.ToLower(format) {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	case "yaml", "yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	default:
+		return nil, fmt.Errorf("protocol config format [%s] not supported", format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse protocol config: %s", err.Error())
+	}
+
+	return cfg, nil
+}
+
+// NewClientProviderFromConfig validates the given protocol config and returns a protocol client provider for it
+func NewClientProviderFromConfig(cfg *Config, registry *compression.Registry, ledgerTime LedgerTimeProvider) (*ClientProvider, error) {
+	if len(cfg.Namespaces) == 0 {
+		return nil, errors.New("protocol config must define at least one namespace")
+	}
+
+	provider := NewClientProvider()
+
+	for _, ns := range cfg.Namespaces {
+		if ns.Namespace == "" {
+			return nil, errors.New("protocol config namespace is empty")
+		}
+
+		if _, err := provider.ForNamespace(ns.Namespace); err == nil {
+			return nil, fmt.Errorf("duplicate protocol config for namespace [%s]", ns.Namespace)
+		}
+
+		protocols, err := ns.protocols(registry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protocol config for namespace [%s]: %s", ns.Namespace, err.Error())
+		}
+
+		client, err := NewClient(ledgerTime, protocols...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protocol config for namespace [%s]: %s", ns.Namespace, err.Error())
+		}
+
+		provider.Add(ns.Namespace, client)
+	}
+
+	return provider, nil
+}
+
+func (c *NamespaceConfig) protocols(registry *compression.Registry) ([]protocol.Protocol, error) {
+	if len(c.Protocols) == 0 {
+		return nil, errors.New("at least one protocol version is required")
+	}
+
+	var protocols []protocol.Protocol
+
+	for i, pc := range c.Protocols {
+		if i > 0 && pc.StartingBlockChainTime <= c.Protocols[i-1].StartingBlockChainTime {
+			return nil, fmt.Errorf("starting blockchain time [%d] must be greater than previous starting blockchain time [%d]",
+				pc.StartingBlockChainTime, c.Protocols[i-1].StartingBlockChainTime)
+		}
+
+		p := pc.protocol()
+
+		if err := validate(p, registry); err != nil {
+			return nil, fmt.Errorf("protocol version with starting blockchain time [%d]: %s", p.StartingBlockChainTime, err.Error())
+		}
+
+		protocols = append(protocols, p)
+	}
+
+	return protocols, nil
+}
+
+func (c *ProtocolConfig) protocol() protocol.Protocol {
+	return protocol.Protocol{
+		StartingBlockChainTime:       c.StartingBlockChainTime,
+		HashAlgorithmInMultiHashCode: c.HashAlgorithmInMultiHashCode,
+		MaxOperationsPerBatch:        c.MaxOperationsPerBatch,
+		MaxDeltaByteSize:             c.MaxDeltaByteSize,
+		CompressionAlgorithm:         c.CompressionAlgorithm,
+		MaxAnchorFileSize:            c.MaxAnchorFileSize,
+		MaxMapFileSize:               c.MaxMapFileSize,
+		MaxChunkFileSize:             c.MaxChunkFileSize,
+	}
+}
+
+func validate(p protocol.Protocol, registry *compression.Registry) error {
+	if _, err := docutil.GetHash(p.HashAlgorithmInMultiHashCode); err != nil {
+		return fmt.Errorf("hash algorithm in multihash code [%d]: %s", p.HashAlgorithmInMultiHashCode, err.Error())
+	}
+
+	if !registry.IsSupported(p.CompressionAlgorithm) {
+		return fmt.Errorf("compression algorithm [%s] not supported", p.CompressionAlgorithm)
+	}
+
+	limits := []struct {
+		name  string
+		value uint
+	}{
+		{"maxOperationsPerBatch", p.MaxOperationsPerBatch},
+		{"maxDeltaByteSize", p.MaxDeltaByteSize},
+		{"maxAnchorFileSize", p.MaxAnchorFileSize},
+		{"maxMapFileSize", p.MaxMapFileSize},
+		{"maxChunkFileSize", p.MaxChunkFileSize},
+	}
+
+	for _, l := range limits {
+		if l.value == 0 {
+			return fmt.Errorf("%s must be greater than zero", l.name)
+		}
+	}
+
+	return nil
+}
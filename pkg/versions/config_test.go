@@ -0,0 +1,221 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/compression"
+)
+
+func TestNewClientProviderFromFile(t *testing.T) {
+	registry := compression.New(compression.WithDefaultAlgorithms())
+
+	t.Run("success - yaml", func(t *testing.T) {
+		clock := NewLedgerClock(99)
+
+		provider, err := NewClientProviderFromFile("testdata/protocol.yaml", registry, clock)
+		require.NoError(t, err)
+
+		pc, err := provider.ForNamespace(ns)
+		require.NoError(t, err)
+		require.Equal(t, uint(0), pc.Current().StartingBlockChainTime)
+
+		clock.Set(100)
+		require.Equal(t, uint(100), pc.Current().StartingBlockChainTime)
+
+		p, err := pc.Get(99)
+		require.NoError(t, err)
+		require.Equal(t, uint(0), p.StartingBlockChainTime)
+		require.Equal(t, uint(18), p.HashAlgorithmInMultiHashCode)
+		require.Equal(t, uint(1), p.MaxOperationsPerBatch)
+		require.Equal(t, uint(2000), p.MaxDeltaByteSize)
+		require.Equal(t, "GZIP", p.CompressionAlgorithm)
+		require.Equal(t, uint(1000000), p.MaxAnchorFileSize)
+		require.Equal(t, uint(1000000), p.MaxMapFileSize)
+		require.Equal(t, uint(10000000), p.MaxChunkFileSize)
+
+		pc, err = provider.ForNamespace("did:other")
+		require.NoError(t, err)
+
+		_, err = pc.Get(49)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [49]")
+	})
+
+	t.Run("success - json", func(t *testing.T) {
+		provider, err := NewClientProviderFromFile("testdata/protocol.json", registry, NewLedgerClock(100))
+		require.NoError(t, err)
+
+		pc, err := provider.ForNamespace(ns)
+		require.NoError(t, err)
+		require.Equal(t, uint(10000), pc.Current().MaxOperationsPerBatch)
+
+		pc, err = provider.ForNamespace("did:other")
+		require.Error(t, err)
+		require.Nil(t, pc)
+		require.Contains(t, err.Error(), "protocol client not found for namespace [did:other]")
+	})
+
+	t.Run("error - file not found", func(t *testing.T) {
+		provider, err := NewClientProviderFromFile("testdata/missing.yaml", registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "failed to read protocol config file [testdata/missing.yaml]")
+	})
+
+	t.Run("error - invalid file", func(t *testing.T) {
+		provider, err := NewClientProviderFromFile("testdata/invalid.yaml", registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "failed to parse protocol config")
+	})
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("error - format not supported", func(t *testing.T) {
+		cfg, err := ParseConfig([]byte("namespaces"), "xml")
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(), "protocol config format [xml] not supported")
+	})
+
+	t.Run("error - unknown json field", func(t *testing.T) {
+		cfg, err := ParseConfig([]byte(`{"namespaces":[{"namespace":"did:sidetree","other":1}]}`), "json")
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(), "unknown field")
+	})
+
+	t.Run("error - unknown yaml field", func(t *testing.T) {
+		cfg, err := ParseConfig([]byte("namespaces:\n  - namespace: did:sidetree\n    other: 1\n"), "yml")
+		require.Error(t, err)
+		require.Nil(t, cfg)
+		require.Contains(t, err.Error(), "field other not found")
+	})
+}
+
+func TestNewClientProviderFromConfig(t *testing.T) {
+	registry := compression.New(compression.WithDefaultAlgorithms())
+
+	t.Run("error - no namespaces", func(t *testing.T) {
+		provider, err := NewClientProviderFromConfig(&Config{}, registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "protocol config must define at least one namespace")
+	})
+
+	t.Run("error - empty namespace", func(t *testing.T) {
+		cfg := newConfig(ns, newProtocolConfig(0))
+		cfg.Namespaces[0].Namespace = ""
+
+		provider, err := NewClientProviderFromConfig(cfg, registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "protocol config namespace is empty")
+	})
+
+	t.Run("error - duplicate namespace", func(t *testing.T) {
+		cfg := newConfig(ns, newProtocolConfig(0))
+		cfg.Namespaces = append(cfg.Namespaces, cfg.Namespaces[0])
+
+		provider, err := NewClientProviderFromConfig(cfg, registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "duplicate protocol config for namespace [did:sidetree]")
+	})
+
+	t.Run("error - no protocol versions", func(t *testing.T) {
+		provider, err := NewClientProviderFromConfig(newConfig(ns), registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "invalid protocol config for namespace [did:sidetree]: at least one protocol version is required")
+	})
+
+	t.Run("error - starting blockchain time not increasing", func(t *testing.T) {
+		provider, err := NewClientProviderFromConfig(newConfig(ns, newProtocolConfig(100), newProtocolConfig(100)), registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "starting blockchain time [100] must be greater than previous starting blockchain time [100]")
+
+		provider, err = NewClientProviderFromConfig(newConfig(ns, newProtocolConfig(100), newProtocolConfig(10)), registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "starting blockchain time [10] must be greater than previous starting blockchain time [100]")
+	})
+
+	t.Run("error - hash algorithm not supported", func(t *testing.T) {
+		pc := newProtocolConfig(0)
+		pc.HashAlgorithmInMultiHashCode = 55
+
+		provider, err := NewClientProviderFromConfig(newConfig(ns, pc), registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "protocol version with starting blockchain time [0]: hash algorithm in multihash code [55]")
+	})
+
+	t.Run("error - compression algorithm not supported", func(t *testing.T) {
+		pc := newProtocolConfig(0)
+		pc.CompressionAlgorithm = "other"
+
+		provider, err := NewClientProviderFromConfig(newConfig(ns, pc), registry, NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "compression algorithm [other] not supported")
+
+		provider, err = NewClientProviderFromConfig(newConfig(ns, newProtocolConfig(0)), compression.New(), NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, provider)
+		require.Contains(t, err.Error(), "compression algorithm [GZIP] not supported")
+	})
+
+	t.Run("error - zero size limits", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			update func(pc *ProtocolConfig)
+		}{
+			{"maxOperationsPerBatch", func(pc *ProtocolConfig) { pc.MaxOperationsPerBatch = 0 }},
+			{"maxDeltaByteSize", func(pc *ProtocolConfig) { pc.MaxDeltaByteSize = 0 }},
+			{"maxAnchorFileSize", func(pc *ProtocolConfig) { pc.MaxAnchorFileSize = 0 }},
+			{"maxMapFileSize", func(pc *ProtocolConfig) { pc.MaxMapFileSize = 0 }},
+			{"maxChunkFileSize", func(pc *ProtocolConfig) { pc.MaxChunkFileSize = 0 }},
+		}
+
+		for _, tc := range tests {
+			pc := newProtocolConfig(0)
+			tc.update(&pc)
+
+			provider, err := NewClientProviderFromConfig(newConfig(ns, pc), registry, NewLedgerClock(0))
+			require.Error(t, err)
+			require.Nil(t, provider)
+			require.Contains(t, err.Error(), tc.name+" must be greater than zero")
+		}
+	})
+}
+
+func newConfig(namespace string, protocols ...ProtocolConfig) *Config {
+	return &Config{
+		Namespaces: []NamespaceConfig{
+			{Namespace: namespace, Protocols: protocols},
+		},
+	}
+}
+
+func newProtocolConfig(startingBlockChainTime uint) ProtocolConfig {
+	return ProtocolConfig{
+		StartingBlockChainTime:       startingBlockChainTime,
+		HashAlgorithmInMultiHashCode: 18,
+		MaxOperationsPerBatch:        100,
+		MaxDeltaByteSize:             1000,
+		CompressionAlgorithm:         "GZIP",
+		MaxAnchorFileSize:            1000,
+		MaxMapFileSize:               1000,
+		MaxChunkFileSize:             1000,
+	}
+}
@@ -0,0 +1,38 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"sync/atomic"
+)
+
+// LedgerTimeProvider returns the current ledger time, i.e. the transaction time of the latest block.
+// It determines which protocol version is currently in force.
+type LedgerTimeProvider interface {
+	LedgerTime() (uint64, error)
+}
+
+// LedgerClock holds the current ledger time. It should be set by the component that observes the ledger
+// (e.g. whenever a new block is observed).
+type LedgerClock struct {
+	time uint64
+}
+
+// NewLedgerClock returns a new ledger clock that is set to the given ledger time
+func NewLedgerClock(ledgerTime uint64) *LedgerClock {
+	return &LedgerClock{time: ledgerTime}
+}
+
+// Set sets the current ledger time
+func (c *LedgerClock) Set(ledgerTime uint64) {
+	atomic.StoreUint64(&c.time, ledgerTime)
+}
+
+// LedgerTime returns the current ledger time
+func (c *LedgerClock) LedgerTime() (uint64, error) {
+	return atomic.LoadUint64(&c.time), nil
+}
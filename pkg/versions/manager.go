@@ -0,0 +1,98 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+// Manager manages the protocol versions of a namespace. Versions are keyed by the starting
+// blockchain time of their protocol parameters.
+type Manager struct {
+	client   *Client
+	versions map[uint]protocol.Version
+}
+
+// NewManager returns a new version manager for the given protocol versions. The given ledger time
+// provider determines the version that is currently in force (see Current).
+func NewManager(ledgerTime LedgerTimeProvider, versions ...protocol.Version) (*Manager, error) {
+	var protocols []protocol.Protocol
+
+	m := make(map[uint]protocol.Version)
+	for _, v := range versions {
+		protocols = append(protocols, v.Protocol())
+		m[v.Protocol().StartingBlockChainTime] = v
+	}
+
+	client, err := NewClient(ledgerTime, protocols...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{client: client, versions: m}, nil
+}
+
+// Current returns the version of protocol that is in force at the current ledger time. Unlike the
+// protocol client, an error is returned if the ledger hasn't reached the first version yet.
+func (m *Manager) Current() (protocol.Version, error) {
+	p, err := m.client.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.versions[p.StartingBlockChainTime], nil
+}
+
+// Get returns the version of protocol that applies at the given transaction time
+func (m *Manager) Get(transactionTime uint64) (protocol.Version, error) {
+	p, err := m.client.Get(transactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.versions[p.StartingBlockChainTime], nil
+}
+
+// Client returns the protocol client for the protocol parameters of the managed versions
+func (m *Manager) Client() protocol.Client {
+	return m.client
+}
+
+// ManagerProvider returns the version manager for a namespace
+type ManagerProvider struct {
+	mutex    sync.RWMutex
+	managers map[string]protocol.VersionManager
+}
+
+// NewManagerProvider returns a new version manager provider
+func NewManagerProvider() *ManagerProvider {
+	return &ManagerProvider{managers: make(map[string]protocol.VersionManager)}
+}
+
+// Add adds the version manager for the given namespace
+func (p *ManagerProvider) Add(namespace string, vm protocol.VersionManager) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.managers[namespace] = vm
+}
+
+// ForNamespace returns the version manager for the given namespace
+func (p *ManagerProvider) ForNamespace(namespace string) (protocol.VersionManager, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	vm, ok := p.managers[namespace]
+	if !ok {
+		return nil, fmt.Errorf("version manager not found for namespace [%s]", namespace)
+	}
+
+	return vm, nil
+}
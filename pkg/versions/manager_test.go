@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+const ns = "did:sidetree"
+
+func TestNewManager(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		v1 := NewVersion(protocol.Protocol{StartingBlockChainTime: 0})
+		v2 := NewVersion(protocol.Protocol{StartingBlockChainTime: 100})
+
+		clock := NewLedgerClock(99)
+
+		m, err := NewManager(clock, v2, v1)
+		require.NoError(t, err)
+		require.NotNil(t, m)
+
+		current, err := m.Current()
+		require.NoError(t, err)
+		require.Equal(t, v1, current)
+
+		clock.Set(100)
+
+		current, err = m.Current()
+		require.NoError(t, err)
+		require.Equal(t, v2, current)
+
+		v, err := m.Get(99)
+		require.NoError(t, err)
+		require.Equal(t, v1, v)
+
+		v, err = m.Get(100)
+		require.NoError(t, err)
+		require.Equal(t, v2, v)
+
+		require.Equal(t, v2.Protocol(), m.Client().Current())
+	})
+
+	t.Run("error - no versions", func(t *testing.T) {
+		m, err := NewManager(NewLedgerClock(0))
+		require.Error(t, err)
+		require.Nil(t, m)
+		require.Contains(t, err.Error(), "at least one protocol version is required")
+	})
+
+	t.Run("error - version not defined for transaction time", func(t *testing.T) {
+		m, err := NewManager(NewLedgerClock(0), NewVersion(protocol.Protocol{StartingBlockChainTime: 100}))
+		require.NoError(t, err)
+
+		v, err := m.Get(10)
+		require.Error(t, err)
+		require.Nil(t, v)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [10]")
+	})
+
+	t.Run("error - no version in force at the current ledger time", func(t *testing.T) {
+		clock := NewLedgerClock(10)
+
+		m, err := NewManager(clock, NewVersion(protocol.Protocol{StartingBlockChainTime: 100}))
+		require.NoError(t, err)
+
+		v, err := m.Current()
+		require.Error(t, err)
+		require.Nil(t, v)
+		require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [10]")
+
+		clock.Set(100)
+
+		v, err = m.Current()
+		require.NoError(t, err)
+		require.Equal(t, uint(100), v.Protocol().StartingBlockChainTime)
+	})
+}
+
+func TestManagerProvider(t *testing.T) {
+	m, err := NewManager(NewLedgerClock(0), NewVersion(protocol.Protocol{}))
+	require.NoError(t, err)
+
+	p := NewManagerProvider()
+	p.Add(ns, m)
+
+	vm, err := p.ForNamespace(ns)
+	require.NoError(t, err)
+	require.Equal(t, m, vm)
+
+	vm, err = p.ForNamespace("did:other")
+	require.Error(t, err)
+	require.Nil(t, vm)
+	require.Contains(t, err.Error(), "version manager not found for namespace [did:other]")
+}
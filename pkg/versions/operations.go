@@ -0,0 +1,111 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"fmt"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+)
+
+// OperationParser parses operations using the parser of the current protocol version
+type OperationParser struct {
+	vm protocol.VersionManager
+}
+
+// NewOperationParser returns a new operation parser
+func NewOperationParser(vm protocol.VersionManager) *OperationParser {
+	return &OperationParser{vm: vm}
+}
+
+// Parse parses and validates operation
+func (p *OperationParser) Parse(namespace string, operationBuffer []byte) (*batch.Operation, error) {
+	v, err := p.vm.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	return v.OperationParser().Parse(namespace, operationBuffer)
+}
+
+// OperationApplier applies operations using the applier of the protocol version that applies
+// at the operation's transaction time
+type OperationApplier struct {
+	vm protocol.VersionManager
+}
+
+// NewOperationApplier returns a new operation applier
+func NewOperationApplier(vm protocol.VersionManager) *OperationApplier {
+	return &OperationApplier{vm: vm}
+}
+
+// Apply validates the given operation against the resolution model and returns the new resolution model
+func (a *OperationApplier) Apply(op *batch.Operation, rm *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	v, err := a.vm.Get(op.TransactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.OperationApplier().Apply(op, rm)
+}
+
+// OperationHandler creates batch files using the handler of the current protocol version
+type OperationHandler struct {
+	vm protocol.VersionManager
+}
+
+// NewOperationHandler returns a new operation handler
+func NewOperationHandler(vm protocol.VersionManager) *OperationHandler {
+	return &OperationHandler{vm: vm}
+}
+
+// PrepareTxnFiles will create batch files(chunk, map, anchor) from batch operations,
+// store those files in CAS and return anchor string along with the number of operations in the batch
+func (h *OperationHandler) PrepareTxnFiles(ops []*batch.Operation) (string, int, error) {
+	v, err := h.vm.Current()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if v.OperationHandler() == nil {
+		return "", 0, fmt.Errorf("operation handler is not configured for protocol version [%d]", v.Protocol().StartingBlockChainTime)
+	}
+
+	return v.OperationHandler().PrepareTxnFiles(ops)
+}
+
+// OperationProvider assembles operations using the provider of the protocol version that applies
+// at the transaction time in the transaction's namespace
+type OperationProvider struct {
+	vmp protocol.VersionManagerProvider
+}
+
+// NewOperationProvider returns a new operation provider
+func NewOperationProvider(vmp protocol.VersionManagerProvider) *OperationProvider {
+	return &OperationProvider{vmp: vmp}
+}
+
+// GetTxnOperations will read batch files(chunk, map, anchor) and assemble batch operations from those files
+func (p *OperationProvider) GetTxnOperations(sidetreeTxn *txn.SidetreeTxn) ([]*batch.Operation, error) {
+	vm, err := p.vmp.ForNamespace(sidetreeTxn.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := vm.Get(sidetreeTxn.TransactionTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.OperationProvider() == nil {
+		return nil, fmt.Errorf("operation provider is not configured for protocol version [%d]", v.Protocol().StartingBlockChainTime)
+	}
+
+	return v.OperationProvider().GetTxnOperations(sidetreeTxn)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/txn"
+	"github.com/trustbloc/sidetree-core-go/pkg/composer"
+	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/operationapplier"
+)
+
+func TestNewVersion(t *testing.T) {
+	p := protocol.Protocol{StartingBlockChainTime: 10}
+
+	t.Run("defaults", func(t *testing.T) {
+		v := NewVersion(p)
+		require.Equal(t, p, v.Protocol())
+		require.IsType(t, &operation.Parser{}, v.OperationParser())
+		require.IsType(t, &operationapplier.Applier{}, v.OperationApplier())
+		require.IsType(t, &composer.DocumentComposer{}, v.DocumentComposer())
+		require.Nil(t, v.OperationHandler())
+		require.Nil(t, v.OperationProvider())
+	})
+
+	t.Run("options", func(t *testing.T) {
+		parser := &mockParser{}
+		applier := &mockApplier{}
+		dc := composer.New()
+		handler := &mockHandler{}
+		provider := &mockProvider{}
+
+		v := NewVersion(p,
+			WithOperationParser(parser),
+			WithOperationApplier(applier),
+			WithDocumentComposer(dc),
+			WithOperationHandler(handler),
+			WithOperationProvider(provider),
+		)
+
+		require.Equal(t, parser, v.OperationParser())
+		require.Equal(t, applier, v.OperationApplier())
+		require.Equal(t, dc, v.DocumentComposer())
+		require.Equal(t, handler, v.OperationHandler())
+		require.Equal(t, provider, v.OperationProvider())
+	})
+}
+
+func TestOperationParser_Parse(t *testing.T) {
+	v1 := NewVersion(protocol.Protocol{StartingBlockChainTime: 0}, WithOperationParser(&mockParser{err: errors.New("v1 parser")}))
+	v2 := NewVersion(protocol.Protocol{StartingBlockChainTime: 100}, WithOperationParser(&mockParser{}))
+
+	m, err := NewManager(NewLedgerClock(100), v1, v2)
+	require.NoError(t, err)
+
+	op, err := NewOperationParser(m).Parse(ns, []byte("operation"))
+	require.NoError(t, err)
+	require.Equal(t, ns, op.Namespace)
+}
+
+func TestOperationApplier_Apply(t *testing.T) {
+	v1 := NewVersion(protocol.Protocol{StartingBlockChainTime: 10}, WithOperationApplier(&mockApplier{err: errors.New("v1 applier")}))
+	v2 := NewVersion(protocol.Protocol{StartingBlockChainTime: 100}, WithOperationApplier(&mockApplier{}))
+
+	m, err := NewManager(NewLedgerClock(0), v1, v2)
+	require.NoError(t, err)
+
+	applier := NewOperationApplier(m)
+
+	rm, err := applier.Apply(&batch.Operation{TransactionTime: 100}, &protocol.ResolutionModel{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), rm.LastOperationTransactionTime)
+
+	rm, err = applier.Apply(&batch.Operation{TransactionTime: 99}, &protocol.ResolutionModel{})
+	require.Error(t, err)
+	require.Nil(t, rm)
+	require.Contains(t, err.Error(), "v1 applier")
+
+	rm, err = applier.Apply(&batch.Operation{TransactionTime: 9}, &protocol.ResolutionModel{})
+	require.Error(t, err)
+	require.Nil(t, rm)
+	require.Contains(t, err.Error(), "protocol parameters are not defined for transaction time [9]")
+}
+
+func TestOperationHandler_PrepareTxnFiles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		v1 := NewVersion(protocol.Protocol{StartingBlockChainTime: 0}, WithOperationHandler(&mockHandler{anchor: "v1"}))
+		v2 := NewVersion(protocol.Protocol{StartingBlockChainTime: 100}, WithOperationHandler(&mockHandler{anchor: "v2"}))
+
+		m, err := NewManager(NewLedgerClock(100), v1, v2)
+		require.NoError(t, err)
+
+		anchor, n, err := NewOperationHandler(m).PrepareTxnFiles([]*batch.Operation{{}})
+		require.NoError(t, err)
+		require.Equal(t, "v2", anchor)
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("error - handler not configured", func(t *testing.T) {
+		m, err := NewManager(NewLedgerClock(5), NewVersion(protocol.Protocol{StartingBlockChainTime: 5}))
+		require.NoError(t, err)
+
+		anchor, _, err := NewOperationHandler(m).PrepareTxnFiles(nil)
+		require.Error(t, err)
+		require.Empty(t, anchor)
+		require.Contains(t, err.Error(), "operation handler is not configured for protocol version [5]")
+	})
+}
+
+func TestOperationProvider_GetTxnOperations(t *testing.T) {
+	v1 := NewVersion(protocol.Protocol{StartingBlockChainTime: 0}, WithOperationProvider(&mockProvider{numOps: 1}))
+	v2 := NewVersion(protocol.Protocol{StartingBlockChainTime: 100})
+
+	m, err := NewManager(NewLedgerClock(0), v1, v2)
+	require.NoError(t, err)
+
+	vmp := NewManagerProvider()
+	vmp.Add(ns, m)
+
+	provider := NewOperationProvider(vmp)
+
+	t.Run("success", func(t *testing.T) {
+		ops, err := provider.GetTxnOperations(&txn.SidetreeTxn{Namespace: ns, TransactionTime: 99})
+		require.NoError(t, err)
+		require.Len(t, ops, 1)
+	})
+
+	t.Run("error - provider not configured", func(t *testing.T) {
+		ops, err := provider.GetTxnOperations(&txn.SidetreeTxn{Namespace: ns, TransactionTime: 100})
+		require.Error(t, err)
+		require.Nil(t, ops)
+		require.Contains(t, err.Error(), "operation provider is not configured for protocol version [100]")
+	})
+
+	t.Run("error - namespace not found", func(t *testing.T) {
+		ops, err := provider.GetTxnOperations(&txn.SidetreeTxn{Namespace: "did:other", TransactionTime: 100})
+		require.Error(t, err)
+		require.Nil(t, ops)
+		require.Contains(t, err.Error(), "version manager not found for namespace [did:other]")
+	})
+}
+
+type mockParser struct {
+	err error
+}
+
+func (m *mockParser) Parse(namespace string, _ []byte) (*batch.Operation, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &batch.Operation{Namespace: namespace}, nil
+}
+
+type mockApplier struct {
+	err error
+}
+
+func (m *mockApplier) Apply(op *batch.Operation, _ *protocol.ResolutionModel) (*protocol.ResolutionModel, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &protocol.ResolutionModel{LastOperationTransactionTime: op.TransactionTime}, nil
+}
+
+type mockHandler struct {
+	anchor string
+}
+
+func (m *mockHandler) PrepareTxnFiles(ops []*batch.Operation) (string, int, error) {
+	return m.anchor, len(ops), nil
+}
+
+type mockProvider struct {
+	numOps int
+}
+
+func (m *mockProvider) GetTxnOperations(*txn.SidetreeTxn) ([]*batch.Operation, error) {
+	return make([]*batch.Operation, m.numOps), nil
+}
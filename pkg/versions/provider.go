@@ -0,0 +1,46 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+// ClientProvider returns the protocol client for a namespace
+type ClientProvider struct {
+	mutex   sync.RWMutex
+	clients map[string]protocol.Client
+}
+
+// NewClientProvider returns a new protocol client provider
+func NewClientProvider() *ClientProvider {
+	return &ClientProvider{clients: make(map[string]protocol.Client)}
+}
+
+// Add adds the protocol client for the given namespace
+func (p *ClientProvider) Add(namespace string, pc protocol.Client) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.clients[namespace] = pc
+}
+
+// ForNamespace returns the protocol client for the given namespace
+func (p *ClientProvider) ForNamespace(namespace string) (protocol.Client, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	pc, ok := p.clients[namespace]
+	if !ok {
+		return nil, fmt.Errorf("protocol client not found for namespace [%s]", namespace)
+	}
+
+	return pc, nil
+}
@@ -0,0 +1,32 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+)
+
+func TestClientProvider(t *testing.T) {
+	client, err := NewClient(NewLedgerClock(0), protocol.Protocol{})
+	require.NoError(t, err)
+
+	p := NewClientProvider()
+	p.Add(ns, client)
+
+	pc, err := p.ForNamespace(ns)
+	require.NoError(t, err)
+	require.Equal(t, client, pc)
+
+	pc, err = p.ForNamespace("did:other")
+	require.Error(t, err)
+	require.Nil(t, pc)
+	require.Contains(t, err.Error(), "protocol client not found for namespace [did:other]")
+}
@@ -0,0 +1,127 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versions
+
+import (
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/composer"
+	"github.com/trustbloc/sidetree-core-go/pkg/operation"
+	"github.com/trustbloc/sidetree-core-go/pkg/operationapplier"
+)
+
+// Option is a protocol version option
+type Option func(opts *Version)
+
+// Version implements a protocol version. It holds the protocol parameters along with
+// the operation parser, operation applier, document composer, and the handler and provider
+// of batch files(chunk, map, anchor) for that version.
+type Version struct {
+	protocol protocol.Protocol
+	parser   protocol.OperationParser
+	applier  protocol.OperationApplier
+	composer protocol.DocumentComposer
+	handler  protocol.OperationHandler
+	provider protocol.OperationProvider
+}
+
+// NewVersion returns a new protocol version for the given protocol parameters. Unless specified
+// with options, the operation parser, operation applier and document composer default to the
+// implementations in this library. The operation handler and provider have no defaults since
+// they depend on CAS.
+func NewVersion(p protocol.Protocol, opts ...Option) *Version {
+	v := &Version{protocol: p}
+
+	// apply options
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	// the implementations of the version always use its protocol parameters
+	pc := &Client{
+		protocols:  []protocol.Protocol{p},
+		ledgerTime: NewLedgerClock(uint64(p.StartingBlockChainTime)),
+	}
+
+	if v.parser == nil {
+		v.parser = operation.NewParser(pc)
+	}
+
+	if v.composer == nil {
+		v.composer = composer.New()
+	}
+
+	if v.applier == nil {
+		v.applier = operationapplier.New(pc, v.composer)
+	}
+
+	return v
+}
+
+// Protocol returns the protocol parameters
+func (v *Version) Protocol() protocol.Protocol {
+	return v.protocol
+}
+
+// OperationParser returns the operation parser
+func (v *Version) OperationParser() protocol.OperationParser {
+	return v.parser
+}
+
+// OperationApplier returns the operation applier
+func (v *Version) OperationApplier() protocol.OperationApplier {
+	return v.applier
+}
+
+// DocumentComposer returns the document composer
+func (v *Version) DocumentComposer() protocol.DocumentComposer {
+	return v.composer
+}
+
+// OperationHandler returns the handler that creates batch files
+func (v *Version) OperationHandler() protocol.OperationHandler {
+	return v.handler
+}
+
+// OperationProvider returns the provider that assembles operations from batch files
+func (v *Version) OperationProvider() protocol.OperationProvider {
+	return v.provider
+}
+
+// WithOperationParser sets the operation parser
+func WithOperationParser(parser protocol.OperationParser) Option {
+	return func(opts *Version) {
+		opts.parser = parser
+	}
+}
+
+// WithOperationApplier sets the operation applier
+func WithOperationApplier(applier protocol.OperationApplier) Option {
+	return func(opts *Version) {
+		opts.applier = applier
+	}
+}
+
+// WithDocumentComposer sets the document composer
+func WithDocumentComposer(composer protocol.DocumentComposer) Option {
+	return func(opts *Version) {
+		opts.composer = composer
+	}
+}
+
+// WithOperationHandler sets the handler that creates batch files
+func WithOperationHandler(handler protocol.OperationHandler) Option {
+	return func(opts *Version) {
+		opts.handler = handler
+	}
+}
+
+// WithOperationProvider sets the provider that assembles operations from batch files
+func WithOperationProvider(provider protocol.OperationProvider) Option {
+	return func(opts *Version) {
+		opts.provider = provider
+	}
+}